@@ -0,0 +1,269 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// now returns the current time. Tests replace it.
+var now = time.Now
+
+// getCluster fetches the cluster name in the namespace of p.
+func (p *plugin) getCluster(ctx context.Context, name string) (*v1beta1.PostgresCluster, error) {
+	cluster := &v1beta1.PostgresCluster{}
+	err := p.Client.Get(ctx, client.ObjectKey{Namespace: p.Namespace, Name: name}, cluster)
+	return cluster, errors.WithStack(err)
+}
+
+// trigger calls change on cluster, sets annotation to a new value, and sends
+// both to Kubernetes in one patch. The operator acts when the value of the
+// annotation changes.
+func (p *plugin) trigger(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, annotation string,
+	change func(*v1beta1.PostgresCluster),
+) error {
+	before := cluster.DeepCopy()
+	change(cluster)
+
+	annotations := cluster.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[annotation] = now().UTC().Format(time.RFC3339)
+	cluster.SetAnnotations(annotations)
+
+	return errors.WithStack(p.Client.Patch(ctx, cluster, client.MergeFrom(before)))
+}
+
+// defaultRepo returns the name of the first pgBackRest repository of cluster.
+func defaultRepo(cluster *v1beta1.PostgresCluster) string {
+	if repos := cluster.Spec.Backups.PGBackRest.Repos; len(repos) > 0 {
+		return repos[0].Name
+	}
+	return ""
+}
+
+// checkRepo returns an error when cluster has no pgBackRest repository named repo.
+func checkRepo(cluster *v1beta1.PostgresCluster, repo string) error {
+	for _, r := range cluster.Spec.Backups.PGBackRest.Repos {
+		if r.Name == repo {
+			return nil
+		}
+	}
+	return errors.Errorf("cluster %q has no pgBackRest repository %q", cluster.Name, repo)
+}
+
+// backup starts a manual backup by setting the PGBackRestBackup annotation.
+func (p *plugin) backup(ctx context.Context, args []string) error {
+	var options stringsFlag
+	fs := p.flags("backup")
+	repo := fs.String("repo", "", "the pgBackRest repository to back up to; defaults to the one in the spec")
+	fs.Var(&options, "option", "an option for pgbackrest backup, such as --type=full; repeatable")
+
+	name, err := parseCluster(fs, args)
+	if err == nil {
+		err = p.connect()
+	}
+
+	var cluster *v1beta1.PostgresCluster
+	if err == nil {
+		cluster, err = p.getCluster(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep what is already in the spec unless it was given.
+	manual := cluster.Spec.Backups.PGBackRest.Manual
+	if manual == nil {
+		manual = &v1beta1.PGBackRestManualBackup{RepoName: defaultRepo(cluster)}
+	} else {
+		manual = manual.DeepCopy()
+	}
+	if *repo != "" {
+		manual.RepoName = *repo
+	}
+	if len(options) > 0 {
+		manual.Options = options
+	}
+	if err := checkRepo(cluster, manual.RepoName); err != nil {
+		return err
+	}
+
+	err = p.trigger(ctx, cluster, naming.PGBackRestBackup, func(cluster *v1beta1.PostgresCluster) {
+		cluster.Spec.Backups.PGBackRest.Manual = manual
+	})
+	if err == nil {
+		fmt.Fprintf(p.Stdout, "postgrescluster/%s backup requested to %s\n", name, manual.RepoName)
+	}
+	return err
+}
+
+// restore starts an in-place point-in-time restore by setting the
+// PGBackRestRestore annotation.
+func (p *plugin) restore(ctx context.Context, args []string) error {
+	var options stringsFlag
+	fs := p.flags("restore")
+	repo := fs.String("repo", "", "the pgBackRest repository to restore from; defaults to the first one")
+	target := fs.String("target", "", `the time to recover to, such as "2021-06-09 14:15:11-04"`)
+	fs.Var(&options, "option", "another option for pgbackrest restore, such as --set=...; repeatable")
+	confirm := fs.Bool("yes", false, "confirm that the data of the cluster will be replaced")
+
+	name, err := parseCluster(fs, args)
+	if err == nil && *target == "" {
+		err = errors.New("--target is required")
+	}
+	if err == nil && !*confirm {
+		err = errors.New("a restore replaces the data of every instance; pass --yes to continue")
+	}
+	if err == nil {
+		err = p.connect()
+	}
+
+	var cluster *v1beta1.PostgresCluster
+	if err == nil {
+		cluster, err = p.getCluster(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *repo == "" {
+		*repo = defaultRepo(cluster)
+	}
+	if err := checkRepo(cluster, *repo); err != nil {
+		return err
+	}
+
+	source := &v1beta1.PostgresClusterDataSource{
+		RepoName: *repo,
+		Options: append([]string{
+			"--type=time",
+			"--target=" + strconv.Quote(*target),
+		}, options...),
+	}
+
+	err = p.trigger(ctx, cluster, naming.PGBackRestRestore, func(cluster *v1beta1.PostgresCluster) {
+		cluster.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+			Enabled:                   initialize.Bool(true),
+			PostgresClusterDataSource: source,
+		}
+	})
+	if err == nil {
+		fmt.Fprintf(p.Stdout, "postgrescluster/%s restore to %q requested from %s\n", name, *target, *repo)
+	}
+	return err
+}
+
+// switchover changes the primary by setting the PatroniSwitchover annotation.
+func (p *plugin) switchover(ctx context.Context, args []string) error {
+	fs := p.flags("switchover")
+	to := fs.String("to", "", "the instance to promote; defaults to any healthy replica")
+
+	name, err := parseCluster(fs, args)
+	if err == nil {
+		err = p.connect()
+	}
+
+	var cluster *v1beta1.PostgresCluster
+	if err == nil {
+		cluster, err = p.getCluster(ctx, name)
+	}
+	if err == nil && *to != "" {
+		err = p.checkInstance(ctx, cluster, *to)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = p.trigger(ctx, cluster, naming.PatroniSwitchover, func(cluster *v1beta1.PostgresCluster) {
+		if cluster.Spec.Patroni == nil {
+			cluster.Spec.Patroni = &v1beta1.PatroniSpec{}
+		}
+		cluster.Spec.Patroni.Switchover = &v1beta1.PatroniSwitchover{Enabled: true}
+		if *to != "" {
+			cluster.Spec.Patroni.Switchover.TargetInstance = initialize.String(*to)
+		}
+	})
+	if err == nil {
+		fmt.Fprintf(p.Stdout, "postgrescluster/%s switchover requested\n", name)
+	}
+	return err
+}
+
+// checkInstance returns an error when cluster has no replica instance named instance.
+func (p *plugin) checkInstance(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instance string,
+) error {
+	pods, err := p.instancePods(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels[naming.LabelInstance] != instance {
+			continue
+		}
+		if pod.Labels[naming.LabelRole] == naming.RolePatroniLeader {
+			return errors.Errorf("instance %q is already the primary", instance)
+		}
+		return nil
+	}
+	return errors.Errorf("cluster %q has no instance %q", cluster.Name, instance)
+}
+
+// instancePods returns the Postgres pods of cluster.
+func (p *plugin) instancePods(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*corev1.PodList, error) {
+	selector, err := naming.AsSelector(naming.ClusterInstances(cluster.Name))
+
+	pods := &corev1.PodList{}
+	if err == nil {
+		err = p.Client.List(ctx, pods,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabelsSelector{Selector: selector})
+	}
+	return pods, errors.WithStack(err)
+}
+
+// primaryPod returns the pod of the primary instance of cluster.
+func (p *plugin) primaryPod(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*corev1.Pod, error) {
+	pods, err := p.instancePods(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Labels[naming.LabelRole] == naming.RolePatroniLeader {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, errors.Errorf("cluster %q has no primary", cluster.Name)
+}
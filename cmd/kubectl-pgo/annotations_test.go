@@ -0,0 +1,120 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func testCluster() *v1beta1.PostgresCluster {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1"}, {Name: "repo2"},
+	}
+	return cluster
+}
+
+func testPod(name, instance, role string) *corev1.Pod {
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", name
+	pod.Labels = map[string]string{
+		naming.LabelCluster:  "hippo",
+		naming.LabelInstance: instance,
+		naming.LabelRole:     role,
+	}
+	return pod
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC) }
+
+	cluster := testCluster()
+	cluster.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{
+		RepoName: "repo1", Options: []string{"--type=full"},
+	}
+	p, stdout := testPlugin(t, cluster)
+
+	assert.NilError(t, p.backup(ctx, []string{"hippo", "--repo=repo2"}))
+	assert.Equal(t, stdout.String(), "postgrescluster/hippo backup requested to repo2\n")
+
+	assert.NilError(t, p.Client.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+	assert.Equal(t, cluster.Annotations[naming.PGBackRestBackup], "2022-03-04T05:06:07Z")
+	assert.DeepEqual(t, cluster.Spec.Backups.PGBackRest.Manual, &v1beta1.PGBackRestManualBackup{
+		RepoName: "repo2", Options: []string{"--type=full"},
+	})
+
+	assert.ErrorContains(t, p.backup(ctx, []string{"hippo", "--repo=repo9"}),
+		`no pgBackRest repository "repo9"`)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	cluster := testCluster()
+	p, _ := testPlugin(t, cluster)
+
+	assert.ErrorContains(t, p.restore(ctx, []string{"hippo", "--yes"}), "--target")
+	assert.ErrorContains(t, p.restore(ctx,
+		[]string{"hippo", "--target=2022-03-04 05:06:07+00"}), "--yes")
+
+	assert.NilError(t, p.restore(ctx,
+		[]string{"hippo", "--target=2022-03-04 05:06:07+00", "--option=--set=x", "--yes"}))
+
+	assert.NilError(t, p.Client.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+	assert.Assert(t, cluster.Annotations[naming.PGBackRestRestore] != "")
+	assert.DeepEqual(t, cluster.Spec.Backups.PGBackRest.Restore, &v1beta1.PGBackRestRestore{
+		Enabled: initialize.Bool(true),
+		PostgresClusterDataSource: &v1beta1.PostgresClusterDataSource{
+			RepoName: "repo1",
+			Options: []string{
+				"--type=time", `--target="2022-03-04 05:06:07+00"`, "--set=x",
+			},
+		},
+	})
+}
+
+func TestSwitchover(t *testing.T) {
+	ctx := context.Background()
+	cluster := testCluster()
+	p, _ := testPlugin(t, cluster,
+		testPod("hippo-a-0", "hippo-a", naming.RolePatroniLeader),
+		testPod("hippo-b-0", "hippo-b", naming.RolePatroniReplica))
+
+	assert.ErrorContains(t, p.switchover(ctx, []string{"hippo", "--to=hippo-c"}),
+		`no instance "hippo-c"`)
+	assert.ErrorContains(t, p.switchover(ctx, []string{"hippo", "--to=hippo-a"}),
+		"already the primary")
+
+	assert.NilError(t, p.switchover(ctx, []string{"hippo", "--to=hippo-b"}))
+
+	assert.NilError(t, p.Client.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+	assert.Assert(t, cluster.Annotations[naming.PatroniSwitchover] != "")
+	assert.DeepEqual(t, cluster.Spec.Patroni.Switchover, &v1beta1.PatroniSwitchover{
+		Enabled: true, TargetInstance: initialize.String("hippo-b"),
+	})
+}
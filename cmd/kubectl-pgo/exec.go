@@ -0,0 +1,129 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+)
+
+// executor returns a function that calls exec in the database container of pod.
+// It has the signature of both postgres.Executor and patroni.Executor.
+func (p *plugin) executor(pod *corev1.Pod) func(
+	context.Context, io.Reader, io.Writer, io.Writer, ...string,
+) error {
+	return func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		return p.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase,
+			stdin, stdout, stderr, command...)
+	}
+}
+
+// show prints information about a cluster. "show config" prints the dynamic
+// configuration that Patroni stores for the cluster.
+func (p *plugin) show(ctx context.Context, args []string) error {
+	fs := p.flags("show config")
+
+	positional, err := parse(fs, args)
+	if err == nil && (len(positional) != 2 || positional[0] != "config") {
+		err = errors.Errorf("expected \"config\" and one cluster name, got %q", positional)
+	}
+	if err == nil {
+		err = p.connect()
+	}
+	if err != nil {
+		return err
+	}
+
+	cluster, err := p.getCluster(ctx, positional[1])
+	if err != nil {
+		return err
+	}
+
+	pod, err := p.primaryPod(ctx, cluster)
+	if err == nil {
+		err = p.executor(pod)(ctx, nil, p.Stdout, p.Stderr, "patronictl", "show-config")
+	}
+	return errors.WithStack(err)
+}
+
+// psql starts an interactive psql on the primary of a cluster. Arguments
+// after "--" are passed to psql.
+func (p *plugin) psql(ctx context.Context, args []string) error {
+	fs := p.flags("psql")
+
+	positional, err := parse(fs, args)
+	if err == nil && len(positional) < 1 {
+		err = errors.New("expected one cluster name")
+	}
+	if err == nil {
+		err = p.connect()
+	}
+	if err != nil {
+		return err
+	}
+
+	cluster, err := p.getCluster(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	pod, err := p.primaryPod(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	// PodExecutor does not allocate a terminal, so let kubectl do that.
+	command := exec.CommandContext(ctx, "kubectl", psqlArgs(p, pod, terminal(p.Stdin), positional[1:])...)
+	command.Stdin, command.Stdout, command.Stderr = p.Stdin, p.Stdout, p.Stderr
+	return errors.WithStack(command.Run())
+}
+
+// psqlArgs returns the arguments to "kubectl" that start psql in pod.
+func psqlArgs(p *plugin, pod *corev1.Pod, tty bool, args []string) []string {
+	result := []string{}
+	if p.Kubeconfig != "" {
+		result = append(result, "--kubeconfig="+p.Kubeconfig)
+	}
+
+	result = append(result, "exec", "--stdin")
+	if tty {
+		result = append(result, "--tty")
+	}
+
+	result = append(result,
+		"--namespace="+pod.Namespace, pod.Name,
+		"--container="+naming.ContainerDatabase, "--", "psql")
+
+	return append(result, args...)
+}
+
+// terminal returns whether r is a terminal.
+func terminal(r io.Reader) bool {
+	if f, ok := r.(*os.File); ok {
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+)
+
+func TestShowConfig(t *testing.T) {
+	ctx := context.Background()
+	p, stdout := testPlugin(t, testCluster(),
+		testPod("hippo-a-0", "hippo-a", naming.RolePatroniLeader),
+		testPod("hippo-b-0", "hippo-b", naming.RolePatroniReplica))
+
+	p.PodExec = func(
+		namespace, pod, container string,
+		stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		assert.Equal(t, namespace, "ns1")
+		assert.Equal(t, pod, "hippo-a-0")
+		assert.Equal(t, container, "database")
+		assert.DeepEqual(t, command, []string{"patronictl", "show-config"})
+		_, err := io.WriteString(stdout, "loop_wait: 10\n")
+		return err
+	}
+
+	assert.ErrorContains(t, p.show(ctx, []string{"hippo"}), `expected "config"`)
+	assert.NilError(t, p.show(ctx, []string{"config", "hippo"}))
+	assert.Equal(t, stdout.String(), "loop_wait: 10\n")
+}
+
+func TestPSQLArgs(t *testing.T) {
+	pod := testPod("hippo-a-0", "hippo-a", naming.RolePatroniLeader)
+
+	assert.Equal(t,
+		strings.Join(psqlArgs(&plugin{}, pod, true, nil), " "),
+		"exec --stdin --tty --namespace=ns1 hippo-a-0 --container=database -- psql")
+
+	assert.Equal(t,
+		strings.Join(psqlArgs(&plugin{Kubeconfig: "kc"}, pod, false, []string{"-c", "SELECT 1"}), " "),
+		"--kubeconfig=kc exec --stdin --namespace=ns1 hippo-a-0 --container=database -- psql -c SELECT 1")
+}
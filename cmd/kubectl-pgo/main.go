@@ -0,0 +1,203 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+)
+
+const usage = `kubectl-pgo performs day-2 operations on PostgresClusters.
+
+Usage:
+  kubectl pgo <command> CLUSTER [flags]
+
+Commands:
+  backup       Start a manual pgBackRest backup
+  restore      Restore a cluster in place to a point in time
+  switchover   Change the primary instance of a cluster
+  status       Show the instances, roles, lag, and last backup of a cluster
+  show config  Show the Patroni configuration of a cluster
+  psql         Start psql on the primary instance of a cluster
+  support      Write an archive of diagnostics for a cluster
+
+Every command accepts:
+  -n, --namespace   the namespace of the cluster
+      --kubeconfig  the kubeconfig file to use
+`
+
+// plugin holds what every command needs to reach a cluster.
+type plugin struct {
+	Client     client.Client
+	Config     *rest.Config
+	Kubeconfig string
+	Namespace  string
+	PodExec    runtime.PodExecutor
+	PodLogs    func(context.Context, string, string, *corev1.PodLogOptions) (io.ReadCloser, error)
+
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+// commands are the subcommands of the plugin by name.
+var commands = map[string]func(*plugin, context.Context, []string) error{
+	"backup":     (*plugin).backup,
+	"psql":       (*plugin).psql,
+	"restore":    (*plugin).restore,
+	"show":       (*plugin).show,
+	"status":     (*plugin).status,
+	"support":    (*plugin).support,
+	"switchover": (*plugin).switchover,
+}
+
+func main() {
+	p := &plugin{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Fprint(p.Stdout, usage)
+		return
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(p.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := command(p, context.Background(), os.Args[2:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(p.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// flags returns a FlagSet for the command name that includes the flags every
+// command accepts.
+func (p *plugin) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("kubectl pgo "+name, flag.ContinueOnError)
+	fs.SetOutput(p.Stderr)
+	fs.StringVar(&p.Namespace, "n", p.Namespace, "the namespace of the cluster")
+	fs.StringVar(&p.Namespace, "namespace", p.Namespace, "the namespace of the cluster")
+	fs.StringVar(&p.Kubeconfig, "kubeconfig", p.Kubeconfig, "the kubeconfig file to use")
+	return fs
+}
+
+// parse parses args with fs and returns the arguments that are not flags.
+// Unlike fs.Parse, flags may come after other arguments. Everything after
+// "--" is returned as-is.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// parseCluster parses args with fs and returns the one cluster name in them.
+func parseCluster(fs *flag.FlagSet, args []string) (string, error) {
+	positional, err := parse(fs, args)
+	if err == nil && len(positional) != 1 {
+		err = errors.Errorf("expected one cluster name, got %q", positional)
+	}
+	if err != nil {
+		return "", err
+	}
+	return positional[0], nil
+}
+
+// connect fills in the parts of p that talk to Kubernetes. Parts that are
+// already set, such as in tests, are kept.
+func (p *plugin) connect() error {
+	if p.Client != nil {
+		return nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = p.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{}
+	overrides.Context.Namespace = p.Namespace
+
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	config, err := loader.ClientConfig()
+
+	if err == nil {
+		p.Config = config
+		p.Namespace, _, err = loader.Namespace()
+	}
+
+	if err == nil {
+		p.Client, err = newClient(config)
+	}
+	if err == nil {
+		p.PodExec, err = runtime.NewPodExecutor(config)
+	}
+
+	var clientset kubernetes.Interface
+	if err == nil {
+		clientset, err = kubernetes.NewForConfig(config)
+	}
+	if err == nil {
+		p.PodLogs = podLogs(clientset)
+	}
+
+	return err
+}
+
+// newClient returns a client that knows the PostgresCluster API.
+func newClient(config *rest.Config) (client.Client, error) {
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(config, client.Options{Scheme: scheme})
+}
+
+// stringsFlag is a flag.Value that collects every occurrence of a flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, " ") }
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		args       []string
+		positional []string
+		repo       string
+	}{
+		{args: []string{"hippo"}, positional: []string{"hippo"}},
+		{args: []string{"hippo", "--repo=repo2"}, positional: []string{"hippo"}, repo: "repo2"},
+		{args: []string{"--repo", "repo2", "hippo"}, positional: []string{"hippo"}, repo: "repo2"},
+		{args: []string{"-n", "ns", "config", "hippo"}, positional: []string{"config", "hippo"}},
+		{
+			args:       []string{"hippo", "--", "-c", "--repo=x"},
+			positional: []string{"hippo", "-c", "--repo=x"},
+		},
+	} {
+		p := &plugin{Stderr: io.Discard}
+		fs := p.flags("test")
+		repo := fs.String("repo", "", "")
+
+		positional, err := parse(fs, tt.args)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, positional, tt.positional)
+		assert.Equal(t, *repo, tt.repo)
+	}
+
+	p := &plugin{Stderr: io.Discard}
+	_, err := parseCluster(p.flags("test"), []string{"one", "two"})
+	assert.ErrorContains(t, err, "one cluster name")
+
+	_, err = parse(p.flags("test"), []string{"--unknown"})
+	assert.ErrorContains(t, err, "not defined")
+}
+
+// testPlugin returns a plugin that uses a fake client with objects in the
+// "ns1" namespace.
+func testPlugin(t *testing.T, objects ...client.Object) (*plugin, *bytes.Buffer) {
+	t.Helper()
+
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	var stdout bytes.Buffer
+	return &plugin{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Namespace: "ns1",
+		Stdout:    &stdout,
+		Stderr:    io.Discard,
+	}, &stdout
+}
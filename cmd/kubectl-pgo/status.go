@@ -0,0 +1,145 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// status prints the instances of a cluster, their roles and replication lag,
+// and the last backup that completed.
+func (p *plugin) status(ctx context.Context, args []string) error {
+	fs := p.flags("status")
+
+	name, err := parseCluster(fs, args)
+	if err == nil {
+		err = p.connect()
+	}
+
+	var cluster *v1beta1.PostgresCluster
+	if err == nil {
+		cluster, err = p.getCluster(ctx, name)
+	}
+
+	var pods *corev1.PodList
+	if err == nil {
+		pods, err = p.instancePods(ctx, cluster)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the primary knows how far behind each replica is. Print what is
+	// known when it cannot be asked.
+	var replicas map[string]postgres.ReplicaStatus
+	if primary, _ := p.primaryPod(ctx, cluster); primary != nil {
+		replicas, err = postgres.ReplicaStatuses(ctx, p.executor(primary))
+		if err != nil {
+			fmt.Fprintln(p.Stderr, "warning: unable to get replication lag:", err)
+		}
+	}
+
+	writeStatus(p.Stdout, cluster, pods.Items, replicas)
+	return nil
+}
+
+// writeStatus prints the status of cluster and its instance pods to w.
+func writeStatus(
+	w io.Writer, cluster *v1beta1.PostgresCluster,
+	pods []corev1.Pod, replicas map[string]postgres.ReplicaStatus,
+) {
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE\tPOD\tROLE\tREADY\tLAG")
+
+	for i := range pods {
+		pod := &pods[i]
+
+		role := pod.Labels[naming.LabelRole]
+		switch role {
+		case naming.RolePatroniLeader:
+			role = "primary"
+		case "":
+			role = "unknown"
+		}
+
+		lag := ""
+		if status, ok := replicas[pod.Name]; ok {
+			if status.Lag < 0 {
+				lag = status.State
+			} else {
+				lag = resource.NewQuantity(status.Lag, resource.BinarySI).String()
+			}
+		} else if role == naming.RolePatroniReplica && replicas != nil {
+			lag = "not streaming"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n",
+			pod.Labels[naming.LabelInstance], pod.Name, role, podReady(pod), lag)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Last backup:", lastBackup(cluster))
+}
+
+// podReady returns whether the Ready condition of pod is true.
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// lastBackup describes the most recent backup of cluster that completed.
+func lastBackup(cluster *v1beta1.PostgresCluster) string {
+	var completed *metav1.Time
+	var description string
+
+	if status := cluster.Status.PGBackRest; status != nil {
+		if manual := status.ManualBackup; manual != nil && manual.CompletionTime != nil {
+			completed, description = manual.CompletionTime, "manual"
+		}
+		for _, scheduled := range status.ScheduledBackups {
+			if scheduled.CompletionTime != nil &&
+				(completed == nil || completed.Before(scheduled.CompletionTime)) {
+				completed = scheduled.CompletionTime
+				description = fmt.Sprintf("scheduled %s to %s", scheduled.Type, scheduled.RepoName)
+			}
+		}
+	}
+
+	if completed == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%s (%s)", completed.UTC().Format(time.RFC3339), description)
+}
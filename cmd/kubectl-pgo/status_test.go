@@ -0,0 +1,80 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestWriteStatus(t *testing.T) {
+	cluster := testCluster()
+
+	primary := testPod("hippo-a-0", "hippo-a", naming.RolePatroniLeader)
+	primary.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+	}
+	replica := testPod("hippo-b-0", "hippo-b", naming.RolePatroniReplica)
+	stopped := testPod("hippo-c-0", "hippo-c", naming.RolePatroniReplica)
+
+	var out bytes.Buffer
+	writeStatus(&out, cluster, []corev1.Pod{*stopped, *replica, *primary},
+		map[string]postgres.ReplicaStatus{
+			"hippo-b-0": {State: "streaming", Lag: 16 << 20},
+		})
+
+	assert.Equal(t, out.String(), strings.Join([]string{
+		"INSTANCE  POD        ROLE     READY  LAG",
+		"hippo-a   hippo-a-0  primary  true   ",
+		"hippo-b   hippo-b-0  replica  false  16Mi",
+		"hippo-c   hippo-c-0  replica  false  not streaming",
+		"",
+		"Last backup: none",
+		"",
+	}, "\n"))
+}
+
+func TestLastBackup(t *testing.T) {
+	cluster := testCluster()
+	assert.Equal(t, lastBackup(cluster), "none")
+
+	at := func(hour int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2022, 3, 4, hour, 0, 0, 0, time.UTC)}
+	}
+
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		ManualBackup: &v1beta1.PGBackRestJobStatus{CompletionTime: at(2)},
+		ScheduledBackups: []v1beta1.PGBackRestScheduledBackupStatus{
+			{RepoName: "repo1", Type: "full", CompletionTime: at(1)},
+			{RepoName: "repo1", Type: "incr", CompletionTime: at(3)},
+			{RepoName: "repo1", Type: "diff"},
+		},
+	}
+	assert.Equal(t, lastBackup(cluster), "2022-03-04T03:00:00Z (scheduled incr to repo1)")
+
+	cluster.Status.PGBackRest.ScheduledBackups = nil
+	assert.Equal(t, lastBackup(cluster), "2022-03-04T02:00:00Z (manual)")
+}
@@ -0,0 +1,400 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// redacted replaces every value of a Secret in a support bundle.
+const redacted = "<redacted>"
+
+// supportLists are the kinds of object that a support bundle includes. They
+// are the kinds the operator owns, plus Pods.
+func supportLists() []client.ObjectList {
+	return []client.ObjectList{
+		&corev1.ConfigMapList{},
+		&corev1.EndpointsList{},
+		&corev1.PersistentVolumeClaimList{},
+		&corev1.PodList{},
+		&corev1.SecretList{},
+		&corev1.ServiceList{},
+		&corev1.ServiceAccountList{},
+		&appsv1.DeploymentList{},
+		&appsv1.StatefulSetList{},
+		&batchv1.JobList{},
+		&batchv1beta1.CronJobList{},
+		&policyv1beta1.PodDisruptionBudgetList{},
+		&rbacv1.RoleList{},
+		&rbacv1.RoleBindingList{},
+	}
+}
+
+// bundle writes files to a gzipped tar archive. Problems collecting a file do
+// not stop the bundle; they are written to "errors.txt" at the end.
+type bundle struct {
+	dir      string
+	modified time.Time
+	problems []string
+	tar      *tar.Writer
+}
+
+// add writes data to the archive as name within the directory of the bundle.
+func (b *bundle) add(name string, data []byte) error {
+	err := b.tar.WriteHeader(&tar.Header{
+		Name:    path.Join(b.dir, name),
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: b.modified,
+	})
+	if err == nil {
+		_, err = b.tar.Write(data)
+	}
+	return errors.WithStack(err)
+}
+
+// collect adds the output of fn to the archive as name. When fn fails, its
+// error is kept for "errors.txt" along with whatever it wrote.
+func (b *bundle) collect(name string, fn func(io.Writer) error) error {
+	var buffer bytes.Buffer
+	if err := fn(&buffer); err != nil {
+		b.problems = append(b.problems, fmt.Sprintf("%s: %v", name, err))
+		if buffer.Len() == 0 {
+			return nil
+		}
+	}
+	return b.add(name, buffer.Bytes())
+}
+
+// support writes an archive of everything needed to diagnose a cluster.
+func (p *plugin) support(ctx context.Context, args []string) error {
+	fs := p.flags("support")
+	output := fs.String("output", "", "the file to write; defaults to CLUSTER-support-TIME.tar.gz")
+	since := fs.Duration("since", 0, "only include logs newer than this, such as 6h; defaults to all")
+
+	name, err := parseCluster(fs, args)
+	if err == nil {
+		err = p.connect()
+	}
+
+	var cluster *v1beta1.PostgresCluster
+	if err == nil {
+		cluster, err = p.getCluster(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	started := now().UTC()
+	dir := fmt.Sprintf("%s-support-%s", name, started.Format("20060102T150405Z"))
+	if *output == "" {
+		*output = dir + ".tar.gz"
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	compressed := gzip.NewWriter(file)
+	b := &bundle{
+		dir:      dir,
+		modified: started,
+		tar:      tar.NewWriter(compressed),
+	}
+
+	err = p.writeSupport(ctx, b, cluster, *since)
+
+	if err == nil {
+		err = b.tar.Close()
+	}
+	if err == nil {
+		err = compressed.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, problem := range b.problems {
+		fmt.Fprintln(p.Stderr, "warning:", problem)
+	}
+	fmt.Fprintf(p.Stdout, "postgrescluster/%s support bundle written to %s\n", name, *output)
+	return nil
+}
+
+// writeSupport adds the objects, logs, commands, and events of cluster to b.
+func (p *plugin) writeSupport(
+	ctx context.Context, b *bundle, cluster *v1beta1.PostgresCluster, since time.Duration,
+) error {
+	// Objects of the cluster and the UIDs of those objects for finding events.
+	uids := map[types.UID]bool{cluster.UID: true}
+	err := b.add("postgrescluster.yaml", p.supportYAML(cluster))
+
+	var pods []corev1.Pod
+	for _, list := range supportLists() {
+		if err != nil {
+			break
+		}
+		objects, kind, listErr := p.supportObjects(ctx, cluster, list)
+		if listErr != nil {
+			b.problems = append(b.problems, fmt.Sprintf("%s: %v", kind, listErr))
+		}
+
+		for _, object := range objects {
+			uids[object.GetUID()] = true
+			if pod, ok := object.(*corev1.Pod); ok {
+				pods = append(pods, *pod)
+			}
+			if err == nil {
+				err = b.add(path.Join(strings.ToLower(kind), object.GetName()+".yaml"),
+					p.supportYAML(object))
+			}
+		}
+	}
+
+	if err == nil {
+		err = b.collect("events.yaml", func(w io.Writer) error {
+			return p.supportEvents(ctx, cluster.Namespace, uids, w)
+		})
+	}
+
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for i := range pods {
+		if err == nil {
+			err = p.supportLogs(ctx, b, &pods[i], since)
+		}
+	}
+
+	if err == nil {
+		err = p.supportPrimary(ctx, b, cluster)
+	}
+
+	if err == nil && len(b.problems) > 0 {
+		err = b.add("errors.txt", []byte(strings.Join(b.problems, "\n")+"\n"))
+	}
+	return err
+}
+
+// supportObjects lists the objects of cluster into list and returns them with
+// their kind. Secrets are redacted.
+func (p *plugin) supportObjects(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, list client.ObjectList,
+) ([]client.Object, string, error) {
+	gvk, err := apiutil.GVKForObject(list, p.Client.Scheme())
+	kind := strings.TrimSuffix(gvk.Kind, "List")
+
+	var selector labels.Selector
+	if err == nil {
+		selector, err = naming.AsSelector(naming.Cluster(cluster.Name))
+	}
+	if err == nil {
+		err = p.Client.List(ctx, list,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	var objects []client.Object
+	if err == nil {
+		err = meta.EachListItem(list, func(item runtime.Object) error {
+			object := item.(client.Object)
+			object.GetObjectKind().SetGroupVersionKind(gvk.GroupVersion().WithKind(kind))
+			if secret, ok := object.(*corev1.Secret); ok {
+				redactSecret(secret)
+			}
+			objects = append(objects, object)
+			return nil
+		})
+	}
+
+	return objects, kind, errors.WithStack(err)
+}
+
+// redactSecret removes the values of secret but keeps their keys.
+func redactSecret(secret *corev1.Secret) {
+	values := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for key := range secret.Data {
+		values[key] = redacted
+	}
+	for key := range secret.StringData {
+		values[key] = redacted
+	}
+	secret.Data, secret.StringData = nil, values
+
+	// Clients like "kubectl apply" keep a copy of the whole object here.
+	delete(secret.Annotations, corev1.LastAppliedConfigAnnotation)
+}
+
+// supportYAML returns object as YAML without its managed fields.
+func (p *plugin) supportYAML(object client.Object) []byte {
+	object = object.DeepCopyObject().(client.Object)
+	object.SetManagedFields(nil)
+
+	if gvk, err := apiutil.GVKForObject(object, p.Client.Scheme()); err == nil {
+		object.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+
+	data, err := yaml.Marshal(object)
+	if err != nil {
+		data = []byte(fmt.Sprintf("# %v\n", err))
+	}
+	return data
+}
+
+// supportEvents writes events about objects with uids to w, oldest first.
+func (p *plugin) supportEvents(
+	ctx context.Context, namespace string, uids map[types.UID]bool, w io.Writer,
+) error {
+	var events corev1.EventList
+	if err := p.Client.List(ctx, &events, client.InNamespace(namespace)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var selected []corev1.Event
+	for _, event := range events.Items {
+		if uids[event.InvolvedObject.UID] {
+			event.ManagedFields = nil
+			selected = append(selected, event)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].LastTimestamp.Before(&selected[j].LastTimestamp)
+	})
+
+	data, err := yaml.Marshal(selected)
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	return errors.WithStack(err)
+}
+
+// supportLogs adds the logs of every container in pod to b. When a container
+// has restarted, the logs of its previous run are added too.
+func (p *plugin) supportLogs(
+	ctx context.Context, b *bundle, pod *corev1.Pod, since time.Duration,
+) error {
+	restarts := make(map[string]int32)
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		restarts[status.Name] = status.RestartCount
+	}
+
+	var err error
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		for _, previous := range []bool{false, true} {
+			if err != nil || (previous && restarts[container.Name] == 0) {
+				continue
+			}
+
+			options := &corev1.PodLogOptions{Container: container.Name, Previous: previous}
+			if since > 0 {
+				seconds := int64(since.Seconds())
+				options.SinceSeconds = &seconds
+			}
+
+			name := container.Name + ".log"
+			if previous {
+				name = container.Name + ".previous.log"
+			}
+
+			err = b.collect(path.Join("logs", pod.Name, name), func(w io.Writer) error {
+				stream, err := p.PodLogs(ctx, pod.Namespace, pod.Name, options)
+				if err == nil {
+					_, err = io.Copy(w, stream)
+					_ = stream.Close()
+				}
+				return errors.WithStack(err)
+			})
+		}
+	}
+	return err
+}
+
+// supportPrimary adds the output of Patroni, pgBackRest, and PostgreSQL
+// commands on the primary of cluster to b.
+func (p *plugin) supportPrimary(
+	ctx context.Context, b *bundle, cluster *v1beta1.PostgresCluster,
+) error {
+	primary, err := p.primaryPod(ctx, cluster)
+	if err != nil {
+		b.problems = append(b.problems, err.Error())
+		return nil
+	}
+	exec := p.executor(primary)
+
+	err = b.collect("patronictl-list.txt", func(w io.Writer) error {
+		return exec(ctx, nil, w, w, "patronictl", "list")
+	})
+	if err == nil {
+		err = b.collect("pgbackrest-info.txt", func(w io.Writer) error {
+			return exec(ctx, nil, w, w, "pgbackrest", "info")
+		})
+	}
+	if err == nil {
+		err = b.collect("postgres-settings.txt", func(w io.Writer) error {
+			stdout, stderr, err := postgres.Executor(exec).Exec(ctx, strings.NewReader(`
+SELECT name, setting, unit, source
+  FROM pg_catalog.pg_settings
+ ORDER BY name;
+`), nil)
+			_, _ = io.WriteString(w, stdout)
+			return errors.Wrap(err, strings.TrimSpace(stderr))
+		})
+	}
+	return err
+}
+
+// podLogs returns a function that streams the logs of a pod using clientset.
+func podLogs(clientset kubernetes.Interface) func(
+	context.Context, string, string, *corev1.PodLogOptions,
+) (io.ReadCloser, error) {
+	return func(
+		ctx context.Context, namespace, pod string, options *corev1.PodLogOptions,
+	) (io.ReadCloser, error) {
+		return clientset.CoreV1().Pods(namespace).GetLogs(pod, options).Stream(ctx)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+)
+
+func TestRedactSecret(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.Annotations = map[string]string{
+		corev1.LastAppliedConfigAnnotation: `{"data":{"password":"c2VjcmV0"}}`,
+		"other":                            "kept",
+	}
+	secret.Data = map[string][]byte{"password": []byte("secret")}
+	secret.StringData = map[string]string{"uri": "postgres://secret"}
+
+	redactSecret(secret)
+
+	assert.Assert(t, secret.Data == nil)
+	assert.DeepEqual(t, secret.StringData, map[string]string{
+		"password": redacted,
+		"uri":      redacted,
+	})
+	assert.DeepEqual(t, secret.Annotations, map[string]string{"other": "kept"})
+}
+
+func TestSupport(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC) }
+
+	cluster := testCluster()
+	cluster.UID = "cluster-uid"
+
+	secret := &corev1.Secret{}
+	secret.Namespace, secret.Name, secret.UID = "ns1", "hippo-pguser-hippo", "secret-uid"
+	secret.Labels = map[string]string{naming.LabelCluster: "hippo"}
+	secret.Data = map[string][]byte{"password": []byte("swordfish")}
+
+	primary := testPod("hippo-a-0", "hippo-a", naming.RolePatroniLeader)
+	primary.Spec.Containers = []corev1.Container{{Name: "database"}}
+	primary.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "database", RestartCount: 1}}
+
+	other := &corev1.ConfigMap{}
+	other.Namespace, other.Name = "ns1", "not-hippo"
+
+	event := &corev1.Event{}
+	event.Namespace, event.Name = "ns1", "hippo.1"
+	event.InvolvedObject.UID = "cluster-uid"
+	event.Message = "something happened"
+
+	unrelated := &corev1.Event{}
+	unrelated.Namespace, unrelated.Name = "ns1", "other.1"
+	unrelated.InvolvedObject.UID = "other-uid"
+
+	p, stdout := testPlugin(t, cluster, secret, primary, other, event, unrelated)
+
+	p.PodExec = func(
+		namespace, pod, container string,
+		stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		switch command[0] {
+		case "patronictl":
+			_, _ = io.WriteString(stdout, "patroni members\n")
+		case "pgbackrest":
+			_, _ = io.WriteString(stderr, "no repository\n")
+			return errors.New("exit status 1")
+		case "psql":
+			_, _ = io.WriteString(stdout, "settings\n")
+		}
+		return nil
+	}
+	p.PodLogs = func(
+		_ context.Context, namespace, pod string, options *corev1.PodLogOptions,
+	) (io.ReadCloser, error) {
+		if options.Previous {
+			return ioutil.NopCloser(strings.NewReader("before restart\n")), nil
+		}
+		return ioutil.NopCloser(strings.NewReader("after restart\n")), nil
+	}
+
+	output := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.NilError(t, p.support(ctx, []string{"hippo", "--output=" + output}))
+	assert.Equal(t, stdout.String(),
+		"postgrescluster/hippo support bundle written to "+output+"\n")
+
+	// Read every file in the archive.
+	files := make(map[string]string)
+	{
+		file, err := os.Open(output)
+		assert.NilError(t, err)
+		defer file.Close()
+
+		compressed, err := gzip.NewReader(file)
+		assert.NilError(t, err)
+
+		archive := tar.NewReader(compressed)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NilError(t, err)
+
+			data, err := ioutil.ReadAll(archive)
+			assert.NilError(t, err)
+			files[strings.TrimPrefix(header.Name, "hippo-support-20220304T050607Z/")] = string(data)
+		}
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.DeepEqual(t, names, []string{
+		"errors.txt",
+		"events.yaml",
+		"logs/hippo-a-0/database.log",
+		"logs/hippo-a-0/database.previous.log",
+		"patronictl-list.txt",
+		"pgbackrest-info.txt",
+		"pod/hippo-a-0.yaml",
+		"postgres-settings.txt",
+		"postgrescluster.yaml",
+		"secret/hippo-pguser-hippo.yaml",
+	})
+
+	assert.Assert(t, strings.Contains(files["postgrescluster.yaml"], "kind: PostgresCluster"))
+	assert.Assert(t, strings.Contains(files["secret/hippo-pguser-hippo.yaml"], "password: <redacted>"))
+	assert.Assert(t, !strings.Contains(files["secret/hippo-pguser-hippo.yaml"], "swordfish"))
+
+	assert.Assert(t, strings.Contains(files["events.yaml"], "something happened"))
+	assert.Assert(t, !strings.Contains(files["events.yaml"], "other-uid"))
+
+	assert.Equal(t, files["logs/hippo-a-0/database.previous.log"], "before restart\n")
+	assert.Equal(t, files["patronictl-list.txt"], "patroni members\n")
+	assert.Equal(t, files["pgbackrest-info.txt"], "no repository\n")
+	assert.Equal(t, files["errors.txt"], "pgbackrest-info.txt: exit status 1\n")
+}
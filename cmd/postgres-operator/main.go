@@ -28,6 +28,8 @@ import (
 
 	"github.com/adifri/postgres-operator/v5/internal/controller/postgrescluster"
 	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/controller/standalone_pgadmin"
+	"github.com/adifri/postgres-operator/v5/internal/credentials"
 	"github.com/adifri/postgres-operator/v5/internal/logging"
 	"github.com/adifri/postgres-operator/v5/internal/upgradecheck"
 	"github.com/adifri/postgres-operator/v5/internal/util"
@@ -79,9 +81,15 @@ func main() {
 	// deprecation warnings when using an older version of a resource for backwards compatibility).
 	rest.SetDefaultWarningHandler(rest.NoWarnings{})
 
-	mgr, err := runtime.CreateRuntimeManager(os.Getenv("PGO_TARGET_NAMESPACE"), cfg, false)
+	// Configure health probes and leader election so that more than one
+	// replica of the operator can run at a time.
+	options, err := runtime.FromEnv(os.Getenv)
 	assertNoError(err)
 
+	mgr, err := runtime.CreateRuntimeManager(os.Getenv("PGO_TARGET_NAMESPACE"), cfg, false, options)
+	assertNoError(err)
+	assertNoError(runtime.AddHealthChecks(mgr))
+
 	// add all PostgreSQL Operator controllers to the runtime manager
 	err = addControllersToManager(ctx, mgr)
 	assertNoError(err)
@@ -109,14 +117,62 @@ func main() {
 // addControllersToManager adds all PostgreSQL Operator controllers to the provided controller
 // runtime manager.
 func addControllersToManager(ctx context.Context, mgr manager.Manager) error {
+	openshift := isOpenshift(ctx, mgr.GetConfig())
+	namespaceScoped := runtime.NamespaceScoped(os.Getenv)
+
 	r := &postgrescluster.Reconciler{
-		Client:      mgr.GetClient(),
-		Owner:       postgrescluster.ControllerName,
-		Recorder:    mgr.GetEventRecorderFor(postgrescluster.ControllerName),
-		Tracer:      otel.Tracer(postgrescluster.ControllerName),
-		IsOpenShift: isOpenshift(ctx, mgr.GetConfig()),
+		Client:          mgr.GetClient(),
+		Owner:           postgrescluster.ControllerName,
+		Recorder:        mgr.GetEventRecorderFor(postgrescluster.ControllerName),
+		Tracer:          otel.Tracer(postgrescluster.ControllerName),
+		IsOpenShift:     openshift,
+		NamespaceScoped: namespaceScoped,
+		CredentialStore: credentialStore(ctx),
+	}
+	if err := r.SetupWithManager(ctx, mgr); err != nil {
+		return err
+	}
+
+	// The webhook server needs a certificate; serve admission webhooks only
+	// when one is configured.
+	if os.Getenv("PGO_WEBHOOK_CERT_DIR") != "" {
+		if err := r.SetupWebhooksWithManager(mgr); err != nil {
+			return err
+		}
+	}
+
+	pgAdminReconciler := &standalone_pgadmin.PGAdminReconciler{
+		Client:          mgr.GetClient(),
+		Owner:           standalone_pgadmin.ControllerName,
+		Recorder:        mgr.GetEventRecorderFor(standalone_pgadmin.ControllerName),
+		IsOpenShift:     openshift,
+		NamespaceScoped: namespaceScoped,
 	}
-	return r.SetupWithManager(mgr)
+	return pgAdminReconciler.SetupWithManager(mgr)
+}
+
+// credentialStore returns the store for the credentials of PostgreSQL users
+// configured in the environment, or nil when there is none.
+func credentialStore(ctx context.Context) credentials.Store {
+	address := os.Getenv("PGO_VAULT_ADDR")
+	if address == "" {
+		return nil
+	}
+
+	vault := &credentials.Vault{
+		Address:   address,
+		Mount:     os.Getenv("PGO_VAULT_KV_MOUNT"),
+		Namespace: os.Getenv("PGO_VAULT_NAMESPACE"),
+	}
+	if path := os.Getenv("PGO_VAULT_TOKEN_FILE"); path != "" {
+		vault.Token = credentials.FileToken(path)
+	} else {
+		token := os.Getenv("PGO_VAULT_TOKEN")
+		vault.Token = func() (string, error) { return token, nil }
+	}
+
+	logging.FromContext(ctx).Info("using Vault credential store", "address", address)
+	return vault
 }
 
 func isOpenshift(ctx context.Context, cfg *rest.Config) bool {
@@ -0,0 +1,114 @@
+//go:build envtest
+// +build envtest
+
+package main
+
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// informerCache is a cache.Cache that remembers the objects it was asked to
+// inform about.
+type informerCache struct {
+	cache.Cache
+
+	mutex   *sync.Mutex
+	objects *[]client.Object
+}
+
+func (c informerCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	c.mutex.Lock()
+	*c.objects = append(*c.objects, obj)
+	c.mutex.Unlock()
+
+	return c.Cache.GetInformer(ctx, obj)
+}
+
+func TestAddControllersToManagerNamespaceSelector(t *testing.T) {
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{filepath.Join("..", "..", "config", "crd", "bases")},
+	}
+	config, err := env.Start()
+	assert.NilError(t, err)
+	t.Cleanup(func() { assert.Check(t, env.Stop()) })
+
+	t.Setenv("PGO_TARGET_NAMESPACE", "")
+	t.Setenv("PGO_TARGET_NAMESPACE_SELECTOR", "tenant-group=blue")
+	t.Setenv("PGO_HEALTH_PROBE_BIND_ADDRESS", "0")
+	t.Setenv("PGO_WEBHOOK_CERT_DIR", "")
+	t.Setenv("PGO_VAULT_ADDR", "")
+
+	options, err := runtime.FromEnv(os.Getenv)
+	assert.NilError(t, err)
+
+	var mutex sync.Mutex
+	var objects []client.Object
+	recording := func(options *manager.Options) {
+		newCache := options.NewCache
+		assert.Assert(t, newCache != nil, "expected a cache for the selector")
+
+		options.NewCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+			c, err := newCache(config, opts)
+			return informerCache{Cache: c, mutex: &mutex, objects: &objects}, err
+		}
+	}
+
+	mgr, err := runtime.CreateRuntimeManager("", config, true, options, recording)
+	assert.NilError(t, err)
+	assert.NilError(t, runtime.AddHealthChecks(mgr))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	assert.NilError(t, addControllersToManager(ctx, mgr))
+
+	started := make(chan error, 1)
+	go func() { started <- mgr.Start(ctx) }()
+	t.Cleanup(func() { cancel(); assert.Check(t, <-started) })
+
+	// Every informer must sync with only the namespaced permissions of
+	// selector mode, so the manager becomes ready.
+	timeout, done := context.WithTimeout(ctx, time.Minute)
+	defer done()
+	assert.Assert(t, mgr.GetCache().WaitForCacheSync(timeout))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	assert.Assert(t, len(objects) > 0)
+	for _, object := range objects {
+		_, catalog := object.(*v1beta1.PostgresImageCatalog)
+		assert.Assert(t, !catalog,
+			"expected no watch of cluster-scoped image catalogs, got %T", object)
+	}
+}
@@ -14,7 +14,7 @@
 # limitations under the License.
 
 directory=$( cd "$( dirname "${BASH_SOURCE[0]}" )" && pwd )
-crd_build_dir="$directory"/../build/crd
+crd_build_dir="$directory"/../build/crd/postgresclusters
 
 # Generate a Kustomize patch file for removing any TODOs we inherit from the Kubernetes API.
 # Right now there are two TODOs in our CRD. This script focuses on removing these specific TODOs
@@ -38,9 +38,27 @@ func defaultFromEnv(value, key string) string {
 // - https://redhat-connect.gitbook.io/certified-operator-guide/troubleshooting-and-resources/offline-enabled-operators
 // - https://osbs.readthedocs.io/en/latest/users.html#pullspec-locations
 
+// catalogImages returns the images of cluster that came from its image
+// catalog, if any.
+func catalogImages(cluster *v1beta1.PostgresCluster) v1beta1.PostgresImageCatalogStatus {
+	if cluster.Status.ImageCatalog != nil {
+		return *cluster.Status.ImageCatalog
+	}
+	return v1beta1.PostgresImageCatalogStatus{}
+}
+
+// defaultFromCatalog returns the first of value and catalog that is not empty.
+func defaultFromCatalog(value, catalog string) string {
+	if value == "" {
+		return catalog
+	}
+	return value
+}
+
 // PGBackRestContainerImage returns the container image to use for pgBackRest.
 func PGBackRestContainerImage(cluster *v1beta1.PostgresCluster) string {
-	image := cluster.Spec.Backups.PGBackRest.Image
+	image := defaultFromCatalog(cluster.Spec.Backups.PGBackRest.Image,
+		catalogImages(cluster).PGBackRest)
 
 	return defaultFromEnv(image, "RELATED_IMAGE_PGBACKREST")
 }
@@ -52,10 +70,17 @@ func PGAdminContainerImage(cluster *v1beta1.PostgresCluster) string {
 		cluster.Spec.UserInterface.PGAdmin != nil {
 		image = cluster.Spec.UserInterface.PGAdmin.Image
 	}
+	image = defaultFromCatalog(image, catalogImages(cluster).PGAdmin)
 
 	return defaultFromEnv(image, "RELATED_IMAGE_PGADMIN")
 }
 
+// StandalonePGAdminContainerImage returns the container image to use for a
+// standalone pgAdmin.
+func StandalonePGAdminContainerImage(pgadmin *v1beta1.PGAdmin) string {
+	return defaultFromEnv(pgadmin.Spec.Image, "RELATED_IMAGE_PGADMIN")
+}
+
 // PGBouncerContainerImage returns the container image to use for pgBouncer.
 func PGBouncerContainerImage(cluster *v1beta1.PostgresCluster) string {
 	var image string
@@ -63,6 +88,7 @@ func PGBouncerContainerImage(cluster *v1beta1.PostgresCluster) string {
 		cluster.Spec.Proxy.PGBouncer != nil {
 		image = cluster.Spec.Proxy.PGBouncer.Image
 	}
+	image = defaultFromCatalog(image, catalogImages(cluster).PGBouncer)
 
 	return defaultFromEnv(image, "RELATED_IMAGE_PGBOUNCER")
 }
@@ -76,13 +102,31 @@ func PGExporterContainerImage(cluster *v1beta1.PostgresCluster) string {
 		cluster.Spec.Monitoring.PGMonitor.Exporter != nil {
 		image = cluster.Spec.Monitoring.PGMonitor.Exporter.Image
 	}
+	image = defaultFromCatalog(image, catalogImages(cluster).PGExporter)
 
 	return defaultFromEnv(image, "RELATED_IMAGE_PGEXPORTER")
 }
 
 // PostgresContainerImage returns the container image to use for PostgreSQL.
+// When a change to that image was rolled back, it returns the image from
+// before the change.
 func PostgresContainerImage(cluster *v1beta1.PostgresCluster) string {
-	image := cluster.Spec.Image
+	image := SpecifiedPostgresContainerImage(cluster)
+
+	if n := len(cluster.Status.ImageRollouts); n > 0 {
+		latest := cluster.Status.ImageRollouts[n-1]
+		if latest.Phase == v1beta1.PostgresImageRolloutRolledBack && latest.ToImage == image {
+			return latest.FromImage
+		}
+	}
+
+	return image
+}
+
+// SpecifiedPostgresContainerImage returns the container image for PostgreSQL
+// from the spec, image catalog, or environment of cluster.
+func SpecifiedPostgresContainerImage(cluster *v1beta1.PostgresCluster) string {
+	image := defaultFromCatalog(cluster.Spec.Image, catalogImages(cluster).Postgres)
 	key := "RELATED_IMAGE_POSTGRES_" + fmt.Sprint(cluster.Spec.PostgresVersion)
 
 	if version := cluster.Spec.PostGISVersion; version != "" {
@@ -61,12 +61,30 @@ func TestPGAdminContainerImage(t *testing.T) {
 	setEnv(t, "RELATED_IMAGE_PGADMIN", "env-var-pgadmin")
 	assert.Equal(t, PGAdminContainerImage(cluster), "env-var-pgadmin")
 
+	cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{PGAdmin: "catalog-image"}
+	assert.Equal(t, PGAdminContainerImage(cluster), "catalog-image")
+
 	assert.NilError(t, yaml.Unmarshal([]byte(`{
 		userInterface: { pgAdmin: { image: spec-image } },
 	}`), &cluster.Spec))
 	assert.Equal(t, PGAdminContainerImage(cluster), "spec-image")
 }
 
+func TestStandalonePGAdminContainerImage(t *testing.T) {
+	pgadmin := &v1beta1.PGAdmin{}
+
+	unsetEnv(t, "RELATED_IMAGE_PGADMIN")
+	assert.Equal(t, StandalonePGAdminContainerImage(pgadmin), "")
+
+	setEnv(t, "RELATED_IMAGE_PGADMIN", "env-var-pgadmin")
+	assert.Equal(t, StandalonePGAdminContainerImage(pgadmin), "env-var-pgadmin")
+
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		image: spec-image,
+	}`), &pgadmin.Spec))
+	assert.Equal(t, StandalonePGAdminContainerImage(pgadmin), "spec-image")
+}
+
 func TestPGBackRestContainerImage(t *testing.T) {
 	cluster := &v1beta1.PostgresCluster{}
 
@@ -79,6 +97,9 @@ func TestPGBackRestContainerImage(t *testing.T) {
 	setEnv(t, "RELATED_IMAGE_PGBACKREST", "env-var-pgbackrest")
 	assert.Equal(t, PGBackRestContainerImage(cluster), "env-var-pgbackrest")
 
+	cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{PGBackRest: "catalog-image"}
+	assert.Equal(t, PGBackRestContainerImage(cluster), "catalog-image")
+
 	assert.NilError(t, yaml.Unmarshal([]byte(`{
 		backups: { pgBackRest: { image: spec-image } },
 	}`), &cluster.Spec))
@@ -97,6 +118,9 @@ func TestPGBouncerContainerImage(t *testing.T) {
 	setEnv(t, "RELATED_IMAGE_PGBOUNCER", "env-var-pgbouncer")
 	assert.Equal(t, PGBouncerContainerImage(cluster), "env-var-pgbouncer")
 
+	cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{PGBouncer: "catalog-image"}
+	assert.Equal(t, PGBouncerContainerImage(cluster), "catalog-image")
+
 	assert.NilError(t, yaml.Unmarshal([]byte(`{
 		proxy: { pgBouncer: { image: spec-image } },
 	}`), &cluster.Spec))
@@ -115,6 +139,9 @@ func TestPGExporterContainerImage(t *testing.T) {
 	setEnv(t, "RELATED_IMAGE_PGEXPORTER", "env-var-pgexporter")
 	assert.Equal(t, PGExporterContainerImage(cluster), "env-var-pgexporter")
 
+	cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{PGExporter: "catalog-image"}
+	assert.Equal(t, PGExporterContainerImage(cluster), "catalog-image")
+
 	assert.NilError(t, yaml.Unmarshal([]byte(`{
 		monitoring: { pgMonitor: { exporter: { image: spec-image } } },
 	}`), &cluster.Spec))
@@ -134,10 +161,14 @@ func TestPostgresContainerImage(t *testing.T) {
 	setEnv(t, "RELATED_IMAGE_POSTGRES_12", "env-var-postgres")
 	assert.Equal(t, PostgresContainerImage(cluster), "env-var-postgres")
 
+	cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{Postgres: "catalog-image"}
+	assert.Equal(t, PostgresContainerImage(cluster), "catalog-image")
+
 	cluster.Spec.Image = "spec-image"
 	assert.Equal(t, PostgresContainerImage(cluster), "spec-image")
 
 	cluster.Spec.Image = ""
+	cluster.Status.ImageCatalog = nil
 	cluster.Spec.PostGISVersion = "3.0"
 	setEnv(t, "RELATED_IMAGE_POSTGRES_12_GIS_3.0", "env-var-postgis")
 	assert.Equal(t, PostgresContainerImage(cluster), "env-var-postgis")
@@ -145,3 +176,21 @@ func TestPostgresContainerImage(t *testing.T) {
 	cluster.Spec.Image = "spec-image"
 	assert.Equal(t, PostgresContainerImage(cluster), "spec-image")
 }
+
+func TestPostgresContainerImageRolledBack(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Spec.Image = "new-image"
+	cluster.Status.ImageRollouts = []v1beta1.PostgresImageRolloutStatus{{
+		FromImage: "old-image", ToImage: "new-image",
+		Phase: v1beta1.PostgresImageRolloutProgressing,
+	}}
+	assert.Equal(t, PostgresContainerImage(cluster), "new-image")
+
+	cluster.Status.ImageRollouts[0].Phase = v1beta1.PostgresImageRolloutRolledBack
+	assert.Equal(t, PostgresContainerImage(cluster), "old-image")
+	assert.Equal(t, SpecifiedPostgresContainerImage(cluster), "new-image")
+
+	// A different image is rolled out again.
+	cluster.Spec.Image = "newer-image"
+	assert.Equal(t, PostgresContainerImage(cluster), "newer-image")
+}
@@ -0,0 +1,279 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/adifri/postgres-operator/v5/internal/patroni"
+	"github.com/adifri/postgres-operator/v5/internal/pgbouncer"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// DefaultingWebhookPath is where the webhook server fills in default
+	// values of PostgresClusters.
+	DefaultingWebhookPath = "/mutate-postgres-operator-crunchydata-com-v1beta1-postgrescluster"
+
+	// ValidatingWebhookPath is where the webhook server validates
+	// PostgresClusters.
+	ValidatingWebhookPath = "/validate-postgres-operator-crunchydata-com-v1beta1-postgrescluster"
+)
+
+// SetupWebhooksWithManager adds the defaulting and validating webhooks for
+// PostgresClusters to the webhook server of mgr.
+func (r *Reconciler) SetupWebhooksWithManager(mgr manager.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err == nil {
+		server := mgr.GetWebhookServer()
+		server.Register(DefaultingWebhookPath,
+			&webhook.Admission{Handler: &clusterDefaulter{decoder: decoder}})
+		server.Register(ValidatingWebhookPath,
+			&webhook.Admission{Handler: &clusterValidator{
+				decoder: decoder, namespaceScoped: r.NamespaceScoped,
+			}})
+	}
+	return err
+}
+
+// clusterDefaulter is an admission.Handler that fills in the default values
+// of PostgresClusters.
+type clusterDefaulter struct{ decoder *admission.Decoder }
+
+// Handle implements admission.Handler.
+func (d *clusterDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	cluster := &v1beta1.PostgresCluster{}
+	if err := d.decoder.Decode(req, cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	cluster.Default()
+
+	marshaled, err := json.Marshal(cluster)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// clusterValidator is an admission.Handler that rejects PostgresClusters that
+// PGO cannot reconcile.
+type clusterValidator struct {
+	decoder *admission.Decoder
+
+	// namespaceScoped is true when PGO cannot read cluster-scoped objects.
+	namespaceScoped bool
+}
+
+// Handle implements admission.Handler.
+func (v *clusterValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	cluster := &v1beta1.PostgresCluster{}
+	if err := v.decoder.Decode(req, cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var errs field.ErrorList
+	switch req.Operation {
+	case admissionv1.Create:
+		errs = append(validateCluster(cluster), v.validateScope(cluster)...)
+
+	case admissionv1.Update:
+		old := &v1beta1.PostgresCluster{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		errs = validateClusterUpdate(old, cluster)
+		if !equality.Semantic.DeepEqual(old.Spec, cluster.Spec) {
+			errs = append(errs, v.validateScope(cluster)...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return admission.Allowed("")
+	}
+
+	status := apierrors.NewInvalid(
+		v1beta1.GroupVersion.WithKind("PostgresCluster").GroupKind(), cluster.Name, errs)
+	return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{
+		Allowed: false, Result: &status.ErrStatus,
+	}}
+}
+
+// validateScope returns the problems in the spec of cluster that come from
+// what PGO is allowed to read. A namespace-scoped PGO has only a Role, which
+// cannot grant access to cluster-scoped PostgresImageCatalogs.
+func (v *clusterValidator) validateScope(cluster *v1beta1.PostgresCluster) field.ErrorList {
+	var errs field.ErrorList
+	if v.namespaceScoped && cluster.Spec.ImageCatalog != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "imageCatalog"),
+			"image catalogs require PGO to be installed for the whole Kubernetes cluster"))
+	}
+	return errs
+}
+
+// validateCluster returns the problems in the spec of cluster that PGO would
+// otherwise find only while reconciling it.
+func validateCluster(cluster *v1beta1.PostgresCluster) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	// Backups, restores, and standby clusters refer to repositories by name.
+	repos := sets.NewString()
+	for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		repos.Insert(repo.Name)
+	}
+	repoExists := func(path *field.Path, name string) {
+		if name != "" && !repos.Has(name) {
+			errs = append(errs, field.NotFound(path, name))
+		}
+	}
+
+	pgbackrest := spec.Child("backups", "pgbackrest")
+	if manual := cluster.Spec.Backups.PGBackRest.Manual; manual != nil {
+		repoExists(pgbackrest.Child("manual", "repoName"), manual.RepoName)
+	}
+	if restore := cluster.Spec.Backups.PGBackRest.Restore; restore != nil &&
+		restore.PostgresClusterDataSource != nil &&
+		(restore.ClusterName == "" || restore.ClusterName == cluster.Name) &&
+		(restore.ClusterNamespace == "" || restore.ClusterNamespace == cluster.Namespace) {
+		repoExists(pgbackrest.Child("restore", "repoName"), restore.RepoName)
+	}
+
+	if standby := cluster.Spec.Standby; standby != nil && standby.Enabled {
+		if standby.Host == "" && standby.RepoName == "" {
+			errs = append(errs, field.Required(spec.Child("standby"),
+				"Standby requires a host or repoName to be enabled"))
+		}
+		repoExists(spec.Child("standby", "repoName"), standby.RepoName)
+	}
+
+	// A failover must name the instance to promote, and any target must be
+	// an instance of this cluster.
+	if patroniSpec := cluster.Spec.Patroni; patroniSpec != nil &&
+		patroniSpec.Switchover != nil && patroniSpec.Switchover.Enabled {
+		switchover := patroniSpec.Switchover
+		path := spec.Child("patroni", "switchover", "targetInstance")
+
+		if switchover.TargetInstance == nil || *switchover.TargetInstance == "" {
+			if switchover.Type == v1beta1.PatroniSwitchoverTypeFailover {
+				errs = append(errs, field.Required(path,
+					"TargetInstance required when running failover"))
+			}
+		} else {
+			var found bool
+			for _, set := range cluster.Spec.InstanceSets {
+				found = found || strings.HasPrefix(*switchover.TargetInstance,
+					cluster.Name+"-"+set.Name+"-")
+			}
+			if !found {
+				errs = append(errs, field.Invalid(path, *switchover.TargetInstance,
+					"must be an instance of one of spec.instances"))
+			}
+		}
+	}
+
+	// The canary of a rollout must be one of the instance sets.
+	if policy := cluster.Spec.RolloutPolicy; policy != nil && policy.Canary != "" &&
+		!instanceSetExists(cluster, policy.Canary) {
+		errs = append(errs, field.NotFound(
+			spec.Child("rolloutPolicy", "canary"), policy.Canary))
+	}
+
+	// Replicas replay CREATE TABLESPACE from the primary, so every instance
+	// set must mount the same tablespace volumes.
+	if instanceSets := cluster.Spec.InstanceSets; len(instanceSets) > 1 {
+		first := tablespaceNames(&instanceSets[0])
+		for i := range instanceSets[1:] {
+			if names := tablespaceNames(&instanceSets[i+1]); !names.Equal(first) {
+				errs = append(errs, field.Invalid(
+					spec.Child("instances").Index(i+1).Child("tablespaceVolumes"),
+					names.List(), fmt.Sprintf(
+						"must define the same tablespace volumes as spec.instances[0]: %q",
+						first.List())))
+			}
+		}
+	}
+
+	// PostgreSQL verifies client certificates with the authority that PGO
+	// generates, which is not used with a custom TLS secret.
+	if cluster.Spec.CustomTLSSecret != nil {
+		for i, user := range cluster.Spec.Users {
+			if user.Certificate != nil {
+				errs = append(errs, field.Forbidden(
+					spec.Child("users").Index(i).Child("certificate"),
+					"client certificates are not available with spec.customTLSSecret"))
+			}
+		}
+	}
+
+	// PgBouncer cannot use pools that conflict with its other settings.
+	errs = append(errs, pgbouncer.ValidatePools(cluster)...)
+
+	if err := postgres.CustomHBAs(cluster, &postgres.HBAs{}); err != nil {
+		if aggregate, ok := err.(utilerrors.Aggregate); ok {
+			for _, err := range aggregate.Errors() {
+				if fieldErr, ok := err.(*field.Error); ok {
+					errs = append(errs, fieldErr)
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateClusterUpdate returns the problems in changing the spec of old to
+// that of cluster. Changes to metadata alone are always allowed so that
+// existing clusters can be deleted.
+func validateClusterUpdate(old, cluster *v1beta1.PostgresCluster) field.ErrorList {
+	if equality.Semantic.DeepEqual(old.Spec, cluster.Spec) {
+		return nil
+	}
+
+	errs := validateCluster(cluster)
+	spec := field.NewPath("spec")
+
+	// The data source is used only to initialize the cluster.
+	if patroni.ClusterBootstrapped(old) &&
+		!equality.Semantic.DeepEqual(old.Spec.DataSource, cluster.Spec.DataSource) {
+		errs = append(errs, field.Forbidden(spec.Child("dataSource"),
+			"cannot be changed after the cluster is initialized"))
+	}
+
+	// PostgreSQL cannot read data files of a later major version.
+	if cluster.Spec.PostgresVersion < old.Spec.PostgresVersion {
+		errs = append(errs, field.Forbidden(spec.Child("postgresVersion"),
+			fmt.Sprintf("cannot be downgraded from %d to %d",
+				old.Spec.PostgresVersion, cluster.Spec.PostgresVersion)))
+	}
+
+	return errs
+}
@@ -0,0 +1,370 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	pgoruntime "github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// admissionCluster returns a PostgresCluster that passes validation.
+func admissionCluster() *v1beta1.PostgresCluster {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.PostgresVersion = 14
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "one"}}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1"}, {Name: "repo2"},
+	}
+	return cluster
+}
+
+// fieldErrors validates cluster, or the change to it from old when old is not
+// nil. It returns the field and type of each problem.
+func fieldErrors(cluster *v1beta1.PostgresCluster, old *v1beta1.PostgresCluster) []string {
+	var errs field.ErrorList
+	if old == nil {
+		errs = validateCluster(cluster)
+	} else {
+		errs = validateClusterUpdate(old, cluster)
+	}
+
+	var result []string
+	for _, err := range errs {
+		result = append(result, err.Field+": "+string(err.Type))
+	}
+	return result
+}
+
+func TestValidateCluster(t *testing.T) {
+	assert.Assert(t, len(fieldErrors(admissionCluster(), nil)) == 0)
+
+	for _, tt := range []struct {
+		name     string
+		mutate   func(*v1beta1.PostgresCluster)
+		expected []string
+	}{
+		{
+			name: "ManualRepo",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{RepoName: "repo3"}
+			},
+			expected: []string{"spec.backups.pgbackrest.manual.repoName: FieldValueNotFound"},
+		},
+		{
+			name: "RestoreRepo",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+					PostgresClusterDataSource: &v1beta1.PostgresClusterDataSource{RepoName: "repo4"},
+				}
+			},
+			expected: []string{"spec.backups.pgbackrest.restore.repoName: FieldValueNotFound"},
+		},
+		{
+			name: "RestoreOtherCluster",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+					PostgresClusterDataSource: &v1beta1.PostgresClusterDataSource{
+						ClusterName: "rhino", RepoName: "repo4",
+					},
+				}
+			},
+		},
+		{
+			name: "StandbyNothing",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true}
+			},
+			expected: []string{"spec.standby: FieldValueRequired"},
+		},
+		{
+			name: "StandbyRepo",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, RepoName: "repo3"}
+			},
+			expected: []string{"spec.standby.repoName: FieldValueNotFound"},
+		},
+		{
+			name: "StandbyDisabled",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: false, RepoName: "repo3"}
+			},
+		},
+		{
+			name: "FailoverTarget",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Patroni = &v1beta1.PatroniSpec{Switchover: &v1beta1.PatroniSwitchover{
+					Enabled: true, Type: v1beta1.PatroniSwitchoverTypeFailover,
+				}}
+			},
+			expected: []string{"spec.patroni.switchover.targetInstance: FieldValueRequired"},
+		},
+		{
+			name: "SwitchoverTarget",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Patroni = &v1beta1.PatroniSpec{Switchover: &v1beta1.PatroniSwitchover{
+					Enabled: true, TargetInstance: initialize.String("hippo-two-abcd"),
+				}}
+			},
+			expected: []string{"spec.patroni.switchover.targetInstance: FieldValueInvalid"},
+		},
+		{
+			name: "SwitchoverValid",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Patroni = &v1beta1.PatroniSpec{Switchover: &v1beta1.PatroniSwitchover{
+					Enabled: true, Type: v1beta1.PatroniSwitchoverTypeFailover,
+					TargetInstance: initialize.String("hippo-one-abcd"),
+				}}
+			},
+		},
+		{
+			name: "RolloutCanary",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.RolloutPolicy = &v1beta1.PostgresRolloutPolicySpec{Canary: "two"}
+			},
+			expected: []string{"spec.rolloutPolicy.canary: FieldValueNotFound"},
+		},
+		{
+			name: "TablespaceVolumes",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+					{Name: "one", TablespaceVolumes: []v1beta1.TablespaceVolume{{Name: "fast"}}},
+					{Name: "two", TablespaceVolumes: []v1beta1.TablespaceVolume{{Name: "fast"}}},
+					{Name: "three"},
+				}
+			},
+			expected: []string{"spec.instances[2].tablespaceVolumes: FieldValueInvalid"},
+		},
+		{
+			name: "UserCertificate",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.CustomTLSSecret = &corev1.SecretProjection{}
+				c.Spec.Users = []v1beta1.PostgresUserSpec{
+					{Name: "a"},
+					{Name: "b", Certificate: &v1beta1.PostgresUserCertificateSpec{}},
+				}
+			},
+			expected: []string{"spec.users[1].certificate: FieldValueForbidden"},
+		},
+		{
+			name: "PGBouncerPools",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+				c.Spec.Proxy.PGBouncer.Config.Users = map[string]string{"u": ""}
+				c.Spec.Proxy.PGBouncer.Pools = &v1beta1.PGBouncerPools{
+					Databases: []v1beta1.PGBouncerDatabasePool{
+						{Database: "app"}, {Database: "pgbouncer"},
+					},
+					Users: []v1beta1.PGBouncerUserPool{{User: "u"}},
+				}
+			},
+			expected: []string{
+				"spec.proxy.pgBouncer.pools.databases[1].database: FieldValueInvalid",
+				"spec.proxy.pgBouncer.pools.users[0].user: FieldValueDuplicate",
+			},
+		},
+		{
+			name: "AuthenticationRules",
+			mutate: func(c *v1beta1.PostgresCluster) {
+				c.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+					Rules: []v1beta1.PostgresHBARuleSpec{
+						{Method: "md5", Networks: []string{"nope"}},
+					},
+				}
+			},
+			expected: []string{"spec.authentication.rules[0].networks[0]: FieldValueInvalid"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := admissionCluster()
+			tt.mutate(cluster)
+			assert.DeepEqual(t, fieldErrors(cluster, nil), tt.expected)
+		})
+	}
+}
+
+func TestValidateClusterUpdate(t *testing.T) {
+	old := admissionCluster()
+	old.Spec.DataSource = &v1beta1.DataSource{
+		PostgresCluster: &v1beta1.PostgresClusterDataSource{ClusterName: "rhino", RepoName: "repo1"},
+	}
+
+	t.Run("MetadataOnly", func(t *testing.T) {
+		// An invalid spec does not prevent changes to metadata, like finalizers.
+		old := old.DeepCopy()
+		old.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true}
+		old.Finalizers = []string{naming.Finalizer}
+
+		cluster := old.DeepCopy()
+		cluster.Finalizers = nil
+		assert.Assert(t, len(fieldErrors(cluster, old)) == 0)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		cluster := old.DeepCopy()
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true}
+		assert.DeepEqual(t, fieldErrors(cluster, old), []string{"spec.standby: FieldValueRequired"})
+	})
+
+	t.Run("DataSource", func(t *testing.T) {
+		cluster := old.DeepCopy()
+		cluster.Spec.DataSource = nil
+		assert.Assert(t, len(fieldErrors(cluster, old)) == 0, "expected change before bootstrap")
+
+		bootstrapped := old.DeepCopy()
+		bootstrapped.Status.Patroni.SystemIdentifier = "1234"
+		assert.DeepEqual(t, fieldErrors(cluster, bootstrapped),
+			[]string{"spec.dataSource: FieldValueForbidden"})
+	})
+
+	t.Run("PostgresVersion", func(t *testing.T) {
+		cluster := old.DeepCopy()
+		cluster.Spec.PostgresVersion = 15
+		assert.Assert(t, len(fieldErrors(cluster, old)) == 0)
+
+		cluster.Spec.PostgresVersion = 13
+		assert.DeepEqual(t, fieldErrors(cluster, old),
+			[]string{"spec.postgresVersion: FieldValueForbidden"})
+	})
+}
+
+func TestAdmissionHandlers(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := pgoruntime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+	decoder, err := admission.NewDecoder(scheme)
+	assert.NilError(t, err)
+
+	request := func(t testing.TB, operation admissionv1.Operation, objects ...runtime.Object) admission.Request {
+		raw := make([][]byte, len(objects))
+		for i := range objects {
+			var err error
+			raw[i], err = json.Marshal(objects[i])
+			assert.NilError(t, err)
+		}
+
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			Kind: metav1.GroupVersionKind{
+				Group: v1beta1.GroupVersion.Group, Version: v1beta1.GroupVersion.Version,
+				Kind: "PostgresCluster",
+			},
+		}}
+		req.Object.Raw = raw[0]
+		if len(raw) > 1 {
+			req.OldObject.Raw = raw[1]
+		}
+		return req
+	}
+
+	t.Run("Defaulter", func(t *testing.T) {
+		cluster := admissionCluster()
+		cluster.Spec.InstanceSets[0].Name = ""
+
+		response := (&clusterDefaulter{decoder: decoder}).Handle(ctx,
+			request(t, admissionv1.Create, cluster))
+		assert.Assert(t, response.Allowed)
+
+		var paths []string
+		for _, patch := range response.Patches {
+			paths = append(paths, patch.Path)
+		}
+		joined := strings.Join(paths, " ")
+		assert.Assert(t, strings.Contains(joined, "/spec/instances/0/name"), "got %v", paths)
+		assert.Assert(t, strings.Contains(joined, "/spec/port"), "got %v", paths)
+	})
+
+	t.Run("ValidatorCreate", func(t *testing.T) {
+		validator := &clusterValidator{decoder: decoder}
+
+		response := validator.Handle(ctx, request(t, admissionv1.Create, admissionCluster()))
+		assert.Assert(t, response.Allowed)
+
+		cluster := admissionCluster()
+		cluster.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{RepoName: "repo3"}
+
+		response = validator.Handle(ctx, request(t, admissionv1.Create, cluster))
+		assert.Assert(t, !response.Allowed)
+		assert.Equal(t, response.Result.Code, int32(http.StatusUnprocessableEntity))
+		assert.Assert(t, strings.Contains(response.Result.Message,
+			`spec.backups.pgbackrest.manual.repoName: Not found: "repo3"`), response.Result.Message)
+	})
+
+	t.Run("ValidatorUpdate", func(t *testing.T) {
+		validator := &clusterValidator{decoder: decoder}
+
+		old := admissionCluster()
+		cluster := admissionCluster()
+		cluster.Spec.PostgresVersion = 13
+
+		response := validator.Handle(ctx, request(t, admissionv1.Update, cluster, old))
+		assert.Assert(t, !response.Allowed)
+		assert.Assert(t, strings.Contains(response.Result.Message,
+			"cannot be downgraded from 14 to 13"), response.Result.Message)
+	})
+
+	t.Run("ValidatorNamespaceScoped", func(t *testing.T) {
+		validator := &clusterValidator{decoder: decoder, namespaceScoped: true}
+
+		cluster := admissionCluster()
+		cluster.Spec.ImageCatalog = &v1beta1.PostgresImageCatalogReference{Name: "stable"}
+
+		response := validator.Handle(ctx, request(t, admissionv1.Create, cluster))
+		assert.Assert(t, !response.Allowed)
+		assert.Assert(t, strings.Contains(response.Result.Message,
+			"spec.imageCatalog: Forbidden"), response.Result.Message)
+
+		// Changes to metadata alone are allowed.
+		old := cluster.DeepCopy()
+		cluster.Finalizers = []string{naming.Finalizer}
+		response = validator.Handle(ctx, request(t, admissionv1.Update, cluster, old))
+		assert.Assert(t, response.Allowed)
+
+		cluster.Spec.Port = initialize.Int32(5433)
+		response = validator.Handle(ctx, request(t, admissionv1.Update, cluster, old))
+		assert.Assert(t, !response.Allowed)
+
+		// A cluster-wide operator allows catalogs.
+		validator.namespaceScoped = false
+		response = validator.Handle(ctx, request(t, admissionv1.Create, cluster))
+		assert.Assert(t, response.Allowed)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		req := request(t, admissionv1.Create, admissionCluster())
+		req.Object.Raw = []byte(`{`)
+
+		response := (&clusterValidator{decoder: decoder}).Handle(ctx, req)
+		assert.Assert(t, !response.Allowed)
+		assert.Equal(t, response.Result.Code, int32(http.StatusBadRequest))
+	})
+}
@@ -0,0 +1,334 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// autoGrowInterval is how often filesystem usage is checked when any volume
+// of a cluster can grow automatically.
+const autoGrowInterval = 5 * time.Minute
+
+// diskUsage is the size and used space, in bytes, of one filesystem.
+type diskUsage struct{ size, used int64 }
+
+// percentUsed returns the percentage of the filesystem that is used,
+// rounded down.
+func (u diskUsage) percentUsed() int64 {
+	if u.size <= 0 {
+		return 0
+	}
+	return u.used * 100 / u.size
+}
+
+// parseDiskUsage parses the output of `df --output=size,used` into one
+// diskUsage per line after the header.
+func parseDiskUsage(output string) ([]diskUsage, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return nil, errors.Errorf("unexpected df output: %q", output)
+	}
+
+	usage := make([]diskUsage, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("unexpected df output: %q", line)
+		}
+
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		used, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		usage = append(usage, diskUsage{size: size, used: used})
+	}
+	return usage, nil
+}
+
+// nextVolumeSize returns the size to which a volume of current size should
+// grow according to spec. It returns false when usage is below the threshold.
+// When current is already at the maximum, the returned size equals current.
+func nextVolumeSize(
+	spec *v1beta1.VolumeAutoGrowSpec, current resource.Quantity, usage diskUsage,
+) (resource.Quantity, bool) {
+	threshold, growth := int64(80), int64(25)
+	if spec.ThresholdPercent != nil {
+		threshold = int64(*spec.ThresholdPercent)
+	}
+	if spec.GrowthPercent != nil {
+		growth = int64(*spec.GrowthPercent)
+	}
+
+	if usage.percentUsed() < threshold {
+		return current, false
+	}
+
+	// Grow by a percentage of the current size rounded up to a whole mebibyte
+	// so the result is easy to read.
+	const mebibyte = 1 << 20
+	next := current.Value() + (current.Value()*growth+99)/100
+	next = (next + mebibyte - 1) / mebibyte * mebibyte
+
+	if maximum := spec.MaxSize.Value(); next > maximum {
+		next = maximum
+	}
+	if next <= current.Value() {
+		return current, true
+	}
+	return *resource.NewQuantity(next, resource.BinarySI), true
+}
+
+// autoGrowRequest returns the storage request of a volume defined by spec that
+// has grown to grown, if any. It never returns less than spec requests.
+func autoGrowRequest(
+	spec corev1.PersistentVolumeClaimSpec, grown *resource.Quantity,
+) corev1.PersistentVolumeClaimSpec {
+	requested := spec.Resources.Requests[corev1.ResourceStorage]
+
+	if grown != nil && grown.Cmp(requested) > 0 {
+		// Copy the requests so the cluster spec is not changed.
+		spec.Resources.Requests = spec.Resources.Requests.DeepCopy()
+		spec.Resources.Requests[corev1.ResourceStorage] = grown.DeepCopy()
+	}
+	return spec
+}
+
+// diskUsageInPod returns the filesystem usage of each path in container of pod.
+func (r *Reconciler) diskUsageInPod(
+	pod *corev1.Pod, container string, paths ...string,
+) ([]diskUsage, error) {
+	var stdout, stderr bytes.Buffer
+
+	err := r.PodExec(pod.Namespace, pod.Name, container, nil, &stdout, &stderr,
+		append([]string{"df", "--block-size=1", "--output=size,used", "--"}, paths...)...)
+
+	var usage []diskUsage
+	if err == nil {
+		usage, err = parseDiskUsage(stdout.String())
+	}
+	if err == nil && len(usage) != len(paths) {
+		err = errors.Errorf("expected %d filesystems, got %d", len(paths), len(usage))
+	}
+	if err != nil {
+		err = errors.Wrap(err, stderr.String())
+	}
+	return usage, err
+}
+
+// growVolume updates size when usage crosses the threshold in spec and emits
+// an event describing what happened. The current size of the volume is the
+// larger of requested and size.
+func (r *Reconciler) growVolume(
+	cluster *v1beta1.PostgresCluster, spec *v1beta1.VolumeAutoGrowSpec,
+	description string, requested resource.Quantity, size **resource.Quantity,
+	usage diskUsage,
+) {
+	// Forget the grown size once the spec requests as much.
+	if *size != nil && (*size).Cmp(requested) <= 0 {
+		*size = nil
+	}
+
+	current := requested
+	if *size != nil {
+		current = **size
+	}
+
+	next, over := nextVolumeSize(spec, current, usage)
+	switch {
+	case !over:
+	case next.Cmp(current) > 0:
+		*size = &next
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "VolumeAutoGrow",
+			"Growing %s from %s to %s; filesystem is %d%% full",
+			description, current.String(), next.String(), usage.percentUsed())
+	default:
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "VolumeAutoGrowLimit",
+			"Cannot grow %s beyond %s; filesystem is %d%% full",
+			description, spec.MaxSize.String(), usage.percentUsed())
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources="pods",verbs={list}
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileVolumeAutoGrow checks the filesystem usage of volumes that can grow
+// automatically and records their new size in cluster status. The volumes
+// themselves are resized when they are next applied.
+func (r *Reconciler) reconcileVolumeAutoGrow(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	log := logging.FromContext(ctx)
+	var enabled bool
+
+	for i := range cluster.Spec.InstanceSets {
+		set := &cluster.Spec.InstanceSets[i]
+		if set.DataVolumeAutoGrow == nil && set.WALVolumeAutoGrow == nil {
+			continue
+		}
+		enabled = true
+
+		var status *v1beta1.PostgresInstanceSetStatus
+		for j := range cluster.Status.InstanceSets {
+			if cluster.Status.InstanceSets[j].Name == set.Name {
+				status = &cluster.Status.InstanceSets[j]
+			}
+		}
+		if status == nil {
+			continue
+		}
+
+		// The WAL volume is only mounted when it is defined.
+		paths := []string{"/pgdata"}
+		if set.WALVolumeClaimSpec != nil {
+			paths = append(paths, "/pgwal")
+		}
+
+		// Grow each kind of volume once according to the fullest one.
+		var data, wal diskUsage
+		var measured bool
+		for _, instance := range instances.bySet[set.Name] {
+			running, known := instance.IsRunning(naming.ContainerDatabase)
+			if !running || !known || len(instance.Pods) != 1 {
+				continue
+			}
+
+			usage, err := r.diskUsageInPod(instance.Pods[0], naming.ContainerDatabase, paths...)
+			if err != nil {
+				log.V(1).Info("unable to check filesystem usage",
+					"instance", instance.Name, "error", err.Error())
+				continue
+			}
+
+			measured = true
+			if usage[0].percentUsed() >= data.percentUsed() {
+				data = usage[0]
+			}
+			if len(usage) > 1 && usage[1].percentUsed() >= wal.percentUsed() {
+				wal = usage[1]
+			}
+		}
+		if !measured {
+			continue
+		}
+
+		if spec := set.DataVolumeAutoGrow; spec != nil {
+			r.growVolume(cluster, spec,
+				"data volumes of instance set "+strconv.Quote(set.Name),
+				set.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage],
+				&status.DataVolumeSize, data)
+		}
+		if spec := set.WALVolumeAutoGrow; spec != nil && set.WALVolumeClaimSpec != nil {
+			r.growVolume(cluster, spec,
+				"WAL volumes of instance set "+strconv.Quote(set.Name),
+				set.WALVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage],
+				&status.WALVolumeSize, wal)
+		}
+	}
+
+	// Repository volumes are mounted in the dedicated repository host.
+	var repoHost *corev1.Pod
+	if cluster.Status.PGBackRest != nil {
+		for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+			if repo.Volume == nil || repo.Volume.AutoGrow == nil {
+				continue
+			}
+			enabled = true
+
+			var status *v1beta1.RepoStatus
+			for j := range cluster.Status.PGBackRest.Repos {
+				if cluster.Status.PGBackRest.Repos[j].Name == repo.Name {
+					status = &cluster.Status.PGBackRest.Repos[j]
+				}
+			}
+			if status == nil {
+				continue
+			}
+
+			if repoHost == nil {
+				pods := &corev1.PodList{}
+				if err := errors.WithStack(r.Client.List(ctx, pods,
+					client.InNamespace(cluster.Namespace),
+					client.MatchingLabelsSelector{
+						Selector: naming.PGBackRestDedicatedSelector(cluster.Name),
+					})); err != nil {
+					return reconcile.Result{}, err
+				}
+				for j := range pods.Items {
+					for _, container := range pods.Items[j].Status.ContainerStatuses {
+						if container.Name == naming.PGBackRestRepoContainerName &&
+							container.State.Running != nil &&
+							pods.Items[j].DeletionTimestamp == nil {
+							repoHost = &pods.Items[j]
+						}
+					}
+				}
+				if repoHost == nil {
+					break
+				}
+			}
+
+			usage, err := r.diskUsageInPod(repoHost,
+				naming.PGBackRestRepoContainerName, "/pgbackrest/"+repo.Name)
+			if err != nil {
+				log.V(1).Info("unable to check filesystem usage",
+					"repo", repo.Name, "error", err.Error())
+				continue
+			}
+
+			r.growVolume(cluster, repo.Volume.AutoGrow,
+				"volume of repository "+strconv.Quote(repo.Name),
+				repo.Volume.VolumeClaimSpec.Resources.Requests[corev1.ResourceStorage],
+				&status.VolumeSize, usage[0])
+		}
+	}
+
+	if !enabled {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{RequeueAfter: autoGrowInterval}, nil
+}
+
+// instanceSetStatus returns the status of the instance set named name. It
+// returns an empty status when there is none.
+func instanceSetStatus(
+	cluster *v1beta1.PostgresCluster, name string,
+) v1beta1.PostgresInstanceSetStatus {
+	for _, status := range cluster.Status.InstanceSets {
+		if status.Name == name {
+			return status
+		}
+	}
+	return v1beta1.PostgresInstanceSetStatus{Name: name}
+}
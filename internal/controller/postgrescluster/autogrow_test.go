@@ -0,0 +1,224 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/testing/cmp"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestParseDiskUsage(t *testing.T) {
+	usage, err := parseDiskUsage(`
+     1B-blocks        Used
+   10725883904  8580707124
+    1063256064     1048576
+`)
+	assert.NilError(t, err)
+	assert.Equal(t, len(usage), 2)
+	assert.Equal(t, usage[0], diskUsage{size: 10725883904, used: 8580707124})
+	assert.Equal(t, usage[1], diskUsage{size: 1063256064, used: 1048576})
+	assert.Equal(t, usage[0].percentUsed(), int64(80))
+	assert.Equal(t, usage[1].percentUsed(), int64(0))
+
+	_, err = parseDiskUsage("")
+	assert.ErrorContains(t, err, "unexpected")
+
+	_, err = parseDiskUsage("1B-blocks Used\nabc 123\n")
+	assert.ErrorContains(t, err, "invalid syntax")
+}
+
+func TestNextVolumeSize(t *testing.T) {
+	spec := &v1beta1.VolumeAutoGrowSpec{MaxSize: resource.MustParse("2Gi")}
+	spec.Default()
+
+	const gibibyte = 1 << 30
+	current := resource.MustParse("1Gi")
+
+	next, over := nextVolumeSize(spec, current, diskUsage{size: gibibyte, used: gibibyte / 2})
+	assert.Assert(t, !over)
+	assert.Equal(t, next.String(), "1Gi")
+
+	next, over = nextVolumeSize(spec, current, diskUsage{size: gibibyte, used: gibibyte * 9 / 10})
+	assert.Assert(t, over)
+	assert.Equal(t, next.String(), "1280Mi")
+
+	// Growth stops at the maximum.
+	next, over = nextVolumeSize(spec, resource.MustParse("1800Mi"), diskUsage{size: 100, used: 90})
+	assert.Assert(t, over)
+	assert.Equal(t, next.String(), "2Gi")
+
+	next, over = nextVolumeSize(spec, resource.MustParse("2Gi"), diskUsage{size: 100, used: 90})
+	assert.Assert(t, over)
+	assert.Equal(t, next.String(), "2Gi")
+
+	// Small volumes grow by at least a mebibyte.
+	spec.GrowthPercent = initialize.Int32(1)
+	next, _ = nextVolumeSize(spec, resource.MustParse("10Mi"), diskUsage{size: 100, used: 100})
+	assert.Equal(t, next.String(), "11Mi")
+}
+
+func TestAutoGrowRequest(t *testing.T) {
+	spec := corev1.PersistentVolumeClaimSpec{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+
+	assert.DeepEqual(t, autoGrowRequest(spec, nil), spec)
+
+	smaller := resource.MustParse("512Mi")
+	assert.DeepEqual(t, autoGrowRequest(spec, &smaller), spec)
+
+	larger := resource.MustParse("2Gi")
+	grown := autoGrowRequest(spec, &larger)
+	assert.Assert(t, cmp.MarshalMatches(grown.Resources, `
+requests:
+  storage: 2Gi
+	`))
+	assert.Assert(t, cmp.MarshalMatches(spec.Resources, `
+requests:
+  storage: 1Gi
+	`), "expected spec to be unchanged")
+}
+
+func TestReconcileVolumeAutoGrow(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name: "00",
+		DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}}
+	cluster.Status.InstanceSets = []v1beta1.PostgresInstanceSetStatus{{Name: "00"}}
+
+	pod := func(name string) corev1.Pod {
+		pod := corev1.Pod{}
+		pod.Namespace = "ns1"
+		pod.Name = name + "-0"
+		pod.Labels = map[string]string{
+			naming.LabelInstanceSet: "00",
+			naming.LabelInstance:    name,
+		}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: naming.ContainerDatabase,
+			State: corev1.ContainerState{
+				Running: new(corev1.ContainerStateRunning),
+			},
+		}}
+		return pod
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{Recorder: recorder}
+	reconciler.Client = fake.NewClientBuilder().Build()
+
+	usage := map[string]string{
+		"one-0": "1B-blocks Used\n100 50\n",
+		"two-0": "1B-blocks Used\n100 85\n",
+	}
+	reconciler.PodExec = func(
+		namespace, pod, container string,
+		_ io.Reader, stdout, _ io.Writer, command ...string,
+	) error {
+		assert.Equal(t, container, naming.ContainerDatabase)
+		assert.DeepEqual(t, command,
+			[]string{"df", "--block-size=1", "--output=size,used", "--", "/pgdata"})
+		_, err := io.WriteString(stdout, usage[pod])
+		return err
+	}
+
+	instances := newObservedInstances(cluster, nil, []corev1.Pod{pod("one"), pod("two")})
+
+	t.Run("Disabled", func(t *testing.T) {
+		result, err := reconciler.reconcileVolumeAutoGrow(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 0*autoGrowInterval)
+		assert.Assert(t, cluster.Status.InstanceSets[0].DataVolumeSize == nil)
+	})
+
+	cluster.Spec.InstanceSets[0].DataVolumeAutoGrow = &v1beta1.VolumeAutoGrowSpec{
+		MaxSize: resource.MustParse("1500Mi"),
+	}
+	cluster.Spec.InstanceSets[0].DataVolumeAutoGrow.Default()
+
+	t.Run("Grow", func(t *testing.T) {
+		result, err := reconciler.reconcileVolumeAutoGrow(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, autoGrowInterval)
+
+		size := cluster.Status.InstanceSets[0].DataVolumeSize
+		assert.Assert(t, size != nil)
+		assert.Equal(t, size.String(), "1280Mi")
+
+		assert.Equal(t, len(recorder.Events), 1)
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Normal VolumeAutoGrow "), "%q", event)
+		assert.Assert(t, cmp.Contains(event, "from 1Gi to 1280Mi"))
+		assert.Assert(t, cmp.Contains(event, "85% full"))
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		_, err := reconciler.reconcileVolumeAutoGrow(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.InstanceSets[0].DataVolumeSize.String(), "1500Mi")
+		assert.Equal(t, len(recorder.Events), 1)
+		<-recorder.Events
+
+		_, err = reconciler.reconcileVolumeAutoGrow(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.InstanceSets[0].DataVolumeSize.String(), "1500Mi")
+
+		assert.Equal(t, len(recorder.Events), 1)
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Warning VolumeAutoGrowLimit "), "%q", event)
+	})
+
+	t.Run("SpecCaughtUp", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets[0].DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] =
+			resource.MustParse("2Gi")
+		cluster.Spec.InstanceSets[0].DataVolumeAutoGrow.MaxSize = resource.MustParse("2Gi")
+		usage["two-0"] = "1B-blocks Used\n100 10\n"
+
+		_, err := reconciler.reconcileVolumeAutoGrow(ctx, cluster,
+			newObservedInstances(cluster, nil, []corev1.Pod{pod("one"), pod("two")}))
+		assert.NilError(t, err)
+		assert.Assert(t, cluster.Status.InstanceSets[0].DataVolumeSize == nil)
+	})
+}
@@ -0,0 +1,270 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pgbackrest"
+	"github.com/adifri/postgres-operator/v5/internal/pgbouncer"
+	"github.com/adifri/postgres-operator/v5/internal/pki"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// certificateLifetimes returns how long the leaf certificates of cluster are
+// valid and how long before expiration they are replaced. Zero means the
+// default of the pki package.
+func certificateLifetimes(cluster *v1beta1.PostgresCluster) (lifetime, renewBefore time.Duration) {
+	if spec := cluster.Spec.Certificates; spec != nil {
+		if spec.LeafLifetime != nil && spec.LeafLifetime.Duration > 0 {
+			lifetime = spec.LeafLifetime.Duration
+		}
+		if spec.RenewBefore != nil && spec.RenewBefore.Duration > 0 {
+			renewBefore = spec.RenewBefore.Duration
+		}
+	}
+	return
+}
+
+// userCertificateLifetimes returns how long the client certificate of user is
+// valid and how long before expiration it is replaced. Values that are not
+// set on user come from cluster.
+func userCertificateLifetimes(
+	cluster *v1beta1.PostgresCluster, user *v1beta1.PostgresUserSpec,
+) (lifetime, renewBefore time.Duration) {
+	lifetime, renewBefore = certificateLifetimes(cluster)
+	if spec := user.Certificate; spec != nil {
+		if spec.Lifetime != nil && spec.Lifetime.Duration > 0 {
+			lifetime = spec.Lifetime.Duration
+		}
+		if spec.RenewBefore != nil && spec.RenewBefore.Duration > 0 {
+			renewBefore = spec.RenewBefore.Duration
+		}
+	}
+	return
+}
+
+// certificateSource is a certificate in a Secret key.
+type certificateSource struct {
+	SecretName string
+	Key        string
+
+	// Generated is true when PGO replaces the certificate itself.
+	Generated bool
+
+	// RenewBefore is how long before expiration the certificate should be
+	// replaced. Zero means one third of its lifetime.
+	RenewBefore time.Duration
+}
+
+// projectedCertificateKey returns the key of projection that is mounted as
+// path. When no items are specified, the key serves as the path.
+func projectedCertificateKey(projection *corev1.SecretProjection, path string) string {
+	for _, item := range projection.Items {
+		if item.Path == path {
+			return item.Key
+		}
+	}
+	return path
+}
+
+// certificateSources returns the certificates of cluster in the order they
+// are reported in its status.
+func certificateSources(
+	cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	primaryCertificate *corev1.SecretProjection, replicationSecret *corev1.Secret,
+) []certificateSource {
+	_, renewBefore := certificateLifetimes(cluster)
+	issued := cluster.Spec.CertificateIssuer != nil
+
+	// The root certificate is replaced after two thirds of its lifetime.
+	sources := []certificateSource{{
+		SecretName: naming.RootCertSecret, Key: "root.crt", Generated: true,
+	}}
+
+	if primaryCertificate != nil {
+		sources = append(sources, certificateSource{
+			SecretName:  primaryCertificate.Name,
+			Key:         projectedCertificateKey(primaryCertificate, clusterCertFile),
+			Generated:   cluster.Spec.CustomTLSSecret == nil && !issued,
+			RenewBefore: renewBefore,
+		})
+	}
+
+	if replicationSecret != nil {
+		sources = append(sources, certificateSource{
+			SecretName:  replicationSecret.Name,
+			Key:         naming.ReplicationCert,
+			Generated:   cluster.Spec.CustomReplicationClientTLSSecret == nil && !issued,
+			RenewBefore: renewBefore,
+		})
+	}
+
+	if instances != nil {
+		for _, instance := range instances.forCluster {
+			if instance.Runner == nil {
+				continue
+			}
+			source := certificateSource{
+				SecretName:  naming.InstanceCertificates(instance.Runner).Name,
+				Key:         instanceCertFile,
+				Generated:   !issued,
+				RenewBefore: renewBefore,
+			}
+			if issued {
+				source.SecretName = naming.InstanceIssuedCertificate(instance.Runner).Name
+				source.Key = clusterCertFile
+			}
+			sources = append(sources, source)
+		}
+	}
+
+	if cluster.Spec.CustomTLSSecret == nil {
+		for i := range cluster.Spec.Users {
+			if user := &cluster.Spec.Users[i]; user.Certificate != nil {
+				_, renewBefore := userCertificateLifetimes(cluster, user)
+				sources = append(sources, certificateSource{
+					SecretName:  naming.PostgresUserSecret(cluster, string(user.Name)).Name,
+					Key:         corev1.TLSCertKey,
+					Generated:   !issued,
+					RenewBefore: renewBefore,
+				})
+			}
+		}
+	}
+
+	if pgbackrest.DedicatedRepoHostEnabled(cluster) {
+		for _, key := range pgbackrest.SecretCertificateKeys() {
+			sources = append(sources, certificateSource{
+				SecretName:  naming.PGBackRestSecret(cluster).Name,
+				Key:         key,
+				Generated:   !issued,
+				RenewBefore: renewBefore,
+			})
+		}
+	}
+
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil {
+		source := certificateSource{
+			SecretName:  naming.ClusterPGBouncer(cluster).Name,
+			Key:         pgbouncer.FrontendCertificateKey(),
+			Generated:   true,
+			RenewBefore: renewBefore,
+		}
+		if custom := cluster.Spec.Proxy.PGBouncer.CustomTLSSecret; custom != nil {
+			source.SecretName = custom.Name
+			source.Key = projectedCertificateKey(custom, corev1.TLSCertKey)
+			source.Generated = false
+		}
+		sources = append(sources, source)
+	}
+
+	return sources
+}
+
+// certificateWarningTime returns when to warn that certificate is nearing
+// expiration. Certificates that PGO replaces itself are only reported when
+// their replacement is long overdue.
+func certificateWarningTime(source certificateSource, certificate pki.Certificate) time.Time {
+	renewal := certificate.RenewalTime(source.RenewBefore)
+	if source.Generated {
+		return renewal.Add(certificate.NotAfter().Sub(renewal) / 2)
+	}
+	return renewal
+}
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+
+// reconcileCertificateStatus publishes when every certificate of cluster
+// expires and emits Warning events for those that are nearing expiration. It
+// returns a Result that checks again at the next renewal or warning time.
+func (r *Reconciler) reconcileCertificateStatus(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	primaryCertificate *corev1.SecretProjection, replicationSecret *corev1.Secret,
+) (reconcile.Result, error) {
+	var err error
+	var next time.Time
+	var statuses []v1beta1.CertificateStatus
+
+	now := time.Now()
+	secrets := make(map[string]*corev1.Secret)
+
+	for _, source := range certificateSources(cluster, instances, primaryCertificate, replicationSecret) {
+		secret, found := secrets[source.SecretName]
+		if !found {
+			secret = &corev1.Secret{}
+			secret.Namespace, secret.Name = cluster.Namespace, source.SecretName
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)))
+			if err != nil {
+				break
+			}
+			secrets[source.SecretName] = secret
+		}
+
+		// Skip certificates that are not there yet.
+		var certificate pki.Certificate
+		if certificate.UnmarshalText(secret.Data[source.Key]) != nil {
+			continue
+		}
+
+		statuses = append(statuses, v1beta1.CertificateStatus{
+			SecretName: source.SecretName,
+			Key:        source.Key,
+			NotAfter:   metav1.NewTime(certificate.NotAfter()),
+		})
+
+		warning := certificateWarningTime(source, certificate)
+		switch {
+		case !now.Before(certificate.NotAfter()):
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CertificateExpired",
+				"Certificate %q of Secret %q expired at %s",
+				source.Key, source.SecretName, certificate.NotAfter().UTC().Format(time.RFC3339))
+		case !now.Before(warning):
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CertificateExpiring",
+				"Certificate %q of Secret %q expires at %s",
+				source.Key, source.SecretName, certificate.NotAfter().UTC().Format(time.RFC3339))
+		}
+
+		// Check again when the certificate should be replaced or warned about.
+		for _, t := range []time.Time{
+			certificate.RenewalTime(source.RenewBefore), warning, certificate.NotAfter(),
+		} {
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+
+	var result reconcile.Result
+	if err == nil {
+		cluster.Status.Certificates = statuses
+
+		if !next.IsZero() {
+			// Wait a moment past the time so certificates are out of date.
+			result.RequeueAfter = next.Sub(now) + time.Second
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,287 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pki"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestCertificateLifetimes(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+
+	lifetime, renewBefore := certificateLifetimes(cluster)
+	assert.Equal(t, lifetime, time.Duration(0))
+	assert.Equal(t, renewBefore, time.Duration(0))
+
+	cluster.Spec.Certificates = &v1beta1.CertificatesSpec{
+		LeafLifetime: &metav1.Duration{Duration: 720 * time.Hour},
+		RenewBefore:  &metav1.Duration{Duration: 168 * time.Hour},
+	}
+
+	lifetime, renewBefore = certificateLifetimes(cluster)
+	assert.Equal(t, lifetime, 720*time.Hour)
+	assert.Equal(t, renewBefore, 168*time.Hour)
+}
+
+func TestCertificateSources(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Name = "hippo"
+	cluster.Spec.Certificates = &v1beta1.CertificatesSpec{
+		RenewBefore: &metav1.Duration{Duration: time.Hour},
+	}
+
+	runner := &appsv1.StatefulSet{}
+	runner.Name = "hippo-00-abcd"
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-00-abcd", Runner: runner},
+		{Name: "hippo-00-efgh"},
+	}}
+
+	primary := clusterCertSecretProjection(&corev1.Secret{
+		ObjectMeta: naming.PostgresTLSSecret(cluster),
+	})
+	replication := &corev1.Secret{ObjectMeta: naming.ReplicationClientCertSecret(cluster)}
+
+	t.Run("Generated", func(t *testing.T) {
+		assert.DeepEqual(t, certificateSources(cluster, instances, primary, replication),
+			[]certificateSource{
+				{SecretName: "pgo-root-cacert", Key: "root.crt", Generated: true},
+				{SecretName: "hippo-cluster-cert", Key: "tls.crt", Generated: true, RenewBefore: time.Hour},
+				{SecretName: "hippo-replication-cert", Key: "tls.crt", Generated: true, RenewBefore: time.Hour},
+				{SecretName: "hippo-00-abcd-certs", Key: "dns.crt", Generated: true, RenewBefore: time.Hour},
+			})
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "custom"},
+			Items: []corev1.KeyToPath{
+				{Key: "some.crt", Path: "tls.crt"},
+				{Key: "some.key", Path: "tls.key"},
+			},
+		}
+		cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{
+			CustomTLSSecret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "bouncer"},
+			},
+		}}
+
+		sources := certificateSources(cluster, nil, cluster.Spec.CustomTLSSecret, nil)
+		assert.DeepEqual(t, sources, []certificateSource{
+			{SecretName: "pgo-root-cacert", Key: "root.crt", Generated: true},
+			{SecretName: "custom", Key: "some.crt", RenewBefore: time.Hour},
+			{SecretName: "bouncer", Key: "tls.crt", RenewBefore: time.Hour},
+		})
+	})
+
+	t.Run("Users", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+			{Name: "app"},
+			{Name: "service", Certificate: &v1beta1.PostgresUserCertificateSpec{
+				RenewBefore: &metav1.Duration{Duration: 2 * time.Hour},
+			}},
+		}
+
+		sources := certificateSources(cluster, nil, nil, nil)
+		assert.DeepEqual(t, sources[1:], []certificateSource{
+			{SecretName: "hippo-pguser-service", Key: "tls.crt", Generated: true, RenewBefore: 2 * time.Hour},
+		})
+	})
+
+	t.Run("Issued", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CertificateIssuer = &v1beta1.CertificateIssuerReference{Name: "some"}
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+			Name: "repo1", Volume: &v1beta1.RepoPVC{},
+		}}
+
+		sources := certificateSources(cluster, instances, primary, replication)
+		assert.DeepEqual(t, sources[3:], []certificateSource{
+			{SecretName: "hippo-00-abcd-issued", Key: "tls.crt", RenewBefore: time.Hour},
+			{SecretName: "hippo-pgbackrest", Key: "pgbackrest-client.crt", RenewBefore: time.Hour},
+			{SecretName: "hippo-pgbackrest", Key: "pgbackrest-repo-host.crt", RenewBefore: time.Hour},
+		})
+	})
+}
+
+func TestReconcileCertificateStatus(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	// This leaf is valid for 70 minutes: from an hour ago to ten minutes from now.
+	short := &pki.RootCertificateAuthority{
+		Certificate: root.Certificate, PrivateKey: root.PrivateKey,
+		LeafLifetime: 10 * time.Minute,
+	}
+	expiring, err := short.GenerateLeafCertificate("", nil)
+	assert.NilError(t, err)
+
+	leaf, err := root.GenerateLeafCertificate("", nil)
+	assert.NilError(t, err)
+
+	secret := func(name, key string, certificate pki.Certificate) *corev1.Secret {
+		secret := &corev1.Secret{Data: map[string][]byte{}}
+		secret.Namespace, secret.Name = "ns1", name
+		secret.Data[key], err = certificate.MarshalText()
+		assert.NilError(t, err)
+		return secret
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.CustomReplicationClientTLSSecret = &corev1.SecretProjection{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "custom-replication"},
+	}
+	cluster.Spec.Certificates = &v1beta1.CertificatesSpec{
+		RenewBefore: &metav1.Duration{Duration: 30 * time.Minute},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{Recorder: recorder}
+	reconciler.Client = fake.NewClientBuilder().WithObjects(
+		secret("pgo-root-cacert", "root.crt", root.Certificate),
+		secret("hippo-cluster-cert", "tls.crt", leaf.Certificate),
+		secret("custom-replication", "tls.crt", expiring.Certificate),
+	).Build()
+
+	primary := clusterCertSecretProjection(&corev1.Secret{
+		ObjectMeta: naming.PostgresTLSSecret(cluster),
+	})
+	replication := &corev1.Secret{}
+	replication.Name = "custom-replication"
+
+	result, err := reconciler.reconcileCertificateStatus(ctx, cluster, nil, primary, replication)
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, cluster.Status.Certificates, []v1beta1.CertificateStatus{
+		{SecretName: "pgo-root-cacert", Key: "root.crt",
+			NotAfter: metav1.NewTime(root.Certificate.NotAfter())},
+		{SecretName: "hippo-cluster-cert", Key: "tls.crt",
+			NotAfter: metav1.NewTime(leaf.Certificate.NotAfter())},
+		{SecretName: "custom-replication", Key: "tls.crt",
+			NotAfter: metav1.NewTime(expiring.Certificate.NotAfter())},
+	})
+
+	// The custom certificate is within 30 minutes of expiring.
+	assert.Equal(t, len(recorder.Events), 1)
+	event := <-recorder.Events
+	assert.Assert(t, strings.HasPrefix(event, "Warning CertificateExpiring "), "%q", event)
+	assert.Assert(t, cmp.Contains(event, `"custom-replication"`))
+
+	// Check again when the custom certificate expires.
+	assert.Assert(t, result.RequeueAfter > 9*time.Minute && result.RequeueAfter <= 11*time.Minute,
+		"got %v", result.RequeueAfter)
+
+	t.Run("Missing", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Namespace = "elsewhere"
+
+		result, err := reconciler.reconcileCertificateStatus(ctx, cluster, nil, primary, replication)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, time.Duration(0))
+		assert.Assert(t, cluster.Status.Certificates == nil)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+}
+
+func TestReconcilePostgresUserCertificate(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{Recorder: recorder}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	user := &v1beta1.PostgresUserSpec{
+		Name: "service",
+		Certificate: &v1beta1.PostgresUserCertificateSpec{
+			Lifetime: &metav1.Duration{Duration: 48 * time.Hour},
+		},
+	}
+
+	intent := &corev1.Secret{Data: map[string][]byte{}}
+	assert.NilError(t, reconciler.reconcilePostgresUserCertificate(
+		ctx, cluster, root, user, nil, intent))
+
+	var authority pki.Certificate
+	leaf := &pki.LeafCertificate{}
+	assert.NilError(t, authority.UnmarshalText(intent.Data["ca.crt"]))
+	assert.NilError(t, leaf.Certificate.UnmarshalText(intent.Data["tls.crt"]))
+	assert.NilError(t, leaf.PrivateKey.UnmarshalText(intent.Data["tls.key"]))
+
+	assert.Assert(t, authority.Equal(root.Certificate))
+	assert.Equal(t, leaf.Certificate.CommonName(), "service")
+	assert.Assert(t, time.Until(leaf.Certificate.NotAfter()) <= 48*time.Hour)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		again := &corev1.Secret{Data: map[string][]byte{}}
+		assert.NilError(t, reconciler.reconcilePostgresUserCertificate(
+			ctx, cluster, root, user, intent, again))
+		assert.DeepEqual(t, again.Data, intent.Data)
+	})
+
+	t.Run("Shortened", func(t *testing.T) {
+		user := user.DeepCopy()
+		user.Certificate.Lifetime.Duration = 24 * time.Hour
+
+		again := &corev1.Secret{Data: map[string][]byte{}}
+		assert.NilError(t, reconciler.reconcilePostgresUserCertificate(
+			ctx, cluster, root, user, intent, again))
+
+		replaced := &pki.LeafCertificate{}
+		assert.NilError(t, replaced.Certificate.UnmarshalText(again.Data["tls.crt"]))
+		assert.Assert(t, !replaced.Certificate.Equal(leaf.Certificate))
+		assert.Assert(t, time.Until(replaced.Certificate.NotAfter()) <= 24*time.Hour)
+	})
+
+	t.Run("CustomTLSSecret", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{}
+
+		intent := &corev1.Secret{Data: map[string][]byte{}}
+		assert.NilError(t, reconciler.reconcilePostgresUserCertificate(
+			ctx, cluster, root, user, nil, intent))
+		assert.Equal(t, len(intent.Data), 0)
+
+		assert.Equal(t, len(recorder.Events), 1)
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Warning InvalidUser "), "%q", event)
+		assert.Assert(t, cmp.Contains(event, `"service"`))
+	})
+}
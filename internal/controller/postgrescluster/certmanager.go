@@ -0,0 +1,213 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pki"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// certificateGVK identifies the cert-manager Certificate API. Its Go types are
+// not a dependency of this module, so Certificates are unstructured.
+// - https://cert-manager.io/docs/usage/certificate/
+var certificateGVK = schema.GroupVersionKind{
+	Group: "cert-manager.io", Version: "v1", Kind: "Certificate",
+}
+
+// certificateIssuerRequeue is how often to check a Certificate that its issuer
+// has not yet filled.
+const certificateIssuerRequeue = 10 * time.Second
+
+// errCertificateNotIssued means an issuer has not yet filled the Secret of a
+// Certificate. Reconcile stops there and checks again without reporting an
+// error; the Progressing condition says what it is waiting for.
+var errCertificateNotIssued = errors.New("certificate not yet issued")
+
+// These are the key usages of certificates from an issuer. Servers and
+// clients of Patroni and pgBackRest use the same certificates.
+var (
+	issuedClientUsages = []string{"digital signature", "key encipherment", "client auth"}
+	issuedServerUsages = []string{"digital signature", "key encipherment", "server auth", "client auth"}
+)
+
+// issuedCertificate is a leaf certificate and private key from a cert-manager
+// Secret along with the certificate authority that issued it.
+type issuedCertificate struct {
+	Authority pki.Certificate
+	Leaf      *pki.LeafCertificate
+	Secret    *corev1.Secret
+}
+
+// parseIssuedCertificate reads the certificate authority, certificate, and
+// private key that cert-manager stores in secret. It returns false when any of
+// them are missing or cannot be parsed.
+func parseIssuedCertificate(secret *corev1.Secret) (*issuedCertificate, bool) {
+	issued := &issuedCertificate{Leaf: &pki.LeafCertificate{}, Secret: secret}
+
+	// The "ca.crt" file can be a bundle, and "tls.crt" can be followed by
+	// intermediates. Only the first certificate of each is parsed.
+	ok := issued.Authority.UnmarshalText(secret.Data[rootCertFile]) == nil &&
+		issued.Leaf.Certificate.UnmarshalText(secret.Data[clusterCertFile]) == nil &&
+		issued.Leaf.PrivateKey.UnmarshalText(secret.Data[clusterKeyFile]) == nil
+
+	return issued, ok
+}
+
+// generateIssuedCertificate returns a cert-manager Certificate that stores a
+// certificate from the issuer of cluster in a Secret with the same name.
+func (r *Reconciler) generateIssuedCertificate(
+	cluster *v1beta1.PostgresCluster, meta metav1.ObjectMeta,
+	commonName string, dnsNames []string, usages []string,
+) (*unstructured.Unstructured, error) {
+	issuer := cluster.Spec.CertificateIssuer
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(certificateGVK)
+	certificate.SetNamespace(meta.Namespace)
+	certificate.SetName(meta.Name)
+	if annotations := cluster.Spec.Metadata.GetAnnotationsOrNil(); len(annotations) > 0 {
+		certificate.SetAnnotations(naming.Merge(annotations))
+	}
+	certificate.SetLabels(naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+		}))
+
+	issuerRef := map[string]interface{}{"name": issuer.Name}
+	if issuer.Kind != "" {
+		issuerRef["kind"] = issuer.Kind
+	}
+	if issuer.Group != "" {
+		issuerRef["group"] = issuer.Group
+	}
+
+	// PGO reads private keys in the same format that it generates them.
+	spec := map[string]interface{}{
+		"secretName": meta.Name,
+		"issuerRef":  issuerRef,
+		"privateKey": map[string]interface{}{
+			"algorithm":      "ECDSA",
+			"encoding":       "PKCS1",
+			"rotationPolicy": "Always",
+			"size":           int64(256),
+		},
+	}
+	if commonName != "" {
+		spec["commonName"] = commonName
+	}
+	if len(dnsNames) > 0 {
+		names := make([]interface{}, len(dnsNames))
+		for i := range dnsNames {
+			// Kubernetes DNS names can be fully qualified with a trailing dot,
+			// but certificates do not include it.
+			names[i] = strings.TrimSuffix(dnsNames[i], ".")
+		}
+		spec["dnsNames"] = names
+	}
+	if lifetime, renewBefore := certificateLifetimes(cluster); lifetime > 0 {
+		spec["duration"] = lifetime.String()
+		if renewBefore > 0 && renewBefore < lifetime {
+			spec["renewBefore"] = renewBefore.String()
+		}
+	} else if renewBefore > 0 {
+		spec["renewBefore"] = renewBefore.String()
+	}
+	if len(usages) > 0 {
+		values := make([]interface{}, len(usages))
+		for i := range usages {
+			values[i] = usages[i]
+		}
+		spec["usages"] = values
+	}
+	certificate.Object["spec"] = spec
+
+	err := errors.WithStack(r.setControllerReference(cluster, certificate))
+
+	return certificate, err
+}
+
+// +kubebuilder:rbac:groups="cert-manager.io",resources="certificates",verbs={create,patch}
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+
+// reconcileIssuedCertificate creates or updates a cert-manager Certificate
+// and returns the certificate that cert-manager stored in its Secret. It
+// returns an error until the Secret exists and has every file.
+func (r *Reconciler) reconcileIssuedCertificate(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, meta metav1.ObjectMeta,
+	commonName string, dnsNames []string, usages []string,
+) (*issuedCertificate, error) {
+	certificate, err := r.generateIssuedCertificate(
+		cluster, meta, commonName, dnsNames, usages)
+
+	// The apply method compares objects to their zero value which does not
+	// work for unstructured objects, so build the apply-patch directly.
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(certificate)
+		if err == nil {
+			err = r.patch(ctx, certificate,
+				client.RawPatch(client.Apply.Type(), data), client.ForceOwnership)
+		}
+		err = errors.WithStack(err)
+	}
+
+	secret := &corev1.Secret{ObjectMeta: meta}
+	if err == nil {
+		err = errors.WithStack(client.IgnoreNotFound(
+			r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)))
+	}
+
+	var issued *issuedCertificate
+	if err == nil {
+		var ok bool
+		if issued, ok = parseIssuedCertificate(secret); !ok {
+			err = errCertificateNotIssued
+			setCertificateNotIssuedCondition(cluster, secret)
+		}
+	}
+
+	return issued, err
+}
+
+// setCertificateNotIssuedCondition records in cluster that reconciling waits
+// for its issuer to fill secret.
+func setCertificateNotIssuedCondition(cluster *v1beta1.PostgresCluster, secret *corev1.Secret) {
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:   v1beta1.PostgresClusterProgressing,
+		Status: metav1.ConditionTrue,
+		Reason: "WaitingForCertificate",
+		Message: fmt.Sprintf("Waiting for issuer %q to fill Secret %q of Certificate %q",
+			cluster.Spec.CertificateIssuer.Name, secret.Name, secret.Name),
+
+		ObservedGeneration: cluster.GetGeneration(),
+	})
+}
@@ -0,0 +1,179 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/pki"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestGenerateIssuedCertificate(t *testing.T) {
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.UID = "some-uid"
+	cluster.Spec.CertificateIssuer = &v1beta1.CertificateIssuerReference{
+		Name: "corporate", Kind: "ClusterIssuer", Group: "cert-manager.io",
+	}
+	cluster.Spec.Metadata = &v1beta1.Metadata{
+		Labels: map[string]string{"some": "label"},
+	}
+
+	certificate, err := reconciler.generateIssuedCertificate(cluster,
+		metav1.ObjectMeta{Namespace: "ns1", Name: "hippo-cluster-issued"}, "",
+		[]string{"hippo-primary.ns1.svc.cluster.local.", "hippo-primary"},
+		issuedServerUsages)
+	assert.NilError(t, err)
+
+	assert.Equal(t, certificate.GetAPIVersion(), "cert-manager.io/v1")
+	assert.Equal(t, certificate.GetKind(), "Certificate")
+
+	data, err := yaml.Marshal(certificate.Object)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  labels:
+    postgres-operator.crunchydata.com/cluster: hippo
+    some: label
+  name: hippo-cluster-issued
+  namespace: ns1
+  ownerReferences:
+  - apiVersion: postgres-operator.crunchydata.com/v1beta1
+    blockOwnerDeletion: true
+    controller: true
+    kind: PostgresCluster
+    name: hippo
+    uid: some-uid
+spec:
+  dnsNames:
+  - hippo-primary.ns1.svc.cluster.local
+  - hippo-primary
+  issuerRef:
+    group: cert-manager.io
+    kind: ClusterIssuer
+    name: corporate
+  privateKey:
+    algorithm: ECDSA
+    encoding: PKCS1
+    rotationPolicy: Always
+    size: 256
+  secretName: hippo-cluster-issued
+  usages:
+  - digital signature
+  - key encipherment
+  - server auth
+  - client auth
+`[1:])
+
+	t.Run("CommonName", func(t *testing.T) {
+		certificate, err := reconciler.generateIssuedCertificate(cluster,
+			metav1.ObjectMeta{Namespace: "ns1", Name: "some-client"},
+			"_crunchyrepl", nil, issuedClientUsages)
+		assert.NilError(t, err)
+
+		spec := certificate.Object["spec"].(map[string]interface{})
+		assert.Equal(t, spec["commonName"], "_crunchyrepl")
+		assert.Assert(t, spec["dnsNames"] == nil)
+	})
+
+	t.Run("Lifetimes", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Certificates = &v1beta1.CertificatesSpec{
+			LeafLifetime: &metav1.Duration{Duration: 720 * time.Hour},
+			RenewBefore:  &metav1.Duration{Duration: 168 * time.Hour},
+		}
+
+		certificate, err := reconciler.generateIssuedCertificate(cluster,
+			metav1.ObjectMeta{Namespace: "ns1", Name: "some-client"},
+			"_crunchyrepl", nil, issuedClientUsages)
+		assert.NilError(t, err)
+
+		spec := certificate.Object["spec"].(map[string]interface{})
+		assert.Equal(t, spec["duration"], "720h0m0s")
+		assert.Equal(t, spec["renewBefore"], "168h0m0s")
+	})
+}
+
+func TestParseIssuedCertificate(t *testing.T) {
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	leaf, err := root.GenerateLeafCertificate("", []string{"some.host"})
+	assert.NilError(t, err)
+
+	secret := &corev1.Secret{Data: map[string][]byte{}}
+
+	_, ok := parseIssuedCertificate(secret)
+	assert.Assert(t, !ok, "expected empty Secret to be not ready")
+
+	secret.Data["ca.crt"], err = root.Certificate.MarshalText()
+	assert.NilError(t, err)
+	secret.Data["tls.crt"], err = leaf.Certificate.MarshalText()
+	assert.NilError(t, err)
+
+	_, ok = parseIssuedCertificate(secret)
+	assert.Assert(t, !ok, "expected missing private key to be not ready")
+
+	secret.Data["tls.key"], err = leaf.PrivateKey.MarshalText()
+	assert.NilError(t, err)
+
+	issued, ok := parseIssuedCertificate(secret)
+	assert.Assert(t, ok)
+	assert.Assert(t, issued.Secret == secret)
+	assert.Assert(t, issued.Authority.Equal(root.Certificate))
+	assert.Assert(t, issued.Leaf.Certificate.Equal(leaf.Certificate))
+	assert.Assert(t, issued.Leaf.PrivateKey.Equal(leaf.PrivateKey))
+}
+
+func TestSetCertificateNotIssuedCondition(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Generation = 2
+	cluster.Spec.CertificateIssuer = &v1beta1.CertificateIssuerReference{Name: "ca-issuer"}
+
+	secret := &corev1.Secret{}
+	secret.Name = "hippo-cluster-cert"
+
+	setCertificateNotIssuedCondition(cluster, secret)
+
+	condition := meta.FindStatusCondition(cluster.Status.Conditions,
+		v1beta1.PostgresClusterProgressing)
+	assert.Assert(t, condition != nil)
+	assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	assert.Equal(t, condition.Reason, "WaitingForCertificate")
+	assert.Equal(t, condition.ObservedGeneration, int64(2))
+	assert.Equal(t, condition.Message,
+		`Waiting for issuer "ca-issuer" to fill Secret "hippo-cluster-cert" of Certificate "hippo-cluster-cert"`)
+}
@@ -39,6 +39,20 @@ import (
 func (r *Reconciler) reconcileClusterConfigMap(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
 	pgHBAs postgres.HBAs, pgParameters postgres.Parameters,
+) (*corev1.ConfigMap, error) {
+	clusterConfigMap, err := r.generateClusterConfigMap(ctx, cluster, pgHBAs, pgParameters)
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, clusterConfigMap))
+	}
+
+	return clusterConfigMap, err
+}
+
+// generateClusterConfigMap returns the ConfigMap that contains the Patroni
+// configuration shared by every instance of cluster.
+func (r *Reconciler) generateClusterConfigMap(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	pgHBAs postgres.HBAs, pgParameters postgres.Parameters,
 ) (*corev1.ConfigMap, error) {
 	clusterConfigMap := &corev1.ConfigMap{ObjectMeta: naming.ClusterConfigMap(cluster)}
 	clusterConfigMap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
@@ -55,9 +69,6 @@ func (r *Reconciler) reconcileClusterConfigMap(
 		err = patroni.ClusterConfigMap(ctx, cluster, pgHBAs, pgParameters,
 			clusterConfigMap)
 	}
-	if err == nil {
-		err = errors.WithStack(r.apply(ctx, clusterConfigMap))
-	}
 
 	return clusterConfigMap, err
 }
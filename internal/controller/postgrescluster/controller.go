@@ -21,6 +21,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"sync"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
@@ -33,17 +34,23 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/credentials"
 	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/patroni"
 	"github.com/adifri/postgres-operator/v5/internal/pgaudit"
 	"github.com/adifri/postgres-operator/v5/internal/pgbackrest"
 	"github.com/adifri/postgres-operator/v5/internal/pgbouncer"
@@ -66,10 +73,23 @@ type Reconciler struct {
 	Tracer      trace.Tracer
 	IsOpenShift bool
 
+	// NamespaceScoped is true when the operator watches one namespace with
+	// the permissions of a Role. It cannot read cluster-scoped resources then.
+	NamespaceScoped bool
+
+	// CredentialStore keeps the credentials of PostgreSQL users outside of
+	// Kubernetes. It is nil when no store is configured.
+	CredentialStore credentials.Store
+
 	PodExec func(
 		namespace, pod, container string,
 		stdin io.Reader, stdout, stderr io.Writer, command ...string,
 	) error
+
+	// volumeSnapshotSessions hold PostgreSQL in backup mode between calls to
+	// Reconcile while storage cuts VolumeSnapshots.
+	volumeSnapshotMutex    sync.Mutex
+	volumeSnapshotSessions map[types.UID]*volumeSnapshotSession
 }
 
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
@@ -179,6 +199,24 @@ func (r *Reconciler) Reconcile(
 	// occurs while attempting to patch the status, while otherwise simply returning the
 	// Result and error variables that are populated while reconciling the PostgresCluster.
 	patchClusterStatus := func() (reconcile.Result, error) {
+		// Waiting for cert-manager is not an error. Check again soon rather
+		// than back off.
+		if errors.Is(err, errCertificateNotIssued) {
+			err = nil
+			result = updateReconcileResult(result,
+				reconcile.Result{RequeueAfter: certificateIssuerRequeue})
+		}
+		// Summarize health whenever instances have been observed, even when
+		// reconciliation stops early.
+		if instances != nil {
+			setHealthConditions(cluster, instances)
+		}
+		// Check soon whether PgBouncer paused by this reconcile can resume.
+		if before.Status.Proxy.PGBouncer.PausedAt == nil &&
+			cluster.Status.Proxy.PGBouncer.PausedAt != nil {
+			result = updateReconcileResult(result,
+				reconcile.Result{RequeueAfter: pgbouncerResumeInterval})
+		}
 		if !equality.Semantic.DeepEqual(before.Status, cluster.Status) {
 			// NOTE(cbandy): Kubernetes prior to v1.16.10 and v1.17.6 does not track
 			// managed fields on the status subresource: https://issue.k8s.io/88901
@@ -211,18 +249,55 @@ func (r *Reconciler) Reconcile(
 		}
 	}
 
+	// Resolve images before anything that uses them. Nothing is reconciled
+	// until the images of a new cluster are known.
+	if ok, err := r.reconcileImageCatalog(ctx, cluster); err != nil || !ok {
+		if err != nil {
+			log.Error(err, "resolving images")
+			span.RecordError(err)
+		}
+		return result, err
+	}
+
 	pgHBAs := postgres.NewHBAs()
 	pgmonitor.PostgreSQLHBAs(cluster, &pgHBAs)
 	pgbouncer.PostgreSQL(cluster, &pgHBAs)
+	postgres.UserHBAs(cluster, &pgHBAs)
+	if err = postgres.CustomHBAs(cluster, &pgHBAs); err != nil {
+		// Postgres does not start with invalid pg_hba.conf records. Reject the
+		// rules rather than risk an outage.
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "InvalidAuthenticationRules",
+			err.Error())
+		return result, err
+	}
 
 	pgParameters := postgres.NewParameters()
 	pgaudit.PostgreSQLParameters(&pgParameters)
 	pgbackrest.PostgreSQL(cluster, &pgParameters)
 	pgmonitor.PostgreSQLParameters(cluster, &pgParameters)
 
+	// When planning, record what applying the spec would change and change
+	// nothing else.
+	if _, planning := cluster.Annotations[naming.PlanChanges]; planning {
+		err = r.reconcilePlan(ctx, cluster, pgHBAs, pgParameters)
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:    v1beta1.PostgresClusterProgressing,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Planning",
+			Message: "No spec changes will be applied. The changes they would make are in status.plan.",
+
+			ObservedGeneration: cluster.GetGeneration(),
+		})
+		return patchClusterStatus()
+	}
+	cluster.Status.Plan = nil
+
 	if err == nil {
 		rootCA, err = r.reconcileRootCertificate(ctx, cluster)
 	}
+	if err == nil {
+		err = updateResult(rootRotationResult(cluster), nil)
+	}
 
 	if err == nil {
 		// Since any existing data directories must be moved prior to bootstrapping the
@@ -249,6 +324,18 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		err = updateResult(r.reconcilePatroniStatus(ctx, cluster, instances))
 	}
+	if err == nil {
+		err = updateResult(r.reconcileVolumeAutoGrow(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileImageRollout(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileRolloutPolicy(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.resumePGBouncer(ctx, cluster, instances))
+	}
 	if err == nil {
 		err = r.reconcilePatroniSwitchover(ctx, cluster, instances)
 	}
@@ -288,6 +375,9 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		clusterReplicationSecret, err = r.reconcileReplicationSecret(ctx, cluster, rootCA)
 	}
+	if err == nil {
+		rootRotationWritten(cluster, "replication")
+	}
 	if err == nil {
 		patroniLeaderService, err = r.reconcilePatroniLeaderLease(ctx, cluster)
 	}
@@ -300,12 +390,20 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		primaryCertificate, err = r.reconcileClusterCertificate(ctx, rootCA, cluster, primaryService)
 	}
+	if err == nil {
+		rootRotationWritten(cluster, "postgres")
+	}
 	if err == nil {
 		err = r.reconcilePatroniDistributedConfiguration(ctx, cluster)
 	}
 	if err == nil {
 		err = r.reconcilePatroniDynamicConfiguration(ctx, cluster, instances, pgHBAs, pgParameters)
 	}
+	if err == nil {
+		cluster.Status.Authentication = &v1beta1.PostgresAuthenticationStatus{
+			Rules: patroni.HBARules(cluster, pgHBAs),
+		}
+	}
 	if err == nil {
 		monitoringSecret, err = r.reconcileMonitoringSecret(ctx, cluster)
 	}
@@ -315,17 +413,26 @@ func (r *Reconciler) Reconcile(
 			rootCA, clusterPodService, instanceServiceAccount, instances,
 			patroniLeaderService, primaryCertificate, clusterVolumes)
 	}
+	if err == nil {
+		rootRotationWritten(cluster, "instances")
+	}
 
 	if err == nil {
 		err = r.reconcilePostgresDatabases(ctx, cluster, instances)
 	}
 	if err == nil {
-		err = r.reconcilePostgresUsers(ctx, cluster, instances)
+		err = updateResult(r.reconcilePostgresUsers(ctx, cluster, instances, rootCA))
+	}
+	if err == nil {
+		rootRotationWritten(cluster, "users")
 	}
 
 	if err == nil {
 		err = updateResult(r.reconcilePGBackRest(ctx, cluster, instances, rootCA))
 	}
+	if err == nil {
+		err = updateResult(r.reconcileVolumeSnapshots(ctx, cluster, instances, clusterVolumes))
+	}
 	if err == nil {
 		err = r.reconcilePGBouncer(ctx, cluster, instances, primaryCertificate, rootCA)
 	}
@@ -338,6 +445,10 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		err = r.reconcilePGAdmin(ctx, cluster)
 	}
+	if err == nil {
+		err = updateResult(r.reconcileCertificateStatus(ctx, cluster,
+			instances, primaryCertificate, clusterReplicationSecret))
+	}
 	if err == nil {
 		// This is after [Reconciler.rolloutInstances] to ensure that recreating
 		// Pods takes precedence.
@@ -419,10 +530,10 @@ func (r *Reconciler) setOwnerReference(
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch
 
 // SetupWithManager adds the PostgresCluster controller to the provided runtime manager
-func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
+func (r *Reconciler) SetupWithManager(ctx context.Context, mgr manager.Manager) error {
 	if r.PodExec == nil {
 		var err error
-		r.PodExec, err = newPodExecutor(mgr.GetConfig())
+		r.PodExec, err = runtime.NewPodExecutor(mgr.GetConfig())
 		if err != nil {
 			return err
 		}
@@ -443,7 +554,7 @@ func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 		opts.MaxConcurrentReconciles = 2
 	}
 
-	return builder.ControllerManagedBy(mgr).
+	b := builder.ControllerManagedBy(mgr).
 		For(&v1beta1.PostgresCluster{}).
 		WithOptions(opts).
 		Owns(&corev1.ConfigMap{}).
@@ -461,6 +572,13 @@ func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 		Owns(&policyv1beta1.PodDisruptionBudget{}).
 		Watches(&source.Kind{Type: &corev1.Pod{}}, r.watchPods()).
 		Watches(&source.Kind{Type: &appsv1.StatefulSet{}},
-			r.controllerRefHandlerFuncs()). // watch all StatefulSets
-		Complete(r)
+			r.controllerRefHandlerFuncs()) // watch all StatefulSets
+
+	// PostgresImageCatalogs are cluster-scoped; a Role cannot grant access to them.
+	if !r.NamespaceScoped {
+		b = b.Watches(&source.Kind{Type: &v1beta1.PostgresImageCatalog{}},
+			handler.EnqueueRequestsFromMapFunc(r.watchImageCatalogs(ctx)))
+	}
+
+	return b.Complete(r)
 }
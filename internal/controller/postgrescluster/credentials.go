@@ -0,0 +1,137 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/adifri/postgres-operator/v5/internal/credentials"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// credentialStorePollInterval is how often to read passwords from the
+// credential store. The store does not say when they change.
+const credentialStorePollInterval = time.Minute
+
+// credentialStorePath returns where the credentials of user are kept in the
+// credential store.
+func credentialStorePath(cluster *v1beta1.PostgresCluster, user *v1beta1.PostgresUserSpec) string {
+	if user.CredentialStore != nil && user.CredentialStore.Path != "" {
+		return user.CredentialStore.Path
+	}
+	return cluster.Namespace + "/" + cluster.Name + "/" + string(user.Name)
+}
+
+// credentialStoreMode returns how the credentials of user relate to the
+// credential store, if at all.
+func credentialStoreMode(user *v1beta1.PostgresUserSpec) string {
+	if user.CredentialStore == nil {
+		return ""
+	}
+	if user.CredentialStore.Mode == "" {
+		return v1beta1.PostgresCredentialStoreModeSource
+	}
+	return user.CredentialStore.Mode
+}
+
+// sourcePostgresUserPassword returns existing with the password of user from
+// the credential store. When the store cannot provide a password, it emits a
+// Warning event and returns existing unchanged. It returns false when there
+// is no password to use at all. Either way, the password can change in the
+// store at any time, so call this again after credentialStorePollInterval.
+func (r *Reconciler) sourcePostgresUserPassword(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	user *v1beta1.PostgresUserSpec, existing *corev1.Secret,
+) (*corev1.Secret, bool) {
+	path := credentialStorePath(cluster, user)
+
+	var password string
+	if r.CredentialStore == nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CredentialStoreUnavailable",
+			"Unable to read the password of user %q: no credential store is configured",
+			user.Name)
+	} else if data, err := r.CredentialStore.Read(ctx, path); errors.Is(err, credentials.ErrNotFound) ||
+		(err == nil && data["password"] == "") {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CredentialNotFound",
+			"No password for user %q at %q in the credential store", user.Name, path)
+	} else if err != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CredentialStoreError",
+			"Unable to read the password of user %q: %v", user.Name, err)
+	} else {
+		password = data["password"]
+	}
+
+	// Keep the current password until the store provides one.
+	if password == "" {
+		return existing, existing != nil && len(existing.Data["password"]) > 0
+	}
+	if existing != nil && string(existing.Data["password"]) == password {
+		return existing, true
+	}
+
+	// The password changed, so its verifier must be generated again.
+	sourced := &corev1.Secret{Data: map[string][]byte{"password": []byte(password)}}
+	return sourced, true
+}
+
+// publishedCredentials returns the values of secret that go into the
+// credential store. Verifiers and client certificates stay in Kubernetes.
+func publishedCredentials(secret *corev1.Secret) map[string]string {
+	data := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		switch key {
+		case "verifier", corev1.TLSCertKey, corev1.TLSPrivateKeyKey, rootCertFile:
+		default:
+			data[key] = string(value)
+		}
+	}
+	return data
+}
+
+// publishPostgresUserCredentials writes the credentials in the Secret of user
+// to the credential store when they differ from what is stored there. Any
+// problem with the store is reported as a Warning event.
+func (r *Reconciler) publishPostgresUserCredentials(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	user *v1beta1.PostgresUserSpec, secret *corev1.Secret,
+) {
+	if r.CredentialStore == nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CredentialStoreUnavailable",
+			"Unable to publish the credentials of user %q: no credential store is configured",
+			user.Name)
+		return
+	}
+
+	path := credentialStorePath(cluster, user)
+	intent := publishedCredentials(secret)
+
+	stored, err := r.CredentialStore.Read(ctx, path)
+	if errors.Is(err, credentials.ErrNotFound) {
+		err = nil
+	}
+	if err == nil && !equality.Semantic.DeepEqual(stored, intent) {
+		err = r.CredentialStore.Write(ctx, path, intent)
+	}
+	if err != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "CredentialStoreError",
+			"Unable to publish the credentials of user %q: %v", user.Name, err)
+	}
+}
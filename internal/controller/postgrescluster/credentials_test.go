@@ -0,0 +1,282 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/credentials"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// memoryStore is a credentials.Store that keeps values in a map.
+type memoryStore struct {
+	data   map[string]map[string]string
+	err    error
+	writes int
+}
+
+func (m *memoryStore) Read(_ context.Context, path string) (map[string]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if data, ok := m.data[path]; ok {
+		return data, nil
+	}
+	return nil, credentials.ErrNotFound
+}
+
+func (m *memoryStore) Write(_ context.Context, path string, data map[string]string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.writes++
+	m.data[path] = data
+	return nil
+}
+
+// drainEvents returns the reasons of events recorded so far.
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var reasons []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			reasons = append(reasons, strings.Fields(event)[1])
+		default:
+			return reasons
+		}
+	}
+}
+
+func TestCredentialStorePath(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	user := &v1beta1.PostgresUserSpec{Name: "rhino"}
+	assert.Equal(t, credentialStorePath(cluster, user), "ns1/hippo/rhino")
+	assert.Equal(t, credentialStoreMode(user), "")
+
+	user.CredentialStore = &v1beta1.PostgresUserCredentialStoreSpec{}
+	assert.Equal(t, credentialStorePath(cluster, user), "ns1/hippo/rhino")
+	assert.Equal(t, credentialStoreMode(user), "Source")
+
+	user.CredentialStore.Path = "db/prod/rhino"
+	user.CredentialStore.Mode = "Publish"
+	assert.Equal(t, credentialStorePath(cluster, user), "db/prod/rhino")
+	assert.Equal(t, credentialStoreMode(user), "Publish")
+}
+
+func TestSourcePostgresUserPassword(t *testing.T) {
+	ctx := context.Background()
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	user := &v1beta1.PostgresUserSpec{
+		Name:            "rhino",
+		CredentialStore: &v1beta1.PostgresUserCredentialStoreSpec{},
+	}
+	existing := &corev1.Secret{Data: map[string][]byte{
+		"password": []byte("current"),
+		"verifier": []byte("SCRAM-SHA-256$current"),
+	}}
+
+	t.Run("Unconfigured", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder}
+
+		secret, ok := reconciler.sourcePostgresUserPassword(ctx, cluster, user, existing)
+		assert.Assert(t, ok)
+		assert.Assert(t, secret == existing)
+
+		_, ok = reconciler.sourcePostgresUserPassword(ctx, cluster, user, nil)
+		assert.Assert(t, !ok, "expected nothing to use")
+		assert.DeepEqual(t, drainEvents(recorder),
+			[]string{"CredentialStoreUnavailable", "CredentialStoreUnavailable"})
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder, CredentialStore: &memoryStore{
+			data: map[string]map[string]string{"ns1/hippo/rhino": {"user": "rhino"}},
+		}}
+
+		secret, ok := reconciler.sourcePostgresUserPassword(ctx, cluster, user, existing)
+		assert.Assert(t, ok)
+		assert.Assert(t, secret == existing)
+
+		other := user.DeepCopy()
+		other.CredentialStore.Path = "elsewhere"
+		_, ok = reconciler.sourcePostgresUserPassword(ctx, cluster, other, nil)
+		assert.Assert(t, !ok, "expected nothing to use")
+		assert.DeepEqual(t, drainEvents(recorder),
+			[]string{"CredentialNotFound", "CredentialNotFound"})
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder, CredentialStore: &memoryStore{
+			err: errors.New("sealed"),
+		}}
+
+		secret, ok := reconciler.sourcePostgresUserPassword(ctx, cluster, user, existing)
+		assert.Assert(t, ok)
+		assert.Assert(t, secret == existing)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"CredentialStoreError"})
+	})
+
+	t.Run("Same", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder, CredentialStore: &memoryStore{
+			data: map[string]map[string]string{"ns1/hippo/rhino": {"password": "current"}},
+		}}
+
+		secret, ok := reconciler.sourcePostgresUserPassword(ctx, cluster, user, existing)
+		assert.Assert(t, ok)
+		assert.Assert(t, secret == existing, "expected the verifier to be kept")
+		assert.Assert(t, len(drainEvents(recorder)) == 0)
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder, CredentialStore: &memoryStore{
+			data: map[string]map[string]string{"ns1/hippo/rhino": {"password": "vaulted"}},
+		}}
+
+		for _, existing := range []*corev1.Secret{existing, nil} {
+			secret, ok := reconciler.sourcePostgresUserPassword(ctx, cluster, user, existing)
+			assert.Assert(t, ok)
+			assert.Equal(t, string(secret.Data["password"]), "vaulted")
+			assert.Assert(t, len(secret.Data["verifier"]) == 0)
+		}
+		assert.Equal(t, string(existing.Data["password"]), "current", "expected no change")
+		assert.Assert(t, len(drainEvents(recorder)) == 0)
+
+		// The password from the store goes into the Secret with a new verifier.
+		scheme, err := runtime.CreatePostgresOperatorScheme()
+		assert.NilError(t, err)
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Port = initialize.Int32(5432)
+
+		secret, _ := reconciler.sourcePostgresUserPassword(ctx, cluster, user, existing)
+		intent, err := reconciler.generatePostgresUserSecret(cluster, user, secret)
+		assert.NilError(t, err)
+		assert.Equal(t, string(intent.Data["password"]), "vaulted")
+		assert.Assert(t, strings.HasPrefix(string(intent.Data["verifier"]), "SCRAM-SHA-256$"))
+	})
+}
+
+func TestReconcilePostgresUserSecretsSkipped(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Users = []v1beta1.PostgresUserSpec{{
+		Name:            "rhino",
+		CredentialStore: &v1beta1.PostgresUserCredentialStoreSpec{},
+	}}
+
+	reconciler := &Reconciler{
+		Client:          fake.NewClientBuilder().WithScheme(scheme).Build(),
+		CredentialStore: &memoryStore{data: map[string]map[string]string{}},
+		Recorder:        record.NewFakeRecorder(10),
+	}
+
+	// The user waits for the store, and the store is read again later.
+	users, secrets, result, err := reconciler.reconcilePostgresUserSecrets(ctx, cluster, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(users), 0)
+	assert.Equal(t, len(secrets), 0)
+	assert.Equal(t, result.RequeueAfter, credentialStorePollInterval)
+}
+
+func TestPublishPostgresUserCredentials(t *testing.T) {
+	ctx := context.Background()
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	user := &v1beta1.PostgresUserSpec{
+		Name: "rhino",
+		CredentialStore: &v1beta1.PostgresUserCredentialStoreSpec{
+			Mode: "Publish",
+		},
+	}
+	secret := &corev1.Secret{Data: map[string][]byte{
+		"user":     []byte("rhino"),
+		"password": []byte("generated"),
+		"verifier": []byte("SCRAM-SHA-256$generated"),
+		"tls.crt":  []byte("certificate"),
+		"tls.key":  []byte("private"),
+		"ca.crt":   []byte("authority"),
+	}}
+
+	t.Run("Unconfigured", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder}
+
+		reconciler.publishPostgresUserCredentials(ctx, cluster, user, secret)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"CredentialStoreUnavailable"})
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder, CredentialStore: &memoryStore{
+			err: errors.New("sealed"),
+		}}
+
+		reconciler.publishPostgresUserCredentials(ctx, cluster, user, secret)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"CredentialStoreError"})
+	})
+
+	t.Run("Written", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		store := &memoryStore{data: map[string]map[string]string{}}
+		reconciler := &Reconciler{Recorder: recorder, CredentialStore: store}
+
+		reconciler.publishPostgresUserCredentials(ctx, cluster, user, secret)
+		assert.Equal(t, store.writes, 1)
+		assert.DeepEqual(t, store.data["ns1/hippo/rhino"], map[string]string{
+			"user":     "rhino",
+			"password": "generated",
+		})
+
+		// Nothing is written when the store is up to date.
+		reconciler.publishPostgresUserCredentials(ctx, cluster, user, secret)
+		assert.Equal(t, store.writes, 1)
+
+		// Changes are written.
+		changed := secret.DeepCopy()
+		changed.Data["password"] = []byte("replaced")
+		reconciler.publishPostgresUserCredentials(ctx, cluster, user, changed)
+		assert.Equal(t, store.writes, 2)
+		assert.Equal(t, store.data["ns1/hippo/rhino"]["password"], "replaced")
+
+		assert.Assert(t, len(drainEvents(recorder)) == 0)
+	})
+}
@@ -0,0 +1,213 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// primaryCondition describes whether an instance of cluster accepts writes.
+func primaryCondition(instances *observedInstances) metav1.Condition {
+	condition := metav1.Condition{Type: v1beta1.PostgresClusterPrimaryAvailable}
+
+	if _, instance := instances.writablePod(naming.ContainerDatabase); instance != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "PrimaryRunning"
+		condition.Message = fmt.Sprintf("Instance %s is the primary.", instance.Name)
+	} else {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NoPrimary"
+		condition.Message = "No instance is accepting writes."
+	}
+	return condition
+}
+
+// replicasCondition describes whether every replica that cluster asks for is
+// ready.
+func replicasCondition(
+	cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) metav1.Condition {
+	condition := metav1.Condition{Type: v1beta1.PostgresClusterReplicasHealthy}
+
+	// One of the requested instances is the primary.
+	var requested int
+	for _, set := range cluster.Spec.InstanceSets {
+		if set.Replicas != nil {
+			requested += int(*set.Replicas)
+		}
+	}
+	requested--
+
+	var ready int
+	var unready []string
+	for _, instance := range instances.forCluster {
+		if instance.Spec == nil {
+			continue
+		}
+		if primary, known := instance.IsPrimary(); primary && known {
+			continue
+		}
+		if r, known := instance.IsReady(); r && known {
+			ready++
+		} else {
+			unready = append(unready, instance.Name)
+		}
+	}
+	sort.Strings(unready)
+
+	switch {
+	case requested <= 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "NoReplicas"
+		condition.Message = "The cluster has no replicas."
+	case ready >= requested && len(unready) == 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ReplicasReady"
+		condition.Message = fmt.Sprintf("%d of %d replicas are ready.", ready, requested)
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ReplicasNotReady"
+		condition.Message = fmt.Sprintf("%d of %d replicas are ready.", ready, requested)
+		if len(unready) > 0 {
+			condition.Message += " Not ready: " + strings.Join(unready, ", ") + "."
+		}
+	}
+	return condition
+}
+
+// backupsCondition describes whether every pgBackRest repository of cluster
+// is ready and the latest of each kind of backup did not fail.
+func backupsCondition(cluster *v1beta1.PostgresCluster) metav1.Condition {
+	condition := metav1.Condition{Type: v1beta1.PostgresClusterBackupsHealthy}
+
+	status := cluster.Status.PGBackRest
+	if status == nil {
+		status = &v1beta1.PGBackRestStatus{}
+	}
+
+	repos := make(map[string]v1beta1.RepoStatus, len(status.Repos))
+	for _, repo := range status.Repos {
+		repos[repo.Name] = repo
+	}
+
+	var unready []string
+	for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		observed, ok := repos[repo.Name]
+		if !ok || !observed.StanzaCreated || (repo.Volume != nil && !observed.Bound) {
+			unready = append(unready, repo.Name)
+		}
+	}
+
+	// A Job that is done without succeeding has failed. Only the most recent
+	// Job of each CronJob matters.
+	var failed []string
+	if manual := status.ManualBackup; manual != nil && manual.Finished && manual.Succeeded == 0 {
+		failed = append(failed, "manual")
+	}
+	latest := make(map[string]v1beta1.PGBackRestScheduledBackupStatus)
+	for _, scheduled := range status.ScheduledBackups {
+		if previous, ok := latest[scheduled.CronJobName]; !ok ||
+			(previous.StartTime != nil && scheduled.StartTime != nil &&
+				previous.StartTime.Before(scheduled.StartTime)) {
+			latest[scheduled.CronJobName] = scheduled
+		}
+	}
+	for name, scheduled := range latest {
+		if scheduled.Active == 0 && scheduled.Succeeded == 0 && scheduled.Failed > 0 {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+
+	switch {
+	case len(unready) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "RepoNotReady"
+		condition.Message = "Repositories not ready: " + strings.Join(unready, ", ") + "."
+	case len(failed) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "BackupFailed"
+		condition.Message = "The latest backups failed: " + strings.Join(failed, ", ") + "."
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "BackupsHealthy"
+		condition.Message = "Every repository is ready."
+	}
+	return condition
+}
+
+// setHealthConditions sets conditions on cluster that summarize its health.
+// "Ready" means the primary and every replica are ready. "Degraded" means any
+// part of the cluster is unhealthy after it started for the first time.
+func setHealthConditions(cluster *v1beta1.PostgresCluster, instances *observedInstances) {
+	parts := []metav1.Condition{
+		primaryCondition(instances),
+		replicasCondition(cluster, instances),
+		backupsCondition(cluster),
+	}
+
+	ready := metav1.Condition{
+		Type:    v1beta1.PostgresClusterReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "InstancesReady",
+		Message: "The primary and every replica are ready.",
+	}
+	for _, part := range parts[:2] {
+		if part.Status != metav1.ConditionTrue {
+			ready.Status, ready.Reason, ready.Message = part.Status, part.Reason, part.Message
+			break
+		}
+	}
+
+	degraded := metav1.Condition{
+		Type:    v1beta1.PostgresClusterDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Healthy",
+		Message: "Every part of the cluster is healthy.",
+	}
+	var problems []string
+	for _, part := range parts {
+		if part.Status != metav1.ConditionTrue {
+			if len(problems) == 0 {
+				degraded.Reason = part.Reason
+			}
+			problems = append(problems, part.Message)
+		}
+	}
+	if len(problems) > 0 {
+		degraded.Message = strings.Join(problems, " ")
+
+		// A cluster that has never run is starting, not degraded.
+		if cluster.Status.Patroni.SystemIdentifier == "" {
+			degraded.Reason = "Initializing"
+		} else {
+			degraded.Status = metav1.ConditionTrue
+		}
+	}
+
+	for _, condition := range append(parts, ready, degraded) {
+		condition.ObservedGeneration = cluster.GetGeneration()
+		meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+	}
+}
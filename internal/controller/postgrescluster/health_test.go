@@ -0,0 +1,167 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestSetHealthConditions(t *testing.T) {
+	// The cluster has three instances and a ready repository.
+	base := testCluster()
+	base.Generation = 2
+	base.Spec.InstanceSets[0].Replicas = initialize.Int32(3)
+	base.Status.Patroni.SystemIdentifier = "123"
+	base.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		Repos: []v1beta1.RepoStatus{{Name: "repo1", Bound: true, StanzaCreated: true}},
+	}
+
+	status := func(cluster *v1beta1.PostgresCluster) map[string]string {
+		out := make(map[string]string)
+		for _, condition := range cluster.Status.Conditions {
+			out[condition.Type] = string(condition.Status) + "/" + condition.Reason
+		}
+		return out
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		setHealthConditions(cluster, &observedInstances{forCluster: []*Instance{
+			testInstance("a", true, true), testInstance("b", false, true), testInstance("c", false, true),
+		}})
+
+		assert.DeepEqual(t, status(cluster), map[string]string{
+			"BackupsHealthy":   "True/BackupsHealthy",
+			"Degraded":         "False/Healthy",
+			"PrimaryAvailable": "True/PrimaryRunning",
+			"Ready":            "True/InstancesReady",
+			"ReplicasHealthy":  "True/ReplicasReady",
+		})
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, "PrimaryAvailable")
+		assert.Equal(t, condition.Message, "Instance a is the primary.")
+		assert.Equal(t, condition.ObservedGeneration, int64(2))
+	})
+
+	t.Run("ReplicaNotReady", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		setHealthConditions(cluster, &observedInstances{forCluster: []*Instance{
+			testInstance("a", true, true), testInstance("b", false, true), testInstance("c", false, false),
+		}})
+
+		assert.DeepEqual(t, status(cluster), map[string]string{
+			"BackupsHealthy":   "True/BackupsHealthy",
+			"Degraded":         "True/ReplicasNotReady",
+			"PrimaryAvailable": "True/PrimaryRunning",
+			"Ready":            "False/ReplicasNotReady",
+			"ReplicasHealthy":  "False/ReplicasNotReady",
+		})
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, "ReplicasHealthy")
+		assert.Equal(t, condition.Message, "1 of 2 replicas are ready. Not ready: c.")
+	})
+
+	t.Run("MissingReplica", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		setHealthConditions(cluster, &observedInstances{forCluster: []*Instance{
+			testInstance("a", true, true), testInstance("b", false, true),
+		}})
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, "ReplicasHealthy")
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Message, "1 of 2 replicas are ready.")
+	})
+
+	t.Run("NoPrimary", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		cluster.Spec.InstanceSets[0].Replicas = initialize.Int32(1)
+		setHealthConditions(cluster, &observedInstances{forCluster: []*Instance{
+			testInstance("a", false, false),
+		}})
+
+		assert.DeepEqual(t, status(cluster), map[string]string{
+			"BackupsHealthy":   "True/BackupsHealthy",
+			"Degraded":         "True/NoPrimary",
+			"PrimaryAvailable": "False/NoPrimary",
+			"Ready":            "False/NoPrimary",
+			"ReplicasHealthy":  "True/NoReplicas",
+		})
+	})
+
+	t.Run("Initializing", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		cluster.Status.Patroni.SystemIdentifier = ""
+		cluster.Status.PGBackRest = nil
+		setHealthConditions(cluster, &observedInstances{})
+
+		assert.DeepEqual(t, status(cluster), map[string]string{
+			"BackupsHealthy":   "False/RepoNotReady",
+			"Degraded":         "False/Initializing",
+			"PrimaryAvailable": "False/NoPrimary",
+			"Ready":            "False/NoPrimary",
+			"ReplicasHealthy":  "False/ReplicasNotReady",
+		})
+	})
+}
+
+func TestBackupsCondition(t *testing.T) {
+	cluster := testCluster()
+	cluster.Spec.Backups.PGBackRest.Repos = append(cluster.Spec.Backups.PGBackRest.Repos,
+		v1beta1.PGBackRestRepo{Name: "repo2", S3: &v1beta1.RepoS3{}})
+
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		Repos: []v1beta1.RepoStatus{
+			{Name: "repo1", StanzaCreated: true},
+			{Name: "repo2", StanzaCreated: true},
+		},
+	}
+
+	// A volume repository must be bound.
+	condition := backupsCondition(cluster)
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	assert.Equal(t, condition.Reason, "RepoNotReady")
+	assert.Equal(t, condition.Message, "Repositories not ready: repo1.")
+
+	cluster.Status.PGBackRest.Repos[0].Bound = true
+	assert.Equal(t, backupsCondition(cluster).Status, metav1.ConditionTrue)
+
+	// Only the latest Job of a CronJob matters.
+	at := func(hour int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2022, 3, 4, hour, 0, 0, 0, time.UTC)}
+	}
+	cluster.Status.PGBackRest.ScheduledBackups = []v1beta1.PGBackRestScheduledBackupStatus{
+		{CronJobName: "hippo-repo1-full", StartTime: at(1), Failed: 1},
+		{CronJobName: "hippo-repo1-full", StartTime: at(2), Succeeded: 1},
+		{CronJobName: "hippo-repo2-full", StartTime: at(2), Failed: 1, Active: 1},
+	}
+	assert.Equal(t, backupsCondition(cluster).Status, metav1.ConditionTrue)
+
+	cluster.Status.PGBackRest.ScheduledBackups[2].Active = 0
+	cluster.Status.PGBackRest.ManualBackup = &v1beta1.PGBackRestJobStatus{Finished: true, Failed: 1}
+
+	condition = backupsCondition(cluster)
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	assert.Equal(t, condition.Reason, "BackupFailed")
+	assert.Equal(t, condition.Message, "The latest backups failed: hippo-repo2-full, manual.")
+}
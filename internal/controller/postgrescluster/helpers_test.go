@@ -36,6 +36,7 @@ import (
 
 	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
 	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
 	"github.com/adifri/postgres-operator/v5/internal/testing/cmp"
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -208,6 +209,30 @@ func testCluster() *v1beta1.PostgresCluster {
 	return cluster.DeepCopy()
 }
 
+// testInstance returns an Instance with one pod that runs Postgres. The pod is
+// the Patroni leader when primary is true, and it is ready when ready is true.
+func testInstance(name string, primary, ready bool) *Instance {
+	pod := &corev1.Pod{}
+	pod.Name = name + "-0"
+	pod.Annotations = map[string]string{"status": `{"role":"replica"}`}
+	pod.Labels = map[string]string{naming.LabelRole: naming.RolePatroniReplica}
+	if primary {
+		pod.Annotations["status"] = `{"role":"master"}`
+		pod.Labels[naming.LabelRole] = naming.RolePatroniLeader
+	}
+	pod.Status.Conditions = []corev1.PodCondition{{
+		Type: corev1.PodReady, Status: corev1.ConditionFalse,
+	}}
+	if ready {
+		pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+	return &Instance{Name: name, Pods: []*corev1.Pod{pod}, Spec: &v1beta1.PostgresInstanceSetSpec{}}
+}
+
 // setupManager creates the runtime manager used during controller testing
 func setupManager(t *testing.T, cfg *rest.Config,
 	contollerSetup func(mgr manager.Manager)) (context.Context, context.CancelFunc) {
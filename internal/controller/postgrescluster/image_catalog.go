@@ -0,0 +1,162 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresimagecatalogs",verbs={get,list,watch}
+
+// catalogImages returns the images of cluster that come from catalog. Images
+// that are set in the spec of cluster are left empty.
+func catalogImages(
+	cluster *v1beta1.PostgresCluster, catalog *v1beta1.PostgresImageCatalog,
+) *v1beta1.PostgresImageCatalogStatus {
+	images := &v1beta1.PostgresImageCatalogStatus{Name: catalog.Name}
+	spec := &catalog.Spec
+
+	if cluster.Spec.Image == "" {
+		images.Postgres = spec.PostgresImage(
+			cluster.Spec.PostgresVersion, cluster.Spec.PostGISVersion)
+	}
+	if cluster.Spec.Backups.PGBackRest.Image == "" {
+		images.PGBackRest = spec.ComponentImage(v1beta1.ImageCatalogComponentPGBackRest)
+	}
+	if cluster.Spec.UserInterface == nil ||
+		cluster.Spec.UserInterface.PGAdmin == nil ||
+		cluster.Spec.UserInterface.PGAdmin.Image == "" {
+		images.PGAdmin = spec.ComponentImage(v1beta1.ImageCatalogComponentPGAdmin)
+	}
+	if cluster.Spec.Proxy == nil ||
+		cluster.Spec.Proxy.PGBouncer == nil ||
+		cluster.Spec.Proxy.PGBouncer.Image == "" {
+		images.PGBouncer = spec.ComponentImage(v1beta1.ImageCatalogComponentPGBouncer)
+	}
+	if cluster.Spec.Monitoring == nil ||
+		cluster.Spec.Monitoring.PGMonitor == nil ||
+		cluster.Spec.Monitoring.PGMonitor.Exporter == nil ||
+		cluster.Spec.Monitoring.PGMonitor.Exporter.Image == "" {
+		images.PGExporter = spec.ComponentImage(v1beta1.ImageCatalogComponentPGExporter)
+	}
+
+	return images
+}
+
+// reconcileImageCatalog resolves the images of cluster through the
+// PostgresImageCatalog in its spec and records them in its status, where
+// the functions of the config package find them. When the catalog is missing,
+// it keeps the images it resolved before. It returns false when cluster
+// should not be reconciled further because its images are not yet known.
+// A namespace-scoped operator cannot read catalogs, so it keeps the images
+// cluster has and reports that in a condition.
+func (r *Reconciler) reconcileImageCatalog(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (bool, error) {
+	reference := cluster.Spec.ImageCatalog
+	if reference == nil {
+		cluster.Status.ImageCatalog = nil
+
+		// Avoid a panic! Fixed in Kubernetes v1.21.0 and controller-runtime v0.9.0-alpha.0.
+		// - https://issue.k8s.io/99714
+		if len(cluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.ImageCatalogResolved)
+		}
+		return true, nil
+	}
+
+	condition := metav1.Condition{
+		Type:    v1beta1.ImageCatalogResolved,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Resolved",
+		Message: "Images are taken from the catalog.",
+
+		ObservedGeneration: cluster.Generation,
+	}
+
+	if r.NamespaceScoped {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NamespaceScoped"
+		condition.Message = "Image catalogs require the operator to be installed " +
+			"for the whole Kubernetes cluster. Using the images it had before."
+
+		// Record an event only when the catalog starts being ignored.
+		if previous := meta.FindStatusCondition(cluster.Status.Conditions,
+			v1beta1.ImageCatalogResolved); previous == nil ||
+			previous.Reason != condition.Reason {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ImageCatalogIgnored",
+				"PostgresImageCatalog %q is ignored; image catalogs require the operator "+
+					"to be installed for the whole cluster", reference.Name)
+		}
+		meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+		return true, nil
+	}
+
+	catalog := &v1beta1.PostgresImageCatalog{}
+	err := errors.WithStack(r.Client.Get(ctx,
+		client.ObjectKey{Name: reference.Name}, catalog))
+
+	if apierrors.IsNotFound(err) {
+		previous := cluster.Status.ImageCatalog
+		resolved := previous != nil && previous.Name == reference.Name
+
+		message := "Waiting for it to be created"
+		if resolved {
+			message = "Using the images it had before"
+		}
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ImageCatalogNotFound",
+			"PostgresImageCatalog %q not found. %s.", reference.Name, message)
+
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NotFound"
+		condition.Message = "PostgresImageCatalog not found. " + message + "."
+		meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+
+		return resolved, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	images := catalogImages(cluster, catalog)
+	if cluster.Spec.Image == "" && images.Postgres == "" {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ImageNotInCatalog",
+			"PostgresImageCatalog %q has no image for PostgreSQL %d%s; using the default",
+			reference.Name, cluster.Spec.PostgresVersion,
+			postGISSuffix(cluster.Spec.PostGISVersion))
+	}
+
+	cluster.Status.ImageCatalog = images
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+	return true, nil
+}
+
+// postGISSuffix describes version as part of a PostgreSQL version.
+func postGISSuffix(version string) string {
+	if version == "" {
+		return ""
+	}
+	return " with PostGIS " + version
+}
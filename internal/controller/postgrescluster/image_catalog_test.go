@@ -0,0 +1,202 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/adifri/postgres-operator/v5/internal/config"
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func testImageCatalog(t testing.TB) *v1beta1.PostgresImageCatalog {
+	catalog := &v1beta1.PostgresImageCatalog{}
+	catalog.Name = "stable"
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		postgres: [
+			{ postgresVersion: 13, image: postgres-13,
+				postGIS: [{ version: "3.1", image: postgis-13-3.1 }] },
+			{ postgresVersion: 14, image: postgres-14 },
+		],
+		components: [
+			{ name: pgadmin, image: pgadmin },
+			{ name: pgbackrest, image: pgbackrest },
+			{ name: pgbouncer, image: pgbouncer },
+			{ name: pgexporter, image: pgexporter },
+		],
+	}`), &catalog.Spec))
+	return catalog
+}
+
+func TestCatalogImages(t *testing.T) {
+	catalog := testImageCatalog(t)
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Spec.PostgresVersion = 14
+
+	assert.DeepEqual(t, catalogImages(cluster, catalog), &v1beta1.PostgresImageCatalogStatus{
+		Name:       "stable",
+		Postgres:   "postgres-14",
+		PGAdmin:    "pgadmin",
+		PGBackRest: "pgbackrest",
+		PGBouncer:  "pgbouncer",
+		PGExporter: "pgexporter",
+	})
+
+	cluster.Spec.PostgresVersion = 13
+	cluster.Spec.PostGISVersion = "3.1"
+	assert.Equal(t, catalogImages(cluster, catalog).Postgres, "postgis-13-3.1")
+
+	cluster.Spec.PostGISVersion = "3.0"
+	assert.Equal(t, catalogImages(cluster, catalog).Postgres, "")
+
+	// Images in the spec are not taken from the catalog.
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		image: spec-postgres,
+		backups: { pgbackrest: { image: spec-pgbackrest } },
+		monitoring: { pgmonitor: { exporter: { image: spec-exporter } } },
+		proxy: { pgBouncer: { image: spec-pgbouncer } },
+		userInterface: { pgAdmin: { image: spec-pgadmin } },
+	}`), &cluster.Spec))
+	assert.DeepEqual(t, catalogImages(cluster, catalog),
+		&v1beta1.PostgresImageCatalogStatus{Name: "stable"})
+}
+
+func TestReconcileImageCatalog(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	base := &v1beta1.PostgresCluster{}
+	base.Namespace, base.Name = "ns1", "hippo"
+	base.Spec.PostgresVersion = 14
+	base.Spec.ImageCatalog = &v1beta1.PostgresImageCatalogReference{Name: "stable"}
+
+	t.Run("Unset", func(t *testing.T) {
+		reconciler := &Reconciler{}
+		cluster := base.DeepCopy()
+		cluster.Spec.ImageCatalog = nil
+		cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{Name: "stable"}
+		cluster.Status.Conditions = []metav1.Condition{{Type: "ImageCatalogResolved"}}
+
+		ok, err := reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.Assert(t, cluster.Status.ImageCatalog == nil)
+		assert.Assert(t, len(cluster.Status.Conditions) == 0)
+	})
+
+	t.Run("NamespaceScoped", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{NamespaceScoped: true, Recorder: recorder}
+		cluster := base.DeepCopy()
+		previous := &v1beta1.PostgresImageCatalogStatus{Name: "stable", Postgres: "before"}
+		cluster.Status.ImageCatalog = previous.DeepCopy()
+
+		// The catalog is not read; the images the cluster has are kept.
+		ok, err := reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, cluster.Status.ImageCatalog, previous)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, "ImageCatalogResolved")
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "NamespaceScoped")
+
+		// The event is recorded only when the catalog starts being ignored.
+		ok, err = reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"ImageCatalogIgnored"})
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).Build(),
+			Recorder: recorder,
+		}
+
+		// A new cluster waits for its catalog.
+		cluster := base.DeepCopy()
+		ok, err := reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !ok)
+		assert.Assert(t, cluster.Status.ImageCatalog == nil)
+
+		// An existing cluster keeps the images it has.
+		previous := &v1beta1.PostgresImageCatalogStatus{Name: "stable", Postgres: "before"}
+		cluster.Status.ImageCatalog = previous.DeepCopy()
+		ok, err = reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, cluster.Status.ImageCatalog, previous)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, "ImageCatalogResolved")
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "NotFound")
+
+		// Images from another catalog are not kept.
+		cluster.Status.ImageCatalog.Name = "other"
+		ok, err = reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !ok)
+
+		assert.DeepEqual(t, drainEvents(recorder), []string{
+			"ImageCatalogNotFound", "ImageCatalogNotFound", "ImageCatalogNotFound",
+		})
+	})
+
+	t.Run("Found", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(testImageCatalog(t)).Build(),
+			Recorder: recorder,
+		}
+
+		cluster := base.DeepCopy()
+		cluster.Status.ImageCatalog = &v1beta1.PostgresImageCatalogStatus{Name: "stable", Postgres: "before"}
+
+		ok, err := reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.Equal(t, cluster.Status.ImageCatalog.Postgres, "postgres-14")
+		assert.Equal(t, config.PostgresContainerImage(cluster), "postgres-14")
+		assert.Equal(t, config.PGBackRestContainerImage(cluster), "pgbackrest")
+		assert.Assert(t, len(drainEvents(recorder)) == 0)
+		assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, "ImageCatalogResolved"))
+
+		// A version that is not in the catalog comes from the environment.
+		cluster.Spec.PostgresVersion = 12
+		ok, err = reconciler.reconcileImageCatalog(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.Equal(t, cluster.Status.ImageCatalog.Postgres, "")
+		assert.DeepEqual(t, drainEvents(recorder), []string{"ImageNotInCatalog"})
+	})
+}
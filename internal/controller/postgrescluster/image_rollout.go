@@ -0,0 +1,290 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/config"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// imageRolloutHistory is how many image rollouts are kept in status.
+	imageRolloutHistory = 10
+
+	// imageRolloutRequeue is how often health checks run while an image
+	// rollout is being verified.
+	imageRolloutRequeue = 10 * time.Second
+)
+
+// updateStrategy returns the update strategy of cluster with defaults.
+func updateStrategy(cluster *v1beta1.PostgresCluster) (string, time.Duration, int64) {
+	kind := v1beta1.PostgresUpdateStrategyRolling
+	timeout := 300 * time.Second
+	maxLag := resource.MustParse("16Mi")
+
+	if spec := cluster.Spec.UpdateStrategy; spec != nil {
+		if spec.Type != "" {
+			kind = spec.Type
+		}
+		if spec.HealthCheckTimeoutSeconds != nil {
+			timeout = time.Duration(*spec.HealthCheckTimeoutSeconds) * time.Second
+		}
+		if spec.MaxReplicationLag != nil {
+			maxLag = *spec.MaxReplicationLag
+		}
+	}
+	return kind, timeout, maxLag.Value()
+}
+
+// latestImageRollout returns the most recent image rollout of cluster, if any.
+func latestImageRollout(cluster *v1beta1.PostgresCluster) *v1beta1.PostgresImageRolloutStatus {
+	if n := len(cluster.Status.ImageRollouts); n > 0 {
+		return &cluster.Status.ImageRollouts[n-1]
+	}
+	return nil
+}
+
+// imageRolloutHolds returns true when instance should not be redeployed
+// because the instances that already run the new image are being verified.
+func imageRolloutHolds(cluster *v1beta1.PostgresCluster, instance *Instance) bool {
+	latest := latestImageRollout(cluster)
+	return latest != nil &&
+		latest.Phase == v1beta1.PostgresImageRolloutVerifying &&
+		instancePostgresImage(instance) != latest.ToImage
+}
+
+// instancePostgresImage returns the PostgreSQL image that instance is running.
+// It returns an empty string when that is not known.
+func instancePostgresImage(instance *Instance) string {
+	if len(instance.Pods) == 1 {
+		for _, container := range instance.Pods[0].Spec.Containers {
+			if container.Name == naming.ContainerDatabase {
+				return container.Image
+			}
+		}
+	}
+	return ""
+}
+
+// startImageRollout records a new image rollout when instances of cluster run
+// an image other than the one specified. It returns the latest rollout.
+func (r *Reconciler) startImageRollout(
+	cluster *v1beta1.PostgresCluster, instances *observedInstances, now metav1.Time,
+) *v1beta1.PostgresImageRolloutStatus {
+	specified := config.SpecifiedPostgresContainerImage(cluster)
+	latest := latestImageRollout(cluster)
+
+	if latest != nil && latest.ToImage == specified {
+		return latest
+	}
+
+	// Prefer the image of the primary as the one to return to.
+	var from string
+	for _, instance := range instances.forCluster {
+		image := instancePostgresImage(instance)
+		if instance.Spec == nil || image == "" || image == specified {
+			continue
+		}
+		if primary, known := instance.IsPrimary(); (known && primary) || from == "" {
+			from = image
+		}
+	}
+
+	// Nothing to do when every instance already runs the specified image.
+	if from == "" {
+		return latest
+	}
+
+	if latest != nil && (latest.Phase == v1beta1.PostgresImageRolloutProgressing ||
+		latest.Phase == v1beta1.PostgresImageRolloutVerifying) {
+		latest.Phase = v1beta1.PostgresImageRolloutSuperseded
+		latest.CompletionTime = &now
+	}
+
+	cluster.Status.ImageRollouts = append(cluster.Status.ImageRollouts,
+		v1beta1.PostgresImageRolloutStatus{
+			FromImage: from,
+			ToImage:   specified,
+			Phase:     v1beta1.PostgresImageRolloutProgressing,
+			StartTime: now,
+		})
+	if n := len(cluster.Status.ImageRollouts); n > imageRolloutHistory {
+		cluster.Status.ImageRollouts = cluster.Status.ImageRollouts[n-imageRolloutHistory:]
+	}
+
+	r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "ImageRolloutStarted",
+		"Updating PostgreSQL from %q to %q", from, specified)
+
+	return latestImageRollout(cluster)
+}
+
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileImageRollout tracks changes to the PostgreSQL image of cluster when
+// its update strategy is "Verified". It checks the health of every instance
+// that runs the new image and holds the rollout until they are healthy. When
+// an instance is not healthy in time, it reverts the rollout so that
+// instances return to the previous image.
+func (r *Reconciler) reconcileImageRollout(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	kind, timeout, maxLag := updateStrategy(cluster)
+	if kind != v1beta1.PostgresUpdateStrategyVerified {
+		return reconcile.Result{}, nil
+	}
+
+	now := metav1.Now()
+	rollout := r.startImageRollout(cluster, instances, now)
+	if rollout == nil ||
+		(rollout.Phase != v1beta1.PostgresImageRolloutProgressing &&
+			rollout.Phase != v1beta1.PostgresImageRolloutVerifying) {
+		return reconcile.Result{}, nil
+	}
+
+	// The primary knows how far each replica is behind. Ask only when there
+	// is a replica to check.
+	var replicas map[string]postgres.ReplicaStatus
+	replicaStatus := func() (map[string]postgres.ReplicaStatus, error) {
+		if replicas != nil {
+			return replicas, nil
+		}
+		pod, _ := instances.writablePod(naming.ContainerDatabase)
+		if pod == nil {
+			return nil, nil
+		}
+		exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+			return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+		}
+		var err error
+		replicas, err = postgres.ReplicaStatuses(ctx, exec)
+		return replicas, errors.WithStack(err)
+	}
+
+	sorted := append([]*Instance(nil), instances.forCluster...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var verified []string
+	var waiting string
+	upgraded := true
+
+	for _, instance := range sorted {
+		if instance.Spec == nil {
+			continue
+		}
+
+		terminating, known := instance.IsTerminating()
+		if !known || terminating {
+			upgraded = false
+			if waiting == "" {
+				waiting = fmt.Sprintf("Waiting for instance %q to be recreated", instance.Name)
+			}
+			continue
+		}
+		if instancePostgresImage(instance) != rollout.ToImage {
+			upgraded = false
+			continue
+		}
+
+		healthy, reason := true, ""
+		primary, _ := instance.IsPrimary()
+
+		if ready, known := instance.IsReady(); !known || !ready {
+			healthy, reason = false, "is not ready"
+		} else if !primary {
+			status, err := replicaStatus()
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+
+			replica, ok := status[instance.Pods[0].Name]
+			switch {
+			case status == nil:
+				healthy, reason = false, "has no primary to stream from"
+			case !ok:
+				healthy, reason = false, "is not connected to the primary"
+			case replica.State != "streaming":
+				healthy, reason = false, fmt.Sprintf("is %s rather than streaming", replica.State)
+			case replica.Lag < 0 || replica.Lag > maxLag:
+				healthy, reason = false, fmt.Sprintf(
+					"has %d bytes of WAL to replay; the most allowed is %d", replica.Lag, maxLag)
+			}
+		}
+
+		if healthy {
+			verified = append(verified, instance.Name)
+			continue
+		}
+
+		// Measure from when the pod started with the new image or when the
+		// rollout started, whichever is later.
+		since := instance.Pods[0].CreationTimestamp.Time
+		if rollout.StartTime.After(since) {
+			since = rollout.StartTime.Time
+		}
+
+		if now.Sub(since) > timeout {
+			rollout.Phase = v1beta1.PostgresImageRolloutRolledBack
+			rollout.Message = fmt.Sprintf("Instance %q %s after %v", instance.Name, reason, timeout)
+			rollout.Verified = verified
+			rollout.CompletionTime = &now
+
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ImageRolloutRolledBack",
+				"Returning PostgreSQL to %q: %s", rollout.FromImage, rollout.Message)
+			return reconcile.Result{}, nil
+		}
+
+		if waiting == "" {
+			waiting = fmt.Sprintf("Instance %q %s", instance.Name, reason)
+		}
+	}
+
+	rollout.Verified = verified
+
+	switch {
+	case waiting != "":
+		rollout.Phase = v1beta1.PostgresImageRolloutVerifying
+		rollout.Message = waiting
+		return reconcile.Result{RequeueAfter: imageRolloutRequeue}, nil
+
+	case upgraded:
+		rollout.Phase = v1beta1.PostgresImageRolloutComplete
+		rollout.Message = ""
+		rollout.CompletionTime = &now
+
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "ImageRolloutComplete",
+			"Updated PostgreSQL from %q to %q", rollout.FromImage, rollout.ToImage)
+		return reconcile.Result{}, nil
+
+	default:
+		rollout.Phase = v1beta1.PostgresImageRolloutProgressing
+		rollout.Message = ""
+		return reconcile.Result{}, nil
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/config"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// imageRolloutInstance returns a ready instance whose pod runs image and was
+// created age ago.
+func imageRolloutInstance(name, image string, primary bool, age time.Duration) *Instance {
+	instance := testInstance(name, primary, true)
+	instance.Pods[0].CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+	instance.Pods[0].Spec.Containers = []corev1.Container{
+		{Name: naming.ContainerDatabase, Image: image},
+	}
+	return instance
+}
+
+// imageRolloutReconciler returns a reconciler that reports the replicas of
+// the primary as stdout.
+func imageRolloutReconciler(t testing.TB, stdout string) (*Reconciler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	return &Reconciler{
+		Recorder: recorder,
+		PodExec: func(
+			namespace, pod, container string, _ io.Reader, out, _ io.Writer, _ ...string,
+		) error {
+			assert.Equal(t, pod, "primary-0")
+			assert.Equal(t, container, naming.ContainerDatabase)
+			_, err := io.WriteString(out, stdout)
+			return err
+		},
+	}, recorder
+}
+
+func TestReconcileImageRollout(t *testing.T) {
+	ctx := context.Background()
+
+	base := &v1beta1.PostgresCluster{}
+	base.Namespace, base.Name = "ns1", "hippo"
+	base.Spec.Image = "postgres:new"
+	base.Spec.UpdateStrategy = &v1beta1.PostgresUpdateStrategySpec{
+		Type: v1beta1.PostgresUpdateStrategyVerified,
+	}
+
+	t.Run("Rolling", func(t *testing.T) {
+		reconciler, recorder := imageRolloutReconciler(t, `{}`)
+		cluster := base.DeepCopy()
+		cluster.Spec.UpdateStrategy = nil
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{imageRolloutInstance("primary", "postgres:old", true, time.Hour)},
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, len(cluster.Status.ImageRollouts) == 0)
+		assert.Assert(t, len(drainEvents(recorder)) == 0)
+	})
+
+	t.Run("Start", func(t *testing.T) {
+		reconciler, recorder := imageRolloutReconciler(t, `{}`)
+		cluster := base.DeepCopy()
+
+		_, err := reconciler.reconcileImageRollout(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{
+				imageRolloutInstance("primary", "postgres:old", true, time.Hour),
+				imageRolloutInstance("replica", "postgres:other", false, time.Hour),
+			},
+		})
+		assert.NilError(t, err)
+		assert.Assert(t, len(cluster.Status.ImageRollouts) == 1)
+
+		rollout := cluster.Status.ImageRollouts[0]
+		assert.Equal(t, rollout.FromImage, "postgres:old", "expected the image of the primary")
+		assert.Equal(t, rollout.ToImage, "postgres:new")
+		assert.Equal(t, rollout.Phase, v1beta1.PostgresImageRolloutProgressing)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"ImageRolloutStarted"})
+
+		// Another image supersedes the rollout.
+		cluster.Spec.Image = "postgres:newer"
+		_, err = reconciler.reconcileImageRollout(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{imageRolloutInstance("primary", "postgres:old", true, time.Hour)},
+		})
+		assert.NilError(t, err)
+		assert.Assert(t, len(cluster.Status.ImageRollouts) == 2)
+		assert.Equal(t, cluster.Status.ImageRollouts[0].Phase, v1beta1.PostgresImageRolloutSuperseded)
+		assert.Equal(t, cluster.Status.ImageRollouts[1].ToImage, "postgres:newer")
+	})
+
+	t.Run("Verifying", func(t *testing.T) {
+		reconciler, _ := imageRolloutReconciler(t, `{"replica-0":{"state":"catchup","lag":100}}`)
+		cluster := base.DeepCopy()
+
+		primary := imageRolloutInstance("primary", "postgres:old", true, time.Hour)
+		replica := imageRolloutInstance("replica", "postgres:new", false, time.Second)
+		observed := &observedInstances{forCluster: []*Instance{primary, replica}}
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+
+		rollout := latestImageRollout(cluster)
+		assert.Equal(t, rollout.Phase, v1beta1.PostgresImageRolloutVerifying)
+		assert.Assert(t, len(rollout.Verified) == 0)
+		assert.Equal(t, rollout.Message, `Instance "replica" is catchup rather than streaming`)
+
+		// The primary is not replaced while the replica is verified.
+		assert.Assert(t, imageRolloutHolds(cluster, primary))
+		assert.Assert(t, !imageRolloutHolds(cluster, replica))
+	})
+
+	t.Run("Progressing", func(t *testing.T) {
+		reconciler, _ := imageRolloutReconciler(t, `{"replica-0":{"state":"streaming","lag":100}}`)
+		cluster := base.DeepCopy()
+
+		primary := imageRolloutInstance("primary", "postgres:old", true, time.Hour)
+		replica := imageRolloutInstance("replica", "postgres:new", false, time.Second)
+		observed := &observedInstances{forCluster: []*Instance{primary, replica}}
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+
+		rollout := latestImageRollout(cluster)
+		assert.Equal(t, rollout.Phase, v1beta1.PostgresImageRolloutProgressing)
+		assert.DeepEqual(t, rollout.Verified, []string{"replica"})
+		assert.Assert(t, !imageRolloutHolds(cluster, primary))
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		reconciler, recorder := imageRolloutReconciler(t, `{"replica-0":{"state":"streaming","lag":0}}`)
+		cluster := base.DeepCopy()
+		cluster.Status.ImageRollouts = []v1beta1.PostgresImageRolloutStatus{{
+			FromImage: "postgres:old", ToImage: "postgres:new",
+			Phase:     v1beta1.PostgresImageRolloutProgressing,
+			StartTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+		}}
+
+		_, err := reconciler.reconcileImageRollout(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{
+				imageRolloutInstance("primary", "postgres:new", true, time.Second),
+				imageRolloutInstance("replica", "postgres:new", false, time.Minute),
+			},
+		})
+		assert.NilError(t, err)
+
+		rollout := latestImageRollout(cluster)
+		assert.Equal(t, rollout.Phase, v1beta1.PostgresImageRolloutComplete)
+		assert.DeepEqual(t, rollout.Verified, []string{"primary", "replica"})
+		assert.Assert(t, rollout.CompletionTime != nil)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"ImageRolloutComplete"})
+	})
+
+	t.Run("RolledBack", func(t *testing.T) {
+		reconciler, recorder := imageRolloutReconciler(t, `{"replica-0":{"state":"streaming","lag":1073741824}}`)
+		cluster := base.DeepCopy()
+		cluster.Status.ImageRollouts = []v1beta1.PostgresImageRolloutStatus{{
+			FromImage: "postgres:old", ToImage: "postgres:new",
+			Phase:     v1beta1.PostgresImageRolloutVerifying,
+			StartTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+		}}
+
+		_, err := reconciler.reconcileImageRollout(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{
+				imageRolloutInstance("primary", "postgres:old", true, time.Hour),
+				imageRolloutInstance("replica", "postgres:new", false, 10*time.Minute),
+			},
+		})
+		assert.NilError(t, err)
+
+		rollout := latestImageRollout(cluster)
+		assert.Equal(t, rollout.Phase, v1beta1.PostgresImageRolloutRolledBack)
+		assert.Assert(t, rollout.CompletionTime != nil)
+		assert.DeepEqual(t, drainEvents(recorder), []string{"ImageRolloutRolledBack"})
+
+		// Instances return to the previous image.
+		assert.Equal(t, config.PostgresContainerImage(cluster), "postgres:old")
+
+		// The rollout is not started again for the same image.
+		_, err = reconciler.reconcileImageRollout(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{imageRolloutInstance("primary", "postgres:old", true, time.Hour)},
+		})
+		assert.NilError(t, err)
+		assert.Assert(t, len(cluster.Status.ImageRollouts) == 1)
+	})
+}
@@ -320,10 +320,18 @@ func (r *Reconciler) observeInstances(
 
 	observed := newObservedInstances(cluster, runners.Items, pods.Items)
 
+	// Keep the sizes of volumes that grew automatically.
+	previous := make(map[string]v1beta1.PostgresInstanceSetStatus)
+	for _, status := range cluster.Status.InstanceSets {
+		previous[status.Name] = status
+	}
+
 	// Fill out status sorted by set name.
 	cluster.Status.InstanceSets = cluster.Status.InstanceSets[:0]
 	for _, name := range observed.setNames.List() {
 		status := v1beta1.PostgresInstanceSetStatus{Name: name}
+		status.DataVolumeSize = previous[name].DataVolumeSize
+		status.WALVolumeSize = previous[name].WALVolumeSize
 
 		for _, instance := range observed.bySet[name] {
 			status.Replicas += int32(len(instance.Pods))
@@ -527,13 +535,25 @@ func (r *Reconciler) reconcileInstanceSets(
 		numInstancePods += len(instances.forCluster[i].Pods)
 	}
 
+	// Replace instances whose volumes cannot change to match their set. This
+	// adds an instance to any set being migrated.
+	surge, err := r.reconcileVolumeMigration(ctx, cluster, instances, clusterVolumes)
+	if err != nil {
+		return err
+	}
+
 	// Range over instance sets to scale up and ensure that each set has
 	// at least the number of replicas defined in the spec. The set can
 	// have more replicas than defined
 	for i := range cluster.Spec.InstanceSets {
 		set := &cluster.Spec.InstanceSets[i]
+		scaled := set
+		if surge[set.Name] > 0 {
+			scaled = set.DeepCopy()
+			scaled.Replicas = initialize.Int32(*set.Replicas + int32(surge[set.Name]))
+		}
 		_, err := r.scaleUpInstances(
-			ctx, cluster, instances, set,
+			ctx, cluster, instances, scaled,
 			clusterConfigMap, clusterReplicationSecret,
 			rootCA, clusterPodService, instanceServiceAccount,
 			patroniLeaderService, primaryCertificate,
@@ -551,7 +571,7 @@ func (r *Reconciler) reconcileInstanceSets(
 	// Scaledown is called on the whole cluster in order to consider all
 	// instances. This is necessary because we have no way to determine
 	// which instance or instance set contains the primary pod.
-	err := r.scaleDownInstances(ctx, cluster, instances)
+	err = r.scaleDownInstances(ctx, cluster, instances, surge)
 	if err != nil {
 		return err
 	}
@@ -565,6 +585,9 @@ func (r *Reconciler) reconcileInstanceSets(
 	// Rollout changes to instances by calling rolloutInstance.
 	err = r.rolloutInstances(ctx, cluster, instances,
 		func(ctx context.Context, instance *Instance) error {
+			if imageRolloutHolds(cluster, instance) {
+				return nil
+			}
 			return r.rolloutInstance(ctx, cluster, instances, instance)
 		})
 
@@ -713,7 +736,14 @@ func (r *Reconciler) rolloutInstance(
 		ctx, span = r.Tracer.Start(ctx, "patroni-change-primary")
 		defer span.End()
 
+		// Pause PgBouncer so client queries wait for the new primary. A later
+		// reconcile resumes it. See [Reconciler.resumePGBouncer].
+		if err := r.pausePGBouncer(ctx, cluster); err != nil {
+			span.RecordError(err)
+			return err
+		}
 		success, err := patroni.Executor(exec).ChangePrimaryAndWait(ctx, pod.Name, "")
+
 		if err = errors.WithStack(err); err == nil && !success {
 			err = errors.New("unable to switchover")
 		}
@@ -827,13 +857,18 @@ func (r *Reconciler) rolloutInstances(
 			numAvailable++
 		}
 
+		// Skip instances that the rollout policy is holding back.
+		if !rolloutAllows(cluster, instance) {
+			continue
+		}
+
 		if matches, known := instance.PodMatchesPodTemplate(); known && !matches {
 			consider = append(consider, instance)
 			continue
 		}
 	}
 
-	const maxUnavailable = 1
+	maxUnavailable := rolloutMaxUnavailable(cluster)
 	numUnavailable := numSpecified - numAvailable
 
 	// When multiple instances need to redeploy, sort them so the lowest
@@ -880,12 +915,14 @@ func (r *Reconciler) scaleDownInstances(
 	ctx context.Context,
 	cluster *v1beta1.PostgresCluster,
 	observedInstances *observedInstances,
+	surge map[string]int,
 ) error {
 
-	// want defines the number of replicas we want for each instance set
+	// want defines the number of replicas we want for each instance set,
+	// including any instances added to replace others
 	want := map[string]int{}
 	for _, set := range cluster.Spec.InstanceSets {
-		want[set.Name] = int(*set.Replicas)
+		want[set.Name] = int(*set.Replicas) + surge[set.Name]
 	}
 
 	// grab all pods for the cluster using the observed instances
@@ -1067,6 +1104,7 @@ func (r *Reconciler) reconcileInstance(
 		instanceCertificates *corev1.Secret
 		postgresDataVolume   *corev1.PersistentVolumeClaim
 		postgresWALVolume    *corev1.PersistentVolumeClaim
+		tablespaceVolumes    map[string]*corev1.PersistentVolumeClaim
 	)
 
 	if err == nil {
@@ -1083,23 +1121,59 @@ func (r *Reconciler) reconcileInstance(
 		postgresWALVolume, err = r.reconcilePostgresWALVolume(ctx, cluster, spec, instance, observed, clusterVolumes)
 	}
 	if err == nil {
-		postgres.InstancePod(
-			ctx, cluster, spec,
-			primaryCertificate, replicationCertSecretProjection(clusterReplicationSecret),
-			postgresDataVolume, postgresWALVolume,
-			&instance.Spec.Template.Spec)
-
-		addPGBackRestToInstancePodSpec(
-			cluster, instanceCertificates, &instance.Spec.Template.Spec)
+		tablespaceVolumes, err = r.reconcilePostgresTablespaceVolumes(ctx, cluster, spec, instance, clusterVolumes)
+	}
+	if err == nil {
+		err = generateInstancePod(ctx, cluster, spec,
+			clusterConfigMap, clusterReplicationSecret, clusterPodService,
+			patroniLeaderService, primaryCertificate, instanceConfigMap,
+			instanceCertificates, postgresDataVolume, postgresWALVolume,
+			tablespaceVolumes, &instance.Spec.Template)
+	}
 
-		err = patroni.InstancePod(
-			ctx, cluster, clusterConfigMap, clusterPodService, patroniLeaderService,
-			spec, instanceCertificates, instanceConfigMap, &instance.Spec.Template)
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, instance))
+	}
+	if err == nil {
+		log.V(1).Info("reconciled instance", "instance", instance.Name)
 	}
 
+	return err
+}
+
+// generateInstancePod populates template with the PostgreSQL, Patroni, and
+// pgBackRest containers of an instance and everything they mount.
+func generateInstancePod(
+	ctx context.Context,
+	cluster *v1beta1.PostgresCluster,
+	spec *v1beta1.PostgresInstanceSetSpec,
+	clusterConfigMap *corev1.ConfigMap,
+	clusterReplicationSecret *corev1.Secret,
+	clusterPodService *corev1.Service,
+	patroniLeaderService *corev1.Service,
+	primaryCertificate *corev1.SecretProjection,
+	instanceConfigMap *corev1.ConfigMap,
+	instanceCertificates *corev1.Secret,
+	postgresDataVolume, postgresWALVolume *corev1.PersistentVolumeClaim,
+	tablespaceVolumes map[string]*corev1.PersistentVolumeClaim,
+	template *corev1.PodTemplateSpec,
+) error {
+	postgres.InstancePod(
+		ctx, cluster, spec,
+		primaryCertificate, replicationCertSecretProjection(clusterReplicationSecret),
+		postgresDataVolume, postgresWALVolume, tablespaceVolumes,
+		&template.Spec)
+
+	addPGBackRestToInstancePodSpec(
+		cluster, instanceCertificates, &template.Spec)
+
+	err := patroni.InstancePod(
+		ctx, cluster, clusterConfigMap, clusterPodService, patroniLeaderService,
+		spec, instanceCertificates, instanceConfigMap, template)
+
 	// Add pgMonitor resources to the instance Pod spec
 	if err == nil {
-		err = addPGMonitorToInstancePodSpec(cluster, &instance.Spec.Template)
+		err = addPGMonitorToInstancePodSpec(cluster, template)
 	}
 
 	// add nss_wrapper init container and add nss_wrapper env vars to the database and pgbackrest
@@ -1108,25 +1182,18 @@ func (r *Reconciler) reconcileInstance(
 		addNSSWrapper(
 			config.PostgresContainerImage(cluster),
 			cluster.Spec.ImagePullPolicy,
-			&instance.Spec.Template)
-
+			template)
 	}
+
 	// add an emptyDir volume to the PodTemplateSpec and an associated '/tmp' volume mount to
 	// all containers included within that spec
 	if err == nil {
-		addTMPEmptyDir(&instance.Spec.Template)
+		addTMPEmptyDir(template)
 	}
 
 	// mount shared memory to the Postgres instance
 	if err == nil {
-		addDevSHM(&instance.Spec.Template)
-	}
-
-	if err == nil {
-		err = errors.WithStack(r.apply(ctx, instance))
-	}
-	if err == nil {
-		log.V(1).Info("reconciled instance", "instance", instance.Name)
+		addDevSHM(template)
 	}
 
 	return err
@@ -1351,19 +1418,30 @@ func (r *Reconciler) reconcileInstanceCertificates(
 	instanceCerts.Type = corev1.SecretTypeOpaque
 	instanceCerts.Data = make(map[string][]byte)
 
+	trusted := root.TrustBundle()
 	var leafCert *pki.LeafCertificate
 
-	if err == nil {
+	if err == nil && cluster.Spec.CertificateIssuer != nil {
+		// Use the certificate and authority from the issuer.
+		var issued *issuedCertificate
+		issued, err = r.reconcileIssuedCertificate(ctx, cluster,
+			naming.InstanceIssuedCertificate(instance), "",
+			naming.InstancePodDNSNames(ctx, instance), issuedServerUsages)
+		if err == nil {
+			trusted = pki.CertificateBundle{issued.Authority}
+			leafCert = issued.Leaf
+		}
+	} else if err == nil {
 		leafCert, err = r.instanceCertificate(ctx, instance, existing, instanceCerts, root)
 	}
 	if err == nil {
 		err = patroni.InstanceCertificates(ctx,
-			root.Certificate, leafCert.Certificate,
+			trusted, leafCert.Certificate,
 			leafCert.PrivateKey, instanceCerts)
 	}
 	if err == nil {
 		err = pgbackrest.InstanceCertificates(ctx, cluster,
-			root.Certificate, leafCert.Certificate, leafCert.PrivateKey,
+			trusted, leafCert.Certificate, leafCert.PrivateKey,
 			instanceCerts)
 	}
 	if err == nil {
@@ -0,0 +1,241 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/patroni"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// findVolume returns the volume named name in clusterVolumes, if any.
+func findVolume(
+	clusterVolumes []corev1.PersistentVolumeClaim, name string,
+) *corev1.PersistentVolumeClaim {
+	for i := range clusterVolumes {
+		if name != "" && clusterVolumes[i].Name == name {
+			return &clusterVolumes[i]
+		}
+	}
+	return nil
+}
+
+// volumeNeedsReplacement returns whether or not Kubernetes cannot change
+// existing to match spec. The storage class and access modes of a volume are
+// immutable, and its storage request can only grow.
+func volumeNeedsReplacement(
+	existing *corev1.PersistentVolumeClaim, spec corev1.PersistentVolumeClaimSpec,
+) bool {
+	if spec.StorageClassName != nil && (existing.Spec.StorageClassName == nil ||
+		*existing.Spec.StorageClassName != *spec.StorageClassName) {
+		return true
+	}
+	if len(spec.AccessModes) > 0 &&
+		!reflect.DeepEqual(spec.AccessModes, existing.Spec.AccessModes) {
+		return true
+	}
+
+	requested := spec.Resources.Requests[corev1.ResourceStorage]
+	current := existing.Spec.Resources.Requests[corev1.ResourceStorage]
+	return requested.Cmp(current) < 0
+}
+
+// keepImmutableVolumeFields returns spec with the fields of existing that
+// Kubernetes cannot change. It leaves a volume that is going to be replaced
+// as it is.
+func keepImmutableVolumeFields(
+	spec corev1.PersistentVolumeClaimSpec, existing *corev1.PersistentVolumeClaim,
+) corev1.PersistentVolumeClaimSpec {
+	spec.StorageClassName = existing.Spec.StorageClassName
+	spec.AccessModes = existing.Spec.AccessModes
+
+	requested := spec.Resources.Requests[corev1.ResourceStorage]
+	if current := existing.Spec.Resources.Requests[corev1.ResourceStorage]; requested.Cmp(current) < 0 {
+		// Copy the requests so the cluster spec is not changed.
+		spec.Resources.Requests = spec.Resources.Requests.DeepCopy()
+		spec.Resources.Requests[corev1.ResourceStorage] = current.DeepCopy()
+	}
+	return spec
+}
+
+// instanceVolumesOutdated returns whether or not any volume of instance in set
+// cannot change to match the spec of set.
+func instanceVolumesOutdated(
+	cluster *v1beta1.PostgresCluster, set *v1beta1.PostgresInstanceSetSpec,
+	instance string, clusterVolumes []corev1.PersistentVolumeClaim,
+) bool {
+	status := instanceSetStatus(cluster, set.Name)
+
+	for i := range clusterVolumes {
+		volume := &clusterVolumes[i]
+		if volume.Labels[naming.LabelInstance] != instance {
+			continue
+		}
+
+		switch volume.Labels[naming.LabelRole] {
+		case naming.RolePostgresData:
+			if volumeNeedsReplacement(volume,
+				autoGrowRequest(set.DataVolumeClaimSpec, status.DataVolumeSize)) {
+				return true
+			}
+		case naming.RolePostgresWAL:
+			if set.WALVolumeClaimSpec != nil && volumeNeedsReplacement(volume,
+				autoGrowRequest(*set.WALVolumeClaimSpec, status.WALVolumeSize)) {
+				return true
+			}
+		case naming.RolePostgresTablespace:
+			for _, tablespace := range set.TablespaceVolumes {
+				if tablespace.Name == volume.Labels[naming.LabelTablespace] &&
+					volumeNeedsReplacement(volume, tablespace.DataVolumeClaimSpec) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// reconcileVolumeMigration replaces instances whose volumes cannot change to
+// match their instance set when that set allows it. Instances of one set are
+// replaced at a time: it adds one instance with new volumes, waits for it to
+// be a ready replica, then removes one instance with old volumes. It returns
+// the number of instances to add to each set while doing so and reports
+// progress in the PersistentVolumeMigrating condition.
+func (r *Reconciler) reconcileVolumeMigration(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, clusterVolumes []corev1.PersistentVolumeClaim,
+) (map[string]int, error) {
+	surge := make(map[string]int)
+
+	if cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown {
+		return surge, nil
+	}
+
+	progress := func(reason, message string) {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:    v1beta1.PersistentVolumeMigrating,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: message,
+
+			ObservedGeneration: cluster.Generation,
+		})
+	}
+
+	for i := range cluster.Spec.InstanceSets {
+		set := &cluster.Spec.InstanceSets[i]
+		if set.VolumeChangePolicy != v1beta1.VolumeChangeReplace {
+			continue
+		}
+
+		var busy bool
+		var outdated, updated []*Instance
+		for _, instance := range instances.bySet[set.Name] {
+			if terminating, known := instance.IsTerminating(); terminating || !known {
+				busy = true
+				continue
+			}
+			if instanceVolumesOutdated(cluster, set, instance.Name, clusterVolumes) {
+				outdated = append(outdated, instance)
+			} else {
+				updated = append(updated, instance)
+			}
+		}
+		if len(outdated) == 0 {
+			continue
+		}
+
+		// Add one instance with new volumes to this set.
+		surge[set.Name] = 1
+
+		// Wait for instances to start or stop, and for the added instance.
+		var candidate *Instance
+		for _, instance := range updated {
+			if ready, known := instance.IsReady(); !ready || !known ||
+				len(instance.Pods[0].Labels[naming.LabelRole]) == 0 {
+				busy = true
+			} else if candidate == nil {
+				candidate = instance
+			}
+		}
+		if busy || candidate == nil || len(outdated)+len(updated) <= int(*set.Replicas) {
+			progress("Waiting", fmt.Sprintf(
+				"Waiting for an instance with new volumes in set %q; %d instance(s) remain",
+				set.Name, len(outdated)))
+			return surge, nil
+		}
+
+		// Remove the lowest priority instance with old volumes. When that is
+		// the primary, switch over to the ready instance first.
+		sort.Sort(byPriority(outdated))
+		target := outdated[0]
+
+		if primary, known := target.IsPrimary(); primary && known {
+			progress("Switchover", fmt.Sprintf(
+				"Switching over from instance %q to %q in set %q",
+				target.Name, candidate.Name, set.Name))
+
+			pod := target.Pods[0]
+			exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+				return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+			}
+
+			// Pause PgBouncer so client queries wait for the new primary. A
+			// later reconcile resumes it. See [Reconciler.resumePGBouncer].
+			if err := r.pausePGBouncer(ctx, cluster); err != nil {
+				return surge, err
+			}
+			success, err := patroni.Executor(exec).ChangePrimaryAndWait(ctx, pod.Name, candidate.Pods[0].Name)
+
+			if err = errors.WithStack(err); err == nil && !success {
+				err = errors.New("unable to switchover")
+			}
+			return surge, err
+		}
+
+		progress("Replacing", fmt.Sprintf(
+			"Removing instance %q with old volumes in set %q; %d instance(s) remain",
+			target.Name, set.Name, len(outdated)-1))
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "VolumeMigration",
+			"Removing instance %q after instance %q became ready with new volumes",
+			target.Name, candidate.Name)
+
+		return surge, r.deleteInstance(ctx, cluster, target.Name)
+	}
+
+	if meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.PersistentVolumeMigrating) != nil {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:    v1beta1.PersistentVolumeMigrating,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Complete",
+			Message: "Every instance has the volumes of its instance set",
+
+			ObservedGeneration: cluster.Generation,
+		})
+	}
+	return surge, nil
+}
@@ -0,0 +1,283 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestVolumeNeedsReplacement(t *testing.T) {
+	existing := &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: initialize.String("slow"),
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("2Gi"),
+				},
+			},
+		},
+	}
+
+	spec := func(class, size string, modes ...corev1.PersistentVolumeAccessMode) corev1.PersistentVolumeClaimSpec {
+		s := corev1.PersistentVolumeClaimSpec{AccessModes: modes}
+		if class != "" {
+			s.StorageClassName = initialize.String(class)
+		}
+		s.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse(size),
+		}
+		return s
+	}
+
+	assert.Assert(t, !volumeNeedsReplacement(existing, spec("slow", "2Gi", corev1.ReadWriteOnce)))
+	assert.Assert(t, !volumeNeedsReplacement(existing, spec("", "2Gi")),
+		"expected the default class and modes to match")
+	assert.Assert(t, !volumeNeedsReplacement(existing, spec("slow", "3Gi")),
+		"expected growth to happen in place")
+
+	assert.Assert(t, volumeNeedsReplacement(existing, spec("fast", "2Gi")))
+	assert.Assert(t, volumeNeedsReplacement(existing, spec("slow", "1Gi")))
+	assert.Assert(t, volumeNeedsReplacement(existing, spec("", "2Gi", corev1.ReadWriteMany)))
+
+	t.Run("KeepImmutableVolumeFields", func(t *testing.T) {
+		desired := spec("fast", "1Gi", corev1.ReadWriteMany)
+		kept := keepImmutableVolumeFields(desired, existing)
+
+		assert.Equal(t, *kept.StorageClassName, "slow")
+		assert.DeepEqual(t, kept.AccessModes,
+			[]corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce})
+		assert.Equal(t, kept.Resources.Requests.Storage().String(), "2Gi")
+		assert.Assert(t, !volumeNeedsReplacement(existing, kept))
+
+		assert.Equal(t, desired.Resources.Requests.Storage().String(), "1Gi",
+			"expected the desired spec to be unchanged")
+	})
+}
+
+func TestReconcileVolumeMigration(t *testing.T) {
+	ctx := context.Background()
+
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name:     "00",
+		Replicas: initialize.Int32(1),
+		DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: initialize.String("fast"),
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}}
+
+	volume := func(instance, class string) corev1.PersistentVolumeClaim {
+		pvc := corev1.PersistentVolumeClaim{}
+		pvc.Namespace = cluster.Namespace
+		pvc.Name = instance + "-pgdata"
+		pvc.Labels = map[string]string{
+			naming.LabelCluster:     cluster.Name,
+			naming.LabelInstanceSet: "00",
+			naming.LabelInstance:    instance,
+			naming.LabelRole:        naming.RolePostgresData,
+		}
+		pvc.Spec.StorageClassName = initialize.String(class)
+		pvc.Spec.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse("1Gi"),
+		}
+		return pvc
+	}
+
+	instance := func(name, role string, ready bool) *Instance {
+		pod := &corev1.Pod{}
+		pod.Namespace = cluster.Namespace
+		pod.Name = name + "-0"
+		pod.Labels = map[string]string{
+			naming.LabelCluster:     cluster.Name,
+			naming.LabelInstanceSet: "00",
+			naming.LabelInstance:    name,
+		}
+		if role != "" {
+			pod.Labels[naming.LabelRole] = role
+		}
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodReady, Status: status,
+		}}
+		return &Instance{Name: name, Pods: []*corev1.Pod{pod}, Spec: &cluster.Spec.InstanceSets[0]}
+	}
+
+	observe := func(instances ...*Instance) *observedInstances {
+		observed := &observedInstances{
+			byName: make(map[string]*Instance),
+			bySet:  make(map[string][]*Instance),
+		}
+		for _, i := range instances {
+			observed.byName[i.Name] = i
+			observed.bySet["00"] = append(observed.bySet["00"], i)
+			observed.forCluster = append(observed.forCluster, i)
+		}
+		return observed
+	}
+
+	t.Run("InPlace", func(t *testing.T) {
+		reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+
+		surge, err := reconciler.reconcileVolumeMigration(ctx, cluster.DeepCopy(),
+			observe(instance("old", naming.RolePatroniLeader, true)),
+			[]corev1.PersistentVolumeClaim{volume("old", "slow")})
+		assert.NilError(t, err)
+		assert.Equal(t, len(surge), 0)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets[0].VolumeChangePolicy = v1beta1.VolumeChangeReplace
+		volumes := []corev1.PersistentVolumeClaim{volume("old", "slow"), volume("new", "fast")}
+
+		t.Run("AddInstance", func(t *testing.T) {
+			reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+
+			surge, err := reconciler.reconcileVolumeMigration(ctx, cluster,
+				observe(instance("old", naming.RolePatroniLeader, true)), volumes[:1])
+			assert.NilError(t, err)
+			assert.DeepEqual(t, surge, map[string]int{"00": 1})
+
+			condition := meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.PersistentVolumeMigrating)
+			assert.Assert(t, condition != nil)
+			assert.Equal(t, condition.Status, metav1.ConditionTrue)
+			assert.Equal(t, condition.Reason, "Waiting")
+		})
+
+		t.Run("WaitForReplica", func(t *testing.T) {
+			reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+
+			surge, err := reconciler.reconcileVolumeMigration(ctx, cluster,
+				observe(
+					instance("old", naming.RolePatroniLeader, true),
+					instance("new", "", false),
+				), volumes)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, surge, map[string]int{"00": 1})
+			assert.Equal(t, meta.FindStatusCondition(cluster.Status.Conditions,
+				v1beta1.PersistentVolumeMigrating).Reason, "Waiting")
+		})
+
+		t.Run("Switchover", func(t *testing.T) {
+			reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+
+			var calls []string
+			reconciler.PodExec = func(
+				namespace, pod, container string,
+				stdin io.Reader, stdout, stderr io.Writer, command ...string,
+			) error {
+				calls = append(calls, pod+" "+strings.Join(command, " "))
+				_, err := stdout.Write([]byte("Successfully switched over"))
+				return err
+			}
+
+			surge, err := reconciler.reconcileVolumeMigration(ctx, cluster,
+				observe(
+					instance("old", naming.RolePatroniLeader, true),
+					instance("new", naming.RolePatroniReplica, true),
+				), volumes)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, surge, map[string]int{"00": 1})
+
+			assert.Equal(t, len(calls), 1)
+			assert.Assert(t, strings.HasPrefix(calls[0], "old-0 "))
+			assert.Assert(t, strings.Contains(calls[0], "switchover"))
+			assert.Assert(t, strings.Contains(calls[0], "new-0"))
+			assert.Equal(t, meta.FindStatusCondition(cluster.Status.Conditions,
+				v1beta1.PersistentVolumeMigrating).Reason, "Switchover")
+		})
+
+		t.Run("RemoveInstance", func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &Reconciler{Recorder: recorder}
+			reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+			runner := &appsv1.StatefulSet{}
+			runner.Namespace = cluster.Namespace
+			runner.Name = "old"
+			runner.Labels = map[string]string{
+				naming.LabelCluster:  cluster.Name,
+				naming.LabelInstance: "old",
+			}
+			runner.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: v1beta1.GroupVersion.String(), Kind: "PostgresCluster",
+				Name: cluster.Name, UID: cluster.UID, Controller: initialize.Bool(true),
+			}}
+			assert.NilError(t, reconciler.Client.Create(ctx, runner))
+
+			surge, err := reconciler.reconcileVolumeMigration(ctx, cluster,
+				observe(
+					instance("old", naming.RolePatroniReplica, true),
+					instance("new", naming.RolePatroniLeader, true),
+				), volumes)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, surge, map[string]int{"00": 1})
+			assert.Equal(t, meta.FindStatusCondition(cluster.Status.Conditions,
+				v1beta1.PersistentVolumeMigrating).Reason, "Replacing")
+
+			assert.Equal(t, len(recorder.Events), 1)
+			assert.Assert(t, strings.Contains(<-recorder.Events, "VolumeMigration"))
+
+			err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(runner), runner)
+			assert.Assert(t, err != nil, "expected the old instance to be deleted")
+		})
+
+		t.Run("Complete", func(t *testing.T) {
+			reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+
+			surge, err := reconciler.reconcileVolumeMigration(ctx, cluster,
+				observe(instance("new", naming.RolePatroniLeader, true)), volumes[1:])
+			assert.NilError(t, err)
+			assert.Equal(t, len(surge), 0)
+
+			condition := meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.PersistentVolumeMigrating)
+			assert.Equal(t, condition.Status, metav1.ConditionFalse)
+			assert.Equal(t, condition.Reason, "Complete")
+		})
+	})
+}
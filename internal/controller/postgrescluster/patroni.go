@@ -105,6 +105,12 @@ func (r *Reconciler) handlePatroniRestarts(
 			return r.PodExec(pod.Namespace, pod.Name, container, stdin, stdout, stderr, command...)
 		})
 
+		// Pause PgBouncer so client queries wait while the primary restarts.
+		// A later reconcile resumes it. See [Reconciler.resumePGBouncer].
+		if err := r.pausePGBouncer(ctx, cluster); err != nil {
+			return err
+		}
+
 		return errors.WithStack(exec.RestartPendingMembers(ctx, "master", naming.PatroniScope(cluster)))
 	}
 
@@ -348,6 +354,17 @@ func (r *Reconciler) reconcileReplicationSecret(
 		return custom, err
 	}
 
+	// when an issuer is configured, return the Secret of its certificate
+	if cluster.Spec.CertificateIssuer != nil {
+		issued, err := r.reconcileIssuedCertificate(ctx, cluster,
+			naming.ReplicationIssuedCertificate(cluster),
+			postgres.ReplicationUser, nil, issuedClientUsages)
+		if err != nil {
+			return nil, err
+		}
+		return issued.Secret, nil
+	}
+
 	existing := &corev1.Secret{ObjectMeta: naming.ReplicationClientCertSecret(cluster)}
 	err := errors.WithStack(client.IgnoreNotFound(
 		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
@@ -393,7 +410,7 @@ func (r *Reconciler) reconcileReplicationSecret(
 		err = errors.WithStack(err)
 	}
 	if err == nil {
-		intent.Data[naming.ReplicationCACert], err = root.Certificate.MarshalText()
+		intent.Data[naming.ReplicationCACert], err = root.TrustBundle().MarshalText()
 		err = errors.WithStack(err)
 	}
 	if err == nil {
@@ -463,7 +480,7 @@ func (r *Reconciler) reconcilePatroniSwitchover(ctx context.Context,
 		return errors.New("Need more than one instance to switchover")
 	}
 
-	// 	 TODO: Add webhook validation that requires a targetInstance when requesting failover
+	// The validating webhook rejects this, but it may not be installed.
 	if spec.Type == v1beta1.PatroniSwitchoverTypeFailover {
 		if spec.TargetInstance == nil || *spec.TargetInstance == "" {
 			// TODO: event
@@ -572,7 +589,14 @@ func (r *Reconciler) reconcilePatroniSwitchover(ctx context.Context,
 		nextPrimary = targetInstance.Pods[0].Name
 	}
 
+	// Pause PgBouncer so client queries wait for the new primary. A later
+	// reconcile resumes it once the new primary accepts writes, or when the
+	// switchover fails and the old primary still does.
+	if err := r.pausePGBouncer(ctx, cluster); err != nil {
+		return err
+	}
 	success, err := action(ctx, exec, nextPrimary)
+
 	if err = errors.WithStack(err); err == nil && !success {
 		err = errors.New("unable to switchover")
 	}
@@ -32,6 +32,7 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
@@ -1007,6 +1008,7 @@ func (r *Reconciler) prepareForRestore(ctx context.Context,
 func (r *Reconciler) reconcileRestoreJob(ctx context.Context,
 	cluster *v1beta1.PostgresCluster, sourceCluster *v1beta1.PostgresCluster,
 	pgdataVolume, pgwalVolume *corev1.PersistentVolumeClaim,
+	tablespaceVolumes map[string]*corev1.PersistentVolumeClaim,
 	dataSource *v1beta1.PostgresClusterDataSource,
 	instanceName, instanceSetName, configHash, stanzaName string) error {
 
@@ -1114,6 +1116,29 @@ func (r *Reconciler) reconcileRestoreJob(ctx context.Context,
 		volumeMounts = append(volumeMounts, walVolumeMount)
 	}
 
+	// pgBackRest restores tablespaces to the paths they had in the backup.
+	// Mount the tablespace volumes of the instance set at those paths.
+	for _, set := range cluster.Spec.InstanceSets {
+		if set.Name != instanceSetName {
+			continue
+		}
+		for _, tablespace := range set.TablespaceVolumes {
+			if pvc := tablespaceVolumes[tablespace.Name]; pvc != nil {
+				tablespaceVolumeMount := postgres.TablespaceVolumeMount(tablespace.Name)
+				tablespaceVolume := corev1.Volume{
+					Name: tablespaceVolumeMount.Name,
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: pvc.GetName(),
+						},
+					},
+				}
+				volumes = append(volumes, tablespaceVolume)
+				volumeMounts = append(volumeMounts, tablespaceVolumeMount)
+			}
+		}
+	}
+
 	restoreJob := &batchv1.Job{}
 	if err := r.generateRestoreJobIntent(cluster, configHash, instanceName, cmd,
 		volumeMounts, volumes, dataSource, restoreJob); err != nil {
@@ -1498,7 +1523,7 @@ func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 		Name:      instanceName,
 		Namespace: cluster.GetNamespace(),
 	}}
-	// Reconcile the PGDATA and WAL volumes for the restore
+	// Reconcile the PGDATA, WAL, and tablespace volumes for the restore
 	pgdata, err := r.reconcilePostgresDataVolume(ctx, cluster, instanceSet, fakeSTS, clusterVolumes)
 	if err != nil {
 		return errors.WithStack(err)
@@ -1507,10 +1532,14 @@ func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	tablespaces, err := r.reconcilePostgresTablespaceVolumes(ctx, cluster, instanceSet, fakeSTS, clusterVolumes)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	// reconcile the pgBackRest restore Job to populate the cluster's data directory
 	if err := r.reconcileRestoreJob(ctx, cluster, sourceCluster, pgdata, pgwal,
-		dataSource, instanceName, instanceSetName, configHash, pgbackrest.DefaultStanzaName); err != nil {
+		tablespaces, dataSource, instanceName, instanceSetName, configHash, pgbackrest.DefaultStanzaName); err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -1588,7 +1617,7 @@ func (r *Reconciler) reconcileCloudBasedDataSource(ctx context.Context,
 		Name:      instanceName,
 		Namespace: cluster.GetNamespace(),
 	}}
-	// Reconcile the PGDATA and WAL volumes for the restore
+	// Reconcile the PGDATA, WAL, and tablespace volumes for the restore
 	pgdata, err := r.reconcilePostgresDataVolume(ctx, cluster, instanceSet, fakeSTS, clusterVolumes)
 	if err != nil {
 		return errors.WithStack(err)
@@ -1597,6 +1626,10 @@ func (r *Reconciler) reconcileCloudBasedDataSource(ctx context.Context,
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	tablespaces, err := r.reconcilePostgresTablespaceVolumes(ctx, cluster, instanceSet, fakeSTS, clusterVolumes)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	// The `reconcileRestoreJob` was originally designed to take a PostgresClusterDataSource
 	// and rather than reconfigure that func's signature, we translate the PGBackRestDataSource
@@ -1611,7 +1644,7 @@ func (r *Reconciler) reconcileCloudBasedDataSource(ctx context.Context,
 
 	// reconcile the pgBackRest restore Job to populate the cluster's data directory
 	// Note that the 'source cluster' is nil as this is not used by this restore type.
-	if err := r.reconcileRestoreJob(ctx, cluster, nil, pgdata, pgwal, tmpDataSource,
+	if err := r.reconcileRestoreJob(ctx, cluster, nil, pgdata, pgwal, tablespaces, tmpDataSource,
 		instanceName, instanceSetName, configHash, dataSource.Stanza); err != nil {
 		return errors.WithStack(err)
 	}
@@ -1897,7 +1930,9 @@ func (r *Reconciler) reconcilePGBackRestSecret(ctx context.Context,
 	if err == nil {
 		err = r.setControllerReference(cluster, intent)
 	}
-	if err == nil {
+	if err == nil && cluster.Spec.CertificateIssuer != nil {
+		err = r.reconcilePGBackRestIssuedCertificates(ctx, cluster, repoHost, intent)
+	} else if err == nil {
 		err = pgbackrest.Secret(ctx, cluster, repoHost, rootCA, existing, intent)
 	}
 
@@ -1911,6 +1946,37 @@ func (r *Reconciler) reconcilePGBackRestSecret(ctx context.Context,
 	if err == nil && len(intent.Data) != 0 {
 		err = errors.WithStack(r.apply(ctx, intent))
 	}
+	if err == nil && repoHost != nil {
+		rootRotationWritten(cluster, "pgbackrest")
+	}
+	return err
+}
+
+// reconcilePGBackRestIssuedCertificates populates the pgBackRest Secret with
+// certificates from the issuer of cluster. Like the certificates that PGO
+// generates, these are only necessary when there is a repository host.
+func (r *Reconciler) reconcilePGBackRestIssuedCertificates(ctx context.Context,
+	cluster *v1beta1.PostgresCluster, repoHost *appsv1.StatefulSet,
+	intent *corev1.Secret,
+) error {
+	if repoHost == nil {
+		return nil
+	}
+
+	clientCert, err := r.reconcileIssuedCertificate(ctx, cluster,
+		naming.PGBackRestClientIssuedCertificate(cluster),
+		pgbackrest.ClientCommonName(cluster), nil, issuedClientUsages)
+
+	var serverCert *issuedCertificate
+	if err == nil {
+		serverCert, err = r.reconcileIssuedCertificate(ctx, cluster,
+			naming.PGBackRestRepoHostIssuedCertificate(cluster), "",
+			naming.RepoHostPodDNSNames(ctx, repoHost), issuedServerUsages)
+	}
+	if err == nil {
+		err = pgbackrest.IssuedSecret(repoHost,
+			clientCert.Authority, clientCert.Leaf, serverCert.Leaf, intent)
+	}
 	return err
 }
 
@@ -2458,7 +2524,14 @@ func (r *Reconciler) reconcileRepos(ctx context.Context,
 		if repo.Volume == nil {
 			continue
 		}
-		repo, err := r.applyRepoVolumeIntent(ctx, postgresCluster, &repo.Volume.VolumeClaimSpec,
+		var grown *resource.Quantity
+		for _, status := range postgresCluster.Status.PGBackRest.Repos {
+			if status.Name == repo.Name {
+				grown = status.VolumeSize
+			}
+		}
+		spec := autoGrowRequest(repo.Volume.VolumeClaimSpec, grown)
+		repo, err := r.applyRepoVolumeIntent(ctx, postgresCluster, &spec,
 			repo.Name, repoResources)
 		if err != nil {
 			log.Error(err, errMsg)
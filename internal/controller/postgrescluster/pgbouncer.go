@@ -19,6 +19,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
@@ -26,8 +28,11 @@ import (
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/adifri/postgres-operator/v5/internal/initialize"
 	"github.com/adifri/postgres-operator/v5/internal/logging"
@@ -45,22 +50,42 @@ func (r *Reconciler) reconcilePGBouncer(
 	root *pki.RootCertificateAuthority,
 ) error {
 	var (
-		configmap *corev1.ConfigMap
-		secret    *corev1.Secret
+		configmap, readOnlyConfigMap *corev1.ConfigMap
+		readOnlyService              *corev1.Service
+		secret                       *corev1.Secret
 	)
 
-	service, err := r.reconcilePGBouncerService(ctx, cluster)
+	// Report the effective settings of connection pools. Invalid pool
+	// definitions are left out of the PgBouncer configuration.
+	pools, invalid := pgbouncer.Pools(cluster, false)
+	cluster.Status.Proxy.PGBouncer.Pools = pools
+	cluster.Status.Proxy.PGBouncer.ReadOnlyPools, _ = pgbouncer.Pools(cluster, true)
+	setPGBouncerPoolsCondition(cluster, invalid)
+
+	service, err := r.reconcilePGBouncerService(ctx, cluster, false)
+	if err == nil {
+		readOnlyService, err = r.reconcilePGBouncerService(ctx, cluster, true)
+	}
+	if err == nil {
+		configmap, err = r.reconcilePGBouncerConfigMap(ctx, cluster, false)
+	}
+	if err == nil {
+		readOnlyConfigMap, err = r.reconcilePGBouncerConfigMap(ctx, cluster, true)
+	}
+	if err == nil {
+		secret, err = r.reconcilePGBouncerSecret(ctx, cluster, root, service, readOnlyService)
+	}
 	if err == nil {
-		configmap, err = r.reconcilePGBouncerConfigMap(ctx, cluster)
+		err = r.reconcilePGBouncerDeployment(ctx, cluster, false, primaryCertificate, configmap, secret)
 	}
 	if err == nil {
-		secret, err = r.reconcilePGBouncerSecret(ctx, cluster, root, service)
+		err = r.reconcilePGBouncerDeployment(ctx, cluster, true, primaryCertificate, readOnlyConfigMap, secret)
 	}
 	if err == nil {
-		err = r.reconcilePGBouncerDeployment(ctx, cluster, primaryCertificate, configmap, secret)
+		err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 	}
 	if err == nil {
-		err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+		err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, true)
 	}
 	if err == nil {
 		err = r.reconcilePGBouncerInPostgreSQL(ctx, cluster, instances, secret)
@@ -71,14 +96,18 @@ func (r *Reconciler) reconcilePGBouncer(
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=create;delete;patch
 
-// reconcilePGBouncerConfigMap writes the ConfigMap for a PgBouncer Pod.
+// reconcilePGBouncerConfigMap writes the ConfigMap for a PgBouncer Pod. When
+// readOnly is true, it writes the ConfigMap for a read-only PgBouncer Pod.
 func (r *Reconciler) reconcilePGBouncerConfigMap(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, readOnly bool,
 ) (*corev1.ConfigMap, error) {
 	configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	if readOnly {
+		configmap.ObjectMeta = naming.ClusterPGBouncerReadOnly(cluster)
+	}
 	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
 
-	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
+	if !pgbouncerEnabled(cluster, readOnly) {
 		// PgBouncer is disabled; delete the ConfigMap if it exists. Check the
 		// client cache first using Get.
 		key := client.ObjectKeyFromObject(configmap)
@@ -99,10 +128,12 @@ func (r *Reconciler) reconcilePGBouncerConfigMap(
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
 		map[string]string{
 			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
+			naming.LabelRole:    pgbouncerRole(readOnly),
 		})
 
-	if err == nil {
+	if err == nil && readOnly {
+		pgbouncer.ReadOnlyConfigMap(cluster, configmap)
+	} else if err == nil {
 		pgbouncer.ConfigMap(cluster, configmap)
 	}
 	if err == nil {
@@ -198,7 +229,7 @@ func (r *Reconciler) reconcilePGBouncerInPostgreSQL(
 // reconcilePGBouncerSecret writes the Secret for a PgBouncer Pod.
 func (r *Reconciler) reconcilePGBouncerSecret(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
-	root *pki.RootCertificateAuthority, service *corev1.Service,
+	root *pki.RootCertificateAuthority, service, readOnlyService *corev1.Service,
 ) (*corev1.Secret, error) {
 	existing := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncer(cluster)}
 	err := errors.WithStack(
@@ -237,24 +268,31 @@ func (r *Reconciler) reconcilePGBouncerSecret(
 		})
 
 	if err == nil {
-		err = pgbouncer.Secret(ctx, cluster, root, existing, service, intent)
+		err = pgbouncer.Secret(ctx, cluster, root, existing, service, readOnlyService, intent)
 	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, intent))
 	}
+	if err == nil {
+		rootRotationWritten(cluster, "pgbouncer")
+	}
 
 	return intent, err
 }
 
 // generatePGBouncerService returns a v1.Service that exposes PgBouncer pods.
-// The ServiceType comes from the cluster proxy spec.
+// The ServiceType comes from the cluster proxy spec. When readOnly is true,
+// the Service exposes read-only PgBouncer pods.
 func (r *Reconciler) generatePGBouncerService(
-	cluster *v1beta1.PostgresCluster) (*corev1.Service, bool, error,
+	cluster *v1beta1.PostgresCluster, readOnly bool) (*corev1.Service, bool, error,
 ) {
 	service := &corev1.Service{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	if readOnly {
+		service.ObjectMeta = naming.ClusterPGBouncerReadOnly(cluster)
+	}
 	service.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
 
-	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
+	if !pgbouncerEnabled(cluster, readOnly) {
 		return service, false, nil
 	}
 
@@ -266,7 +304,7 @@ func (r *Reconciler) generatePGBouncerService(
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
 		map[string]string{
 			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
+			naming.LabelRole:    pgbouncerRole(readOnly),
 		})
 
 	// Allocate an IP address and/or node port and let Kubernetes manage the
@@ -274,9 +312,14 @@ func (r *Reconciler) generatePGBouncerService(
 	// - https://docs.k8s.io/concepts/services-networking/service/#defining-a-service
 	service.Spec.Selector = map[string]string{
 		naming.LabelCluster: cluster.Name,
-		naming.LabelRole:    naming.RolePGBouncer,
+		naming.LabelRole:    pgbouncerRole(readOnly),
+	}
+
+	spec := cluster.Spec.Proxy.PGBouncer.Service
+	if readOnly {
+		spec = cluster.Spec.Proxy.PGBouncer.ReadOnly.Service
 	}
-	if spec := cluster.Spec.Proxy.PGBouncer.Service; spec != nil {
+	if spec != nil {
 		service.Spec.Type = corev1.ServiceType(spec.Type)
 	} else {
 		service.Spec.Type = corev1.ServiceTypeClusterIP
@@ -301,10 +344,12 @@ func (r *Reconciler) generatePGBouncerService(
 // +kubebuilder:rbac:groups="",resources="services",verbs={create,delete,patch}
 
 // reconcilePGBouncerService writes the Service that resolves to PgBouncer.
+// When readOnly is true, it writes the Service that resolves to read-only
+// PgBouncer.
 func (r *Reconciler) reconcilePGBouncerService(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, readOnly bool,
 ) (*corev1.Service, error) {
-	service, specified, err := r.generatePGBouncerService(cluster)
+	service, specified, err := r.generatePGBouncerService(cluster, readOnly)
 
 	if err == nil && !specified {
 		// PgBouncer is disabled; delete the Service if it exists. Check the client
@@ -323,16 +368,20 @@ func (r *Reconciler) reconcilePGBouncerService(
 	return service, err
 }
 
-// generatePGBouncerDeployment returns an appsv1.Deployment that runs PgBouncer pods.
+// generatePGBouncerDeployment returns an appsv1.Deployment that runs PgBouncer
+// pods. When readOnly is true, the pods connect to PostgreSQL replicas.
 func (r *Reconciler) generatePGBouncerDeployment(
-	cluster *v1beta1.PostgresCluster,
+	cluster *v1beta1.PostgresCluster, readOnly bool,
 	primaryCertificate *corev1.SecretProjection,
 	configmap *corev1.ConfigMap, secret *corev1.Secret,
 ) (*appsv1.Deployment, bool, error) {
 	deploy := &appsv1.Deployment{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	if readOnly {
+		deploy.ObjectMeta = naming.ClusterPGBouncerReadOnly(cluster)
+	}
 	deploy.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
 
-	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
+	if !pgbouncerEnabled(cluster, readOnly) {
 		return deploy, false, nil
 	}
 
@@ -344,12 +393,12 @@ func (r *Reconciler) generatePGBouncerDeployment(
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
 		map[string]string{
 			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
+			naming.LabelRole:    pgbouncerRole(readOnly),
 		})
 	deploy.Spec.Selector = &metav1.LabelSelector{
 		MatchLabels: map[string]string{
 			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
+			naming.LabelRole:    pgbouncerRole(readOnly),
 		},
 	}
 	deploy.Spec.Template.Annotations = naming.Merge(
@@ -360,12 +409,14 @@ func (r *Reconciler) generatePGBouncerDeployment(
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
 		map[string]string{
 			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
+			naming.LabelRole:    pgbouncerRole(readOnly),
 		})
 
 	// if the shutdown flag is set, set pgBouncer replicas to 0
 	if cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown {
 		deploy.Spec.Replicas = initialize.Int32(0)
+	} else if readOnly {
+		deploy.Spec.Replicas = cluster.Spec.Proxy.PGBouncer.ReadOnly.Replicas
 	} else {
 		deploy.Spec.Replicas = cluster.Spec.Proxy.PGBouncer.Replicas
 	}
@@ -439,16 +490,25 @@ func (r *Reconciler) generatePGBouncerDeployment(
 // +kubebuilder:rbac:groups="apps",resources="deployments",verbs={create,delete,patch}
 
 // reconcilePGBouncerDeployment writes the Deployment that runs PgBouncer.
+// When readOnly is true, it writes the Deployment that runs read-only PgBouncer.
 func (r *Reconciler) reconcilePGBouncerDeployment(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, readOnly bool,
 	primaryCertificate *corev1.SecretProjection,
 	configmap *corev1.ConfigMap, secret *corev1.Secret,
 ) error {
 	deploy, specified, err := r.generatePGBouncerDeployment(
-		cluster, primaryCertificate, configmap, secret)
+		cluster, readOnly, primaryCertificate, configmap, secret)
 
 	// Set observations whether the deployment exists or not.
 	defer func() {
+		if readOnly {
+			cluster.Status.Proxy.PGBouncer.ReadOnlyReplicas = deploy.Status.Replicas
+			cluster.Status.Proxy.PGBouncer.ReadOnlyReadyReplicas = deploy.Status.ReadyReplicas
+
+			// The ProxyAvailable condition describes only the primary PgBouncer.
+			return
+		}
+
 		cluster.Status.Proxy.PGBouncer.Replicas = deploy.Status.Replicas
 		cluster.Status.Proxy.PGBouncer.ReadyReplicas = deploy.Status.ReadyReplicas
 
@@ -504,12 +564,21 @@ func (r *Reconciler) reconcilePGBouncerDeployment(
 // A PDB will be created when minAvailable is determined to be greater than 0 and
 // a PGBouncer proxy is defined in the spec. MinAvailable can be defined in the spec
 // or a default value will be set based on the number of replicas defined for PGBouncer.
+// When readOnly is true, it creates a PDB for the read-only PGBouncer deployment.
 func (r *Reconciler) reconcilePGBouncerPodDisruptionBudget(
 	ctx context.Context,
 	cluster *v1beta1.PostgresCluster,
+	readOnly bool,
 ) error {
+	meta := naming.ClusterPGBouncer(cluster)
+	selector := naming.ClusterPGBouncerSelector(cluster)
+	if readOnly {
+		meta = naming.ClusterPGBouncerReadOnly(cluster)
+		selector = naming.ClusterPGBouncerReadOnlySelector(cluster)
+	}
+
 	deleteExistingPDB := func(cluster *v1beta1.PostgresCluster) error {
-		existing := &policyv1beta1.PodDisruptionBudget{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+		existing := &policyv1beta1.PodDisruptionBudget{ObjectMeta: meta}
 		err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing))
 		if err == nil {
 			err = errors.WithStack(r.deleteControlled(ctx, cluster, existing))
@@ -517,36 +586,40 @@ func (r *Reconciler) reconcilePGBouncerPodDisruptionBudget(
 		return client.IgnoreNotFound(err)
 	}
 
-	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
+	if !pgbouncerEnabled(cluster, readOnly) {
 		return deleteExistingPDB(cluster)
 	}
 
-	if cluster.Spec.Proxy.PGBouncer.Replicas == nil {
+	replicas := cluster.Spec.Proxy.PGBouncer.Replicas
+	minAvailable := cluster.Spec.Proxy.PGBouncer.MinAvailable
+	if readOnly {
+		replicas = cluster.Spec.Proxy.PGBouncer.ReadOnly.Replicas
+		minAvailable = cluster.Spec.Proxy.PGBouncer.ReadOnly.MinAvailable
+	}
+
+	if replicas == nil {
 		// Replicas should always have a value because of defaults in the spec
 		return errors.New("Replicas should be defined")
 	}
-	minAvailable := getMinAvailable(cluster.Spec.Proxy.PGBouncer.MinAvailable,
-		*cluster.Spec.Proxy.PGBouncer.Replicas)
+	minAvailable = getMinAvailable(minAvailable, *replicas)
 
 	// If 'minAvailable' is set to '0', we will not reconcile the PDB. If one
 	// already exists, we will remove it.
 	scaled, err := intstr.GetScaledValueFromIntOrPercent(minAvailable,
-		int(*cluster.Spec.Proxy.PGBouncer.Replicas), true)
+		int(*replicas), true)
 	if err == nil && scaled <= 0 {
 		return deleteExistingPDB(cluster)
 	}
 
-	meta := naming.ClusterPGBouncer(cluster)
 	meta.Labels = naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
 		map[string]string{
 			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
+			naming.LabelRole:    pgbouncerRole(readOnly),
 		})
 	meta.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetAnnotationsOrNil())
 
-	selector := naming.ClusterPGBouncerSelector(cluster)
 	pdb := &policyv1beta1.PodDisruptionBudget{}
 	if err == nil {
 		pdb, err = r.generatePodDisruptionBudget(cluster, meta, minAvailable, selector)
@@ -557,3 +630,267 @@ func (r *Reconciler) reconcilePGBouncerPodDisruptionBudget(
 	}
 	return err
 }
+
+// pgbouncerResumeInterval is how often PGO checks whether it can resume
+// PgBouncer that it paused.
+const pgbouncerResumeInterval = 5 * time.Second
+
+// pgbouncerPauseTimeout returns how long PgBouncer of cluster can remain paused.
+func pgbouncerPauseTimeout(cluster *v1beta1.PostgresCluster) time.Duration {
+	timeout := 60 * time.Second
+	if pause := cluster.Spec.Proxy.PGBouncer.Pause; pause != nil && pause.TimeoutSeconds != nil {
+		timeout = time.Duration(*pause.TimeoutSeconds) * time.Second
+	}
+	return timeout
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// pgbouncerConsoles returns the running PgBouncer Pods of cluster, both
+// read-write and read-only, and the Secret that holds the password PGO uses
+// in their admin consoles.
+func (r *Reconciler) pgbouncerConsoles(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) ([]*corev1.Pod, *corev1.Secret, error) {
+	secret := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	err := errors.WithStack(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret))
+
+	pods := &corev1.PodList{}
+	for _, matching := range []metav1.LabelSelector{
+		naming.ClusterPGBouncerSelector(cluster),
+		naming.ClusterPGBouncerReadOnlySelector(cluster),
+	} {
+		var list corev1.PodList
+		var selector labels.Selector
+		if err == nil {
+			selector, err = naming.AsSelector(matching)
+		}
+		if err == nil {
+			err = errors.WithStack(
+				r.Client.List(ctx, &list,
+					client.InNamespace(cluster.Namespace),
+					client.MatchingLabelsSelector{Selector: selector},
+				))
+		}
+		pods.Items = append(pods.Items, list.Items...)
+	}
+
+	var running []*corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == naming.ContainerPGBouncer && status.State.Running != nil {
+				running = append(running, pod)
+			}
+		}
+	}
+
+	return running, secret, err
+}
+
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+
+// pgbouncerConsole returns an Executor that runs commands in the PgBouncer
+// container of pod.
+func (r *Reconciler) pgbouncerConsole(pod *corev1.Pod) pgbouncer.Executor {
+	return func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		return r.PodExec(pod.Namespace, pod.Name, naming.ContainerPGBouncer,
+			stdin, stdout, stderr, command...)
+	}
+}
+
+// pausePGBouncer pauses every running PgBouncer Pod of cluster so that client
+// queries wait rather than fail while the PostgreSQL primary changes. It does
+// nothing when cluster does not specify pausing. Pods are paused at the same
+// time and share one deadline, the timeout in the spec. When any Pod cannot
+// be paused, every Pod is resumed and the error is returned; the primary
+// should not change then. The time of the pause goes in the status of cluster
+// so that a later reconcile can resume PgBouncer. See [Reconciler.resumePGBouncer].
+func (r *Reconciler) pausePGBouncer(ctx context.Context, cluster *v1beta1.PostgresCluster) error {
+	if !pgbouncerEnabled(cluster, false) || cluster.Spec.Proxy.PGBouncer.Pause == nil {
+		return nil
+	}
+
+	pods, secret, err := r.pgbouncerConsoles(ctx, cluster)
+	if err != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "PGBouncerNotPaused",
+			"Unable to find PgBouncer pods to pause: %v", err)
+		return err
+	}
+	if len(pods) == 0 {
+		return nil
+	}
+
+	// Record the pause before any command; PgBouncer may pause even when the
+	// command reports an error.
+	previous := cluster.Status.Proxy.PGBouncer.PausedAt
+	if previous == nil {
+		now := metav1.Now()
+		cluster.Status.Proxy.PGBouncer.PausedAt = &now
+	}
+
+	pauseCtx, cancel := context.WithTimeout(ctx, pgbouncerPauseTimeout(cluster))
+	defer cancel()
+	deadline, _ := pauseCtx.Deadline()
+
+	type result struct {
+		pod *corev1.Pod
+		err error
+	}
+	results := make(chan result, len(pods))
+	for _, pod := range pods {
+		go func(pod *corev1.Pod) {
+			results <- result{pod: pod, err: errors.WithStack(r.pgbouncerConsole(pod).Pause(
+				pauseCtx, cluster, secret, time.Until(deadline)))}
+		}(pod)
+	}
+
+	// Wait for every Pod, but no longer than the deadline. Pods are resumed
+	// only after every PAUSE has returned or the deadline has passed.
+	for remaining := len(pods); remaining > 0; remaining-- {
+		select {
+		case result := <-results:
+			if result.err != nil {
+				err = result.err
+				r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "PGBouncerNotPaused",
+					"Unable to pause PgBouncer pod %v: %v", result.pod.Name, result.err)
+			}
+			continue
+		case <-pauseCtx.Done():
+			err = errors.WithStack(pauseCtx.Err())
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "PGBouncerNotPaused",
+				"Unable to pause PgBouncer pods within %v", pgbouncerPauseTimeout(cluster))
+		}
+		break
+	}
+
+	// PgBouncer carries on with PAUSE after an error, so resume every Pod,
+	// including those that did not report being paused. Leave the time of the
+	// pause in status when any Pod is still paused so a later reconcile can
+	// resume it.
+	if err != nil && r.resumePGBouncerPods(ctx, cluster, pods, secret) {
+		cluster.Status.Proxy.PGBouncer.PausedAt = previous
+	}
+	return err
+}
+
+// resumePGBouncerPods resumes pods at the same time. It returns true when
+// every one of them is resumed.
+func (r *Reconciler) resumePGBouncerPods(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	pods []*corev1.Pod, secret *corev1.Secret,
+) bool {
+	errs := make([]error, len(pods))
+
+	var wg sync.WaitGroup
+	for i := range pods {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = errors.WithStack(
+				r.pgbouncerConsole(pods[i]).Resume(ctx, cluster, secret))
+		}(i)
+	}
+	wg.Wait()
+
+	resumed := true
+	for i, err := range errs {
+		if err != nil {
+			resumed = false
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "PGBouncerNotResumed",
+				"Unable to resume PgBouncer pod %v: %v", pods[i].Name, err)
+		}
+	}
+	return resumed
+}
+
+// resumePGBouncer resumes PgBouncer that was paused by a previous reconcile
+// once a PostgreSQL primary accepts writes, or once the timeout in the spec
+// elapses. It requeues cluster until then.
+func (r *Reconciler) resumePGBouncer(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	pausedAt := cluster.Status.Proxy.PGBouncer.PausedAt
+	if pausedAt == nil {
+		return reconcile.Result{}, nil
+	}
+	if !pgbouncerEnabled(cluster, false) {
+		// PgBouncer is disabled; there is nothing to resume.
+		cluster.Status.Proxy.PGBouncer.PausedAt = nil
+		return reconcile.Result{}, nil
+	}
+
+	remaining := pgbouncerPauseTimeout(cluster) - time.Since(pausedAt.Time)
+	if pod, _ := instances.writablePod(naming.ContainerDatabase); pod == nil && remaining > 0 {
+		if remaining > pgbouncerResumeInterval {
+			remaining = pgbouncerResumeInterval
+		}
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
+	pods, secret, err := r.pgbouncerConsoles(ctx, cluster)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if !r.resumePGBouncerPods(ctx, cluster, pods, secret) {
+		return reconcile.Result{RequeueAfter: pgbouncerResumeInterval}, nil
+	}
+
+	cluster.Status.Proxy.PGBouncer.PausedAt = nil
+	return reconcile.Result{}, nil
+}
+
+// pgbouncerEnabled returns whether or not cluster specifies PgBouncer. When
+// readOnly is true, it returns whether or not cluster specifies read-only
+// PgBouncer.
+func pgbouncerEnabled(cluster *v1beta1.PostgresCluster, readOnly bool) bool {
+	return cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil &&
+		(!readOnly || cluster.Spec.Proxy.PGBouncer.ReadOnly != nil)
+}
+
+// setPGBouncerPoolsCondition describes in the conditions of cluster whether
+// its PgBouncer pools are valid. Pools that are rejected by the validating
+// webhook can still be stored when the webhook is not installed.
+func setPGBouncerPoolsCondition(cluster *v1beta1.PostgresCluster, invalid field.ErrorList) {
+	if !pgbouncerEnabled(cluster, false) {
+		// Avoid a panic! Fixed in Kubernetes v1.21.0 and controller-runtime v0.9.0-alpha.0.
+		// - https://issue.k8s.io/99714
+		if len(cluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.ProxyPoolsValid)
+		}
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    v1beta1.ProxyPoolsValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PoolsValid",
+		Message: "Every pool is in the PgBouncer configuration.",
+
+		ObservedGeneration: cluster.Generation,
+	}
+	if len(invalid) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "InvalidPool"
+		condition.Message = "Invalid pools are left out of the PgBouncer configuration: " +
+			invalid.ToAggregate().Error()
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+}
+
+// pgbouncerRole returns the LabelRole of PgBouncer objects. When readOnly is
+// true, it returns the LabelRole of read-only PgBouncer objects.
+func pgbouncerRole(readOnly bool) string {
+	if readOnly {
+		return naming.RolePGBouncerReadOnly
+	}
+	return naming.RolePGBouncer
+}
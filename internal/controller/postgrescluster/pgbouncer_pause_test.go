@@ -0,0 +1,382 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/testing/cmp"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReconcilerPausePGBouncer(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+
+	pod := func(name, role string, running bool) client.Object {
+		pod := &corev1.Pod{}
+		pod.Namespace = "ns1"
+		pod.Name = name
+		pod.Labels = map[string]string{
+			naming.LabelCluster: "hippo",
+			naming.LabelRole:    role,
+		}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: naming.ContainerPGBouncer,
+		}}
+		if running {
+			pod.Status.ContainerStatuses[0].State.Running = &corev1.ContainerStateRunning{}
+		}
+		return pod
+	}
+
+	secret := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	secret.Data = map[string][]byte{"pgbouncer-password": []byte("pass")}
+
+	reconciler := &Reconciler{}
+	reconciler.Recorder = record.NewFakeRecorder(100)
+	reconciler.Client = fake.NewClientBuilder().WithObjects(
+		pod("rw", naming.RolePGBouncer, true),
+		pod("ro", naming.RolePGBouncerReadOnly, true),
+		pod("stopped", naming.RolePGBouncer, false),
+		pod("other", "something", true),
+		secret,
+	).Build()
+
+	var calls []string
+	var mu sync.Mutex
+	reconciler.PodExec = func(
+		namespace, pod, container string, _ io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		assert.Check(t, namespace == "ns1")
+		assert.Check(t, container == "pgbouncer")
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, command[len(command)-1]+" "+pod)
+		return nil
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		calls = nil
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, reconciler.pausePGBouncer(ctx, cluster))
+		assert.Assert(t, calls == nil)
+
+		cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+			PGBouncer: &v1beta1.PGBouncerPodSpec{Port: initialize.Int32(5432)},
+		}
+		assert.NilError(t, reconciler.pausePGBouncer(ctx, cluster))
+		assert.Assert(t, calls == nil)
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt == nil)
+	})
+
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{
+			Port: initialize.Int32(5432),
+			Pause: &v1beta1.PGBouncerPauseSpec{
+				TimeoutSeconds: initialize.Int32(30),
+			},
+		},
+	}
+
+	t.Run("Running", func(t *testing.T) {
+		calls = nil
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, reconciler.pausePGBouncer(ctx, cluster))
+		sort.Strings(calls)
+		assert.DeepEqual(t, calls, []string{"PAUSE ro", "PAUSE rw"})
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt != nil)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		// Each PAUSE waits for the other to start; they must run together.
+		var started sync.WaitGroup
+		started.Add(2)
+
+		reconciler := &Reconciler{Client: reconciler.Client, Recorder: reconciler.Recorder}
+		reconciler.PodExec = func(
+			_, _, _ string, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			// Every Pod shares one deadline, so none waits the whole timeout.
+			assert.Check(t, command[len(command)-2] == "30" || command[len(command)-2] == "29",
+				"expected timeout, got %q", command[len(command)-2])
+
+			started.Done()
+			started.Wait()
+			return nil
+		}
+
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, reconciler.pausePGBouncer(ctx, cluster))
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		blocked := make(chan struct{})
+		defer close(blocked)
+
+		var resumed []string
+		reconciler := &Reconciler{Client: reconciler.Client, Recorder: recorder}
+		reconciler.PodExec = func(
+			_, pod, _ string, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			if command[len(command)-1] == "PAUSE" {
+				<-blocked
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			resumed = append(resumed, pod)
+			return nil
+		}
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Pause.TimeoutSeconds = initialize.Int32(1)
+
+		start := time.Now()
+		err := reconciler.pausePGBouncer(ctx, cluster)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Assert(t, time.Since(start) < 5*time.Second)
+
+		// Every Pod is resumed, and there is nothing left to resume later.
+		sort.Strings(resumed)
+		assert.DeepEqual(t, resumed, []string{"ro", "rw"})
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt == nil)
+
+		close(recorder.Events)
+		for event := range recorder.Events {
+			assert.Assert(t, cmp.Contains(event, "PGBouncerNotPaused"))
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+
+		calls = nil
+		reconciler := &Reconciler{Client: reconciler.Client, Recorder: recorder}
+		reconciler.PodExec = func(
+			_, pod, _ string, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, command[len(command)-1]+" "+pod)
+
+			if pod == "ro" && command[len(command)-1] == "PAUSE" {
+				return errors.New("boom")
+			}
+			return nil
+		}
+
+		cluster := cluster.DeepCopy()
+		err := reconciler.pausePGBouncer(ctx, cluster)
+		assert.ErrorContains(t, err, "boom")
+
+		// The Pod that paused is resumed, and so is the one that failed.
+		sort.Strings(calls)
+		assert.DeepEqual(t, calls, []string{"PAUSE ro", "PAUSE rw", "RESUME ro", "RESUME rw"})
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt == nil)
+
+		close(recorder.Events)
+		var events []string
+		for event := range recorder.Events {
+			events = append(events, event)
+		}
+		assert.Equal(t, len(events), 1)
+		assert.Assert(t, cmp.Contains(events[0], "PGBouncerNotPaused"))
+		assert.Assert(t, cmp.Contains(events[0], "ro"))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Client: reconciler.Client, Recorder: recorder}
+		reconciler.PodExec = func(
+			_, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			return errors.New("boom")
+		}
+
+		cluster := cluster.DeepCopy()
+		assert.ErrorContains(t, reconciler.pausePGBouncer(ctx, cluster), "boom")
+		close(recorder.Events)
+
+		var events []string
+		for event := range recorder.Events {
+			events = append(events, event)
+		}
+		sort.Strings(events)
+		assert.Equal(t, len(events), 4)
+		assert.Assert(t, cmp.Contains(events[0], "PGBouncerNotPaused"))
+		assert.Assert(t, cmp.Contains(events[0], "boom"))
+		assert.Assert(t, cmp.Contains(events[2], "PGBouncerNotResumed"))
+
+		// PgBouncer may have paused anyway, so a later reconcile resumes it.
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt != nil)
+	})
+}
+
+func TestReconcilerResumePGBouncer(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{
+			Port: initialize.Int32(5432),
+			Pause: &v1beta1.PGBouncerPauseSpec{
+				TimeoutSeconds: initialize.Int32(30),
+			},
+		},
+	}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "rw"
+	pod.Labels = map[string]string{
+		naming.LabelCluster: "hippo",
+		naming.LabelRole:    naming.RolePGBouncer,
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerPGBouncer,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+
+	secret := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	secret.Data = map[string][]byte{"pgbouncer-password": []byte("pass")}
+
+	var calls []string
+	reconciler := &Reconciler{}
+	reconciler.Recorder = record.NewFakeRecorder(100)
+	reconciler.Client = fake.NewClientBuilder().WithObjects(pod, secret).Build()
+	reconciler.PodExec = func(
+		_, pod, _ string, _ io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		calls = append(calls, command[len(command)-1]+" "+pod)
+		return nil
+	}
+
+	// primary returns instances with one primary that accepts writes or not.
+	primary := func(writable bool) *observedInstances {
+		pod := &corev1.Pod{}
+		pod.Name = "hippo-one-abcd-0"
+		pod.Annotations = map[string]string{"status": `{"role":"replica"}`}
+		if writable {
+			pod.Annotations["status"] = `{"role":"master"}`
+		}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  naming.ContainerDatabase,
+			State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+		}}
+		return &observedInstances{forCluster: []*Instance{
+			{Name: "hippo-one-abcd", Pods: []*corev1.Pod{pod}},
+		}}
+	}
+
+	t.Run("NotPaused", func(t *testing.T) {
+		calls = nil
+		result, err := reconciler.resumePGBouncer(ctx, cluster.DeepCopy(), primary(true))
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Assert(t, calls == nil)
+	})
+
+	t.Run("NoPrimary", func(t *testing.T) {
+		calls = nil
+		cluster := cluster.DeepCopy()
+		cluster.Status.Proxy.PGBouncer.PausedAt = &metav1.Time{Time: time.Now()}
+
+		result, err := reconciler.resumePGBouncer(ctx, cluster, primary(false))
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 5*time.Second)
+		assert.Assert(t, calls == nil)
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt != nil)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		calls = nil
+		cluster := cluster.DeepCopy()
+		cluster.Status.Proxy.PGBouncer.PausedAt = &metav1.Time{
+			Time: time.Now().Add(-time.Minute),
+		}
+
+		result, err := reconciler.resumePGBouncer(ctx, cluster, primary(false))
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.DeepEqual(t, calls, []string{"RESUME rw"})
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt == nil)
+	})
+
+	t.Run("Writable", func(t *testing.T) {
+		calls = nil
+		cluster := cluster.DeepCopy()
+		cluster.Status.Proxy.PGBouncer.PausedAt = &metav1.Time{Time: time.Now()}
+
+		result, err := reconciler.resumePGBouncer(ctx, cluster, primary(true))
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.DeepEqual(t, calls, []string{"RESUME rw"})
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt == nil)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		reconciler := &Reconciler{Client: reconciler.Client, Recorder: reconciler.Recorder}
+		reconciler.PodExec = func(
+			_, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			return errors.New("boom")
+		}
+
+		cluster := cluster.DeepCopy()
+		cluster.Status.Proxy.PGBouncer.PausedAt = &metav1.Time{Time: time.Now()}
+
+		// PGO tries again later.
+		result, err := reconciler.resumePGBouncer(ctx, cluster, primary(true))
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 5*time.Second)
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt != nil)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		calls = nil
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy = nil
+		cluster.Status.Proxy.PGBouncer.PausedAt = &metav1.Time{Time: time.Now()}
+
+		result, err := reconciler.resumePGBouncer(ctx, cluster, primary(false))
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Assert(t, calls == nil)
+		assert.Assert(t, cluster.Status.Proxy.PGBouncer.PausedAt == nil)
+	})
+}
@@ -20,6 +20,7 @@ package postgrescluster
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -27,7 +28,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/adifri/postgres-operator/v5/internal/initialize"
@@ -53,7 +56,7 @@ func TestGeneratePGBouncerService(t *testing.T) {
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy = spec
 
-			service, specified, err := reconciler.generatePGBouncerService(cluster)
+			service, specified, err := reconciler.generatePGBouncerService(cluster, false)
 			assert.NilError(t, err)
 			assert.Assert(t, !specified)
 
@@ -113,7 +116,7 @@ ownerReferences:
 			Labels:      map[string]string{"b": "v2"},
 		}
 
-		service, specified, err := reconciler.generatePGBouncerService(cluster)
+		service, specified, err := reconciler.generatePGBouncerService(cluster, false)
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -137,7 +140,7 @@ ownerReferences:
 	})
 
 	t.Run("NoServiceSpec", func(t *testing.T) {
-		service, specified, err := reconciler.generatePGBouncerService(cluster)
+		service, specified, err := reconciler.generatePGBouncerService(cluster, false)
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 		alwaysExpect(t, service)
@@ -166,13 +169,44 @@ ownerReferences:
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: test.Type}
 
-			service, specified, err := reconciler.generatePGBouncerService(cluster)
+			service, specified, err := reconciler.generatePGBouncerService(cluster, false)
 			assert.NilError(t, err)
 			assert.Assert(t, specified)
 			alwaysExpect(t, service)
 			test.Expect(t, service)
 		})
 	}
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+
+		service, specified, err := reconciler.generatePGBouncerService(cluster, true)
+		assert.NilError(t, err)
+		assert.Assert(t, !specified)
+		assert.Equal(t, service.Name, "pg7-pgbouncer-ro")
+
+		cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: "LoadBalancer"}
+		cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+			Service: &v1beta1.ServiceSpec{Type: "NodePort"},
+		}
+
+		service, specified, err = reconciler.generatePGBouncerService(cluster, true)
+		assert.NilError(t, err)
+		assert.Assert(t, specified)
+
+		assert.Equal(t, service.Name, "pg7-pgbouncer-ro")
+		assert.Equal(t, service.Spec.Type, corev1.ServiceTypeNodePort)
+		assert.Assert(t, marshalMatches(service.Spec.Ports, `
+- name: pgbouncer
+  port: 9651
+  protocol: TCP
+  targetPort: pgbouncer
+		`))
+		assert.DeepEqual(t, service.Spec.Selector, map[string]string{
+			"postgres-operator.crunchydata.com/cluster": "pg7",
+			"postgres-operator.crunchydata.com/role":    "pgbouncer-ro",
+		})
+	})
 }
 
 func TestReconcilePGBouncerService(t *testing.T) {
@@ -190,7 +224,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 		cluster := cluster.DeepCopy()
 		cluster.Spec.Proxy = nil
 
-		service, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+		service, err := reconciler.reconcilePGBouncerService(ctx, cluster, false)
 		assert.NilError(t, err)
 		assert.Assert(t, service == nil)
 	})
@@ -202,7 +236,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 	}
 
 	t.Run("NoServiceSpec", func(t *testing.T) {
-		service, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+		service, err := reconciler.reconcilePGBouncerService(ctx, cluster, false)
 		assert.NilError(t, err)
 		assert.Assert(t, service != nil)
 		t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, service)) })
@@ -219,7 +253,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: serviceType}
 
-			service, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+			service, err := reconciler.reconcilePGBouncerService(ctx, cluster, false)
 			assert.NilError(t, err)
 			assert.Assert(t, service != nil)
 			t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, service)) })
@@ -238,13 +272,13 @@ func TestReconcilePGBouncerService(t *testing.T) {
 				cluster := cluster.DeepCopy()
 				cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: beforeType}
 
-				before, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+				before, err := reconciler.reconcilePGBouncerService(ctx, cluster, false)
 				assert.NilError(t, err)
 				t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, before)) })
 
 				cluster.Spec.Proxy.PGBouncer.Service.Type = changeType
 
-				after, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+				after, err := reconciler.reconcilePGBouncerService(ctx, cluster, false)
 
 				// LoadBalancers are provisioned by a separate controller that
 				// updates the Service soon after creation. The API may return
@@ -252,7 +286,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 				// don't send a resourceVersion in our payload. Retry.
 				if apierrors.IsConflict(err) {
 					t.Log("conflict:", err)
-					after, err = reconciler.reconcilePGBouncerService(ctx, cluster)
+					after, err = reconciler.reconcilePGBouncerService(ctx, cluster, false)
 				}
 
 				assert.NilError(t, err, "\n%#v", errors.Unwrap(err))
@@ -280,7 +314,7 @@ func TestGeneratePGBouncerDeployment(t *testing.T) {
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy = spec
 
-			deploy, specified, err := reconciler.generatePGBouncerDeployment(cluster, nil, nil, nil)
+			deploy, specified, err := reconciler.generatePGBouncerDeployment(cluster, false, nil, nil, nil)
 			assert.NilError(t, err)
 			assert.Assert(t, !specified)
 
@@ -313,7 +347,7 @@ namespace: ns3
 		}
 
 		deploy, specified, err := reconciler.generatePGBouncerDeployment(
-			cluster, primary, configmap, secret)
+			cluster, false, primary, configmap, secret)
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -353,7 +387,7 @@ namespace: ns3
 
 	t.Run("PodSpec", func(t *testing.T) {
 		deploy, specified, err := reconciler.generatePGBouncerDeployment(
-			cluster, primary, configmap, secret)
+			cluster, false, primary, configmap, secret)
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -399,13 +433,45 @@ topologySpreadConstraints:
 			cluster.Spec.DisableDefaultPodScheduling = initialize.Bool(true)
 
 			deploy, specified, err := reconciler.generatePGBouncerDeployment(
-				cluster, primary, configmap, secret)
+				cluster, false, primary, configmap, secret)
 			assert.NilError(t, err)
 			assert.Assert(t, specified)
 
 			assert.Assert(t, deploy.Spec.Template.Spec.TopologySpreadConstraints == nil)
 		})
 	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+
+		deploy, specified, err := reconciler.generatePGBouncerDeployment(
+			cluster, true, primary, configmap, secret)
+		assert.NilError(t, err)
+		assert.Assert(t, !specified)
+		assert.Equal(t, deploy.Name, "test-cluster-pgbouncer-ro")
+
+		cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+			Replicas: initialize.Int32(3),
+		}
+
+		deploy, specified, err = reconciler.generatePGBouncerDeployment(
+			cluster, true, primary, configmap, secret)
+		assert.NilError(t, err)
+		assert.Assert(t, specified)
+
+		assert.Equal(t, deploy.Name, "test-cluster-pgbouncer-ro")
+		assert.Equal(t, *deploy.Spec.Replicas, int32(3))
+		assert.DeepEqual(t, deploy.Spec.Selector.MatchLabels, map[string]string{
+			"postgres-operator.crunchydata.com/cluster": "test-cluster",
+			"postgres-operator.crunchydata.com/role":    "pgbouncer-ro",
+		})
+		assert.Equal(t,
+			deploy.Spec.Template.Labels["postgres-operator.crunchydata.com/role"], "pgbouncer-ro")
+
+		// Containers and Volumes should be populated.
+		assert.Assert(t, len(deploy.Spec.Template.Spec.Containers) != 0)
+		assert.Assert(t, len(deploy.Spec.Template.Spec.Volumes) != 0)
+	})
 }
 
 func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
@@ -435,14 +501,14 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		cluster.Namespace = ns.Name
 		cluster.Spec.Proxy = nil
 
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false))
 	})
 
 	t.Run("no replicas in spec", func(t *testing.T) {
 		cluster := testCluster()
 		cluster.Namespace = ns.Name
 		cluster.Spec.Proxy.PGBouncer.Replicas = nil
-		assert.Error(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster),
+		assert.Error(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false),
 			"Replicas should be defined")
 	})
 
@@ -451,7 +517,7 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		cluster.Namespace = ns.Name
 		cluster.Spec.Proxy.PGBouncer.Replicas = initialize.Int32(1)
 		cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringInt32(0)
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false))
 		assert.Assert(t, !foundPDB(cluster))
 	})
 
@@ -464,18 +530,18 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		assert.NilError(t, r.Client.Create(ctx, cluster))
 		t.Cleanup(func() { assert.Check(t, r.Client.Delete(ctx, cluster)) })
 
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false))
 		assert.Assert(t, foundPDB(cluster))
 
 		t.Run("deleted", func(t *testing.T) {
 			cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringInt32(0)
-			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 			if apierrors.IsConflict(err) {
 				// When running in an existing environment another controller will sometimes update
 				// the object. This leads to an error where the ResourceVersion of the object does
 				// not match what we expect. When we run into this conflict, try to reconcile the
 				// object again.
-				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 			}
 			assert.NilError(t, err, errors.Unwrap(err))
 			assert.Assert(t, !foundPDB(cluster))
@@ -491,18 +557,18 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		assert.NilError(t, r.Client.Create(ctx, cluster))
 		t.Cleanup(func() { assert.Check(t, r.Client.Delete(ctx, cluster)) })
 
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false))
 		assert.Assert(t, foundPDB(cluster))
 
 		t.Run("deleted", func(t *testing.T) {
 			cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringString("0%")
-			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 			if apierrors.IsConflict(err) {
 				// When running in an existing environment another controller will sometimes update
 				// the object. This leads to an error where the ResourceVersion of the object does
 				// not match what we expect. When we run into this conflict, try to reconcile the
 				// object again.
-				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 			}
 			assert.NilError(t, err, errors.Unwrap(err))
 			assert.Assert(t, !foundPDB(cluster))
@@ -511,22 +577,84 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		t.Run("delete with 00%", func(t *testing.T) {
 			cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringString("50%")
 
-			assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+			assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false))
 			assert.Assert(t, foundPDB(cluster))
 
 			t.Run("deleted", func(t *testing.T) {
 				cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringString("00%")
-				err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+				err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 				if apierrors.IsConflict(err) {
 					// When running in an existing environment another controller will sometimes update
 					// the object. This leads to an error where the ResourceVersion of the object does
 					// not match what we expect. When we run into this conflict, try to reconcile the
 					// object again.
-					err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+					err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, false)
 				}
 				assert.NilError(t, err, errors.Unwrap(err))
 				assert.Assert(t, !foundPDB(cluster))
 			})
 		})
 	})
+
+	t.Run("read-only created", func(t *testing.T) {
+		cluster := testCluster()
+		cluster.Namespace = ns.Name
+		cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+			Replicas: initialize.Int32(2),
+		}
+
+		assert.NilError(t, r.Client.Create(ctx, cluster))
+		t.Cleanup(func() { assert.Check(t, r.Client.Delete(ctx, cluster)) })
+
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, true))
+
+		got := &policyv1beta1.PodDisruptionBudget{}
+		assert.NilError(t, r.Client.Get(ctx,
+			naming.AsObjectKey(naming.ClusterPGBouncerReadOnly(cluster)), got))
+		assert.Equal(t, got.Spec.MinAvailable.IntValue(), 1)
+		assert.DeepEqual(t, got.Spec.Selector.MatchLabels, map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RolePGBouncerReadOnly,
+		})
+
+		t.Run("deleted", func(t *testing.T) {
+			cluster.Spec.Proxy.PGBouncer.ReadOnly = nil
+			assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, true))
+
+			err := r.Client.Get(ctx,
+				naming.AsObjectKey(naming.ClusterPGBouncerReadOnly(cluster)), got)
+			assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+		})
+	})
+}
+
+func TestSetPGBouncerPoolsCondition(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Generation = 3
+
+	// Nothing happens while PgBouncer is disabled.
+	setPGBouncerPoolsCondition(cluster, nil)
+	assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, "ProxyPoolsValid") == nil)
+
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+	setPGBouncerPoolsCondition(cluster, nil)
+
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, "ProxyPoolsValid")
+	assert.Assert(t, condition != nil)
+	assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	assert.Equal(t, condition.ObservedGeneration, int64(3))
+
+	setPGBouncerPoolsCondition(cluster, field.ErrorList{
+		field.Duplicate(field.NewPath("spec", "proxy", "pgBouncer", "pools", "users").Index(0).Child("user"), "u"),
+	})
+
+	condition = meta.FindStatusCondition(cluster.Status.Conditions, "ProxyPoolsValid")
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	assert.Equal(t, condition.Reason, "InvalidPool")
+	assert.Assert(t, strings.Contains(condition.Message, "pools.users[0].user"), condition.Message)
+
+	// The condition goes away with PgBouncer.
+	cluster.Spec.Proxy = nil
+	setPGBouncerPoolsCondition(cluster, nil)
+	assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, "ProxyPoolsValid") == nil)
 }
@@ -17,13 +17,19 @@ package postgrescluster
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pgbackrest"
 	"github.com/adifri/postgres-operator/v5/internal/pki"
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -33,22 +39,37 @@ const (
 	clusterCertFile = "tls.crt"
 	clusterKeyFile  = "tls.key"
 	rootCertFile    = "ca.crt"
+
+	instanceCertFile = "dns.crt"
+	instanceKeyFile  = "dns.key"
 )
 
+// rootRotationPropagation is how long each phase of a root certificate
+// rotation waits after writing certificates. Kubernetes takes some time to
+// update the files of Secret volumes, and then components reload them.
+const rootRotationPropagation = 3 * time.Minute
+
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=create;patch
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=postgresclusters,verbs=list
 
 // reconcileRootCertificate ensures the root certificate, stored
 // in the relevant secret, has been created and is not 'bad' due
 // to being expired, formatted incorrectly, etc.
 // If it is bad for some reason, a new root certificate is
 // generated for use.
+//
+// When the root certificate should be replaced, a new root certificate is
+// stored alongside it and every cluster in the namespace rotates to it. The
+// returned root is the one that signs the certificates of cluster, and it
+// trusts the other root when necessary. See [advanceRootRotation].
 func (r *Reconciler) reconcileRootCertificate(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
 ) (
 	*pki.RootCertificateAuthority, error,
 ) {
 	const keyCertificate, keyPrivateKey = "root.crt", "root.key"
+	const keyNextCertificate, keyNextPrivateKey = "next.crt", "next.key"
 
 	existing := &corev1.Secret{}
 	existing.Namespace, existing.Name = cluster.Namespace, naming.RootCertSecret
@@ -56,6 +77,7 @@ func (r *Reconciler) reconcileRootCertificate(
 		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
 
 	root := &pki.RootCertificateAuthority{}
+	next := &pki.RootCertificateAuthority{}
 
 	if err == nil {
 		// Unmarshal and validate the stored root. These first errors can
@@ -63,10 +85,43 @@ func (r *Reconciler) reconcileRootCertificate(
 		// correctly regenerated.
 		_ = root.Certificate.UnmarshalText(existing.Data[keyCertificate])
 		_ = root.PrivateKey.UnmarshalText(existing.Data[keyPrivateKey])
+		_ = next.Certificate.UnmarshalText(existing.Data[keyNextCertificate])
+		_ = next.PrivateKey.UnmarshalText(existing.Data[keyNextPrivateKey])
 
 		if !pki.RootIsValid(root) {
 			root, err = pki.NewRootCertificateAuthority()
 			err = errors.WithStack(err)
+
+			// A rotation cannot continue without the root it replaces.
+			next = nil
+		}
+	}
+	if !pki.RootIsValid(next) {
+		next = nil
+	}
+
+	// Move this cluster through a rotation that is in progress. When every
+	// cluster in the namespace has retired the current root, replace it.
+	if err == nil && next != nil {
+		advanceRootRotation(cluster, next, metav1.Now())
+
+		var finished bool
+		finished, err = r.rootRotationFinished(ctx, cluster, next)
+		if finished {
+			root, next = next, nil
+		}
+	}
+
+	// Start a rotation when one is requested or the root is nearing expiration.
+	if err == nil && next == nil &&
+		(rootRotationRequested(cluster) || pki.RootNeedsRotation(root)) {
+		next, err = pki.NewRootCertificateAuthority()
+		err = errors.WithStack(err)
+
+		if err == nil {
+			advanceRootRotation(cluster, next, metav1.Now())
+			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "RootCertificateRotation",
+				"Replacing the root certificate authority of namespace %q", cluster.Namespace)
 		}
 	}
 
@@ -96,18 +151,234 @@ func (r *Reconciler) reconcileRootCertificate(
 		intent.Data[keyPrivateKey], err = root.PrivateKey.MarshalText()
 		err = errors.WithStack(err)
 	}
+	if err == nil && next != nil {
+		intent.Data[keyNextCertificate], err = next.Certificate.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil && next != nil {
+		intent.Data[keyNextPrivateKey], err = next.PrivateKey.MarshalText()
+		err = errors.WithStack(err)
+	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, intent))
 	}
 
+	if err == nil {
+		root = rootForCluster(cluster, root, next)
+		root.LeafLifetime, root.LeafRenewBefore = certificateLifetimes(cluster)
+	}
 	return root, err
 }
 
+// rootRotationRequested returns whether or not the rotate-root-certificate
+// annotation of cluster has a value that has not started a rotation.
+func rootRotationRequested(cluster *v1beta1.PostgresCluster) bool {
+	trigger := cluster.GetAnnotations()[naming.RotateRootCertificate]
+	rotation := cluster.Status.RootCertificateRotation
+
+	return trigger != "" && (rotation == nil || rotation.Trigger != trigger)
+}
+
+// rootRotationResult returns a Result that checks on a root certificate
+// rotation that is in progress.
+func rootRotationResult(cluster *v1beta1.PostgresCluster) reconcile.Result {
+	var result reconcile.Result
+	if rotation := cluster.Status.RootCertificateRotation; rotation != nil &&
+		rotation.Phase != "" && rotation.Phase != v1beta1.RootRotationComplete {
+		result.RequeueAfter = rootRotationPropagation / 3
+	}
+	return result
+}
+
+// rootRotationComponents returns the names of the components of cluster that
+// have certificates signed by the root certificate authority.
+func rootRotationComponents(cluster *v1beta1.PostgresCluster) []string {
+	components := []string{"instances", "postgres", "replication"}
+	if pgbackrest.DedicatedRepoHostEnabled(cluster) {
+		components = append(components, "pgbackrest")
+	}
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil {
+		components = append(components, "pgbouncer")
+	}
+	for i := range cluster.Spec.Users {
+		if cluster.Spec.Users[i].Certificate != nil && cluster.Spec.CustomTLSSecret == nil {
+			components = append(components, "users")
+			break
+		}
+	}
+	sort.Strings(components)
+	return components
+}
+
+// rootRotationWritten records that the certificates of component were written
+// in the current phase of a root certificate rotation.
+func rootRotationWritten(cluster *v1beta1.PostgresCluster, component string) {
+	rotation := cluster.Status.RootCertificateRotation
+	if rotation == nil || rotation.Phase == "" || rotation.Phase == v1beta1.RootRotationComplete {
+		return
+	}
+
+	for i := range rotation.Components {
+		if rotation.Components[i].Name == component {
+			if rotation.Components[i].Phase != rotation.Phase {
+				now := metav1.Now()
+				rotation.Components[i].Phase = rotation.Phase
+				rotation.Components[i].PhaseTime = &now
+			}
+			return
+		}
+	}
+
+	now := metav1.Now()
+	rotation.Components = append(rotation.Components,
+		v1beta1.RootCertificateRotationComponent{
+			Name: component, Phase: rotation.Phase, PhaseTime: &now,
+		})
+	sort.Slice(rotation.Components, func(i, j int) bool {
+		return rotation.Components[i].Name < rotation.Components[j].Name
+	})
+}
+
+// rootRotationSettled returns whether or not every component of cluster wrote
+// its certificates in the current phase of a rotation long enough ago that
+// they are in use.
+func rootRotationSettled(cluster *v1beta1.PostgresCluster, now metav1.Time) bool {
+	rotation := cluster.Status.RootCertificateRotation
+	if rotation == nil {
+		return false
+	}
+
+	written := make(map[string]v1beta1.RootCertificateRotationComponent)
+	for _, component := range rotation.Components {
+		written[component.Name] = component
+	}
+	for _, name := range rootRotationComponents(cluster) {
+		component, ok := written[name]
+		if !ok || component.Phase != rotation.Phase || component.PhaseTime == nil ||
+			now.Sub(component.PhaseTime.Time) < rootRotationPropagation {
+			return false
+		}
+	}
+	return true
+}
+
+// advanceRootRotation moves cluster through the phases of a rotation to the
+// next root certificate authority:
+//
+//  1. Trusting: components trust both roots; the current root signs.
+//  2. Reissuing: components trust both roots; the next root signs.
+//  3. Retiring: components trust only the next root, which signs.
+//  4. Complete: the next root has replaced the current root.
+//
+// Each phase lasts until every component has written its certificates and
+// those have had time to reach every Pod.
+func advanceRootRotation(
+	cluster *v1beta1.PostgresCluster, next *pki.RootCertificateAuthority, now metav1.Time,
+) {
+	rotation := cluster.Status.RootCertificateRotation
+	fingerprint := next.Certificate.Fingerprint()
+
+	if rotation == nil || rotation.Root != fingerprint {
+		cluster.Status.RootCertificateRotation = &v1beta1.RootCertificateRotationStatus{
+			Root:      fingerprint,
+			Trigger:   cluster.GetAnnotations()[naming.RotateRootCertificate],
+			Phase:     v1beta1.RootRotationTrusting,
+			PhaseTime: &now,
+		}
+		return
+	}
+
+	// Forget components that are no longer part of the cluster.
+	expected := sets.NewString(rootRotationComponents(cluster)...)
+	components := rotation.Components[:0]
+	for _, component := range rotation.Components {
+		if expected.Has(component.Name) {
+			components = append(components, component)
+		}
+	}
+	rotation.Components = components
+
+	if rootRotationSettled(cluster, now) {
+		switch rotation.Phase {
+		case v1beta1.RootRotationTrusting:
+			rotation.Phase, rotation.PhaseTime = v1beta1.RootRotationReissuing, &now
+		case v1beta1.RootRotationReissuing:
+			rotation.Phase, rotation.PhaseTime = v1beta1.RootRotationRetiring, &now
+		}
+	}
+}
+
+// rootRotationFinished returns whether or not every cluster in the namespace
+// of cluster has retired the root certificate authority that next replaces.
+func (r *Reconciler) rootRotationFinished(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, next *pki.RootCertificateAuthority,
+) (bool, error) {
+	clusters := &v1beta1.PostgresClusterList{}
+	err := errors.WithStack(
+		r.Client.List(ctx, clusters, client.InNamespace(cluster.Namespace)))
+
+	retired := func(c *v1beta1.PostgresCluster) bool {
+		rotation := c.Status.RootCertificateRotation
+		return rotation != nil &&
+			rotation.Root == next.Certificate.Fingerprint() &&
+			rotation.Phase == v1beta1.RootRotationRetiring &&
+			rootRotationSettled(c, metav1.Now())
+	}
+
+	finished := err == nil && retired(cluster)
+	for i := range clusters.Items {
+		// The status of this cluster is newer than the one in the cache.
+		if clusters.Items[i].UID != cluster.UID {
+			finished = finished && retired(&clusters.Items[i])
+		}
+	}
+	return finished, err
+}
+
+// rootForCluster returns the root certificate authority that signs the
+// certificates of cluster and the roots that cluster should trust according
+// to its phase of a rotation to next, if any.
+func rootForCluster(
+	cluster *v1beta1.PostgresCluster, root, next *pki.RootCertificateAuthority,
+) *pki.RootCertificateAuthority {
+	rotation := cluster.Status.RootCertificateRotation
+
+	if next == nil || rotation == nil || rotation.Root != next.Certificate.Fingerprint() {
+		switch {
+		case rotation == nil || rotation.Phase == v1beta1.RootRotationComplete:
+		case rotation.Root == root.Certificate.Fingerprint():
+			// The rotation is complete once its root is the current root.
+			now := metav1.Now()
+			rotation.Phase, rotation.PhaseTime = v1beta1.RootRotationComplete, &now
+		default:
+			// The rotation was abandoned, perhaps because the current root
+			// was invalid and had to be regenerated. Keep only the trigger
+			// so it does not start another rotation.
+			cluster.Status.RootCertificateRotation =
+				&v1beta1.RootCertificateRotationStatus{Trigger: rotation.Trigger}
+		}
+		return root
+	}
+
+	switch rotation.Phase {
+	case v1beta1.RootRotationTrusting:
+		root.Trusted = []pki.Certificate{next.Certificate}
+		return root
+	case v1beta1.RootRotationReissuing:
+		next.Trusted = []pki.Certificate{root.Certificate}
+		return next
+	default:
+		return next
+	}
+}
+
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=create;patch
 
 // reconcileClusterCertificate first checks if a custom certificate
 // secret is configured. If so, that secret projection is returned.
+// When a certificate issuer is configured, the Secret of its
+// cert-manager Certificate is projected instead.
 // Otherwise, a secret containing a generated leaf certificate, stored in
 // the relevant secret, has been created and is not 'bad' due to being
 // expired, formatted incorrectly, etc. If it is bad for any reason, a new
@@ -126,6 +397,17 @@ func (r *Reconciler) reconcileClusterCertificate(
 		return cluster.Spec.CustomTLSSecret, nil
 	}
 
+	// when an issuer is configured, project the Secret of its certificate
+	if cluster.Spec.CertificateIssuer != nil {
+		issued, err := r.reconcileIssuedCertificate(ctx, cluster,
+			naming.ClusterIssuedCertificate(cluster), "",
+			naming.ServiceDNSNames(ctx, primaryService), issuedServerUsages)
+		if err != nil {
+			return nil, err
+		}
+		return clusterCertSecretProjection(issued.Secret), nil
+	}
+
 	const keyCertificate, keyPrivateKey, rootCA = "tls.crt", "tls.key", "ca.crt"
 
 	existing := &corev1.Secret{ObjectMeta: naming.PostgresTLSSecret(cluster)}
@@ -173,7 +455,7 @@ func (r *Reconciler) reconcileClusterCertificate(
 		err = errors.WithStack(err)
 	}
 	if err == nil {
-		intent.Data[rootCA], err = root.Certificate.MarshalText()
+		intent.Data[rootCA], err = root.TrustBundle().MarshalText()
 		err = errors.WithStack(err)
 	}
 
@@ -206,7 +488,6 @@ func (*Reconciler) instanceCertificate(
 	*pki.LeafCertificate, error,
 ) {
 	var err error
-	const keyCertificate, keyPrivateKey = "dns.crt", "dns.key"
 
 	leaf := &pki.LeafCertificate{}
 
@@ -219,19 +500,19 @@ func (*Reconciler) instanceCertificate(
 		// Unmarshal and validate the stored leaf. These first errors can
 		// be ignored because they result in an invalid leaf which is then
 		// correctly regenerated.
-		_ = leaf.Certificate.UnmarshalText(existing.Data[keyCertificate])
-		_ = leaf.PrivateKey.UnmarshalText(existing.Data[keyPrivateKey])
+		_ = leaf.Certificate.UnmarshalText(existing.Data[instanceCertFile])
+		_ = leaf.PrivateKey.UnmarshalText(existing.Data[instanceKeyFile])
 
 		leaf, err = root.RegenerateLeafWhenNecessary(leaf, dnsFQDN, dnsNames)
 		err = errors.WithStack(err)
 	}
 
 	if err == nil {
-		intent.Data[keyCertificate], err = leaf.Certificate.MarshalText()
+		intent.Data[instanceCertFile], err = leaf.Certificate.MarshalText()
 		err = errors.WithStack(err)
 	}
 	if err == nil {
-		intent.Data[keyPrivateKey], err = leaf.PrivateKey.MarshalText()
+		intent.Data[instanceKeyFile], err = leaf.PrivateKey.MarshalText()
 		err = errors.WithStack(err)
 	}
 
@@ -0,0 +1,188 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pki"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestRootRotationRequested(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	assert.Assert(t, !rootRotationRequested(cluster))
+
+	cluster.Annotations = map[string]string{naming.RotateRootCertificate: "one"}
+	assert.Assert(t, rootRotationRequested(cluster))
+
+	cluster.Status.RootCertificateRotation = &v1beta1.RootCertificateRotationStatus{Trigger: "one"}
+	assert.Assert(t, !rootRotationRequested(cluster), "expected trigger to be handled")
+
+	cluster.Annotations[naming.RotateRootCertificate] = "two"
+	assert.Assert(t, rootRotationRequested(cluster))
+}
+
+func TestRootRotationComponents(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	assert.DeepEqual(t, rootRotationComponents(cluster),
+		[]string{"instances", "postgres", "replication"})
+
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+		Name: "repo1", Volume: &v1beta1.RepoPVC{},
+	}}
+	assert.DeepEqual(t, rootRotationComponents(cluster),
+		[]string{"instances", "pgbackrest", "pgbouncer", "postgres", "replication"})
+
+	cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+		{Name: "app"},
+		{Name: "service", Certificate: &v1beta1.PostgresUserCertificateSpec{}},
+	}
+	assert.DeepEqual(t, rootRotationComponents(cluster),
+		[]string{"instances", "pgbackrest", "pgbouncer", "postgres", "replication", "users"})
+
+	// PGO cannot sign user certificates for a custom server certificate.
+	cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{}
+	assert.DeepEqual(t, rootRotationComponents(cluster),
+		[]string{"instances", "pgbackrest", "pgbouncer", "postgres", "replication"})
+}
+
+func TestAdvanceRootRotation(t *testing.T) {
+	current, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+	next, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	// rootForCluster modifies the roots it is given, so pass it copies.
+	fresh := func(root *pki.RootCertificateAuthority) *pki.RootCertificateAuthority {
+		return &pki.RootCertificateAuthority{
+			Certificate: root.Certificate, PrivateKey: root.PrivateKey,
+		}
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Annotations = map[string]string{naming.RotateRootCertificate: "now"}
+
+	start := metav1.NewTime(time.Now().Add(-time.Hour))
+	advanceRootRotation(cluster, next, start)
+
+	rotation := cluster.Status.RootCertificateRotation
+	assert.Assert(t, rotation != nil)
+	assert.Equal(t, rotation.Root, next.Certificate.Fingerprint())
+	assert.Equal(t, rotation.Trigger, "now")
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationTrusting)
+	assert.Assert(t, !rootRotationRequested(cluster))
+
+	t.Run("Trusting", func(t *testing.T) {
+		root := rootForCluster(cluster, fresh(current), fresh(next))
+		assert.Assert(t, root.Certificate.Equal(current.Certificate), "expected current root to sign")
+		assert.DeepEqual(t, root.TrustBundle(),
+			pki.CertificateBundle{current.Certificate, next.Certificate})
+	})
+
+	// Nothing changes until every component writes its certificates.
+	advanceRootRotation(cluster, next, metav1.Now())
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationTrusting)
+
+	written := func(phaseTime metav1.Time) {
+		for _, name := range rootRotationComponents(cluster) {
+			rootRotationWritten(cluster, name)
+		}
+		for i := range rotation.Components {
+			rotation.Components[i].PhaseTime = &phaseTime
+		}
+	}
+
+	// Nothing changes until those certificates have had time to propagate.
+	written(metav1.Now())
+	assert.Equal(t, len(rotation.Components), 3)
+	advanceRootRotation(cluster, next, metav1.Now())
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationTrusting)
+
+	written(start)
+	advanceRootRotation(cluster, next, metav1.Now())
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationReissuing)
+
+	t.Run("Reissuing", func(t *testing.T) {
+		root := rootForCluster(cluster, fresh(current), fresh(next))
+		assert.Assert(t, root.Certificate.Equal(next.Certificate), "expected next root to sign")
+		assert.DeepEqual(t, root.TrustBundle(),
+			pki.CertificateBundle{next.Certificate, current.Certificate})
+	})
+
+	// Components from the previous phase do not count.
+	advanceRootRotation(cluster, next, metav1.Now())
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationReissuing)
+
+	written(start)
+	advanceRootRotation(cluster, next, metav1.Now())
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationRetiring)
+
+	t.Run("Retiring", func(t *testing.T) {
+		root := rootForCluster(cluster, fresh(current), fresh(next))
+		assert.Assert(t, root.Certificate.Equal(next.Certificate), "expected next root to sign")
+		assert.DeepEqual(t, root.TrustBundle(), pki.CertificateBundle{next.Certificate})
+	})
+
+	// Retiring is the last phase before the next root replaces the current one.
+	written(start)
+	advanceRootRotation(cluster, next, metav1.Now())
+	assert.Equal(t, rotation.Phase, v1beta1.RootRotationRetiring)
+	assert.Assert(t, rootRotationSettled(cluster, metav1.Now()))
+	assert.Equal(t, rootRotationResult(cluster).RequeueAfter, rootRotationPropagation/3)
+
+	t.Run("Complete", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+
+		root := rootForCluster(cluster, fresh(next), nil)
+		assert.Assert(t, root.Certificate.Equal(next.Certificate))
+		assert.DeepEqual(t, root.TrustBundle(), pki.CertificateBundle{next.Certificate})
+
+		assert.Equal(t, cluster.Status.RootCertificateRotation.Phase, v1beta1.RootRotationComplete)
+		assert.Equal(t, rootRotationResult(cluster).RequeueAfter, time.Duration(0))
+	})
+
+	t.Run("Abandoned", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+
+		other, err := pki.NewRootCertificateAuthority()
+		assert.NilError(t, err)
+
+		root := rootForCluster(cluster, other, nil)
+		assert.Assert(t, root.Certificate.Equal(other.Certificate))
+		assert.DeepEqual(t, cluster.Status.RootCertificateRotation,
+			&v1beta1.RootCertificateRotationStatus{Trigger: "now"})
+		assert.Assert(t, !rootRotationRequested(cluster))
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+
+		other, err := pki.NewRootCertificateAuthority()
+		assert.NilError(t, err)
+
+		advanceRootRotation(cluster, other, metav1.Now())
+		assert.Equal(t, cluster.Status.RootCertificateRotation.Phase, v1beta1.RootRotationTrusting)
+		assert.Equal(t, len(cluster.Status.RootCertificateRotation.Components), 0)
+	})
+}
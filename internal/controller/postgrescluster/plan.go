@@ -0,0 +1,575 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pgbackrest"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// planFields appends to fields the paths of values in intent that differ from
+// those in existing. Values that intent does not set are ignored, just as
+// they are by server-side apply.
+func planFields(fields []string, path string, intent, existing interface{}) []string {
+	switch intent := intent.(type) {
+	case nil:
+		return fields
+
+	case map[string]interface{}:
+		existing, _ := existing.(map[string]interface{})
+		if len(intent) == 0 {
+			return fields
+		}
+		keys := make([]string, 0, len(intent))
+		for key := range intent {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			next := key
+			if path != "" {
+				next = path + "." + key
+			}
+			fields = planFields(fields, next, intent[key], existing[key])
+		}
+		return fields
+
+	case []interface{}:
+		existing, _ := existing.([]interface{})
+		if len(intent) == 0 && len(existing) == 0 {
+			return fields
+		}
+		if len(intent) != len(existing) {
+			return append(fields, path)
+		}
+		for i := range intent {
+			fields = planFields(fields, fmt.Sprintf("%s[%d]", path, i), intent[i], existing[i])
+		}
+		return fields
+
+	default:
+		if !reflect.DeepEqual(intent, existing) {
+			fields = append(fields, path)
+		}
+		return fields
+	}
+}
+
+// planObject compares intent to existing and returns the change, if any, to
+// existing. When existing is nil, intent would be created.
+func planObject(
+	kind string, intent, existing client.Object,
+) (*v1beta1.PostgresClusterPlannedChange, error) {
+	change := &v1beta1.PostgresClusterPlannedChange{Kind: kind, Name: intent.GetName()}
+
+	if existing == nil {
+		change.Action = v1beta1.PostgresClusterPlannedCreate
+		return change, nil
+	}
+
+	// Compare only the labels and annotations of metadata. Everything else
+	// there is set by Kubernetes.
+	toMap := func(object client.Object) (map[string]interface{}, error) {
+		var out map[string]interface{}
+		data, err := json.Marshal(object)
+		if err == nil {
+			err = json.Unmarshal(data, &out)
+		}
+		meta, _ := out["metadata"].(map[string]interface{})
+		out["metadata"] = map[string]interface{}{
+			"annotations": meta["annotations"],
+			"labels":      meta["labels"],
+		}
+		delete(out, "apiVersion")
+		delete(out, "kind")
+		delete(out, "status")
+		return out, errors.WithStack(err)
+	}
+
+	intentMap, err := toMap(intent)
+	if err != nil {
+		return nil, err
+	}
+	existingMap, err := toMap(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	change.Fields = planFields(nil, "", intentMap, existingMap)
+	if len(change.Fields) == 0 {
+		return nil, nil
+	}
+
+	change.Action = v1beta1.PostgresClusterPlannedUpdate
+	for _, field := range change.Fields {
+		if strings.HasPrefix(field, "spec.template.") {
+			change.Rollout = true
+		}
+	}
+	return change, nil
+}
+
+// planExisting returns the object in the Kubernetes API that has the same
+// name as intent, or nil when there is none.
+func (r *Reconciler) planExisting(
+	ctx context.Context, intent, existing client.Object,
+) (client.Object, error) {
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(intent), existing)
+	if err != nil {
+		return nil, errors.WithStack(client.IgnoreNotFound(err))
+	}
+	return existing, nil
+}
+
+// planClusterCertificate returns the projection of the primary certificate
+// that reconcileClusterCertificate would return.
+func planClusterCertificate(cluster *v1beta1.PostgresCluster) *corev1.SecretProjection {
+	if cluster.Spec.CustomTLSSecret != nil {
+		return cluster.Spec.CustomTLSSecret
+	}
+	if cluster.Spec.CertificateIssuer != nil {
+		return clusterCertSecretProjection(
+			&corev1.Secret{ObjectMeta: naming.ClusterIssuedCertificate(cluster)})
+	}
+	return clusterCertSecretProjection(
+		&corev1.Secret{ObjectMeta: naming.PostgresTLSSecret(cluster)})
+}
+
+// planReplicationSecret returns the Secret that reconcileReplicationSecret
+// would return, without its data.
+func planReplicationSecret(cluster *v1beta1.PostgresCluster) *corev1.Secret {
+	if cluster.Spec.CustomReplicationClientTLSSecret != nil {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      cluster.Spec.CustomReplicationClientTLSSecret.Name,
+		}}
+	}
+	if cluster.Spec.CertificateIssuer != nil {
+		return &corev1.Secret{ObjectMeta: naming.ReplicationIssuedCertificate(cluster)}
+	}
+	return &corev1.Secret{ObjectMeta: naming.ReplicationClientCertSecret(cluster)}
+}
+
+// planVolume returns the volume of instance that has labels, or one with the
+// name in meta when there is none.
+func planVolume(
+	cluster *v1beta1.PostgresCluster, spec *v1beta1.PostgresInstanceSetSpec,
+	instance *appsv1.StatefulSet, role string, meta metav1.ObjectMeta,
+	clusterVolumes []corev1.PersistentVolumeClaim,
+) (*corev1.PersistentVolumeClaim, error) {
+	name, err := getPGPVCName(map[string]string{
+		naming.LabelCluster:     cluster.Name,
+		naming.LabelInstanceSet: spec.Name,
+		naming.LabelInstance:    instance.Name,
+		naming.LabelRole:        role,
+		naming.LabelData:        naming.DataPostgres,
+	}, clusterVolumes)
+
+	if name != "" {
+		meta = metav1.ObjectMeta{Namespace: cluster.Namespace, Name: name}
+	}
+	return &corev1.PersistentVolumeClaim{ObjectMeta: meta}, errors.WithStack(err)
+}
+
+// planInstances returns the changes to instance StatefulSets of cluster.
+func (r *Reconciler) planInstances(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) ([]v1beta1.PostgresClusterPlannedChange, error) {
+	var changes []v1beta1.PostgresClusterPlannedChange
+
+	clusterVolumes, err := r.observePersistentVolumeClaims(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterConfigMap := &corev1.ConfigMap{ObjectMeta: naming.ClusterConfigMap(cluster)}
+	clusterPodService := &corev1.Service{ObjectMeta: naming.ClusterPodService(cluster)}
+	instanceServiceAccount := &corev1.ServiceAccount{ObjectMeta: naming.ClusterInstanceRBAC(cluster)}
+	patroniLeaderService, err := r.generatePatroniLeaderLeaseService(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var numInstancePods int
+	for i := range instances.forCluster {
+		numInstancePods += len(instances.forCluster[i].Pods)
+	}
+
+	// Instances of sets that are no longer specified are deleted.
+	for _, instance := range instances.forCluster {
+		if instance.Spec == nil && instance.Runner != nil {
+			changes = append(changes, v1beta1.PostgresClusterPlannedChange{
+				Kind: "StatefulSet", Name: instance.Name,
+				Action: v1beta1.PostgresClusterPlannedDelete,
+			})
+		}
+	}
+
+	for i := range cluster.Spec.InstanceSets {
+		spec := &cluster.Spec.InstanceSets[i]
+		var existing []*appsv1.StatefulSet
+		for _, instance := range instances.bySet[spec.Name] {
+			if instance.Runner != nil {
+				existing = append(existing, instance.Runner)
+			}
+		}
+
+		// Instances are added or removed to match replicas.
+		for n := len(existing); n != int(*spec.Replicas); {
+			change := v1beta1.PostgresClusterPlannedChange{
+				Kind: "StatefulSet", Name: cluster.Name + "-" + spec.Name + "-*",
+			}
+			if n < int(*spec.Replicas) {
+				change.Action = v1beta1.PostgresClusterPlannedCreate
+				n++
+			} else {
+				change.Action = v1beta1.PostgresClusterPlannedDelete
+				n--
+			}
+			changes = append(changes, change)
+		}
+
+		for _, runner := range existing {
+			intent := &appsv1.StatefulSet{}
+			intent.Namespace, intent.Name = runner.Namespace, runner.Name
+
+			generateInstanceStatefulSetIntent(ctx, cluster, spec,
+				clusterPodService.Name, instanceServiceAccount.Name, intent,
+				numInstancePods)
+
+			var dataVolume, walVolume *corev1.PersistentVolumeClaim
+			tablespaceVolumes := make(map[string]*corev1.PersistentVolumeClaim)
+
+			dataVolume, err = planVolume(cluster, spec, intent,
+				naming.RolePostgresData, naming.InstancePostgresDataVolume(intent),
+				clusterVolumes)
+			if err == nil {
+				walVolume, err = planVolume(cluster, spec, intent,
+					naming.RolePostgresWAL, naming.InstancePostgresWALVolume(intent),
+					clusterVolumes)
+
+				// There is no WAL volume unless one is specified or exists.
+				if spec.WALVolumeClaimSpec == nil &&
+					findVolume(clusterVolumes, walVolume.Name) == nil {
+					walVolume = nil
+				}
+			}
+			for _, tablespace := range spec.TablespaceVolumes {
+				if err == nil {
+					tablespaceVolumes[tablespace.Name] = &corev1.PersistentVolumeClaim{
+						ObjectMeta: naming.InstancePostgresTablespaceVolume(intent, tablespace.Name),
+					}
+				}
+			}
+
+			if err == nil {
+				err = generateInstancePod(ctx, cluster, spec,
+					clusterConfigMap, planReplicationSecret(cluster), clusterPodService,
+					patroniLeaderService, planClusterCertificate(cluster),
+					&corev1.ConfigMap{ObjectMeta: naming.InstanceConfigMap(intent)},
+					&corev1.Secret{ObjectMeta: naming.InstanceCertificates(intent)},
+					dataVolume, walVolume, tablespaceVolumes, &intent.Spec.Template)
+			}
+
+			var change *v1beta1.PostgresClusterPlannedChange
+			if err == nil {
+				change, err = planObject("StatefulSet", intent, runner)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if change != nil {
+				changes = append(changes, *change)
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// planPGBouncer returns the changes to the PgBouncer Services and Deployments
+// of cluster.
+func (r *Reconciler) planPGBouncer(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) ([]v1beta1.PostgresClusterPlannedChange, error) {
+	var changes []v1beta1.PostgresClusterPlannedChange
+
+	for _, readOnly := range []bool{false, true} {
+		meta := naming.ClusterPGBouncer(cluster)
+		if readOnly {
+			meta = naming.ClusterPGBouncerReadOnly(cluster)
+		}
+
+		service, specified, err := r.generatePGBouncerService(cluster, readOnly)
+		if err == nil {
+			var change *v1beta1.PostgresClusterPlannedChange
+			change, err = r.planOptional(ctx, "Service", service, &corev1.Service{}, specified)
+			if change != nil {
+				changes = append(changes, *change)
+			}
+		}
+
+		var deploy *appsv1.Deployment
+		if err == nil {
+			deploy, specified, err = r.generatePGBouncerDeployment(
+				cluster, readOnly, planClusterCertificate(cluster),
+				&corev1.ConfigMap{ObjectMeta: meta}, &corev1.Secret{ObjectMeta: naming.ClusterPGBouncer(cluster)})
+		}
+		if err == nil {
+			var change *v1beta1.PostgresClusterPlannedChange
+			change, err = r.planOptional(ctx, "Deployment", deploy, &appsv1.Deployment{}, specified)
+			if change != nil {
+				changes = append(changes, *change)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+// planOptional returns the change to an object that exists only when it is
+// specified.
+func (r *Reconciler) planOptional(
+	ctx context.Context, kind string, intent, existing client.Object, specified bool,
+) (*v1beta1.PostgresClusterPlannedChange, error) {
+	found, err := r.planExisting(ctx, intent, existing)
+	if err == nil && specified {
+		return planObject(kind, intent, found)
+	}
+	if err == nil && !specified && found != nil {
+		return &v1beta1.PostgresClusterPlannedChange{
+			Kind: kind, Name: intent.GetName(),
+			Action: v1beta1.PostgresClusterPlannedDelete,
+		}, nil
+	}
+	return nil, err
+}
+
+// planServices returns the changes to the Services of cluster other than
+// those of PgBouncer.
+func (r *Reconciler) planServices(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) ([]v1beta1.PostgresClusterPlannedChange, error) {
+	var changes []v1beta1.PostgresClusterPlannedChange
+
+	leader, err := r.generatePatroniLeaderLeaseService(cluster)
+
+	var primary, replica *corev1.Service
+	if err == nil {
+		primary, _, err = r.generateClusterPrimaryService(cluster, leader)
+	}
+	if err == nil {
+		replica, err = r.generateClusterReplicaService(cluster)
+	}
+
+	for _, intent := range []*corev1.Service{leader, primary, replica} {
+		var change *v1beta1.PostgresClusterPlannedChange
+		if err == nil {
+			change, err = r.planOptional(ctx, "Service", intent, &corev1.Service{}, true)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	return changes, err
+}
+
+// planConfigMaps returns the changes to the Patroni and pgBackRest ConfigMaps
+// of cluster.
+func (r *Reconciler) planConfigMaps(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	pgHBAs postgres.HBAs, pgParameters postgres.Parameters,
+) ([]v1beta1.PostgresClusterPlannedChange, error) {
+	var changes []v1beta1.PostgresClusterPlannedChange
+
+	patroni, err := r.generateClusterConfigMap(ctx, cluster, pgHBAs, pgParameters)
+
+	// The pgBackRest configuration lists every instance and the repository host.
+	var backrest *corev1.ConfigMap
+	if err == nil {
+		var configHash, repoHostName string
+		var instanceNames []string
+
+		_, configHash, err = pgbackrest.CalculateConfigHashes(cluster)
+		if err == nil {
+			for _, instance := range instances.forCluster {
+				instanceNames = append(instanceNames, instance.Name)
+			}
+			sort.Strings(instanceNames)
+
+			if pgbackrest.DedicatedRepoHostEnabled(cluster) {
+				repoHostName = cluster.Name + "-repo-host"
+			}
+
+			backrest = pgbackrest.CreatePGBackRestConfigMapIntent(cluster, repoHostName,
+				configHash, naming.ClusterPodService(cluster).Name, cluster.Namespace,
+				instanceNames)
+		}
+	}
+
+	for _, intent := range []*corev1.ConfigMap{patroni, backrest} {
+		var change *v1beta1.PostgresClusterPlannedChange
+		if err == nil {
+			change, err = r.planOptional(ctx, "ConfigMap", intent, &corev1.ConfigMap{}, true)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	return changes, err
+}
+
+// planJobs returns the pgBackRest Jobs that the annotations of cluster
+// request. A manual backup replaces any finished backup Job, and an in-place
+// restore deletes every instance before it restores.
+func (r *Reconciler) planJobs(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) ([]v1beta1.PostgresClusterPlannedChange, error) {
+	var changes []v1beta1.PostgresClusterPlannedChange
+	backrest := cluster.Spec.Backups.PGBackRest
+
+	backupID := cluster.Annotations[naming.PGBackRestBackup]
+	var backupStatus string
+	if cluster.Status.PGBackRest != nil && cluster.Status.PGBackRest.ManualBackup != nil {
+		backupStatus = cluster.Status.PGBackRest.ManualBackup.ID
+	}
+
+	if backupID != "" && backrest.Manual != nil && backupID != backupStatus {
+		jobs := &batchv1.JobList{}
+		err := errors.WithStack(r.Client.List(ctx, jobs,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabelsSelector{Selector: naming.PGBackRestBackupJobSelector(
+				cluster.Name, backrest.Manual.RepoName, naming.BackupManual)}))
+		if err != nil {
+			return nil, err
+		}
+		for i := range jobs.Items {
+			if jobs.Items[i].Annotations[naming.PGBackRestBackup] != backupID {
+				changes = append(changes, v1beta1.PostgresClusterPlannedChange{
+					Kind: "Job", Name: jobs.Items[i].Name,
+					Action: v1beta1.PostgresClusterPlannedDelete,
+				})
+			}
+		}
+		changes = append(changes, v1beta1.PostgresClusterPlannedChange{
+			Kind: "Job", Name: cluster.Name + "-backup-*",
+			Action: v1beta1.PostgresClusterPlannedCreate,
+		})
+	}
+
+	restoreID := cluster.Annotations[naming.PGBackRestRestore]
+	var restoreStatus string
+	if cluster.Status.PGBackRest != nil && cluster.Status.PGBackRest.Restore != nil {
+		restoreStatus = cluster.Status.PGBackRest.Restore.ID
+	}
+
+	if restoreID != "" && backrest.Restore != nil && backrest.Restore.Enabled != nil &&
+		*backrest.Restore.Enabled && restoreID != restoreStatus {
+		restore := &batchv1.Job{ObjectMeta: naming.PGBackRestRestoreJob(cluster)}
+		existing, err := r.planExisting(ctx, restore, &batchv1.Job{})
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			changes = append(changes, v1beta1.PostgresClusterPlannedChange{
+				Kind: "Job", Name: restore.Name,
+				Action: v1beta1.PostgresClusterPlannedDelete,
+			})
+		}
+		for _, instance := range instances.forCluster {
+			if instance.Runner != nil {
+				changes = append(changes, v1beta1.PostgresClusterPlannedChange{
+					Kind: "StatefulSet", Name: instance.Name,
+					Action: v1beta1.PostgresClusterPlannedDelete,
+				})
+			}
+		}
+		changes = append(changes, v1beta1.PostgresClusterPlannedChange{
+			Kind: "Job", Name: restore.Name,
+			Action: v1beta1.PostgresClusterPlannedCreate,
+		})
+	}
+
+	return changes, nil
+}
+
+// reconcilePlan records in cluster.Status.Plan what applying the spec of
+// cluster would change. It reads from the Kubernetes API but writes nothing.
+func (r *Reconciler) reconcilePlan(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	pgHBAs postgres.HBAs, pgParameters postgres.Parameters,
+) error {
+	plan := &v1beta1.PostgresClusterPlanStatus{
+		ID:                 cluster.Annotations[naming.PlanChanges],
+		ObservedGeneration: cluster.Generation,
+	}
+
+	add := func(changes []v1beta1.PostgresClusterPlannedChange, err error) error {
+		plan.Changes = append(plan.Changes, changes...)
+		return err
+	}
+
+	instances, err := r.observeInstances(ctx, cluster)
+	if err == nil {
+		err = add(r.planConfigMaps(ctx, cluster, instances, pgHBAs, pgParameters))
+	}
+	if err == nil {
+		err = add(r.planServices(ctx, cluster))
+	}
+	if err == nil {
+		err = add(r.planInstances(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = add(r.planPGBouncer(ctx, cluster))
+	}
+	if err == nil {
+		err = add(r.planJobs(ctx, cluster, instances))
+	}
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		if plan.Changes[i].Kind != plan.Changes[j].Kind {
+			return plan.Changes[i].Kind < plan.Changes[j].Kind
+		}
+		return plan.Changes[i].Name < plan.Changes[j].Name
+	})
+
+	cluster.Status.Plan = plan
+	return nil
+}
@@ -0,0 +1,230 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/internal/util"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPlanFields(t *testing.T) {
+	decode := func(s string) interface{} {
+		var out interface{}
+		assert.NilError(t, json.Unmarshal([]byte(s), &out))
+		return out
+	}
+
+	for _, tt := range []struct {
+		intent, existing string
+		expected         []string
+	}{
+		{intent: `{}`, existing: `{"a":1}`},
+		{intent: `{"a":1}`, existing: `{"a":1,"b":2}`},
+		{intent: `{"a":1}`, existing: `{"a":2}`, expected: []string{"a"}},
+		{intent: `{"a":{"b":[]}}`, existing: `{}`},
+		{intent: `{"a":{"b":null}}`, existing: `{}`},
+		{intent: `{"a":{"b":"x"}}`, existing: `{}`, expected: []string{"a.b"}},
+		{intent: `{"a":[{"b":1}]}`, existing: `{"a":[{"b":1,"c":3}]}`},
+		{intent: `{"a":[{"b":1}]}`, existing: `{"a":[{"b":2}]}`, expected: []string{"a[0].b"}},
+		{intent: `{"a":[1,2]}`, existing: `{"a":[1]}`, expected: []string{"a"}},
+	} {
+		assert.DeepEqual(t,
+			planFields(nil, "", decode(tt.intent), decode(tt.existing)), tt.expected)
+	}
+}
+
+func TestPlanObject(t *testing.T) {
+	intent := &appsv1.StatefulSet{}
+	intent.Name = "some"
+	intent.Labels = map[string]string{"a": "b"}
+	intent.Spec.Template.Spec.Containers = []corev1.Container{{Name: "c", Image: "new"}}
+
+	change, err := planObject("StatefulSet", intent, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, change, &v1beta1.PostgresClusterPlannedChange{
+		Kind: "StatefulSet", Name: "some", Action: v1beta1.PostgresClusterPlannedCreate,
+	})
+
+	// Fields set by Kubernetes are not changes.
+	existing := intent.DeepCopy()
+	existing.UID = "uid"
+	existing.Labels["other"] = "value"
+	existing.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	existing.Status.Replicas = 1
+
+	change, err = planObject("StatefulSet", intent, existing)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil, "expected no change, got %#v", change)
+
+	existing.Labels["a"] = "c"
+	existing.Spec.Template.Spec.Containers[0].Image = "old"
+
+	change, err = planObject("StatefulSet", intent, existing)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, change, &v1beta1.PostgresClusterPlannedChange{
+		Kind: "StatefulSet", Name: "some", Action: v1beta1.PostgresClusterPlannedUpdate,
+		Fields: []string{
+			"metadata.labels.a",
+			"spec.template.spec.containers[0].image",
+		},
+		Rollout: true,
+	})
+}
+
+func TestReconcilePlan(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+	assert.NilError(t, util.AddAndSetFeatureGates(""))
+
+	cluster := testCluster()
+	cluster.Namespace = "ns1"
+	cluster.Annotations = map[string]string{naming.PlanChanges: "one"}
+	cluster.Spec.InstanceSets[0].Replicas = initialize.Int32(2)
+	cluster.Default()
+
+	// One instance exists but runs nothing yet.
+	existing := &appsv1.StatefulSet{}
+	existing.Namespace, existing.Name = "ns1", "hippo-instance1-abcd"
+	existing.Labels = map[string]string{
+		naming.LabelCluster:     "hippo",
+		naming.LabelInstanceSet: "instance1",
+		naming.LabelInstance:    "hippo-instance1-abcd",
+	}
+
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build(),
+	}
+
+	assert.NilError(t, reconciler.reconcilePlan(ctx, cluster,
+		postgres.NewHBAs(), postgres.NewParameters()))
+
+	plan := cluster.Status.Plan
+	assert.Equal(t, plan.ID, "one")
+
+	summary := make(map[string]string)
+	for _, change := range plan.Changes {
+		summary[change.Kind+"/"+change.Name] = change.Action
+		if change.Name == "hippo-instance1-abcd" {
+			assert.Assert(t, change.Rollout)
+		}
+	}
+	assert.DeepEqual(t, summary, map[string]string{
+		"ConfigMap/hippo-config":            "Create",
+		"ConfigMap/hippo-pgbackrest-config": "Create",
+		"Deployment/hippo-pgbouncer":        "Create",
+		"Service/hippo-ha":                  "Create",
+		"Service/hippo-pgbouncer":           "Create",
+		"Service/hippo-primary":             "Create",
+		"Service/hippo-replicas":            "Create",
+		"StatefulSet/hippo-instance1-*":     "Create",
+		"StatefulSet/hippo-instance1-abcd":  "Update",
+	})
+
+	// Nothing is written.
+	var configmaps corev1.ConfigMapList
+	assert.NilError(t, reconciler.Client.List(ctx, &configmaps, client.InNamespace("ns1")))
+	assert.Equal(t, len(configmaps.Items), 0)
+}
+
+func TestPlanJobs(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	cluster := testCluster()
+	cluster.Namespace = "ns1"
+	cluster.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{RepoName: "repo1"}
+	cluster.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+		Enabled: initialize.Bool(true),
+	}
+
+	// A manual backup Job finished for an earlier annotation.
+	finished := &batchv1.Job{}
+	finished.Namespace, finished.Name = "ns1", "hippo-backup-abcd"
+	finished.Annotations = map[string]string{naming.PGBackRestBackup: "before"}
+	finished.Labels = naming.PGBackRestBackupJobLabels("hippo", "repo1", naming.BackupManual)
+
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-instance1-abcd", Runner: &appsv1.StatefulSet{}},
+		{Name: "hippo-instance1-efgh"},
+	}}
+
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(finished).Build(),
+	}
+
+	t.Run("NoAnnotations", func(t *testing.T) {
+		changes, err := reconciler.planJobs(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, len(changes) == 0)
+	})
+
+	t.Run("Backup", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Annotations = map[string]string{naming.PGBackRestBackup: "after"}
+
+		changes, err := reconciler.planJobs(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, changes, []v1beta1.PostgresClusterPlannedChange{
+			{Kind: "Job", Name: "hippo-backup-abcd", Action: "Delete"},
+			{Kind: "Job", Name: "hippo-backup-*", Action: "Create"},
+		})
+
+		// Nothing changes once the backup has been taken.
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			ManualBackup: &v1beta1.PGBackRestJobStatus{ID: "after"},
+		}
+		changes, err = reconciler.planJobs(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, len(changes) == 0)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Annotations = map[string]string{naming.PGBackRestRestore: "one"}
+
+		changes, err := reconciler.planJobs(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, changes, []v1beta1.PostgresClusterPlannedChange{
+			{Kind: "StatefulSet", Name: "hippo-instance1-abcd", Action: "Delete"},
+			{Kind: "Job", Name: "hippo-pgbackrest-restore", Action: "Create"},
+		})
+
+		// Nothing changes once the restore has been requested.
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			Restore: &v1beta1.PGBackRestJobStatus{ID: "one"},
+		}
+		changes, err = reconciler.planJobs(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, len(changes) == 0)
+	})
+}
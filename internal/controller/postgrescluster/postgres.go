@@ -32,11 +32,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/adifri/postgres-operator/v5/internal/initialize"
 	"github.com/adifri/postgres-operator/v5/internal/logging"
 	"github.com/adifri/postgres-operator/v5/internal/naming"
 	"github.com/adifri/postgres-operator/v5/internal/pgaudit"
+	"github.com/adifri/postgres-operator/v5/internal/pki"
 	"github.com/adifri/postgres-operator/v5/internal/postgis"
 	"github.com/adifri/postgres-operator/v5/internal/postgres"
 	pgpassword "github.com/adifri/postgres-operator/v5/internal/postgres/password"
@@ -230,6 +232,33 @@ func (r *Reconciler) reconcilePostgresDatabases(
 		}
 	}
 
+	// Gather the list of tablespaces that should exist in PostgreSQL. Only
+	// the volumes mounted in the writable instance can hold new tablespaces.
+	// Replicas replay CREATE TABLESPACE, so a tablespace is created only when
+	// every instance set has its volume.
+
+	tablespaces := []string{}
+	var tablespacesMissing []string
+	for _, set := range cluster.Spec.InstanceSets {
+		if set.Name == pod.Labels[naming.LabelInstanceSet] {
+			for _, tablespace := range set.TablespaceVolumes {
+				if !tablespace.CreateTablespace {
+					continue
+				}
+				everywhere := true
+				for i := range cluster.Spec.InstanceSets {
+					everywhere = everywhere &&
+						tablespaceNames(&cluster.Spec.InstanceSets[i]).Has(tablespace.Name)
+				}
+				if everywhere {
+					tablespaces = append(tablespaces, tablespace.Name)
+				} else {
+					tablespacesMissing = append(tablespacesMissing, tablespace.Name)
+				}
+			}
+		}
+	}
+
 	// Calculate a hash of the SQL that should be executed in PostgreSQL.
 
 	var pgAuditOK, postgisInstallOK bool
@@ -255,7 +284,11 @@ func (r *Reconciler) reconcilePostgresDatabases(
 				"Unable to install PostGIS")
 		}
 
-		return postgres.CreateDatabasesInPostgreSQL(ctx, exec, databases.List())
+		err := postgres.CreateDatabasesInPostgreSQL(ctx, exec, databases.List())
+		if err == nil && len(tablespaces) > 0 {
+			err = postgres.CreateTablespacesInPostgreSQL(ctx, exec, tablespaces)
+		}
+		return err
 	}
 
 	revision, err := safeHash32(func(hasher io.Writer) error {
@@ -282,6 +315,10 @@ func (r *Reconciler) reconcilePostgresDatabases(
 	// Apply the necessary SQL and record its hash in cluster.Status. Include
 	// the hash in any log messages.
 
+	for _, name := range tablespacesMissing {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "TablespaceNotCreated",
+			"Tablespace %q is not created until every instance set has a volume for it", name)
+	}
 	if err == nil {
 		log := logging.FromContext(ctx).WithValues("revision", revision)
 		err = errors.WithStack(create(logging.NewContext(ctx, log), podExecutor))
@@ -297,8 +334,9 @@ func (r *Reconciler) reconcilePostgresDatabases(
 // passwords in PostgreSQL.
 func (r *Reconciler) reconcilePostgresUsers(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
-) error {
-	users, secrets, err := r.reconcilePostgresUserSecrets(ctx, cluster)
+	root *pki.RootCertificateAuthority,
+) (reconcile.Result, error) {
+	users, secrets, result, err := r.reconcilePostgresUserSecrets(ctx, cluster, root)
 	if err == nil {
 		err = r.reconcilePostgresUsersInPostgreSQL(ctx, cluster, instances, users, secrets)
 	}
@@ -309,7 +347,7 @@ func (r *Reconciler) reconcilePostgresUsers(
 		// are available here, too.
 		err = r.reconcilePGAdminUsers(ctx, cluster, users, secrets)
 	}
-	return err
+	return result, err
 }
 
 // +kubebuilder:rbac:groups="",resources="secrets",verbs={list}
@@ -318,11 +356,14 @@ func (r *Reconciler) reconcilePostgresUsers(
 // reconcilePostgresUserSecrets writes Secrets for the PostgreSQL users
 // specified in cluster and deletes existing Secrets that are not specified.
 // It returns the user specifications it acted on (because defaults) and the
-// Secrets it wrote.
+// Secrets it wrote. Users with certificates get them from root or the
+// issuer of cluster. Users with a credential store read or publish their
+// passwords there; the result says when to read the store again.
 func (r *Reconciler) reconcilePostgresUserSecrets(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	root *pki.RootCertificateAuthority,
 ) (
-	[]v1beta1.PostgresUserSpec, map[string]*corev1.Secret, error,
+	[]v1beta1.PostgresUserSpec, map[string]*corev1.Secret, reconcile.Result, error,
 ) {
 	// When users are unspecified, create one user matching the cluster name if
 	// it is also a valid user name.
@@ -403,7 +444,10 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 		}
 	}
 
-	// Reconcile each PostgreSQL user in the cluster spec.
+	// Reconcile each PostgreSQL user in the cluster spec. Users whose
+	// passwords come from the credential store are skipped until it has them.
+	var result reconcile.Result
+	skipped := sets.String{}
 	for userName, user := range userSpecs {
 		secret := userSecrets[userName]
 
@@ -413,15 +457,114 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 			secret = defaultSecret
 		}
 
+		existing := secret
+		if err == nil && credentialStoreMode(user) == v1beta1.PostgresCredentialStoreModeSource {
+			// Read the store again later for a missing or changed password.
+			result.RequeueAfter = credentialStorePollInterval
+
+			var ok bool
+			if existing, ok = r.sourcePostgresUserPassword(ctx, cluster, user, secret); !ok {
+				// Leave this user alone until the store has its password.
+				skipped.Insert(userName)
+				delete(userSecrets, userName)
+				continue
+			}
+		}
+
 		if err == nil {
-			userSecrets[userName], err = r.generatePostgresUserSecret(cluster, user, secret)
+			userSecrets[userName], err = r.generatePostgresUserSecret(cluster, user, existing)
+		}
+		if err == nil && user.Certificate != nil {
+			err = r.reconcilePostgresUserCertificate(ctx, cluster, root, user,
+				secret, userSecrets[userName])
 		}
 		if err == nil {
 			err = errors.WithStack(r.apply(ctx, userSecrets[userName]))
 		}
+		if err == nil && credentialStoreMode(user) == v1beta1.PostgresCredentialStoreModePublish {
+			r.publishPostgresUserCredentials(ctx, cluster, user, userSecrets[userName])
+		}
+	}
+
+	if skipped.Len() > 0 {
+		users := make([]v1beta1.PostgresUserSpec, 0, len(specUsers))
+		for i := range specUsers {
+			if !skipped.Has(string(specUsers[i].Name)) {
+				users = append(users, specUsers[i])
+			}
+		}
+		specUsers = users
 	}
 
-	return specUsers, userSecrets, err
+	return specUsers, userSecrets, result, err
+}
+
+// +kubebuilder:rbac:groups="cert-manager.io",resources="certificates",verbs={create,patch}
+
+// reconcilePostgresUserCertificate adds a client certificate for user to
+// intent, which is the Secret of user. The certificate comes from the issuer
+// of cluster when there is one. Otherwise, root signs it and the certificate
+// in existing is kept until it needs to be replaced.
+func (r *Reconciler) reconcilePostgresUserCertificate(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	root *pki.RootCertificateAuthority, user *v1beta1.PostgresUserSpec,
+	existing, intent *corev1.Secret,
+) error {
+	username := string(user.Name)
+
+	// PostgreSQL verifies client certificates using the certificate authority
+	// of its own certificate, and PGO cannot sign for a custom one.
+	if cluster.Spec.CustomTLSSecret != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidUser",
+			"Cannot issue a certificate for user %q when the cluster has a customTLSSecret", username)
+		return nil
+	}
+
+	var err error
+	var authority pki.CertificateBundle
+	var leaf *pki.LeafCertificate
+
+	if cluster.Spec.CertificateIssuer != nil {
+		var issued *issuedCertificate
+		issued, err = r.reconcileIssuedCertificate(ctx, cluster,
+			naming.PostgresUserIssuedCertificate(cluster, username),
+			username, nil, issuedClientUsages)
+		if err == nil {
+			authority = pki.CertificateBundle{issued.Authority}
+			leaf = issued.Leaf
+		}
+	} else {
+		signer := *root
+		signer.LeafLifetime, signer.LeafRenewBefore = userCertificateLifetimes(cluster, user)
+
+		// Unmarshal and validate the stored leaf. These first errors can
+		// be ignored because they result in an invalid leaf which is then
+		// correctly regenerated.
+		leaf = &pki.LeafCertificate{}
+		if existing != nil {
+			_ = leaf.Certificate.UnmarshalText(existing.Data[corev1.TLSCertKey])
+			_ = leaf.PrivateKey.UnmarshalText(existing.Data[corev1.TLSPrivateKeyKey])
+		}
+
+		// PostgreSQL compares the common name to the name of the user.
+		leaf, err = signer.RegenerateLeafWhenNecessary(leaf, username, nil)
+		err = errors.WithStack(err)
+		authority = root.TrustBundle()
+	}
+
+	if err == nil {
+		intent.Data[corev1.TLSCertKey], err = leaf.Certificate.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		intent.Data[corev1.TLSPrivateKeyKey], err = leaf.PrivateKey.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		intent.Data[rootCertFile], err = authority.MarshalText()
+		err = errors.WithStack(err)
+	}
+	return err
 }
 
 // reconcilePostgresUsersInPostgreSQL creates users inside of PostgreSQL and
@@ -552,7 +695,21 @@ func (r *Reconciler) reconcilePostgresDataVolume(
 		labelMap,
 	)
 
-	pvc.Spec = instanceSpec.DataVolumeClaimSpec
+	pvc.Spec = autoGrowRequest(instanceSpec.DataVolumeClaimSpec,
+		instanceSetStatus(cluster, instanceSpec.Name).DataVolumeSize)
+
+	// New replicas may start from a snapshot backup rather than an empty volume.
+	if existing := findVolume(clusterVolumes, existingPVCName); existing != nil {
+		// Leave a volume that is going to be replaced as it is.
+		if instanceSpec.VolumeChangePolicy == v1beta1.VolumeChangeReplace &&
+			volumeNeedsReplacement(existing, pvc.Spec) {
+			pvc.Spec = keepImmutableVolumeFields(pvc.Spec, existing)
+		}
+		pvc.Spec.DataSource = existing.Spec.DataSource
+	} else {
+		pvc.Spec.DataSource = volumeSnapshotSource(cluster, instanceSpec,
+			instance.Name, naming.RolePostgresData, clusterVolumes)
+	}
 
 	if err == nil {
 		err = r.handlePersistentVolumeClaimError(cluster,
@@ -655,7 +812,20 @@ func (r *Reconciler) reconcilePostgresWALVolume(
 		labelMap,
 	)
 
-	pvc.Spec = *instanceSpec.WALVolumeClaimSpec
+	pvc.Spec = autoGrowRequest(*instanceSpec.WALVolumeClaimSpec,
+		instanceSetStatus(cluster, instanceSpec.Name).WALVolumeSize)
+
+	if existing := findVolume(clusterVolumes, existingPVCName); existing != nil {
+		// Leave a volume that is going to be replaced as it is.
+		if instanceSpec.VolumeChangePolicy == v1beta1.VolumeChangeReplace &&
+			volumeNeedsReplacement(existing, pvc.Spec) {
+			pvc.Spec = keepImmutableVolumeFields(pvc.Spec, existing)
+		}
+		pvc.Spec.DataSource = existing.Spec.DataSource
+	} else {
+		pvc.Spec.DataSource = volumeSnapshotSource(cluster, instanceSpec,
+			instance.Name, naming.RolePostgresWAL, clusterVolumes)
+	}
 
 	if err == nil {
 		err = r.handlePersistentVolumeClaimError(cluster,
@@ -665,6 +835,93 @@ func (r *Reconciler) reconcilePostgresWALVolume(
 	return pvc, err
 }
 
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=create;patch
+
+// tablespaceNames returns the names of the tablespace volumes of set.
+func tablespaceNames(set *v1beta1.PostgresInstanceSetSpec) sets.String {
+	names := sets.NewString()
+	for _, tablespace := range set.TablespaceVolumes {
+		names.Insert(tablespace.Name)
+	}
+	return names
+}
+
+// reconcilePostgresTablespaceVolumes writes the PersistentVolumeClaims for
+// instance's PostgreSQL tablespace volumes. It returns them by tablespace name.
+// Volumes that are removed from the spec are left in place because they may
+// still hold tablespace files.
+func (r *Reconciler) reconcilePostgresTablespaceVolumes(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instanceSpec *v1beta1.PostgresInstanceSetSpec, instance *appsv1.StatefulSet,
+	clusterVolumes []corev1.PersistentVolumeClaim,
+) (map[string]*corev1.PersistentVolumeClaim, error) {
+	volumes := make(map[string]*corev1.PersistentVolumeClaim)
+
+	for _, tablespace := range instanceSpec.TablespaceVolumes {
+		labelMap := map[string]string{
+			naming.LabelCluster:     cluster.Name,
+			naming.LabelInstanceSet: instanceSpec.Name,
+			naming.LabelInstance:    instance.Name,
+			naming.LabelRole:        naming.RolePostgresTablespace,
+			naming.LabelData:        naming.DataPostgres,
+			naming.LabelTablespace:  tablespace.Name,
+		}
+
+		var pvc *corev1.PersistentVolumeClaim
+		existingPVCName, err := getPGPVCName(labelMap, clusterVolumes)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if existingPVCName != "" {
+			pvc = &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Namespace: cluster.GetNamespace(),
+				Name:      existingPVCName,
+			}}
+		} else {
+			pvc = &corev1.PersistentVolumeClaim{
+				ObjectMeta: naming.InstancePostgresTablespaceVolume(instance, tablespace.Name),
+			}
+		}
+
+		pvc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
+
+		err = errors.WithStack(r.setControllerReference(cluster, pvc))
+
+		pvc.Annotations = naming.Merge(
+			cluster.Spec.Metadata.GetAnnotationsOrNil(),
+			instanceSpec.Metadata.GetAnnotationsOrNil())
+
+		pvc.Labels = naming.Merge(
+			cluster.Spec.Metadata.GetLabelsOrNil(),
+			instanceSpec.Metadata.GetLabelsOrNil(),
+			labelMap,
+		)
+
+		pvc.Spec = tablespace.DataVolumeClaimSpec
+
+		if existing := findVolume(clusterVolumes, existingPVCName); existing != nil {
+			// Leave a volume that is going to be replaced as it is.
+			if instanceSpec.VolumeChangePolicy == v1beta1.VolumeChangeReplace &&
+				volumeNeedsReplacement(existing, pvc.Spec) {
+				pvc.Spec = keepImmutableVolumeFields(pvc.Spec, existing)
+			}
+			pvc.Spec.DataSource = existing.Spec.DataSource
+		}
+
+		if err == nil {
+			err = r.handlePersistentVolumeClaimError(cluster,
+				errors.WithStack(r.apply(ctx, pvc)))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		volumes[tablespace.Name] = pvc
+	}
+
+	return volumes, nil
+}
+
 // reconcileDatabaseInitSQL runs custom SQL files in the database. When
 // DatabaseInitSQL is defined, the function will find the primary pod and run
 // SQL from the defined ConfigMap
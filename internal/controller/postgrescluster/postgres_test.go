@@ -21,6 +21,7 @@ package postgrescluster
 import (
 	"context"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -30,6 +31,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
@@ -287,6 +289,43 @@ volumeMode: Filesystem
 		`))
 	})
 
+	t.Run("TablespaceVolumes", func(t *testing.T) {
+		spec := spec.DeepCopy()
+		assert.NilError(t, yaml.Unmarshal([]byte(`{
+			tablespaceVolumes: [{
+				name: fast,
+				dataVolumeClaimSpec: {
+					accessModes: [ReadWriteOnce],
+					resources: { requests: { storage: 2Gi } },
+					storageClassName: "storage-class-for-tablespace",
+				},
+			}],
+		}`), spec))
+
+		pvcs, err := reconciler.reconcilePostgresTablespaceVolumes(ctx, cluster, spec, instance, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, len(pvcs), 1)
+
+		pvc := pvcs["fast"]
+		assert.Assert(t, metav1.IsControlledBy(pvc, cluster))
+
+		assert.Equal(t, pvc.Labels[naming.LabelCluster], cluster.Name)
+		assert.Equal(t, pvc.Labels[naming.LabelInstance], instance.Name)
+		assert.Equal(t, pvc.Labels[naming.LabelInstanceSet], spec.Name)
+		assert.Equal(t, pvc.Labels[naming.LabelRole], "pgtablespace")
+		assert.Equal(t, pvc.Labels[naming.LabelTablespace], "fast")
+
+		assert.Assert(t, marshalMatches(pvc.Spec, `
+accessModes:
+- ReadWriteOnce
+resources:
+  requests:
+    storage: 2Gi
+storageClassName: storage-class-for-tablespace
+volumeMode: Filesystem
+		`))
+	})
+
 	t.Run("WALVolume", func(t *testing.T) {
 		observed := &Instance{}
 
@@ -661,3 +700,58 @@ func TestReconcileDatabaseInitSQLConfigMap(t *testing.T) {
 		assert.Assert(t, called)
 	})
 }
+
+func TestReconcilePostgresDatabasesTablespaces(t *testing.T) {
+	ctx := context.Background()
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-one-abcd-0"
+	pod.Labels = map[string]string{naming.LabelInstanceSet: "one"}
+	pod.Annotations = map[string]string{"status": `{"role":"master"}`}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-one-abcd", Pods: []*corev1.Pod{pod}},
+	}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "one", TablespaceVolumes: []v1beta1.TablespaceVolume{
+			{Name: "fast", CreateTablespace: true},
+			{Name: "slow", CreateTablespace: true},
+		}},
+		{Name: "two", TablespaceVolumes: []v1beta1.TablespaceVolume{
+			{Name: "fast"},
+		}},
+	}
+
+	var sql []string
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{Recorder: recorder}
+	reconciler.PodExec = func(
+		_, _, _ string, stdin io.Reader, _, _ io.Writer, _ ...string,
+	) error {
+		b, err := io.ReadAll(stdin)
+		sql = append(sql, string(b))
+		return err
+	}
+
+	assert.NilError(t, reconciler.reconcilePostgresDatabases(ctx, cluster, instances))
+
+	// Only the tablespace with a volume in every instance set is created.
+	all := strings.Join(sql, "\n")
+	assert.Assert(t, cmp.Contains(all, `"tablespace":"fast"`))
+	assert.Assert(t, !strings.Contains(all, `"tablespace":"slow"`))
+
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	assert.Equal(t, len(events), 1)
+	assert.Assert(t, cmp.Contains(events[0], "TablespaceNotCreated"))
+	assert.Assert(t, cmp.Contains(events[0], `"slow"`))
+}
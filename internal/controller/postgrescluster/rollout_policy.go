@@ -0,0 +1,258 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// rolloutMaxUnavailable returns how many instances of cluster can be
+// unavailable while they are redeployed.
+func rolloutMaxUnavailable(cluster *v1beta1.PostgresCluster) int {
+	if policy := cluster.Spec.RolloutPolicy; policy != nil && policy.MaxUnavailable != nil {
+		return int(*policy.MaxUnavailable)
+	}
+	return 1
+}
+
+// instanceSetExists returns true when cluster has an instance set named name.
+func instanceSetExists(cluster *v1beta1.PostgresCluster, name string) bool {
+	for _, set := range cluster.Spec.InstanceSets {
+		if set.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloutSteps returns the names of instance sets in the order they should be
+// updated according to the rollout policy of cluster. Every set appears in
+// exactly one step.
+func rolloutSteps(cluster *v1beta1.PostgresCluster) [][]string {
+	policy := cluster.Spec.RolloutPolicy
+	if policy == nil {
+		policy = &v1beta1.PostgresRolloutPolicySpec{}
+	}
+
+	var steps [][]string
+	var rest []string
+	for _, set := range cluster.Spec.InstanceSets {
+		if set.Name == policy.Canary {
+			steps = append([][]string{{set.Name}}, steps...)
+		} else if policy.Partition == v1beta1.PostgresRolloutPartitionInstanceSet {
+			steps = append(steps, []string{set.Name})
+		} else {
+			rest = append(rest, set.Name)
+		}
+	}
+	if len(rest) > 0 {
+		steps = append(steps, rest)
+	}
+	return steps
+}
+
+// rolloutAllows returns true when instance may be redeployed according to
+// the rollout policy of cluster.
+func rolloutAllows(cluster *v1beta1.PostgresCluster, instance *Instance) bool {
+	status := cluster.Status.Rollout
+	if cluster.Spec.RolloutPolicy == nil || status == nil || instance.Spec == nil {
+		return true
+	}
+	if status.Phase != v1beta1.PostgresRolloutProgressing {
+		return false
+	}
+	for _, name := range status.InstanceSets {
+		if name == instance.Spec.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileRolloutPolicy decides which instance sets of cluster may be
+// redeployed according to its rollout policy. It records that decision in
+// cluster.Status.Rollout for rolloutInstances to follow.
+func (r *Reconciler) reconcileRolloutPolicy(
+	_ context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	policy := cluster.Spec.RolloutPolicy
+	if policy == nil {
+		cluster.Status.Rollout = nil
+
+		// Avoid a panic! Fixed in Kubernetes v1.21.0 and controller-runtime v0.9.0-alpha.0.
+		// - https://issue.k8s.io/99714
+		if len(cluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.RolloutPolicyValid)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// A canary that is not an instance set is ignored. Report it rather than
+	// update every set without one.
+	condition := metav1.Condition{
+		Type:    v1beta1.RolloutPolicyValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PolicyValid",
+		Message: "The rollout policy refers to existing instance sets.",
+
+		ObservedGeneration: cluster.Generation,
+	}
+	if policy.Canary != "" && !instanceSetExists(cluster, policy.Canary) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CanaryNotFound"
+		condition.Message = fmt.Sprintf(
+			"Canary %q is not an instance set and is ignored.", policy.Canary)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+
+	// Find the instances of each set that need to be redeployed or are not
+	// available.
+	pending := make(map[string][]string)
+	unavailable := make(map[string][]string)
+	var anyPending bool
+
+	for _, instance := range instances.forCluster {
+		if instance.Spec == nil {
+			continue
+		}
+		set := instance.Spec.Name
+
+		if matches, known := instance.PodMatchesPodTemplate(); known && !matches {
+			pending[set] = append(pending[set], instance.Name)
+			anyPending = true
+		}
+		if available, known := instance.IsAvailable(); !known || !available {
+			unavailable[set] = append(unavailable[set], instance.Name)
+		}
+	}
+
+	status := cluster.Status.Rollout
+	if status == nil {
+		status = &v1beta1.PostgresRolloutStatus{}
+		cluster.Status.Rollout = status
+	}
+
+	now := metav1.Now()
+	steps := rolloutSteps(cluster)
+
+	// Indicate that nothing is rolling out.
+	if !anyPending {
+		if status.Phase != "" && status.Phase != v1beta1.PostgresRolloutComplete {
+			status.Phase = v1beta1.PostgresRolloutComplete
+			status.Message = ""
+			status.CompletionTime = &now
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// The current step is the first that has instances to redeploy or
+	// instances that are not available.
+	stepIndex := func(sets []string) int {
+		for i := range steps {
+			if strings.Join(steps[i], ",") == strings.Join(sets, ",") {
+				return i
+			}
+		}
+		return -1
+	}
+	current := -1
+	for i := range steps {
+		for _, set := range steps[i] {
+			if len(pending[set]) > 0 || len(unavailable[set]) > 0 {
+				current = i
+				break
+			}
+		}
+		if current >= 0 {
+			break
+		}
+	}
+
+	previous := stepIndex(status.InstanceSets)
+
+	// The step before this one has completed. The next one waits for the
+	// pause and approval, if any.
+	if previous >= 0 && previous < current && status.Phase != v1beta1.PostgresRolloutComplete {
+		if status.CompletionTime == nil || status.Phase == v1beta1.PostgresRolloutProgressing {
+			status.CompletionTime = &now
+		}
+		next := strings.Join(steps[current], ", ")
+
+		if policy.PauseSeconds != nil {
+			pause := time.Duration(*policy.PauseSeconds) * time.Second
+			if remaining := status.CompletionTime.Add(pause).Sub(now.Time); remaining > 0 {
+				status.Phase = v1beta1.PostgresRolloutPaused
+				status.Message = fmt.Sprintf("Waiting until %s to update %s",
+					status.CompletionTime.Add(pause).UTC().Format(time.RFC3339), next)
+				return reconcile.Result{RequeueAfter: remaining}, nil
+			}
+		}
+
+		if policy.RequireApproval {
+			approval := cluster.Annotations[naming.RolloutApprove]
+			if approval == "" || approval == status.Approved {
+				if status.Phase != v1beta1.PostgresRolloutAwaitingApproval {
+					r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "RolloutAwaitingApproval",
+						"Waiting for approval to update %s", next)
+				}
+				status.Phase = v1beta1.PostgresRolloutAwaitingApproval
+				status.Message = fmt.Sprintf("Set the %q annotation to a new value to update %s",
+					naming.RolloutApprove, next)
+				return reconcile.Result{}, nil
+			}
+			status.Approved = approval
+		}
+	}
+
+	if previous != current || status.Phase != v1beta1.PostgresRolloutProgressing {
+		status.InstanceSets = append([]string(nil), steps[current]...)
+		status.StartTime = &now
+		status.CompletionTime = nil
+
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "RolloutStep",
+			"Updating instance sets %s", strings.Join(steps[current], ", "))
+	}
+	status.Phase = v1beta1.PostgresRolloutProgressing
+	status.Message = ""
+
+	// Later steps do not start while an instance of this one is not
+	// available.
+	var waiting []string
+	for _, set := range steps[current] {
+		if len(pending[set]) == 0 {
+			waiting = append(waiting, unavailable[set]...)
+		}
+	}
+	if len(waiting) > 0 {
+		sort.Strings(waiting)
+		status.Message = fmt.Sprintf("Waiting for instances to be available: %s",
+			strings.Join(waiting, ", "))
+	}
+
+	return reconcile.Result{}, nil
+}
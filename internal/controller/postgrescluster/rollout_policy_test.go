@@ -0,0 +1,254 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestRolloutSteps(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "a"}, {Name: "b"}, {Name: "c"},
+	}
+
+	assert.DeepEqual(t, rolloutSteps(cluster), [][]string{{"a", "b", "c"}})
+
+	cluster.Spec.RolloutPolicy = &v1beta1.PostgresRolloutPolicySpec{Canary: "b"}
+	assert.DeepEqual(t, rolloutSteps(cluster), [][]string{{"b"}, {"a", "c"}})
+
+	cluster.Spec.RolloutPolicy.Partition = v1beta1.PostgresRolloutPartitionInstanceSet
+	assert.DeepEqual(t, rolloutSteps(cluster), [][]string{{"b"}, {"a"}, {"c"}})
+
+	cluster.Spec.RolloutPolicy.Canary = "missing"
+	assert.DeepEqual(t, rolloutSteps(cluster), [][]string{{"a"}, {"b"}, {"c"}})
+}
+
+// rolloutPolicyInstance returns an instance of the set at index of cluster.
+// Its pod runs the latest template when updated is true, and it is available
+// when available is true.
+func rolloutPolicyInstance(
+	cluster *v1beta1.PostgresCluster, index int, updated, available bool,
+) *Instance {
+	set := &cluster.Spec.InstanceSets[index]
+	instance := testInstance(set.Name+"-abcd", false, available)
+	instance.Spec = set
+
+	instance.Pods[0].Labels[appsv1.StatefulSetRevisionLabel] = "old"
+	if updated {
+		instance.Pods[0].Labels[appsv1.StatefulSetRevisionLabel] = "new"
+	}
+	instance.Runner = &appsv1.StatefulSet{}
+	instance.Runner.Status.UpdateRevision = "new"
+	return instance
+}
+
+func TestReconcileRolloutPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	base := &v1beta1.PostgresCluster{}
+	base.Namespace, base.Name = "ns1", "hippo"
+	base.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "canary", Replicas: initialize.Int32(1)},
+		{Name: "main", Replicas: initialize.Int32(1)},
+	}
+	base.Spec.RolloutPolicy = &v1beta1.PostgresRolloutPolicySpec{Canary: "canary"}
+
+	t.Run("Unset", func(t *testing.T) {
+		reconciler := &Reconciler{}
+		cluster := base.DeepCopy()
+		cluster.Spec.RolloutPolicy = nil
+		cluster.Status.Rollout = &v1beta1.PostgresRolloutStatus{}
+
+		cluster.Status.Conditions = []metav1.Condition{{Type: "RolloutPolicyValid"}}
+
+		_, err := reconciler.reconcileRolloutPolicy(ctx, cluster, &observedInstances{})
+		assert.NilError(t, err)
+		assert.Assert(t, cluster.Status.Rollout == nil)
+		assert.Assert(t, len(cluster.Status.Conditions) == 0)
+	})
+
+	t.Run("CanaryNotFound", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder}
+		cluster := base.DeepCopy()
+
+		_, err := reconciler.reconcileRolloutPolicy(ctx, cluster, &observedInstances{})
+		assert.NilError(t, err)
+		assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, "RolloutPolicyValid"))
+
+		// A canary that is not an instance set is reported.
+		cluster.Spec.RolloutPolicy.Canary = "missing"
+		_, err = reconciler.reconcileRolloutPolicy(ctx, cluster, &observedInstances{})
+		assert.NilError(t, err)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, "RolloutPolicyValid")
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "CanaryNotFound")
+		assert.Assert(t, strings.Contains(condition.Message, `"missing"`), condition.Message)
+	})
+
+	t.Run("Canary", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder}
+		cluster := base.DeepCopy()
+
+		canary := rolloutPolicyInstance(cluster, 0, false, true)
+		main := rolloutPolicyInstance(cluster, 1, false, true)
+		observed := &observedInstances{forCluster: []*Instance{canary, main}}
+
+		_, err := reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.Rollout.Phase, v1beta1.PostgresRolloutProgressing)
+		assert.DeepEqual(t, cluster.Status.Rollout.InstanceSets, []string{"canary"})
+		assert.Assert(t, rolloutAllows(cluster, canary))
+		assert.Assert(t, !rolloutAllows(cluster, main))
+		assert.DeepEqual(t, drainEvents(recorder), []string{"RolloutStep"})
+
+		// The canary is updated but not available; the rollout stops there.
+		canary = rolloutPolicyInstance(cluster, 0, true, false)
+		observed.forCluster[0] = canary
+
+		_, err = reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cluster.Status.Rollout.InstanceSets, []string{"canary"})
+		assert.Equal(t, cluster.Status.Rollout.Message,
+			"Waiting for instances to be available: canary-abcd")
+		assert.Assert(t, !rolloutAllows(cluster, main))
+
+		// The canary is healthy; the next step starts.
+		canary = rolloutPolicyInstance(cluster, 0, true, true)
+		observed.forCluster[0] = canary
+
+		_, err = reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cluster.Status.Rollout.InstanceSets, []string{"main"})
+		assert.Assert(t, rolloutAllows(cluster, main))
+
+		// Everything is updated.
+		observed.forCluster[1] = rolloutPolicyInstance(cluster, 1, true, true)
+
+		_, err = reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.Rollout.Phase, v1beta1.PostgresRolloutComplete)
+		assert.Assert(t, cluster.Status.Rollout.CompletionTime != nil)
+	})
+
+	t.Run("Pause", func(t *testing.T) {
+		reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+		cluster := base.DeepCopy()
+		cluster.Spec.RolloutPolicy.PauseSeconds = initialize.Int32(60)
+		cluster.Status.Rollout = &v1beta1.PostgresRolloutStatus{
+			InstanceSets: []string{"canary"},
+			Phase:        v1beta1.PostgresRolloutProgressing,
+		}
+
+		main := rolloutPolicyInstance(cluster, 1, false, true)
+		observed := &observedInstances{forCluster: []*Instance{
+			rolloutPolicyInstance(cluster, 0, true, true), main,
+		}}
+
+		result, err := reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Minute)
+		assert.Equal(t, cluster.Status.Rollout.Phase, v1beta1.PostgresRolloutPaused)
+		assert.Assert(t, !rolloutAllows(cluster, main))
+
+		// The pause has passed.
+		cluster.Status.Rollout.CompletionTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+		result, err = reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, time.Duration(0))
+		assert.Equal(t, cluster.Status.Rollout.Phase, v1beta1.PostgresRolloutProgressing)
+		assert.Assert(t, rolloutAllows(cluster, main))
+	})
+
+	t.Run("Approval", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Recorder: recorder}
+		cluster := base.DeepCopy()
+		cluster.Annotations = map[string]string{naming.RolloutApprove: "before"}
+		cluster.Spec.RolloutPolicy.RequireApproval = true
+		cluster.Status.Rollout = &v1beta1.PostgresRolloutStatus{
+			InstanceSets: []string{"canary"},
+			Phase:        v1beta1.PostgresRolloutProgressing,
+			Approved:     "before",
+		}
+
+		main := rolloutPolicyInstance(cluster, 1, false, true)
+		observed := &observedInstances{forCluster: []*Instance{
+			rolloutPolicyInstance(cluster, 0, true, true), main,
+		}}
+
+		// A value that was used already does not approve.
+		_, err := reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.Rollout.Phase, v1beta1.PostgresRolloutAwaitingApproval)
+		assert.Assert(t, !rolloutAllows(cluster, main))
+		assert.DeepEqual(t, drainEvents(recorder), []string{"RolloutAwaitingApproval"})
+
+		cluster.Annotations[naming.RolloutApprove] = "after"
+
+		_, err = reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.Rollout.Phase, v1beta1.PostgresRolloutProgressing)
+		assert.Equal(t, cluster.Status.Rollout.Approved, "after")
+		assert.DeepEqual(t, cluster.Status.Rollout.InstanceSets, []string{"main"})
+		assert.Assert(t, rolloutAllows(cluster, main))
+	})
+
+	t.Run("RolloutInstances", func(t *testing.T) {
+		reconciler := &Reconciler{
+			Recorder: record.NewFakeRecorder(10),
+			Tracer:   otel.Tracer(t.Name()),
+		}
+		cluster := base.DeepCopy()
+		cluster.Spec.RolloutPolicy.MaxUnavailable = initialize.Int32(2)
+
+		observed := &observedInstances{forCluster: []*Instance{
+			rolloutPolicyInstance(cluster, 0, false, true),
+			rolloutPolicyInstance(cluster, 1, false, true),
+		}}
+
+		_, err := reconciler.reconcileRolloutPolicy(ctx, cluster, observed)
+		assert.NilError(t, err)
+
+		// Only the canary is redeployed, though two are allowed.
+		var redeployed []string
+		assert.NilError(t, reconciler.rolloutInstances(ctx, cluster, observed,
+			func(_ context.Context, instance *Instance) error {
+				redeployed = append(redeployed, instance.Name)
+				return nil
+			}))
+		assert.DeepEqual(t, redeployed, []string{"canary-abcd"})
+	})
+}
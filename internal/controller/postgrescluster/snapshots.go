@@ -0,0 +1,822 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// volumeSnapshotGVK identifies the CSI VolumeSnapshot API. Its Go types are
+// not a dependency of this module, so VolumeSnapshots are unstructured.
+// - https://docs.k8s.io/concepts/storage/volume-snapshots/
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot",
+}
+
+const (
+	// volumeSnapshotCutTimeout is how long PostgreSQL stays in backup mode
+	// while waiting for storage to cut the snapshots of a backup.
+	volumeSnapshotCutTimeout = 5 * time.Minute
+
+	// volumeSnapshotRequeue is how often to check on snapshots that are not
+	// ready to use and on instances restored from snapshots.
+	volumeSnapshotRequeue = 10 * time.Second
+)
+
+// volumeSnapshotPollInterval is how often to check whether storage has cut
+// the snapshots of a backup. It is a variable so tests can shorten it.
+var volumeSnapshotPollInterval = time.Second
+
+// volumeSnapshotSession is a "psql" session that holds PostgreSQL in backup
+// mode while storage cuts the VolumeSnapshots of backup. Closing its input
+// ends the session which aborts the backup, if necessary.
+type volumeSnapshotSession struct {
+	backup  *volumeSnapshotBackup
+	marker  string
+	started time.Time
+	stop    string
+
+	input    *io.PipeWriter
+	lines    *bufio.Scanner
+	stderr   *bytes.Buffer
+	finished chan error
+	timer    *time.Timer
+}
+
+// volumeSnapshotSession returns the session of the backup in progress for
+// cluster, if any.
+func (r *Reconciler) volumeSnapshotSession(
+	cluster *v1beta1.PostgresCluster,
+) *volumeSnapshotSession {
+	r.volumeSnapshotMutex.Lock()
+	defer r.volumeSnapshotMutex.Unlock()
+	return r.volumeSnapshotSessions[cluster.UID]
+}
+
+// setVolumeSnapshotSession records session as the backup in progress for
+// cluster. A nil session removes the record.
+func (r *Reconciler) setVolumeSnapshotSession(
+	cluster *v1beta1.PostgresCluster, session *volumeSnapshotSession,
+) {
+	r.volumeSnapshotMutex.Lock()
+	defer r.volumeSnapshotMutex.Unlock()
+
+	if session == nil {
+		delete(r.volumeSnapshotSessions, cluster.UID)
+		return
+	}
+	if r.volumeSnapshotSessions == nil {
+		r.volumeSnapshotSessions = make(map[types.UID]*volumeSnapshotSession)
+	}
+	r.volumeSnapshotSessions[cluster.UID] = session
+}
+
+// volumeSnapshotBackup is one snapshot backup and its VolumeSnapshots.
+type volumeSnapshotBackup struct {
+	name      string
+	data, wal *unstructured.Unstructured
+}
+
+// complete returns whether or not PostgreSQL finished the backup. Only
+// complete backups can restore volumes.
+func (b *volumeSnapshotBackup) complete() bool {
+	return b.data != nil && b.label() != ""
+}
+
+// label returns the "backup_label" file of the backup, if it is complete.
+func (b *volumeSnapshotBackup) label() string {
+	return b.data.GetAnnotations()[naming.VolumeSnapshotBackupLabel]
+}
+
+// readyToUse returns whether or not every VolumeSnapshot of the backup can
+// provision volumes.
+func (b *volumeSnapshotBackup) readyToUse() bool {
+	ready := func(snapshot *unstructured.Unstructured) bool {
+		value, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+		return value
+	}
+	return b.complete() && ready(b.data) && (b.wal == nil || ready(b.wal))
+}
+
+// startTime returns when PostgreSQL started the backup.
+func (b *volumeSnapshotBackup) startTime() time.Time {
+	var started time.Time
+	if b.data != nil {
+		started, _ = time.Parse(time.RFC3339, b.data.GetAnnotations()[naming.VolumeSnapshotStartTime])
+	}
+	return started
+}
+
+// status returns the status of a complete backup.
+func (b *volumeSnapshotBackup) status() v1beta1.VolumeSnapshotBackupStatus {
+	status := v1beta1.VolumeSnapshotBackupStatus{
+		Name:               b.name,
+		DataVolumeSnapshot: b.data.GetName(),
+		InstanceSet:        b.data.GetLabels()[naming.LabelInstanceSet],
+		StartTime:          metav1.NewTime(b.startTime()),
+		ReadyToUse:         b.readyToUse(),
+	}
+	if b.wal != nil {
+		status.WALVolumeSnapshot = b.wal.GetName()
+	}
+	return status
+}
+
+// snapshots returns the VolumeSnapshots of the backup.
+func (b *volumeSnapshotBackup) snapshots() []*unstructured.Unstructured {
+	var snapshots []*unstructured.Unstructured
+	for _, snapshot := range []*unstructured.Unstructured{b.data, b.wal} {
+		if snapshot != nil {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots
+}
+
+// +kubebuilder:rbac:groups="snapshot.storage.k8s.io",resources="volumesnapshots",verbs={get,list,watch}
+
+// observeVolumeSnapshotBackups returns the snapshot backups of cluster,
+// newest first.
+func (r *Reconciler) observeVolumeSnapshotBackups(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) ([]*volumeSnapshotBackup, error) {
+	selector, err := naming.AsSelector(naming.ClusterVolumeSnapshots(cluster.Name))
+
+	snapshots := &unstructured.UnstructuredList{}
+	snapshots.SetGroupVersionKind(volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"))
+	if err == nil {
+		err = errors.WithStack(r.Client.List(ctx, snapshots,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabelsSelector{Selector: selector},
+		))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*volumeSnapshotBackup)
+	backups := []*volumeSnapshotBackup{}
+	for i := range snapshots.Items {
+		snapshot := &snapshots.Items[i]
+		name := snapshot.GetLabels()[naming.LabelVolumeSnapshot]
+
+		backup := byName[name]
+		if backup == nil {
+			backup = &volumeSnapshotBackup{name: name}
+			byName[name] = backup
+			backups = append(backups, backup)
+		}
+
+		switch snapshot.GetLabels()[naming.LabelRole] {
+		case naming.RolePostgresData:
+			backup.data = snapshot
+		case naming.RolePostgresWAL:
+			backup.wal = snapshot
+		}
+	}
+
+	// Backup names are timestamps, so sort by name when times are equal.
+	sort.Slice(backups, func(i, j int) bool {
+		a, b := backups[i].startTime(), backups[j].startTime()
+		if a.Equal(b) {
+			return backups[i].name > backups[j].name
+		}
+		return a.After(b)
+	})
+
+	return backups, nil
+}
+
+// volumeSnapshotSource returns the VolumeSnapshot from which to provision a
+// new volume with role for instance in set. Data and WAL volumes of an
+// instance come from the same backup. It returns nil when the volume should
+// be empty.
+func volumeSnapshotSource(
+	cluster *v1beta1.PostgresCluster, set *v1beta1.PostgresInstanceSetSpec,
+	instance, role string, clusterVolumes []corev1.PersistentVolumeClaim,
+) *corev1.TypedLocalObjectReference {
+	spec := cluster.Spec.Backups.Snapshots
+
+	// Only replicas are provisioned from snapshots. A cluster is bootstrapped
+	// once Patroni reports its system identifier.
+	if spec == nil || (spec.BootstrapReplicas != nil && !*spec.BootstrapReplicas) ||
+		cluster.Status.Patroni.SystemIdentifier == "" {
+		return nil
+	}
+
+	// Snapshot backups do not include tablespace volumes.
+	if len(set.TablespaceVolumes) > 0 {
+		return nil
+	}
+
+	var backup *v1beta1.VolumeSnapshotBackupStatus
+	var existingData bool
+
+	// When the data volume of instance already exists, use its backup.
+	for i := range clusterVolumes {
+		labels := clusterVolumes[i].Labels
+		if labels[naming.LabelInstance] != instance ||
+			labels[naming.LabelRole] != naming.RolePostgresData {
+			continue
+		}
+		existingData = true
+
+		if source := clusterVolumes[i].Spec.DataSource; source != nil &&
+			source.Kind == volumeSnapshotGVK.Kind {
+			for j := range cluster.Status.VolumeSnapshots {
+				if cluster.Status.VolumeSnapshots[j].DataVolumeSnapshot == source.Name {
+					backup = &cluster.Status.VolumeSnapshots[j]
+				}
+			}
+		}
+	}
+
+	// Otherwise, use the newest backup that is ready and compatible. A backup
+	// without a WAL volume restores into a set with one, but not the reverse.
+	if !existingData {
+		for i := range cluster.Status.VolumeSnapshots {
+			candidate := &cluster.Status.VolumeSnapshots[i]
+			if candidate.ReadyToUse &&
+				(candidate.WALVolumeSnapshot == "" || set.WALVolumeClaimSpec != nil) {
+				backup = candidate
+				break
+			}
+		}
+	}
+
+	if backup == nil {
+		return nil
+	}
+
+	name := backup.DataVolumeSnapshot
+	if role == naming.RolePostgresWAL {
+		name = backup.WALVolumeSnapshot
+	}
+	if name == "" {
+		return nil
+	}
+
+	return &corev1.TypedLocalObjectReference{
+		APIGroup: initialize.String(volumeSnapshotGVK.Group),
+		Kind:     volumeSnapshotGVK.Kind,
+		Name:     name,
+	}
+}
+
+// +kubebuilder:rbac:groups="snapshot.storage.k8s.io",resources="volumesnapshots",verbs={create,delete,patch}
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileVolumeSnapshots takes, prunes, and restores snapshot backups of
+// PostgreSQL volumes according to the spec of cluster, and records the
+// complete backups in its status.
+func (r *Reconciler) reconcileVolumeSnapshots(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, clusterVolumes []corev1.PersistentVolumeClaim,
+) (reconcile.Result, error) {
+	spec := cluster.Spec.Backups.Snapshots
+	session := r.volumeSnapshotSession(cluster)
+	if spec == nil {
+		if session != nil {
+			r.abortVolumeSnapshotBackup(cluster, session)
+		}
+		cluster.Status.VolumeSnapshots = nil
+		if len(cluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions,
+				v1beta1.VolumeSnapshotBackupProgressing)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	log := logging.FromContext(ctx)
+	result := reconcile.Result{}
+
+	backups, err := r.observeVolumeSnapshotBackups(ctx, cluster)
+	if err != nil {
+		return result, err
+	}
+
+	// Backups are held open by a session of this process, so any that are
+	// incomplete without one were interrupted.
+	var inProgress bool
+	complete := backups[:0]
+	for _, backup := range backups {
+		if backup.complete() {
+			complete = append(complete, backup)
+			continue
+		}
+		if session != nil && session.backup.name == backup.name {
+			inProgress = true
+			continue
+		}
+		for _, snapshot := range backup.snapshots() {
+			if err == nil {
+				err = errors.WithStack(client.IgnoreNotFound(
+					r.deleteControlled(ctx, cluster, snapshot)))
+			}
+		}
+	}
+	backups = complete
+
+	// Finish the backup in progress once storage has cut its snapshots.
+	if session != nil && !inProgress {
+		r.abortVolumeSnapshotBackup(cluster, session)
+		err = errors.Errorf("VolumeSnapshots of backup %q were deleted", session.backup.name)
+		r.setVolumeSnapshotBackupCondition(cluster, session.backup, err)
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "VolumeSnapshotFailed", err.Error())
+		session, err = nil, nil
+	}
+	if err == nil && session != nil {
+		var backup *volumeSnapshotBackup
+		backup, err = r.finishVolumeSnapshotBackup(ctx, cluster, session)
+		if err != nil {
+			r.Recorder.Event(cluster, corev1.EventTypeWarning, "VolumeSnapshotFailed", err.Error())
+		}
+		if backup != nil {
+			backups = append([]*volumeSnapshotBackup{backup}, backups...)
+			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "VolumeSnapshotCreated",
+				"Created snapshot backup %q", backup.name)
+		}
+		if err == nil && backup == nil {
+			// Storage has not cut the snapshots yet; check again soon.
+			result = updateReconcileResult(result, reconcile.Result{RequeueAfter: volumeSnapshotPollInterval})
+		} else {
+			r.setVolumeSnapshotBackupCondition(cluster, session.backup, err)
+			session = nil
+		}
+	}
+
+	if err == nil {
+		var pending bool
+		pending, err = r.restoreVolumeSnapshotLabels(ctx, cluster, instances, clusterVolumes, backups)
+		if pending {
+			result.RequeueAfter = volumeSnapshotRequeue
+		}
+	}
+
+	// Take a backup when the annotation changes or the interval elapses.
+	var due bool
+	var latest *volumeSnapshotBackup
+	trigger := cluster.GetAnnotations()[naming.VolumeSnapshotBackup]
+	if len(backups) > 0 {
+		latest = backups[0]
+	}
+	if trigger != "" {
+		due = latest == nil || latest.data.GetAnnotations()[naming.VolumeSnapshotBackup] != trigger
+	}
+	if spec.IntervalMinutes != nil {
+		interval := time.Duration(*spec.IntervalMinutes) * time.Minute
+		if latest == nil {
+			due = true
+		} else if elapsed := time.Since(latest.startTime()); elapsed >= interval {
+			due = true
+		} else {
+			result = updateReconcileResult(result, reconcile.Result{RequeueAfter: interval - elapsed})
+		}
+	}
+
+	// Take one backup at a time.
+	if err == nil && due && session == nil {
+		session, err = r.startVolumeSnapshotBackup(ctx, cluster, trigger, instances, clusterVolumes)
+		if err != nil {
+			r.Recorder.Event(cluster, corev1.EventTypeWarning, "VolumeSnapshotFailed", err.Error())
+		}
+		if session != nil {
+			r.setVolumeSnapshotBackupCondition(cluster, session.backup, nil)
+			result = updateReconcileResult(result, reconcile.Result{RequeueAfter: volumeSnapshotPollInterval})
+		}
+		if err == nil && session == nil {
+			// There is no primary to back up; try again soon.
+			result = updateReconcileResult(result, reconcile.Result{RequeueAfter: volumeSnapshotRequeue})
+		}
+	}
+
+	// Remove the oldest backups beyond the retention limit.
+	retention := 3
+	if spec.Retention != nil {
+		retention = int(*spec.Retention)
+	}
+	for len(backups) > retention {
+		for _, snapshot := range backups[len(backups)-1].snapshots() {
+			if err == nil {
+				err = errors.WithStack(client.IgnoreNotFound(
+					r.deleteControlled(ctx, cluster, snapshot)))
+			}
+		}
+		log.V(1).Info("removed snapshot backup", "backup", backups[len(backups)-1].name)
+		backups = backups[:len(backups)-1]
+	}
+
+	cluster.Status.VolumeSnapshots = nil
+	for _, backup := range backups {
+		cluster.Status.VolumeSnapshots = append(cluster.Status.VolumeSnapshots, backup.status())
+
+		// Storage may take a while to copy a snapshot after cutting it.
+		if !backup.readyToUse() {
+			result = updateReconcileResult(result, reconcile.Result{RequeueAfter: volumeSnapshotRequeue})
+		}
+	}
+
+	return result, err
+}
+
+// restoreVolumeSnapshotLabels writes the "backup_label" file into data volumes
+// that were provisioned from a snapshot backup and are waiting for it. It also
+// removes the marker file from volumes of instances that were interrupted
+// while being backed up. It returns true when an instance might be waiting.
+func (r *Reconciler) restoreVolumeSnapshotLabels(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, clusterVolumes []corev1.PersistentVolumeClaim,
+	backups []*volumeSnapshotBackup,
+) (bool, error) {
+	var pending bool
+
+	// The "postgres-startup" container waits for this marker to be removed.
+	// Its content is the name of the backup that was in progress.
+	const script = `
+declare -r marker="$1" backup="$2"
+[ -f "${marker}" ] || exit 0
+if [ -n "${backup}" ] && [ "$(< "${marker}")" = "${backup}" ]; then
+  cat > "${PGDATA}/backup_label.tmp"
+  mv "${PGDATA}/backup_label.tmp" "${PGDATA}/backup_label"
+fi
+rm -f -- "${marker}"
+`
+
+	for _, instance := range instances.forCluster {
+		if len(instance.Pods) != 1 {
+			continue
+		}
+		pod := instance.Pods[0]
+
+		var source *corev1.TypedLocalObjectReference
+		for i := range clusterVolumes {
+			labels := clusterVolumes[i].Labels
+			if labels[naming.LabelInstance] == instance.Name &&
+				labels[naming.LabelRole] == naming.RolePostgresData {
+				source = clusterVolumes[i].Spec.DataSource
+			}
+		}
+
+		restored := source != nil && source.Kind == volumeSnapshotGVK.Kind
+		if running, known := instance.IsRunning(naming.ContainerDatabase); restored && (!running || !known) {
+			pending = true
+		}
+
+		var startup bool
+		for _, status := range pod.Status.InitContainerStatuses {
+			if status.Name == naming.ContainerPostgresStartup && status.State.Running != nil {
+				startup = true
+			}
+		}
+		if !startup {
+			continue
+		}
+
+		var backup *volumeSnapshotBackup
+		if restored {
+			for _, b := range backups {
+				if b.data.GetName() == source.Name {
+					backup = b
+				}
+			}
+			if backup == nil {
+				// The label cannot be written without the backup. Leave the
+				// marker so PostgreSQL does not start with inconsistent data.
+				r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "VolumeSnapshotMissing",
+					"Instance %q was provisioned from VolumeSnapshot %q which is no longer a complete backup",
+					instance.Name, source.Name)
+				continue
+			}
+		}
+
+		var name, label string
+		if backup != nil {
+			name, label = backup.name, backup.label()
+		}
+
+		var stderr bytes.Buffer
+		err := r.PodExec(pod.Namespace, pod.Name, naming.ContainerPostgresStartup,
+			strings.NewReader(label), nil, &stderr,
+			"bash", "-ceu", "--", script, "-", postgres.SnapshotMarkerFile(cluster), name)
+		if err != nil {
+			return pending, errors.Wrap(err, stderr.String())
+		}
+		if backup != nil {
+			logging.FromContext(ctx).V(1).Info("restored backup label",
+				"instance", instance.Name, "backup", name)
+		}
+	}
+
+	return pending, nil
+}
+
+// setVolumeSnapshotBackupCondition records the progress of backup in the
+// conditions of cluster. A backup is in progress until it either completes or
+// fails with err.
+func (*Reconciler) setVolumeSnapshotBackupCondition(
+	cluster *v1beta1.PostgresCluster, backup *volumeSnapshotBackup, err error,
+) {
+	condition := metav1.Condition{
+		Type:               v1beta1.VolumeSnapshotBackupProgressing,
+		ObservedGeneration: cluster.GetGeneration(),
+	}
+
+	switch {
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "BackupFailed"
+		condition.Message = err.Error()
+	case backup.complete():
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "BackupComplete"
+		condition.Message = fmt.Sprintf("Snapshot backup %q is complete", backup.name)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "WaitingForSnapshots"
+		condition.Message = fmt.Sprintf(
+			"PostgreSQL is in backup mode while storage cuts the VolumeSnapshots of backup %q",
+			backup.name)
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+}
+
+// startVolumeSnapshotBackup puts PostgreSQL on the primary instance into
+// backup mode and creates VolumeSnapshots of its volumes. It returns the
+// session that holds PostgreSQL in backup mode, or nil when there is no
+// primary. Call finishVolumeSnapshotBackup to complete the backup.
+// - https://www.postgresql.org/docs/current/continuous-archiving.html#BACKUP-LOWLEVEL-BASE-BACKUP
+func (r *Reconciler) startVolumeSnapshotBackup(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, trigger string,
+	instances *observedInstances, clusterVolumes []corev1.PersistentVolumeClaim,
+) (*volumeSnapshotSession, error) {
+	var primary *Instance
+	for _, instance := range instances.forCluster {
+		if terminating, known := instance.IsTerminating(); terminating || !known {
+			continue
+		}
+		if writable, known := instance.IsWritable(); !writable || !known {
+			continue
+		}
+		if running, known := instance.IsRunning(naming.ContainerDatabase); running && known {
+			primary = instance
+		}
+	}
+	if primary == nil {
+		return nil, nil
+	}
+
+	pod := primary.Pods[0]
+	started := time.Now().UTC()
+	backup := &volumeSnapshotBackup{name: started.Format("20060102-150405")}
+	marker := postgres.SnapshotMarkerFile(cluster)
+
+	for i := range clusterVolumes {
+		volume := &clusterVolumes[i]
+		if volume.Labels[naming.LabelInstance] != primary.Name {
+			continue
+		}
+
+		var target **unstructured.Unstructured
+		var suffix string
+		switch volume.Labels[naming.LabelRole] {
+		case naming.RolePostgresData:
+			target, suffix = &backup.data, "-pgdata"
+		case naming.RolePostgresWAL:
+			target, suffix = &backup.wal, "-pgwal"
+		default:
+			continue
+		}
+
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		snapshot.SetNamespace(cluster.Namespace)
+		snapshot.SetName(cluster.Name + "-" + backup.name + suffix)
+		snapshot.SetAnnotations(naming.Merge(
+			cluster.Spec.Metadata.GetAnnotationsOrNil(),
+			map[string]string{
+				naming.VolumeSnapshotBackup:    trigger,
+				naming.VolumeSnapshotStartTime: started.Format(time.RFC3339),
+			}))
+		snapshot.SetLabels(naming.Merge(
+			cluster.Spec.Metadata.GetLabelsOrNil(),
+			map[string]string{
+				naming.LabelCluster:        cluster.Name,
+				naming.LabelInstanceSet:    volume.Labels[naming.LabelInstanceSet],
+				naming.LabelInstance:       primary.Name,
+				naming.LabelRole:           volume.Labels[naming.LabelRole],
+				naming.LabelData:           naming.DataPostgres,
+				naming.LabelVolumeSnapshot: backup.name,
+			}))
+		snapshot.Object["spec"] = map[string]interface{}{
+			"volumeSnapshotClassName": cluster.Spec.Backups.Snapshots.VolumeSnapshotClassName,
+			"source": map[string]interface{}{
+				"persistentVolumeClaimName": volume.Name,
+			},
+		}
+		*target = snapshot
+	}
+	if backup.data == nil {
+		return nil, nil
+	}
+
+	// Start a "psql" session that reads commands as they are sent. Closing
+	// its input ends the session which aborts the backup, if necessary.
+	input, inputWriter := io.Pipe()
+	outputReader, output := io.Pipe()
+	finished := make(chan error, 1)
+	stderr := new(bytes.Buffer)
+	go func() {
+		err := r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase,
+			input, output, stderr,
+			"psql", "-Xw", "--quiet", "--no-align", "--tuples-only",
+			"--set=ON_ERROR_STOP=1", "--file=-")
+		_ = input.Close()
+		_ = output.CloseWithError(err)
+		finished <- err
+	}()
+
+	// PostgreSQL v15 renamed the backup functions and removed exclusive mode.
+	start := fmt.Sprintf(`SELECT pg_catalog.pg_start_backup('%s', true, false)`, backup.name)
+	stop := `SELECT labelfile FROM pg_catalog.pg_stop_backup(false, true)`
+	if cluster.Spec.PostgresVersion >= 15 {
+		start = fmt.Sprintf(`SELECT pg_catalog.pg_backup_start('%s', true)`, backup.name)
+		stop = `SELECT labelfile FROM pg_catalog.pg_backup_stop(true)`
+	}
+
+	// Commands run by the shell write directly to standard output. Use one to
+	// know when the backup has started regardless of any buffering by "psql".
+	const sentinel = "::postgres-operator: backup started"
+	_, err := fmt.Fprintf(inputWriter, strings.Join([]string{
+		`SET statement_timeout = '5min';`,
+		start + ` \g /dev/null`,
+		`\! printf '%%s' '%s' > '%s' && echo '%s'`,
+		``,
+	}, "\n"), backup.name, marker, sentinel)
+
+	lines := bufio.NewScanner(outputReader)
+	for err == nil {
+		if !lines.Scan() {
+			err = lines.Err()
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+		} else if lines.Text() == sentinel {
+			break
+		}
+	}
+	if err != nil {
+		_ = inputWriter.Close()
+		if sessionErr := <-finished; sessionErr != nil {
+			err = sessionErr
+		}
+		return nil, errors.Wrapf(err, "unable to start backup: %s", stderr.String())
+	}
+
+	// PostgreSQL is in backup mode. Create the VolumeSnapshots; storage cuts
+	// them in the background.
+	for _, snapshot := range backup.snapshots() {
+		if err == nil {
+			err = errors.WithStack(r.setControllerReference(cluster, snapshot))
+		}
+		if err == nil {
+			err = errors.WithStack(r.Client.Create(ctx, snapshot))
+		}
+	}
+
+	session := &volumeSnapshotSession{
+		backup: backup, marker: marker, started: started, stop: stop,
+		input: inputWriter, lines: lines, stderr: stderr, finished: finished,
+	}
+	if err != nil {
+		_ = inputWriter.Close()
+		<-finished
+		return nil, err
+	}
+
+	// Abort the backup if this cluster is not reconciled again, such as when
+	// it is deleted while in backup mode.
+	session.timer = time.AfterFunc(volumeSnapshotCutTimeout+time.Minute, func() {
+		_ = inputWriter.Close()
+	})
+	r.setVolumeSnapshotSession(cluster, session)
+
+	logging.FromContext(ctx).V(1).Info("started snapshot backup", "backup", backup.name)
+	return session, nil
+}
+
+// abortVolumeSnapshotBackup ends session without stopping the backup so that
+// PostgreSQL aborts it. The incomplete VolumeSnapshots are deleted the next
+// time cluster is reconciled.
+func (r *Reconciler) abortVolumeSnapshotBackup(
+	cluster *v1beta1.PostgresCluster, session *volumeSnapshotSession,
+) {
+	r.setVolumeSnapshotSession(cluster, nil)
+	session.timer.Stop()
+	_ = session.input.Close()
+	<-session.finished
+}
+
+// finishVolumeSnapshotBackup completes the backup of session once storage has
+// cut all its VolumeSnapshots. It returns nil while storage is still cutting
+// them and an error when they are not cut within volumeSnapshotCutTimeout.
+func (r *Reconciler) finishVolumeSnapshotBackup(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, session *volumeSnapshotSession,
+) (*volumeSnapshotBackup, error) {
+	backup := session.backup
+
+	var err error
+	cut := true
+	for _, snapshot := range backup.snapshots() {
+		if err == nil {
+			err = errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), snapshot))
+		}
+		if _, found, _ := unstructured.NestedString(
+			snapshot.Object, "status", "creationTime"); err == nil && !found {
+			cut = false
+		}
+	}
+	if err == nil && !cut {
+		if time.Since(session.started) < volumeSnapshotCutTimeout {
+			return nil, nil
+		}
+		err = errors.Errorf("VolumeSnapshots were not cut within %v", volumeSnapshotCutTimeout)
+	}
+	if err != nil {
+		r.abortVolumeSnapshotBackup(cluster, session)
+		return nil, err
+	}
+
+	r.setVolumeSnapshotSession(cluster, nil)
+	session.timer.Stop()
+	inputWriter, lines, stderr := session.input, session.lines, session.stderr
+
+	// Remove the marker and stop the backup.
+	var label strings.Builder
+	commands := fmt.Sprintf(`\! rm -f -- '%s'`+"\n", session.marker) + session.stop + ";\n"
+	_, err = io.WriteString(inputWriter, commands)
+	err = errors.WithStack(err)
+	_ = inputWriter.Close()
+
+	for lines.Scan() {
+		label.WriteString(lines.Text())
+		label.WriteString("\n")
+	}
+	if sessionErr := <-session.finished; err == nil && sessionErr != nil {
+		err = errors.Wrapf(sessionErr, "unable to stop backup: %s", stderr.String())
+	}
+	if err == nil && strings.TrimSpace(label.String()) == "" {
+		err = errors.New("PostgreSQL returned an empty backup label")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the label, marking the backup complete.
+	before := backup.data.DeepCopy()
+	annotations := backup.data.GetAnnotations()
+	annotations[naming.VolumeSnapshotBackupLabel] = strings.TrimSpace(label.String()) + "\n"
+	backup.data.SetAnnotations(annotations)
+	err = errors.WithStack(r.Client.Patch(ctx, backup.data, client.MergeFrom(before)))
+
+	if err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
@@ -0,0 +1,504 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/testing/cmp"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestVolumeSnapshotSource(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	set := &v1beta1.PostgresInstanceSetSpec{Name: "00"}
+
+	assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresData, nil) == nil,
+		"expected nothing when snapshots are disabled")
+
+	cluster.Spec.Backups.Snapshots = &v1beta1.VolumeSnapshotBackups{}
+	cluster.Status.VolumeSnapshots = []v1beta1.VolumeSnapshotBackupStatus{
+		{Name: "3", DataVolumeSnapshot: "d3", WALVolumeSnapshot: "w3", ReadyToUse: true},
+		{Name: "2", DataVolumeSnapshot: "d2", ReadyToUse: false},
+		{Name: "1", DataVolumeSnapshot: "d1", ReadyToUse: true},
+	}
+
+	assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresData, nil) == nil,
+		"expected nothing before the cluster is bootstrapped")
+
+	cluster.Status.Patroni.SystemIdentifier = "12345"
+
+	t.Run("NoWALVolume", func(t *testing.T) {
+		source := volumeSnapshotSource(cluster, set, "one", naming.RolePostgresData, nil)
+		assert.Assert(t, cmp.MarshalMatches(source, `
+apiGroup: snapshot.storage.k8s.io
+kind: VolumeSnapshot
+name: d1
+		`))
+		assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresWAL, nil) == nil)
+	})
+
+	t.Run("WALVolume", func(t *testing.T) {
+		set := set.DeepCopy()
+		set.WALVolumeClaimSpec = new(corev1.PersistentVolumeClaimSpec)
+
+		assert.Equal(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresData, nil).Name, "d3")
+		assert.Equal(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresWAL, nil).Name, "w3")
+	})
+
+	t.Run("TablespaceVolumes", func(t *testing.T) {
+		set := set.DeepCopy()
+		set.TablespaceVolumes = []v1beta1.TablespaceVolume{{Name: "fast"}}
+
+		assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresData, nil) == nil,
+			"expected nothing when the set has tablespaces")
+	})
+
+	t.Run("ExistingDataVolume", func(t *testing.T) {
+		set := set.DeepCopy()
+		set.WALVolumeClaimSpec = new(corev1.PersistentVolumeClaimSpec)
+
+		volumes := []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: "one-pgdata", Labels: map[string]string{
+				naming.LabelInstance: "one",
+				naming.LabelRole:     naming.RolePostgresData,
+			}},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				DataSource: &corev1.TypedLocalObjectReference{Kind: "VolumeSnapshot", Name: "d1"},
+			},
+		}}
+
+		// The WAL volume comes from the same backup as the data volume.
+		assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresWAL, volumes) == nil)
+
+		volumes[0].Spec.DataSource.Name = "d3"
+		assert.Equal(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresWAL, volumes).Name, "w3")
+
+		// An empty data volume gets an empty WAL volume.
+		volumes[0].Spec.DataSource = nil
+		assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresWAL, volumes) == nil)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.Snapshots.BootstrapReplicas = initialize.Bool(false)
+		assert.Assert(t, volumeSnapshotSource(cluster, set, "one", naming.RolePostgresData, nil) == nil)
+	})
+}
+
+func TestReconcileVolumeSnapshots(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.UID = "hippo-uid"
+	cluster.Spec.PostgresVersion = 14
+	cluster.Spec.Backups.Snapshots = &v1beta1.VolumeSnapshotBackups{
+		VolumeSnapshotClassName: "csi-snapclass",
+		Retention:               initialize.Int32(1),
+	}
+	cluster.Annotations = map[string]string{naming.VolumeSnapshotBackup: "first"}
+
+	primary := &corev1.Pod{}
+	primary.Namespace = "ns1"
+	primary.Name = "hippo-00-abcd-0"
+	primary.Annotations = map[string]string{"status": `{"role":"master"}`}
+	primary.Labels = map[string]string{
+		naming.LabelCluster:     "hippo",
+		naming.LabelInstanceSet: "00",
+		naming.LabelInstance:    "hippo-00-abcd",
+		naming.LabelRole:        naming.RolePatroniLeader,
+	}
+	primary.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+
+	volumes := []corev1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{Name: "hippo-00-abcd-pgdata", Labels: map[string]string{
+			naming.LabelInstanceSet: "00",
+			naming.LabelInstance:    "hippo-00-abcd",
+			naming.LabelRole:        naming.RolePostgresData,
+		}},
+	}}
+
+	// The VolumeSnapshot API is only available as unstructured objects.
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+	scheme.AddKnownTypeWithName(volumeSnapshotGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(
+		volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"),
+		&unstructured.UnstructuredList{})
+
+	reconciler := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+	reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// Storage cuts every VolumeSnapshot as soon as it exists.
+	cutSnapshots := func() {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"))
+		assert.NilError(t, reconciler.Client.List(ctx, list, client.InNamespace("ns1")))
+		for i := range list.Items {
+			snapshot := &list.Items[i]
+			if _, found, _ := unstructured.NestedString(snapshot.Object, "status", "creationTime"); !found {
+				assert.NilError(t, unstructured.SetNestedField(snapshot.Object,
+					time.Now().Format(time.RFC3339), "status", "creationTime"))
+				assert.NilError(t, reconciler.Client.Update(ctx, snapshot))
+			}
+		}
+	}
+
+	var mutex sync.Mutex
+	var sessions []string
+	reconciler.PodExec = func(
+		namespace, pod, container string,
+		stdin io.Reader, stdout, _ io.Writer, command ...string,
+	) error {
+		assert.Equal(t, namespace, "ns1")
+		assert.Equal(t, pod, "hippo-00-abcd-0")
+		assert.Equal(t, container, naming.ContainerDatabase)
+		assert.Equal(t, command[0], "psql")
+
+		// Respond to commands as they arrive, like "psql" would.
+		var session strings.Builder
+		lines := bufio.NewScanner(stdin)
+		for lines.Scan() {
+			line := lines.Text()
+			session.WriteString(line + "\n")
+
+			if strings.HasPrefix(line, `\! printf`) {
+				// The shell echoes the last argument.
+				echo := strings.TrimSuffix(line, "'")
+				_, err := io.WriteString(stdout, echo[strings.LastIndex(echo, "'")+1:]+"\n")
+				assert.NilError(t, err)
+			}
+			if strings.HasPrefix(line, "SELECT labelfile") {
+				_, err := io.WriteString(stdout, "START WAL LOCATION: 0/2000028\nLABEL: x\n\n")
+				assert.NilError(t, err)
+			}
+		}
+
+		mutex.Lock()
+		sessions = append(sessions, session.String())
+		mutex.Unlock()
+		return nil
+	}
+
+	instances := newObservedInstances(cluster, nil, []corev1.Pod{*primary})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.Snapshots = nil
+		cluster.Status.VolumeSnapshots = []v1beta1.VolumeSnapshotBackupStatus{{Name: "x"}}
+
+		result, err := reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Assert(t, cluster.Status.VolumeSnapshots == nil)
+	})
+
+	var first string
+	t.Run("Backup", func(t *testing.T) {
+		result, err := reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, volumeSnapshotPollInterval, "expected to wait for cut")
+		assert.Equal(t, len(sessions), 0, "expected PostgreSQL to stay in backup mode")
+		assert.Equal(t, len(cluster.Status.VolumeSnapshots), 0)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions,
+			v1beta1.VolumeSnapshotBackupProgressing)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionTrue)
+		assert.Equal(t, condition.Reason, "WaitingForSnapshots")
+
+		// Nothing changes until storage cuts the snapshots.
+		result, err = reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, volumeSnapshotPollInterval, "expected to wait for cut")
+		assert.Equal(t, len(sessions), 0)
+
+		cutSnapshots()
+		result, err = reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, volumeSnapshotRequeue, "expected to wait for ready")
+
+		condition = meta.FindStatusCondition(cluster.Status.Conditions,
+			v1beta1.VolumeSnapshotBackupProgressing)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "BackupComplete")
+
+		assert.Equal(t, len(sessions), 1)
+		assert.Assert(t, cmp.Contains(sessions[0], `pg_catalog.pg_start_backup('`))
+		assert.Assert(t, cmp.Contains(sessions[0], `', true, false) \g /dev/null`))
+		assert.Assert(t, cmp.Contains(sessions[0], `> '/pgdata/pg14_snapshot'`))
+		assert.Assert(t, cmp.Contains(sessions[0], `\! rm -f -- '/pgdata/pg14_snapshot'`))
+		assert.Assert(t, cmp.Contains(sessions[0], `pg_catalog.pg_stop_backup(false, true)`))
+
+		assert.Equal(t, len(cluster.Status.VolumeSnapshots), 1)
+		status := cluster.Status.VolumeSnapshots[0]
+		first = status.Name
+		assert.Equal(t, status.DataVolumeSnapshot, "hippo-"+first+"-pgdata")
+		assert.Equal(t, status.WALVolumeSnapshot, "")
+		assert.Equal(t, status.InstanceSet, "00")
+		assert.Assert(t, !status.ReadyToUse)
+
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		assert.NilError(t, reconciler.Client.Get(ctx,
+			client.ObjectKey{Namespace: "ns1", Name: status.DataVolumeSnapshot}, snapshot))
+
+		assert.Assert(t, cmp.MarshalMatches(snapshot.Object["spec"], `
+source:
+  persistentVolumeClaimName: hippo-00-abcd-pgdata
+volumeSnapshotClassName: csi-snapclass
+		`))
+		assert.Equal(t, snapshot.GetAnnotations()[naming.VolumeSnapshotBackup], "first")
+		assert.Equal(t, snapshot.GetAnnotations()[naming.VolumeSnapshotBackupLabel],
+			"START WAL LOCATION: 0/2000028\nLABEL: x\n")
+		assert.Equal(t, snapshot.GetLabels()[naming.LabelVolumeSnapshot], first)
+		assert.Assert(t, metav1.IsControlledBy(snapshot, cluster))
+
+		// Storage finishes copying the snapshot.
+		assert.NilError(t, unstructured.SetNestedField(snapshot.Object, true, "status", "readyToUse"))
+		assert.NilError(t, reconciler.Client.Update(ctx, snapshot))
+	})
+
+	t.Run("Observe", func(t *testing.T) {
+		result, err := reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Equal(t, len(sessions), 1, "expected no backup")
+
+		assert.Equal(t, len(cluster.Status.VolumeSnapshots), 1)
+		assert.Assert(t, cluster.Status.VolumeSnapshots[0].ReadyToUse)
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		// Backup names have a resolution of one second.
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+		cluster.Annotations[naming.VolumeSnapshotBackup] = "second"
+		_, err := reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+
+		cutSnapshots()
+		_, err = reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Equal(t, len(sessions), 2)
+
+		assert.Equal(t, len(cluster.Status.VolumeSnapshots), 1)
+		assert.Assert(t, cluster.Status.VolumeSnapshots[0].Name != first)
+
+		backups, err := reconciler.observeVolumeSnapshotBackups(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Equal(t, len(backups), 1)
+		assert.Equal(t, backups[0].name, cluster.Status.VolumeSnapshots[0].Name)
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		snapshot.SetNamespace("ns1")
+		snapshot.SetName("hippo-interrupted-pgdata")
+		snapshot.SetLabels(map[string]string{
+			naming.LabelCluster:        "hippo",
+			naming.LabelRole:           naming.RolePostgresData,
+			naming.LabelVolumeSnapshot: "interrupted",
+		})
+		assert.NilError(t, reconciler.setControllerReference(cluster, snapshot))
+		assert.NilError(t, reconciler.Client.Create(ctx, snapshot))
+
+		_, err := reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), snapshot)
+		assert.Assert(t, err != nil, "expected interrupted backup to be deleted")
+	})
+
+	t.Run("NotCut", func(t *testing.T) {
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+		cluster.Annotations[naming.VolumeSnapshotBackup] = "third"
+		result, err := reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, volumeSnapshotPollInterval)
+
+		session := reconciler.volumeSnapshotSession(cluster)
+		assert.Assert(t, session != nil)
+		session.started = session.started.Add(-volumeSnapshotCutTimeout)
+
+		_, err = reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.ErrorContains(t, err, "not cut within")
+		assert.Assert(t, reconciler.volumeSnapshotSession(cluster) == nil)
+
+		assert.Equal(t, len(sessions), 3)
+		assert.Assert(t, !strings.Contains(sessions[2], "stop_backup"),
+			"expected PostgreSQL to abort the backup")
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions,
+			v1beta1.VolumeSnapshotBackupProgressing)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "BackupFailed")
+
+		// The snapshots of the aborted backup are deleted. Change the
+		// annotation back so the backup is not tried again.
+		cluster.Annotations[naming.VolumeSnapshotBackup] = "second"
+		_, err = reconciler.reconcileVolumeSnapshots(ctx, cluster, instances, volumes)
+		assert.NilError(t, err)
+		assert.Assert(t, reconciler.volumeSnapshotSession(cluster) == nil)
+
+		backups, err := reconciler.observeVolumeSnapshotBackups(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Equal(t, len(backups), 1)
+		assert.Assert(t, backups[0].complete())
+	})
+}
+
+func TestRestoreVolumeSnapshotLabels(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.PostgresVersion = 14
+
+	data := &unstructured.Unstructured{}
+	data.SetName("hippo-20220101-000000-pgdata")
+	data.SetAnnotations(map[string]string{naming.VolumeSnapshotBackupLabel: "LABEL: x\n"})
+	backups := []*volumeSnapshotBackup{{name: "20220101-000000", data: data}}
+
+	pod := func(instance string, initializing bool) corev1.Pod {
+		pod := corev1.Pod{}
+		pod.Namespace = "ns1"
+		pod.Name = instance + "-0"
+		pod.Labels = map[string]string{
+			naming.LabelCluster:     "hippo",
+			naming.LabelInstanceSet: "00",
+			naming.LabelInstance:    instance,
+		}
+		if initializing {
+			pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+				Name:  naming.ContainerPostgresStartup,
+				State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+			}}
+		} else {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  naming.ContainerDatabase,
+				State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+			}}
+		}
+		return pod
+	}
+	volume := func(instance, snapshot string) corev1.PersistentVolumeClaim {
+		pvc := corev1.PersistentVolumeClaim{}
+		pvc.Name = instance + "-pgdata"
+		pvc.Labels = map[string]string{
+			naming.LabelInstance: instance,
+			naming.LabelRole:     naming.RolePostgresData,
+		}
+		if snapshot != "" {
+			pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+				APIGroup: initialize.String("snapshot.storage.k8s.io"),
+				Kind:     "VolumeSnapshot",
+				Name:     snapshot,
+			}
+		}
+		return pvc
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{Recorder: recorder}
+
+	type call struct {
+		pod, stdin string
+		args       []string
+	}
+	var calls []call
+	reconciler.PodExec = func(
+		namespace, pod, container string,
+		stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		assert.Equal(t, container, naming.ContainerPostgresStartup)
+		assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+
+		b, err := io.ReadAll(stdin)
+		assert.NilError(t, err)
+		calls = append(calls, call{pod: pod, stdin: string(b), args: command[4:]})
+		return nil
+	}
+
+	instances := newObservedInstances(cluster, nil, []corev1.Pod{
+		pod("restored", true), pod("empty", true), pod("running", false),
+	})
+	volumes := []corev1.PersistentVolumeClaim{
+		volume("restored", "hippo-20220101-000000-pgdata"),
+		volume("empty", ""),
+		volume("running", "hippo-20220101-000000-pgdata"),
+	}
+
+	pending, err := reconciler.restoreVolumeSnapshotLabels(ctx, cluster, instances, volumes, backups)
+	assert.NilError(t, err)
+	assert.Assert(t, pending, "expected to check the restored instance again")
+
+	assert.Equal(t, len(calls), 2)
+	for _, call := range calls {
+		switch call.pod {
+		case "restored-0":
+			assert.DeepEqual(t, call.args, []string{"-", "/pgdata/pg14_snapshot", "20220101-000000"})
+			assert.Equal(t, call.stdin, "LABEL: x\n")
+		case "empty-0":
+			// Removes a marker left by an interrupted backup, if any.
+			assert.DeepEqual(t, call.args, []string{"-", "/pgdata/pg14_snapshot", ""})
+			assert.Equal(t, call.stdin, "")
+		default:
+			t.Fatalf("unexpected pod %q", call.pod)
+		}
+	}
+
+	t.Run("MissingBackup", func(t *testing.T) {
+		calls = nil
+		_, err := reconciler.restoreVolumeSnapshotLabels(ctx, cluster, instances, volumes, nil)
+		assert.NilError(t, err)
+
+		assert.Equal(t, len(calls), 1)
+		assert.Equal(t, calls[0].pod, "empty-0")
+
+		assert.Equal(t, len(recorder.Events), 1, "expected no event for the running instance")
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Warning VolumeSnapshotMissing "), "%q", event)
+	})
+}
@@ -16,14 +16,18 @@
 package postgrescluster
 
 import (
+	"context"
+
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/adifri/postgres-operator/v5/internal/logging"
 	"github.com/adifri/postgres-operator/v5/internal/naming"
 	"github.com/adifri/postgres-operator/v5/internal/patroni"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 // watchPods returns a handler.EventHandler for Pods.
@@ -60,3 +64,28 @@ func (*Reconciler) watchPods() handler.Funcs {
 		},
 	}
 }
+
+// watchImageCatalogs returns a handler.MapFunc that queues every
+// PostgresCluster that uses a PostgresImageCatalog.
+func (r *Reconciler) watchImageCatalogs(ctx context.Context) handler.MapFunc {
+	return func(catalog client.Object) []reconcile.Request {
+		log := logging.FromContext(ctx)
+
+		list := &v1beta1.PostgresClusterList{}
+		if err := r.Client.List(ctx, list); err != nil {
+			log.Error(err, "unable to list PostgresClusters")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range list.Items {
+			if reference := list.Items[i].Spec.ImageCatalog; reference != nil &&
+				reference.Name == catalog.GetName() {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&list.Items[i]),
+				})
+			}
+		}
+		return requests
+	}
+}
@@ -16,15 +16,21 @@
 package postgrescluster
 
 import (
+	"context"
 	"testing"
 
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestWatchPodsUpdate(t *testing.T) {
@@ -141,3 +147,33 @@ func TestWatchPodsUpdate(t *testing.T) {
 		queue.Done(item)
 	})
 }
+
+func TestWatchImageCatalogs(t *testing.T) {
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	cluster := func(namespace, name, catalog string) *v1beta1.PostgresCluster {
+		cluster := &v1beta1.PostgresCluster{}
+		cluster.Namespace, cluster.Name = namespace, name
+		if catalog != "" {
+			cluster.Spec.ImageCatalog = &v1beta1.PostgresImageCatalogReference{Name: catalog}
+		}
+		return cluster
+	}
+
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster("ns1", "one", "stable"),
+		cluster("ns1", "two", "testing"),
+		cluster("ns2", "three", "stable"),
+		cluster("ns2", "four", ""),
+	).Build()}
+
+	catalog := &v1beta1.PostgresImageCatalog{}
+	catalog.Name = "stable"
+
+	requests := reconciler.watchImageCatalogs(context.Background())(catalog)
+	assert.DeepEqual(t, requests, []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns1", Name: "one"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns2", Name: "three"}},
+	})
+}
@@ -0,0 +1,564 @@
+package runtime
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+)
+
+// Namespaces returns the distinct namespaces in value, a comma-separated
+// list. Blank entries are ignored.
+func Namespaces(value string) []string {
+	names := sets.NewString()
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names.Insert(name)
+		}
+	}
+	return names.List()
+}
+
+// NamespacesCacheBuilder returns a cache.NewCacheFunc that caches objects in
+// namespaces. When selector is nil, those are the namespaces listed. Otherwise,
+// they are the namespaces that match selector, and they change as namespaces
+// are created, labeled, and deleted. Cluster-scoped objects, including
+// Namespaces, are cached separately.
+func NamespacesCacheBuilder(namespaces []string, selector labels.Selector) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		var err error
+		if opts.Mapper == nil {
+			opts.Mapper, err = apiutil.NewDiscoveryRESTMapper(config)
+		}
+
+		var cluster cache.Cache
+		if err == nil {
+			opts.Namespace = ""
+			cluster, err = cache.New(config, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return newNamespacesCache(cluster, opts.Mapper, opts.Scheme, namespaces, selector,
+			func(namespace string) (cache.Cache, error) {
+				opts.Namespace = namespace
+				return cache.New(config, opts)
+			})
+	}
+}
+
+// namespacesCache is a cache.Cache of objects in a set of namespaces that can
+// change while it runs. The informers it returns span all those namespaces;
+// event handlers and indexers added to them apply to namespaces added later.
+type namespacesCache struct {
+	cluster  cache.Cache
+	mapper   meta.RESTMapper
+	scheme   *runtime.Scheme
+	selector labels.Selector
+	newCache func(namespace string) (cache.Cache, error)
+
+	// started is closed once the initial namespaces are known.
+	started chan struct{}
+
+	mu        sync.RWMutex
+	ctx       context.Context
+	caches    map[string]*namespaceCache
+	indexes   []fieldIndex
+	informers map[schema.GroupVersionKind]*namespacesInformer
+}
+
+// namespaceCache is the cache of one namespace and the function that stops it.
+type namespaceCache struct {
+	cache.Cache
+	stop context.CancelFunc
+}
+
+// fieldIndex is the arguments of one call to IndexField.
+type fieldIndex struct {
+	object  client.Object
+	field   string
+	extract client.IndexerFunc
+}
+
+var _ cache.Cache = &namespacesCache{}
+
+func newNamespacesCache(
+	cluster cache.Cache, mapper meta.RESTMapper, scheme *runtime.Scheme,
+	namespaces []string, selector labels.Selector,
+	newCache func(string) (cache.Cache, error),
+) (*namespacesCache, error) {
+	c := &namespacesCache{
+		cluster:   cluster,
+		mapper:    mapper,
+		scheme:    scheme,
+		selector:  selector,
+		newCache:  newCache,
+		started:   make(chan struct{}),
+		caches:    make(map[string]*namespaceCache),
+		informers: make(map[schema.GroupVersionKind]*namespacesInformer),
+	}
+
+	var err error
+	if selector == nil {
+		for i := 0; err == nil && i < len(namespaces); i++ {
+			err = c.add(context.Background(), namespaces[i])
+		}
+	}
+	return c, err
+}
+
+// add creates a cache for namespace and registers the informers, event
+// handlers, and indexers of c with it. The cache is started when c is. The
+// caller must hold c.mu.
+func (c *namespacesCache) add(ctx context.Context, namespace string) error {
+	if _, ok := c.caches[namespace]; ok {
+		return nil
+	}
+
+	namespaced, err := c.newCache(namespace)
+
+	for i := 0; err == nil && i < len(c.indexes); i++ {
+		index := c.indexes[i]
+		err = namespaced.IndexField(ctx, index.object, index.field, index.extract)
+	}
+	for gvk, informers := range c.informers {
+		var informer cache.Informer
+		if err == nil {
+			informer, err = namespaced.GetInformerForKind(ctx, gvk)
+		}
+		if err == nil {
+			err = informers.add(namespace, informer)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	entry := &namespaceCache{Cache: namespaced}
+	c.caches[namespace] = entry
+
+	if c.ctx != nil {
+		c.start(namespace, entry)
+	}
+	return nil
+}
+
+// remove stops and forgets the cache of namespace. The caller must hold c.mu.
+func (c *namespacesCache) remove(namespace string) {
+	if entry, ok := c.caches[namespace]; ok {
+		if entry.stop != nil {
+			entry.stop()
+		}
+		delete(c.caches, namespace)
+
+		for _, informers := range c.informers {
+			informers.remove(namespace)
+		}
+	}
+}
+
+// start runs the cache of namespace until c stops or namespace is removed.
+// The caller must hold c.mu.
+func (c *namespacesCache) start(namespace string, entry *namespaceCache) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	entry.stop = cancel
+
+	go func() {
+		if err := entry.Start(ctx); err != nil {
+			logging.FromContext(ctx).Error(err, "unable to start cache", "namespace", namespace)
+		}
+	}()
+}
+
+// selected returns whether or not namespace should be cached.
+func (c *namespacesCache) selected(namespace *corev1.Namespace) bool {
+	return namespace.DeletionTimestamp == nil &&
+		c.selector.Matches(labels.Set(namespace.Labels))
+}
+
+// reselect adds or removes the cache of namespace according to the selector
+// of c.
+func (c *namespacesCache) reselect(ctx context.Context, namespace *corev1.Namespace, deleted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if deleted || !c.selected(namespace) {
+		c.remove(namespace.Name)
+	} else if err := c.add(ctx, namespace.Name); err != nil {
+		logging.FromContext(ctx).Error(err, "unable to watch namespace", "namespace", namespace.Name)
+	}
+}
+
+// watchNamespaces adds the caches of namespaces that match the selector of c
+// then keeps them up to date as namespaces change.
+func (c *namespacesCache) watchNamespaces(ctx context.Context) error {
+	informer, err := c.cluster.GetInformer(ctx, &corev1.Namespace{})
+	if err == nil && !c.cluster.WaitForCacheSync(ctx) {
+		err = errors.New("unable to sync namespaces")
+	}
+
+	list := &corev1.NamespaceList{}
+	if err == nil {
+		err = c.cluster.List(ctx, list)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		c.reselect(ctx, &list.Items[i], false)
+	}
+
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if namespace, ok := obj.(*corev1.Namespace); ok {
+				c.reselect(ctx, namespace, false)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if namespace, ok := obj.(*corev1.Namespace); ok {
+				c.reselect(ctx, namespace, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if namespace, ok := obj.(*corev1.Namespace); ok {
+				c.reselect(ctx, namespace, true)
+			}
+		},
+	})
+	return nil
+}
+
+// Start implements cache.Informers. It runs the caches of all namespaces
+// until ctx is done.
+func (c *namespacesCache) Start(ctx context.Context) error {
+	c.mu.Lock()
+	c.ctx = ctx
+	for namespace, entry := range c.caches {
+		c.start(namespace, entry)
+	}
+	c.mu.Unlock()
+
+	errs := make(chan error, 1)
+	go func() { errs <- c.cluster.Start(ctx) }()
+
+	if c.selector != nil {
+		if err := c.watchNamespaces(ctx); err != nil {
+			return err
+		}
+	}
+	close(c.started)
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			return err
+		}
+		<-ctx.Done()
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// WaitForCacheSync implements cache.Informers.
+func (c *namespacesCache) WaitForCacheSync(ctx context.Context) bool {
+	select {
+	case <-c.started:
+	case <-ctx.Done():
+		return false
+	}
+
+	c.mu.RLock()
+	caches := make([]cache.Cache, 0, len(c.caches))
+	for _, entry := range c.caches {
+		caches = append(caches, entry.Cache)
+	}
+	c.mu.RUnlock()
+
+	synced := c.cluster.WaitForCacheSync(ctx)
+	for _, namespaced := range caches {
+		synced = namespaced.WaitForCacheSync(ctx) && synced
+	}
+	return synced
+}
+
+// clusterScoped returns whether or not objects of gvk are cluster-scoped.
+func (c *namespacesCache) clusterScoped(gvk schema.GroupVersionKind) (bool, error) {
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, err
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameRoot, nil
+}
+
+// clusterScopedObject returns the GroupVersionKind of obj and whether or not
+// it is cluster-scoped.
+func (c *namespacesCache) clusterScopedObject(obj runtime.Object) (schema.GroupVersionKind, bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return gvk, false, err
+	}
+	scoped, err := c.clusterScoped(gvk)
+	return gvk, scoped, err
+}
+
+// GetInformer implements cache.Informers.
+func (c *namespacesCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetInformerForKind(ctx, gvk)
+}
+
+// GetInformerForKind implements cache.Informers. The informer of a namespaced
+// kind spans all the namespaces of c, including those added later.
+func (c *namespacesCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	if scoped, err := c.clusterScoped(gvk); err != nil || scoped {
+		if err != nil {
+			return nil, err
+		}
+		return c.cluster.GetInformerForKind(ctx, gvk)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if informers, ok := c.informers[gvk]; ok {
+		return informers, nil
+	}
+
+	informers := &namespacesInformer{informers: make(map[string]cache.Informer)}
+	for namespace, entry := range c.caches {
+		informer, err := entry.GetInformerForKind(ctx, gvk)
+		if err == nil {
+			err = informers.add(namespace, informer)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.informers[gvk] = informers
+	return informers, nil
+}
+
+// IndexField implements client.FieldIndexer.
+func (c *namespacesCache) IndexField(ctx context.Context, obj client.Object, field string, extract client.IndexerFunc) error {
+	if _, scoped, err := c.clusterScopedObject(obj); err != nil || scoped {
+		if err != nil {
+			return err
+		}
+		return c.cluster.IndexField(ctx, obj, field, extract)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.caches {
+		if err := entry.IndexField(ctx, obj, field, extract); err != nil {
+			return err
+		}
+	}
+	c.indexes = append(c.indexes, fieldIndex{object: obj, field: field, extract: extract})
+	return nil
+}
+
+// Get implements client.Reader. Objects in namespaces that are not cached
+// are not found.
+func (c *namespacesCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	gvk, scoped, err := c.clusterScopedObject(obj)
+	if err != nil {
+		return err
+	}
+	if scoped {
+		return c.cluster.Get(ctx, key, obj)
+	}
+
+	c.mu.RLock()
+	entry, ok := c.caches[key.Namespace]
+	c.mu.RUnlock()
+
+	if !ok {
+		mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		return apierrors.NewNotFound(mapping.Resource.GroupResource(), key.Name)
+	}
+	return entry.Get(ctx, key, obj)
+}
+
+// List implements client.Reader. Listing all namespaces lists those that are
+// cached; listing one that is not cached returns nothing.
+func (c *namespacesCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	_, scoped, err := c.clusterScopedObject(list)
+	if err != nil {
+		return err
+	}
+	if scoped {
+		return c.cluster.List(ctx, list, opts...)
+	}
+
+	var options client.ListOptions
+	options.ApplyOptions(opts)
+
+	c.mu.RLock()
+	namespaces := make([]string, 0, len(c.caches))
+	caches := make(map[string]cache.Cache, len(c.caches))
+	for namespace, entry := range c.caches {
+		if options.Namespace == corev1.NamespaceAll || options.Namespace == namespace {
+			namespaces = append(namespaces, namespace)
+			caches[namespace] = entry.Cache
+		}
+	}
+	c.mu.RUnlock()
+
+	// Visit namespaces in a consistent order so that results are, too.
+	sort.Strings(namespaces)
+
+	var items []runtime.Object
+	var resourceVersion string
+	for _, namespace := range namespaces {
+		each := list.DeepCopyObject().(client.ObjectList)
+		if err := caches[namespace].List(ctx, each, opts...); err != nil {
+			return err
+		}
+
+		extracted, err := meta.ExtractList(each)
+		if err != nil {
+			return err
+		}
+		items = append(items, extracted...)
+		resourceVersion = each.GetResourceVersion()
+	}
+
+	list.SetResourceVersion(resourceVersion)
+	return meta.SetList(list, items)
+}
+
+// namespacesInformer is a cache.Informer that spans the informers of one kind
+// in many namespaces. It remembers its event handlers and indexers so it can
+// add them to the informers of namespaces added later.
+type namespacesInformer struct {
+	mu        sync.Mutex
+	handlers  []func(cache.Informer)
+	indexers  []toolscache.Indexers
+	informers map[string]cache.Informer
+}
+
+var _ cache.Informer = &namespacesInformer{}
+
+// add registers the event handlers and indexers of i with informer.
+func (i *namespacesInformer) add(namespace string, informer cache.Informer) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, indexers := range i.indexers {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	for _, handler := range i.handlers {
+		handler(informer)
+	}
+	i.informers[namespace] = informer
+	return nil
+}
+
+// remove forgets the informer of namespace.
+func (i *namespacesInformer) remove(namespace string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.informers, namespace)
+}
+
+// handle calls handler with every current and future informer of i.
+func (i *namespacesInformer) handle(handler func(cache.Informer)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, informer := range i.informers {
+		handler(informer)
+	}
+	i.handlers = append(i.handlers, handler)
+}
+
+// AddEventHandler implements cache.Informer.
+func (i *namespacesInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	i.handle(func(informer cache.Informer) { informer.AddEventHandler(handler) })
+}
+
+// AddEventHandlerWithResyncPeriod implements cache.Informer.
+func (i *namespacesInformer) AddEventHandlerWithResyncPeriod(
+	handler toolscache.ResourceEventHandler, resyncPeriod time.Duration,
+) {
+	i.handle(func(informer cache.Informer) {
+		informer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	})
+}
+
+// AddIndexers implements cache.Informer.
+func (i *namespacesInformer) AddIndexers(indexers toolscache.Indexers) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, informer := range i.informers {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	i.indexers = append(i.indexers, indexers)
+	return nil
+}
+
+// HasSynced implements cache.Informer.
+func (i *namespacesInformer) HasSynced() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, informer := range i.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,238 @@
+package runtime
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+)
+
+// listingCache is a cache.Cache with fake informers that lists objects it is
+// given. It runs until stopped.
+type listingCache struct {
+	*informertest.FakeInformers
+	objects []runtime.Object
+	stopped chan struct{}
+}
+
+func newListingCache(objects ...runtime.Object) *listingCache {
+	return &listingCache{
+		FakeInformers: &informertest.FakeInformers{Scheme: scheme.Scheme},
+		objects:       objects,
+		stopped:       make(chan struct{}),
+	}
+}
+
+func (c *listingCache) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	return meta.SetList(list, c.objects)
+}
+
+func (c *listingCache) Start(ctx context.Context) error {
+	<-ctx.Done()
+	close(c.stopped)
+	return nil
+}
+
+func (c *listingCache) fakeInformer(t testing.TB, obj runtime.Object) *controllertest.FakeInformer {
+	informer, err := c.FakeInformerFor(obj)
+	assert.NilError(t, err)
+	return informer
+}
+
+func testMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	return mapper
+}
+
+// cachedNamespaces returns the namespaces that c currently caches.
+func cachedNamespaces(c *namespacesCache) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var names []string
+	for name := range c.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestNamespaces(t *testing.T) {
+	assert.Assert(t, len(Namespaces("")) == 0)
+	assert.Assert(t, len(Namespaces(" , ")) == 0)
+	assert.DeepEqual(t, Namespaces("one"), []string{"one"})
+	assert.DeepEqual(t, Namespaces("two, one,,two"), []string{"one", "two"})
+}
+
+func TestNamespacesCacheFixed(t *testing.T) {
+	ctx := context.Background()
+	cluster := newListingCache(&corev1.Namespace{})
+	caches := map[string]*listingCache{}
+
+	c, err := newNamespacesCache(cluster, testMapper(), scheme.Scheme,
+		[]string{"one", "two"}, nil,
+		func(namespace string) (cache.Cache, error) {
+			caches[namespace] = newListingCache(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "cm"},
+			})
+			return caches[namespace], nil
+		})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cachedNamespaces(c), []string{"one", "two"})
+
+	t.Run("Informer", func(t *testing.T) {
+		informer, err := c.GetInformer(ctx, &corev1.ConfigMap{})
+		assert.NilError(t, err)
+
+		var added []string
+		informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				added = append(added, obj.(*corev1.ConfigMap).Namespace)
+			},
+		})
+
+		for _, namespace := range []string{"two", "one"} {
+			caches[namespace].fakeInformer(t, &corev1.ConfigMap{}).Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			})
+		}
+		assert.DeepEqual(t, added, []string{"two", "one"})
+
+		// Cluster-scoped informers come from the cluster cache.
+		_, err = c.GetInformer(ctx, &corev1.Namespace{})
+		assert.NilError(t, err)
+		assert.Assert(t, len(cluster.InformersByGVK) == 1)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		assert.NilError(t, c.Get(ctx,
+			client.ObjectKey{Namespace: "one", Name: "cm"}, &corev1.ConfigMap{}))
+
+		err := c.Get(ctx,
+			client.ObjectKey{Namespace: "other", Name: "cm"}, &corev1.ConfigMap{})
+		assert.Assert(t, apierrors.IsNotFound(err), "got %#v", err)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		list := &corev1.ConfigMapList{}
+		assert.NilError(t, c.List(ctx, list))
+		assert.Equal(t, len(list.Items), 2)
+		assert.Equal(t, list.Items[0].Namespace, "one")
+		assert.Equal(t, list.Items[1].Namespace, "two")
+
+		assert.NilError(t, c.List(ctx, list, client.InNamespace("two")))
+		assert.Equal(t, len(list.Items), 1)
+		assert.Equal(t, list.Items[0].Namespace, "two")
+
+		assert.NilError(t, c.List(ctx, list, client.InNamespace("other")))
+		assert.Equal(t, len(list.Items), 0)
+
+		// Cluster-scoped objects are listed once.
+		namespaces := &corev1.NamespaceList{}
+		assert.NilError(t, c.List(ctx, namespaces))
+		assert.Equal(t, len(namespaces.Items), 1)
+	})
+}
+
+func TestNamespacesCacheSelector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	labeled := func(name string, color string) *corev1.Namespace {
+		namespace := &corev1.Namespace{}
+		namespace.Name = name
+		namespace.Labels = map[string]string{"tenant-group": color}
+		return namespace
+	}
+
+	cluster := newListingCache(labeled("one", "blue"), labeled("two", "red"))
+	caches := map[string]*listingCache{}
+	selector, err := labels.Parse("tenant-group=blue")
+	assert.NilError(t, err)
+
+	c, err := newNamespacesCache(cluster, testMapper(), scheme.Scheme,
+		[]string{"ignored"}, selector,
+		func(namespace string) (cache.Cache, error) {
+			caches[namespace] = newListingCache()
+			return caches[namespace], nil
+		})
+	assert.NilError(t, err)
+	assert.Assert(t, len(cachedNamespaces(c)) == 0)
+
+	// Informers and their handlers apply to namespaces selected later.
+	informer, err := c.GetInformer(ctx, &corev1.ConfigMap{})
+	assert.NilError(t, err)
+
+	var added []string
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			added = append(added, obj.(*corev1.ConfigMap).Namespace)
+		},
+	})
+
+	go func() { assert.Check(t, c.Start(ctx)) }()
+
+	timeout, done := context.WithTimeout(ctx, 5*time.Second)
+	defer done()
+	assert.Assert(t, c.WaitForCacheSync(timeout))
+	assert.DeepEqual(t, cachedNamespaces(c), []string{"one"})
+
+	namespaces := cluster.fakeInformer(t, &corev1.Namespace{})
+
+	// Labeling a namespace selects it.
+	namespaces.Update(labeled("two", "red"), labeled("two", "blue"))
+	assert.DeepEqual(t, cachedNamespaces(c), []string{"one", "two"})
+
+	caches["two"].fakeInformer(t, &corev1.ConfigMap{}).Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "two"},
+	})
+	assert.DeepEqual(t, added, []string{"two"})
+
+	// Relabeling or deleting a namespace stops its cache.
+	namespaces.Update(labeled("two", "blue"), labeled("two", "green"))
+	namespaces.Delete(labeled("one", "blue"))
+	assert.Assert(t, len(cachedNamespaces(c)) == 0)
+
+	for _, namespace := range []string{"one", "two"} {
+		select {
+		case <-caches[namespace].stopped:
+		case <-timeout.Done():
+			t.Fatalf("expected %q to stop", namespace)
+		}
+	}
+
+	// New namespaces are selected.
+	namespaces.Add(labeled("three", "blue"))
+	assert.DeepEqual(t, cachedNamespaces(c), []string{"three"})
+}
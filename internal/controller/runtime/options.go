@@ -0,0 +1,199 @@
+package runtime
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Option changes the options of a manager before it is created.
+type Option func(*manager.Options)
+
+const (
+	// defaultHealthProbeBindAddress is where the manager serves health probes
+	// when they are enabled.
+	defaultHealthProbeBindAddress = ":8081"
+
+	// defaultLeaderElectionID is the name of the Lease that operator replicas
+	// compete for.
+	defaultLeaderElectionID = "postgres-operator-leader"
+)
+
+// +kubebuilder:rbac:groups="coordination.k8s.io",resources="leases",verbs={get,create,update}
+
+// FromEnv returns an Option that configures health probes, leader election,
+// the webhook server, and the namespaces to watch using getenv:
+//
+//   - PGO_HEALTH_PROBE_BIND_ADDRESS is where health probes are served.
+//     Defaults to ":8081"; "0" disables them.
+//   - PGO_LEADER_ELECTION enables Lease-based leader election when "true".
+//   - PGO_LEADER_ELECTION_ID is the name of the Lease.
+//   - PGO_LEADER_ELECTION_NAMESPACE is the namespace of the Lease. Defaults
+//     to PGO_NAMESPACE, then the namespace of the operator Pod.
+//   - PGO_LEADER_ELECTION_LEASE_DURATION, PGO_LEADER_ELECTION_RENEW_DEADLINE,
+//     and PGO_LEADER_ELECTION_RETRY_PERIOD are durations such as "15s".
+//   - PGO_WEBHOOK_CERT_DIR is the directory of the "tls.crt" and "tls.key"
+//     files of the webhook server.
+//   - PGO_WEBHOOK_PORT is the port of the webhook server. Defaults to 9443.
+//   - PGO_TARGET_NAMESPACE_SELECTOR is a label selector of the namespaces to
+//     watch, such as "tenant-group=blue". Namespaces are added and removed as
+//     their labels change. It cannot be combined with PGO_TARGET_NAMESPACE.
+func FromEnv(getenv func(string) string) (Option, error) {
+	probes := getenv("PGO_HEALTH_PROBE_BIND_ADDRESS")
+	if probes == "" {
+		probes = defaultHealthProbeBindAddress
+	}
+
+	enabled := strings.EqualFold(getenv("PGO_LEADER_ELECTION"), "true")
+	id := getenv("PGO_LEADER_ELECTION_ID")
+	if id == "" {
+		id = defaultLeaderElectionID
+	}
+	namespace := getenv("PGO_LEADER_ELECTION_NAMESPACE")
+	if namespace == "" {
+		namespace = getenv("PGO_NAMESPACE")
+	}
+
+	var webhookPort int
+	if value := getenv("PGO_WEBHOOK_PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, errors.Errorf("invalid PGO_WEBHOOK_PORT: %q", value)
+		}
+		webhookPort = port
+	}
+	webhookCertDir := getenv("PGO_WEBHOOK_CERT_DIR")
+
+	var selector labels.Selector
+	if value := getenv("PGO_TARGET_NAMESPACE_SELECTOR"); value != "" {
+		if getenv("PGO_TARGET_NAMESPACE") != "" {
+			return nil, errors.New(
+				"PGO_TARGET_NAMESPACE and PGO_TARGET_NAMESPACE_SELECTOR cannot both be set")
+		}
+
+		var err error
+		if selector, err = labels.Parse(value); err != nil {
+			return nil, errors.Wrap(err, "invalid PGO_TARGET_NAMESPACE_SELECTOR")
+		}
+	}
+
+	// These are the defaults of the leaderelection package.
+	lease, renew, retry := 15*time.Second, 10*time.Second, 2*time.Second
+
+	for _, setting := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"PGO_LEADER_ELECTION_LEASE_DURATION", &lease},
+		{"PGO_LEADER_ELECTION_RENEW_DEADLINE", &renew},
+		{"PGO_LEADER_ELECTION_RETRY_PERIOD", &retry},
+	} {
+		if value := getenv(setting.name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", setting.name)
+			}
+			if d <= 0 {
+				return nil, errors.Errorf("invalid %s: must be positive", setting.name)
+			}
+			*setting.value = d
+		}
+	}
+
+	// The leader must renew its Lease before it expires, and it must be able
+	// to retry within that time.
+	if renew >= lease {
+		return nil, errors.Errorf(
+			"leader election renew deadline (%s) must be less than its lease duration (%s)",
+			renew, lease)
+	}
+	if retry >= renew {
+		return nil, errors.Errorf(
+			"leader election retry period (%s) must be less than its renew deadline (%s)",
+			retry, renew)
+	}
+
+	return func(options *manager.Options) {
+		if options.HealthProbeBindAddress != "0" {
+			options.HealthProbeBindAddress = probes
+		}
+
+		options.LeaderElection = enabled
+		options.LeaderElectionID = id
+		options.LeaderElectionNamespace = namespace
+		options.LeaderElectionResourceLock = resourcelock.LeasesResourceLock
+
+		// The operator exits as soon as the manager stops, so the leader can
+		// release its Lease rather than wait for it to expire.
+		options.LeaderElectionReleaseOnCancel = true
+
+		options.LeaseDuration, options.RenewDeadline, options.RetryPeriod = &lease, &renew, &retry
+
+		if webhookPort != 0 {
+			options.Port = webhookPort
+		}
+		if webhookCertDir != "" {
+			options.CertDir = webhookCertDir
+		}
+		if selector != nil {
+			options.Namespace = ""
+			options.NewCache = NamespacesCacheBuilder(nil, selector)
+		}
+	}, nil
+}
+
+// NamespaceScoped returns whether or not the operator watches only some
+// namespaces according to PGO_TARGET_NAMESPACE or PGO_TARGET_NAMESPACE_SELECTOR
+// in getenv. The operator cannot watch cluster-scoped objects then.
+func NamespaceScoped(getenv func(string) string) bool {
+	return getenv("PGO_TARGET_NAMESPACE") != "" ||
+		getenv("PGO_TARGET_NAMESPACE_SELECTOR") != ""
+}
+
+// AddHealthChecks adds liveness and readiness checks to mgr. The operator is
+// live while it serves the probes, and it is ready once its caches are
+// filled. Replicas that are not the leader are ready, too.
+func AddHealthChecks(mgr manager.Manager) error {
+	err := mgr.AddHealthzCheck("ping", healthz.Ping)
+	if err == nil {
+		err = mgr.AddReadyzCheck("caches", cachesSynced(mgr))
+	}
+	return err
+}
+
+// cachesSynced returns a healthz.Checker that fails until the caches of mgr
+// have synced.
+func cachesSynced(mgr manager.Manager) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), time.Second)
+		defer cancel()
+
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return errors.New("caches have not synced")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,219 @@
+package runtime
+
+/*
+Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+func TestFromEnv(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		option, err := FromEnv(env(nil))
+		assert.NilError(t, err)
+
+		var options manager.Options
+		option(&options)
+
+		assert.Equal(t, options.HealthProbeBindAddress, ":8081")
+		assert.Assert(t, !options.LeaderElection)
+		assert.Equal(t, options.LeaderElectionID, "postgres-operator-leader")
+		assert.Equal(t, options.LeaderElectionNamespace, "")
+		assert.Equal(t, options.LeaderElectionResourceLock, "leases")
+		assert.Assert(t, options.LeaderElectionReleaseOnCancel)
+		assert.Equal(t, *options.LeaseDuration, 15*time.Second)
+		assert.Equal(t, *options.RenewDeadline, 10*time.Second)
+		assert.Equal(t, *options.RetryPeriod, 2*time.Second)
+		assert.Equal(t, options.Port, 0)
+		assert.Equal(t, options.CertDir, "")
+		assert.Assert(t, options.NewCache == nil)
+	})
+
+	t.Run("NamespaceSelector", func(t *testing.T) {
+		option, err := FromEnv(env(map[string]string{
+			"PGO_TARGET_NAMESPACE_SELECTOR": "tenant-group=blue",
+		}))
+		assert.NilError(t, err)
+
+		options := manager.Options{Namespace: "ignored"}
+		option(&options)
+		assert.Equal(t, options.Namespace, "")
+		assert.Assert(t, options.NewCache != nil)
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		option, err := FromEnv(env(map[string]string{
+			"PGO_WEBHOOK_CERT_DIR": "/pgo/webhook",
+			"PGO_WEBHOOK_PORT":     "8443",
+		}))
+		assert.NilError(t, err)
+
+		var options manager.Options
+		option(&options)
+		assert.Equal(t, options.CertDir, "/pgo/webhook")
+		assert.Equal(t, options.Port, 8443)
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		option, err := FromEnv(env(map[string]string{
+			"PGO_NAMESPACE":                      "pgo",
+			"PGO_HEALTH_PROBE_BIND_ADDRESS":      ":9000",
+			"PGO_LEADER_ELECTION":                "True",
+			"PGO_LEADER_ELECTION_ID":             "custom",
+			"PGO_LEADER_ELECTION_LEASE_DURATION": "1m",
+			"PGO_LEADER_ELECTION_RENEW_DEADLINE": "40s",
+			"PGO_LEADER_ELECTION_RETRY_PERIOD":   "5s",
+		}))
+		assert.NilError(t, err)
+
+		var options manager.Options
+		option(&options)
+
+		assert.Equal(t, options.HealthProbeBindAddress, ":9000")
+		assert.Assert(t, options.LeaderElection)
+		assert.Equal(t, options.LeaderElectionID, "custom")
+		assert.Equal(t, options.LeaderElectionNamespace, "pgo")
+		assert.Equal(t, *options.LeaseDuration, time.Minute)
+		assert.Equal(t, *options.RenewDeadline, 40*time.Second)
+		assert.Equal(t, *options.RetryPeriod, 5*time.Second)
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		option, err := FromEnv(env(map[string]string{
+			"PGO_NAMESPACE":                 "pgo",
+			"PGO_LEADER_ELECTION_NAMESPACE": "elsewhere",
+		}))
+		assert.NilError(t, err)
+
+		var options manager.Options
+		option(&options)
+		assert.Equal(t, options.LeaderElectionNamespace, "elsewhere")
+	})
+
+	t.Run("ProbesDisabled", func(t *testing.T) {
+		option, err := FromEnv(env(nil))
+		assert.NilError(t, err)
+
+		options := manager.Options{HealthProbeBindAddress: "0"}
+		option(&options)
+		assert.Equal(t, options.HealthProbeBindAddress, "0")
+	})
+
+	for _, tt := range []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{
+			name:     "WebhookPort",
+			env:      map[string]string{"PGO_WEBHOOK_PORT": "99999"},
+			expected: `invalid PGO_WEBHOOK_PORT: "99999"`,
+		},
+		{
+			name: "NamespaceAndSelector",
+			env: map[string]string{
+				"PGO_TARGET_NAMESPACE":          "one",
+				"PGO_TARGET_NAMESPACE_SELECTOR": "tenant-group=blue",
+			},
+			expected: "cannot both be set",
+		},
+		{
+			name:     "NamespaceSelector",
+			env:      map[string]string{"PGO_TARGET_NAMESPACE_SELECTOR": "tenant-group in blue"},
+			expected: "invalid PGO_TARGET_NAMESPACE_SELECTOR",
+		},
+		{
+			name:     "Unparsable",
+			env:      map[string]string{"PGO_LEADER_ELECTION_RETRY_PERIOD": "often"},
+			expected: "invalid PGO_LEADER_ELECTION_RETRY_PERIOD",
+		},
+		{
+			name:     "Negative",
+			env:      map[string]string{"PGO_LEADER_ELECTION_LEASE_DURATION": "-1s"},
+			expected: "must be positive",
+		},
+		{
+			name:     "RenewAfterLease",
+			env:      map[string]string{"PGO_LEADER_ELECTION_LEASE_DURATION": "10s"},
+			expected: "renew deadline (10s) must be less than its lease duration (10s)",
+		},
+		{
+			name:     "RetryAfterRenew",
+			env:      map[string]string{"PGO_LEADER_ELECTION_RETRY_PERIOD": "30s"},
+			expected: "retry period (30s) must be less than its renew deadline (10s)",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromEnv(env(tt.env))
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestNamespaceScoped(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	assert.Assert(t, !NamespaceScoped(env(nil)))
+	assert.Assert(t, NamespaceScoped(env(map[string]string{
+		"PGO_TARGET_NAMESPACE": "one,two",
+	})))
+	assert.Assert(t, NamespaceScoped(env(map[string]string{
+		"PGO_TARGET_NAMESPACE_SELECTOR": "tenant-group=blue",
+	})))
+}
+
+// syncingCache is a cache.Cache that has or has not synced.
+type syncingCache struct {
+	cache.Cache
+	synced bool
+}
+
+func (c syncingCache) WaitForCacheSync(ctx context.Context) bool {
+	if !c.synced {
+		<-ctx.Done()
+	}
+	return c.synced
+}
+
+// cachingManager is a manager.Manager with a cache.
+type cachingManager struct {
+	manager.Manager
+	cache cache.Cache
+}
+
+func (m cachingManager) GetCache() cache.Cache { return m.cache }
+
+func TestCachesSynced(t *testing.T) {
+	request := httptest.NewRequest("GET", "/readyz", nil)
+
+	check := cachesSynced(cachingManager{cache: syncingCache{synced: true}})
+	assert.NilError(t, check(request))
+
+	check = cachesSynced(cachingManager{cache: syncingCache{synced: false}})
+	assert.ErrorContains(t, check(request), "not synced")
+}
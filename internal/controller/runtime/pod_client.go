@@ -13,7 +13,7 @@
  limitations under the License.
 */
 
-package postgrescluster
+package runtime
 
 import (
 	"io"
@@ -26,9 +26,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
-// podExecutor runs command on container in pod in namespace. Non-nil streams
+// PodExecutor runs command on container in pod in namespace. Non-nil streams
 // (stdin, stdout, and stderr) are attached the to the remote process.
-type podExecutor func(
+type PodExecutor func(
 	namespace, pod, container string,
 	stdin io.Reader, stdout, stderr io.Writer, command ...string,
 ) error
@@ -41,7 +41,9 @@ func newPodClient(config *rest.Config) (rest.Interface, error) {
 
 // +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
 
-func newPodExecutor(config *rest.Config) (podExecutor, error) {
+// NewPodExecutor returns a PodExecutor that calls the "exec" subresource of
+// pods using config.
+func NewPodExecutor(config *rest.Config) (PodExecutor, error) {
 	client, err := newPodClient(config)
 
 	return func(
@@ -34,9 +34,10 @@ var refreshInterval = 60 * time.Minute
 // manager returned is configured specifically for the PostgreSQL Operator, and includes any
 // controllers that will be responsible for managing PostgreSQL clusters using the
 // 'postgrescluster' custom resource.  Additionally, the manager will only watch for resources in
-// the namespace specified, with an empty string resulting in the manager watching all namespaces.
+// the namespaces specified as a comma-separated list, with an empty string resulting in the
+// manager watching all namespaces.  Any opts are applied in order after the defaults.
 func CreateRuntimeManager(namespace string, config *rest.Config,
-	disableMetrics bool) (manager.Manager, error) {
+	disableMetrics bool, opts ...Option) (manager.Manager, error) {
 
 	pgoScheme, err := CreatePostgresOperatorScheme()
 	if err != nil {
@@ -44,14 +45,25 @@ func CreateRuntimeManager(namespace string, config *rest.Config,
 	}
 
 	options := manager.Options{
-		Namespace:  namespace, // if empty then watching all namespaces
 		SyncPeriod: &refreshInterval,
 		Scheme:     pgoScheme,
 	}
+
+	// if empty then watching all namespaces
+	switch namespaces := Namespaces(namespace); len(namespaces) {
+	case 0:
+	case 1:
+		options.Namespace = namespaces[0]
+	default:
+		options.NewCache = NamespacesCacheBuilder(namespaces, nil)
+	}
 	if disableMetrics {
 		options.HealthProbeBindAddress = "0"
 		options.MetricsBindAddress = "0"
 	}
+	for _, opt := range opts {
+		opt(&options)
+	}
 
 	// create controller runtime manager
 	mgr, err := manager.New(config, options)
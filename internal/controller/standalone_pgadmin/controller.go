@@ -0,0 +1,209 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package standalone_pgadmin
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// ControllerName is the name of the PGAdmin controller
+	ControllerName = "pgadmin-controller"
+)
+
+// PGAdminReconciler reconciles a PGAdmin object
+type PGAdminReconciler struct {
+	Client      client.Client
+	Owner       client.FieldOwner
+	Recorder    record.EventRecorder
+	IsOpenShift bool
+
+	// NamespaceScoped is true when the operator watches one namespace with
+	// the permissions of a Role. It cannot read Namespaces then.
+	NamespaceScoped bool
+
+	PodExec func(
+		namespace, pod, container string,
+		stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error
+}
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=pgadmins,verbs=get;list;watch
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=pgadmins/status,verbs=patch
+
+// Reconcile runs one pgAdmin for each PGAdmin and registers the
+// PostgresClusters it selects as servers in pgAdmin.
+func (r *PGAdminReconciler) Reconcile(
+	ctx context.Context, request reconcile.Request) (reconcile.Result, error,
+) {
+	log := logging.FromContext(ctx)
+
+	pgAdmin := &v1beta1.PGAdmin{}
+	if err := r.Client.Get(ctx, request.NamespacedName, pgAdmin); err != nil {
+		// NotFound cannot be fixed by requeuing so ignore it. During background
+		// deletion, we receive delete events from pgAdmin's dependents after
+		// pgAdmin is deleted.
+		if err = client.IgnoreNotFound(err); err != nil {
+			log.Error(err, "unable to fetch PGAdmin")
+		}
+		return reconcile.Result{}, err
+	}
+
+	// Set any defaults that may not have been stored in the API. No DeepCopy
+	// is necessary because controller-runtime makes a copy before returning
+	// from its cache.
+	pgAdmin.Default()
+
+	// Keep a copy of pgAdmin prior to any manipulations.
+	before := pgAdmin.DeepCopy()
+
+	// Nothing to do when pgAdmin is being deleted. Kubernetes garbage
+	// collects the objects it controls.
+	if pgAdmin.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+
+	var (
+		configmap  *corev1.ConfigMap
+		dataVolume *corev1.PersistentVolumeClaim
+		secret     *corev1.Secret
+		servers    []serverGroup
+		result     reconcile.Result
+		err        error
+	)
+
+	servers, err = r.findServers(ctx, pgAdmin)
+
+	if err == nil {
+		_, err = r.reconcileService(ctx, pgAdmin)
+	}
+	if err == nil {
+		configmap, err = r.reconcileConfigMap(ctx, pgAdmin)
+	}
+	if err == nil {
+		secret, err = r.reconcileLoginSecret(ctx, pgAdmin)
+	}
+	if err == nil {
+		dataVolume, err = r.reconcileDataVolume(ctx, pgAdmin)
+	}
+	if err == nil {
+		err = r.reconcileStatefulSet(ctx, pgAdmin, configmap, secret, dataVolume)
+	}
+	if err == nil {
+		result, err = r.reconcileServers(ctx, pgAdmin, servers)
+	}
+	if err == nil {
+		pgAdmin.Status.ObservedGeneration = pgAdmin.GetGeneration()
+	}
+
+	if !equality.Semantic.DeepEqual(before.Status, pgAdmin.Status) {
+		// NOTE(cbandy): Kubernetes prior to v1.16.10 and v1.17.6 does not track
+		// managed fields on the status subresource: https://issue.k8s.io/88901
+		if patchErr := errors.WithStack(r.Client.Status().Patch(
+			ctx, pgAdmin, client.MergeFrom(before), r.Owner)); patchErr != nil {
+			log.Error(patchErr, "patching PGAdmin status")
+			if err == nil {
+				err = patchErr
+			}
+		}
+	}
+
+	if err != nil {
+		log.Error(err, "reconciling PGAdmin")
+	}
+	return result, err
+}
+
+// apply sends an apply patch to object's endpoint in the Kubernetes API and
+// updates object with any returned content. The fieldManager is set to
+// r.Owner and the force parameter is true.
+// - https://docs.k8s.io/reference/using-api/server-side-apply/#managers
+// - https://docs.k8s.io/reference/using-api/server-side-apply/#conflicts
+func (r *PGAdminReconciler) apply(ctx context.Context, object client.Object) error {
+	// Generate an apply-patch by comparing the object to its zero value.
+	zero := reflect.New(reflect.TypeOf(object).Elem()).Interface()
+	data, err := client.MergeFrom(zero.(client.Object)).Data(object)
+	apply := client.RawPatch(client.Apply.Type(), data)
+
+	// Send the apply-patch with force=true.
+	if err == nil {
+		err = r.Client.Patch(ctx, object, apply, r.Owner, client.ForceOwnership)
+	}
+	return err
+}
+
+// The owner reference created by controllerutil.SetControllerReference blocks
+// deletion. The OwnerReferencesPermissionEnforcement plugin requires that the
+// creator of such a reference have either "delete" permission on the owner or
+// "update" permission on the owner's "finalizers" subresource.
+// - https://docs.k8s.io/reference/access-authn-authz/admission-controllers/
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=pgadmins/finalizers,verbs=update
+
+// setControllerReference sets owner as a Controller OwnerReference on controlled.
+func (r *PGAdminReconciler) setControllerReference(
+	owner *v1beta1.PGAdmin, controlled client.Object,
+) error {
+	return controllerutil.SetControllerReference(owner, controlled, r.Client.Scheme())
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=postgresclusters,verbs=get;list;watch
+
+// SetupWithManager adds the PGAdmin controller to the provided runtime manager
+func (r *PGAdminReconciler) SetupWithManager(mgr manager.Manager) error {
+	if r.PodExec == nil {
+		var err error
+		r.PodExec, err = runtime.NewPodExecutor(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		For(&v1beta1.PGAdmin{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&corev1.Secret{}).
+		Owns(&corev1.Service{}).
+		Owns(&appsv1.StatefulSet{}).
+		Watches(&source.Kind{Type: &v1beta1.PostgresCluster{}},
+			handler.EnqueueRequestsFromMapFunc(r.watchPostgresClusters())).
+		Complete(r)
+}
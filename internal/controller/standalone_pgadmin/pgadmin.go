@@ -0,0 +1,514 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package standalone_pgadmin
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/pgadmin"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// serverGroup is a server group of pgAdmin and the PostgresClusters it selects.
+type serverGroup struct {
+	name     string
+	clusters []*v1beta1.PostgresCluster
+}
+
+// objectLabels returns the labels of every object that runs pgAdmin.
+func objectLabels(pgAdmin *v1beta1.PGAdmin) map[string]string {
+	return naming.Merge(
+		pgAdmin.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelStandalonePGAdmin: pgAdmin.Name,
+			naming.LabelRole:              naming.RolePGAdmin,
+		})
+}
+
+// +kubebuilder:rbac:groups="",resources="namespaces",verbs={get,list,watch}
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources="postgresclusters",verbs={list}
+
+// findServers returns the PostgresClusters selected by each server group of
+// pgAdmin, sorted by namespace and name. It also records them in the status
+// of pgAdmin. Namespace selectors need the ClusterRole of the operator; when
+// the operator is namespace-scoped, they are ignored. That is noted in the
+// status of the group and in an event when it first happens.
+func (r *PGAdminReconciler) findServers(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin,
+) ([]serverGroup, error) {
+	groups := make([]serverGroup, 0, len(pgAdmin.Spec.ServerGroups))
+	status := make([]v1beta1.PGAdminServerGroupStatus, 0, len(pgAdmin.Spec.ServerGroups))
+
+	previouslyIgnored := make(map[string]bool)
+	for _, group := range pgAdmin.Status.ServerGroups {
+		previouslyIgnored[group.Name] = group.NamespaceSelectorIgnored
+	}
+
+	for i := range pgAdmin.Spec.ServerGroups {
+		spec := pgAdmin.Spec.ServerGroups[i]
+		group := serverGroup{name: spec.Name}
+
+		// A nil namespace selector means the namespace of pgAdmin.
+		namespaces := []string{pgAdmin.Namespace}
+		ignored := spec.NamespaceSelector != nil && r.NamespaceScoped
+		if ignored && !previouslyIgnored[spec.Name] {
+			r.Recorder.Eventf(pgAdmin, corev1.EventTypeWarning, "NamespaceSelectorIgnored",
+				"Server group %q looks only in namespace %q; namespaceSelector "+
+					"requires the operator to be installed for the whole cluster",
+				spec.Name, pgAdmin.Namespace)
+		}
+		if spec.NamespaceSelector != nil && !r.NamespaceScoped {
+			selector, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+			list := &corev1.NamespaceList{}
+
+			if err == nil {
+				err = errors.WithStack(r.Client.List(ctx, list,
+					client.MatchingLabelsSelector{Selector: selector}))
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			namespaces = namespaces[:0]
+			for j := range list.Items {
+				namespaces = append(namespaces, list.Items[j].Name)
+			}
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&spec.PostgresClusterSelector)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, namespace := range namespaces {
+			list := &v1beta1.PostgresClusterList{}
+			if err := errors.WithStack(r.Client.List(ctx, list,
+				client.InNamespace(namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			)); err != nil {
+				return nil, err
+			}
+			for j := range list.Items {
+				// Skip clusters that are being deleted.
+				if list.Items[j].DeletionTimestamp == nil {
+					cluster := list.Items[j]
+					cluster.Default()
+					group.clusters = append(group.clusters, &cluster)
+				}
+			}
+		}
+
+		sort.Slice(group.clusters, func(a, b int) bool {
+			if group.clusters[a].Namespace != group.clusters[b].Namespace {
+				return group.clusters[a].Namespace < group.clusters[b].Namespace
+			}
+			return group.clusters[a].Name < group.clusters[b].Name
+		})
+
+		names := make([]string, 0, len(group.clusters))
+		for _, cluster := range group.clusters {
+			names = append(names, cluster.Namespace+"/"+cluster.Name)
+		}
+
+		groups = append(groups, group)
+		status = append(status, v1beta1.PGAdminServerGroupStatus{
+			Name: group.name, Clusters: names, NamespaceSelectorIgnored: ignored,
+		})
+	}
+
+	pgAdmin.Status.ServerGroups = status
+	if len(status) == 0 {
+		pgAdmin.Status.ServerGroups = nil
+	}
+
+	return groups, nil
+}
+
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources="pgadmins",verbs={list}
+
+// watchPostgresClusters returns a handler.MapFunc that queues every PGAdmin
+// that might select a PostgresCluster.
+func (r *PGAdminReconciler) watchPostgresClusters() handler.MapFunc {
+	return func(cluster client.Object) []reconcile.Request {
+		ctx := context.Background()
+		log := logging.FromContext(ctx)
+
+		list := &v1beta1.PGAdminList{}
+		if err := r.Client.List(ctx, list); err != nil {
+			log.Error(err, "unable to list PGAdmins")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range list.Items {
+			if selectsCluster(&list.Items[i], cluster) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&list.Items[i]),
+				})
+			}
+		}
+		return requests
+	}
+}
+
+// selectsCluster returns true when the labels of cluster match any server
+// group of pgAdmin. The namespace selector is not checked because the labels
+// of the namespace are not at hand; a PGAdmin that does not select the
+// namespace of cluster is reconciled without consequence.
+func selectsCluster(pgAdmin *v1beta1.PGAdmin, cluster client.Object) bool {
+	for i := range pgAdmin.Spec.ServerGroups {
+		spec := pgAdmin.Spec.ServerGroups[i]
+
+		if spec.NamespaceSelector == nil && pgAdmin.Namespace != cluster.GetNamespace() {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&spec.PostgresClusterSelector)
+		if err == nil && selector.Matches(labels.Set(cluster.GetLabels())) {
+			return true
+		}
+	}
+	return false
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={create,patch}
+
+// reconcileConfigMap writes the ConfigMap for pgAdmin.
+func (r *PGAdminReconciler) reconcileConfigMap(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin,
+) (*corev1.ConfigMap, error) {
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	configmap.Annotations = pgAdmin.Spec.Metadata.GetAnnotationsOrNil()
+	configmap.Labels = objectLabels(pgAdmin)
+
+	err := errors.WithStack(pgadmin.StandaloneConfigMap(pgAdmin, configmap))
+	if err == nil {
+		err = errors.WithStack(r.setControllerReference(pgAdmin, configmap))
+	}
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, configmap))
+	}
+	return configmap, err
+}
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={create,patch}
+
+// reconcileLoginSecret writes the Secret that holds the login of the pgAdmin
+// administrator.
+func (r *PGAdminReconciler) reconcileLoginSecret(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin,
+) (*corev1.Secret, error) {
+	existing := &corev1.Secret{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+	secret.Annotations = pgAdmin.Spec.Metadata.GetAnnotationsOrNil()
+	secret.Labels = objectLabels(pgAdmin)
+	secret.Type = corev1.SecretTypeOpaque
+
+	service := naming.StandalonePGAdmin(pgAdmin)
+	err = errors.WithStack(pgadmin.StandaloneLoginSecret(existing, secret,
+		service.Name+"."+service.Namespace+".svc"))
+
+	if err == nil {
+		err = errors.WithStack(r.setControllerReference(pgAdmin, secret))
+	}
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, secret))
+	}
+	return secret, err
+}
+
+// +kubebuilder:rbac:groups="",resources="services",verbs={create,patch}
+
+// reconcileService writes the Service that resolves to pgAdmin.
+func (r *PGAdminReconciler) reconcileService(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin,
+) (*corev1.Service, error) {
+	service := &corev1.Service{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	service.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+
+	service.Annotations = pgAdmin.Spec.Metadata.GetAnnotationsOrNil()
+	service.Labels = objectLabels(pgAdmin)
+
+	// Allocate an IP address and/or node port and let Kubernetes manage the
+	// Endpoints by selecting Pods of this pgAdmin.
+	// - https://docs.k8s.io/concepts/services-networking/service/#defining-a-service
+	service.Spec.Selector = naming.StandalonePGAdminSelector(pgAdmin.Name).MatchLabels
+	if spec := pgAdmin.Spec.Service; spec != nil {
+		service.Spec.Type = corev1.ServiceType(spec.Type)
+	} else {
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+	}
+
+	// The TargetPort must be the name (not the number) of the pgAdmin
+	// ContainerPort. This name allows the port number to differ between Pods,
+	// which can happen during a rolling update.
+	service.Spec.Ports = []corev1.ServicePort{{
+		Name:       naming.PortPGAdmin,
+		Port:       *initialize.Int32(5050),
+		Protocol:   corev1.ProtocolTCP,
+		TargetPort: intstr.FromString(naming.PortPGAdmin),
+	}}
+
+	err := errors.WithStack(r.setControllerReference(pgAdmin, service))
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, service))
+	}
+	return service, err
+}
+
+// +kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={create,patch}
+
+// reconcileDataVolume writes the PersistentVolumeClaim for pgAdmin data.
+func (r *PGAdminReconciler) reconcileDataVolume(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin,
+) (*corev1.PersistentVolumeClaim, error) {
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	pvc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
+
+	pvc.Annotations = pgAdmin.Spec.Metadata.GetAnnotationsOrNil()
+	pvc.Labels = naming.Merge(objectLabels(pgAdmin), map[string]string{
+		naming.LabelData: naming.DataPGAdmin,
+	})
+	pvc.Spec = pgAdmin.Spec.DataVolumeClaimSpec
+
+	err := errors.WithStack(r.setControllerReference(pgAdmin, pvc))
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, pvc))
+	}
+	return pvc, err
+}
+
+// +kubebuilder:rbac:groups=apps,resources="statefulsets",verbs={create,patch}
+
+// reconcileStatefulSet writes the StatefulSet that runs pgAdmin.
+func (r *PGAdminReconciler) reconcileStatefulSet(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin,
+	configmap *corev1.ConfigMap, secret *corev1.Secret,
+	dataVolume *corev1.PersistentVolumeClaim,
+) error {
+	sts := generateStatefulSet(pgAdmin, configmap, secret, dataVolume)
+
+	// OpenShift assigns a filesystem group based on a SecurityContextConstraint.
+	// Otherwise, set a filesystem group so pgAdmin can write to its volume
+	// regardless of the UID or GID of its container.
+	if !r.IsOpenShift {
+		sts.Spec.Template.Spec.SecurityContext.FSGroup = initialize.Int64(26)
+	}
+
+	err := errors.WithStack(r.setControllerReference(pgAdmin, sts))
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, sts))
+	}
+	return err
+}
+
+// generateStatefulSet returns the StatefulSet that runs pgAdmin.
+func generateStatefulSet(
+	pgAdmin *v1beta1.PGAdmin,
+	configmap *corev1.ConfigMap, secret *corev1.Secret,
+	dataVolume *corev1.PersistentVolumeClaim,
+) *appsv1.StatefulSet {
+	sts := &appsv1.StatefulSet{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	sts.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("StatefulSet"))
+
+	sts.Annotations = pgAdmin.Spec.Metadata.GetAnnotationsOrNil()
+	sts.Labels = objectLabels(pgAdmin)
+
+	selector := naming.StandalonePGAdminSelector(pgAdmin.Name)
+	sts.Spec.Selector = &selector
+	sts.Spec.Template.Annotations = pgAdmin.Spec.Metadata.GetAnnotationsOrNil()
+	sts.Spec.Template.Labels = naming.Merge(objectLabels(pgAdmin), map[string]string{
+		naming.LabelData: naming.DataPGAdmin,
+	})
+
+	// pgAdmin keeps its configuration in a SQLite database on one volume, so
+	// there is only ever one Pod.
+	sts.Spec.Replicas = initialize.Int32(1)
+	sts.Spec.ServiceName = naming.StandalonePGAdmin(pgAdmin).Name
+
+	// Don't clutter the namespace with extra ControllerRevisions.
+	sts.Spec.RevisionHistoryLimit = initialize.Int32(0)
+
+	// Every pod of the StatefulSet is deleted and recreated when the Pod
+	// template changes.
+	// - https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#rolling-updates
+	sts.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+
+	// Use scheduling constraints from the PGAdmin spec.
+	sts.Spec.Template.Spec.Affinity = pgAdmin.Spec.Affinity
+	sts.Spec.Template.Spec.Tolerations = pgAdmin.Spec.Tolerations
+
+	if pgAdmin.Spec.PriorityClassName != nil {
+		sts.Spec.Template.Spec.PriorityClassName = *pgAdmin.Spec.PriorityClassName
+	}
+
+	// Restart containers any time they stop, die, are killed, etc.
+	// - https://docs.k8s.io/concepts/workloads/pods/pod-lifecycle/#restart-policy
+	sts.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+
+	// pgAdmin does not make any Kubernetes API calls. Use the default
+	// ServiceAccount and do not mount its credentials.
+	sts.Spec.Template.Spec.AutomountServiceAccountToken = initialize.Bool(false)
+
+	// Do not add environment variables describing services in this namespace.
+	sts.Spec.Template.Spec.EnableServiceLinks = initialize.Bool(false)
+
+	sts.Spec.Template.Spec.SecurityContext = initialize.RestrictedPodSecurityContext()
+
+	// set the image pull secrets, if any exist
+	sts.Spec.Template.Spec.ImagePullSecrets = pgAdmin.Spec.ImagePullSecrets
+
+	pgadmin.StandalonePod(pgAdmin, configmap, secret, &sts.Spec.Template.Spec, dataVolume)
+
+	return sts
+}
+
+// +kubebuilder:rbac:groups="",resources="pods",verbs={get}
+
+// reconcileServers registers the PostgresClusters of groups as servers in
+// pgAdmin once its container is running. It requeues pgAdmin while any user in
+// the spec does not yet exist in pgAdmin.
+func (r *PGAdminReconciler) reconcileServers(
+	ctx context.Context, pgAdmin *v1beta1.PGAdmin, groups []serverGroup,
+) (reconcile.Result, error) {
+	const container = naming.ContainerPGAdmin
+
+	// Find the running pgAdmin container. When there is none, return early.
+
+	pod := &corev1.Pod{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	pod.Name += "-0"
+
+	err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(pod), pod))
+	if err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var running bool
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			running = status.State.Running != nil
+		}
+	}
+	if terminating := pod.DeletionTimestamp != nil; !running || terminating {
+		return reconcile.Result{}, nil
+	}
+
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues("pod", pod.Name))
+	podExecutor := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		return r.PodExec(pod.Namespace, pod.Name, container, stdin, stdout, stderr, command...)
+	}
+
+	// Calculate a hash of the commands that should be executed in pgAdmin.
+
+	names := make([]string, 0, len(groups))
+	var servers []pgadmin.Server
+	for _, group := range groups {
+		names = append(names, group.name)
+		for _, cluster := range group.clusters {
+			servers = append(servers, pgadmin.Server{Group: group.name, Cluster: cluster})
+		}
+	}
+
+	users := make([]string, 0, len(pgAdmin.Spec.Users))
+	for _, user := range pgAdmin.Spec.Users {
+		users = append(users, user.Username)
+	}
+
+	// Groups registered by a previous reconcile that are no longer specified
+	// are deleted from pgAdmin. Removing a group changes names, so removed is
+	// left out of the hash.
+	specified := sets.NewString(names...)
+	registered := sets.NewString(pgAdmin.Status.RegisteredServerGroups...)
+	removed := registered.Difference(specified).List()
+
+	write := func(ctx context.Context, exec pgadmin.Executor, removed []string) ([]string, error) {
+		return pgadmin.WriteServersInPGAdmin(ctx, exec, users, names, removed, servers)
+	}
+
+	hasher := fnv.New32()
+	_, err = write(logging.NewContext(ctx, logging.Discard()), func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		_, err := fmt.Fprint(hasher, command)
+		if err == nil && stdin != nil {
+			_, err = io.Copy(hasher, stdin)
+		}
+		return err
+	}, nil)
+	revision := rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+
+	if err == nil && pgAdmin.Status.ServersRevision == revision && registered.Equal(specified) {
+		// The necessary commands have already been run; there's nothing more to do.
+		return reconcile.Result{}, nil
+	}
+
+	// Run the necessary commands and record their hash in pgAdmin.Status.
+	// Include the hash in any log messages.
+
+	var missing []string
+	if err == nil {
+		log := logging.FromContext(ctx).WithValues("revision", revision)
+		missing, err = write(logging.NewContext(ctx, log), podExecutor, removed)
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		pgAdmin.Status.RegisteredServerGroups = names
+	}
+
+	// Users that do not exist yet might log in at any time. Run the commands
+	// again later rather than wait for a change to the spec.
+	if err == nil && len(missing) > 0 {
+		logging.FromContext(ctx).V(1).Info("waiting for pgAdmin users", "users", missing)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	if err == nil {
+		pgAdmin.Status.ServersRevision = revision
+	}
+	return reconcile.Result{}, err
+}
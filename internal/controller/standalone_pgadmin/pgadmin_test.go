@@ -0,0 +1,351 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package standalone_pgadmin
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/adifri/postgres-operator/v5/internal/controller/runtime"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/testing/cmp"
+	"github.com/adifri/postgres-operator/v5/internal/testing/events"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestFindServers(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	namespace := func(name string, labels map[string]string) client.Object {
+		ns := &corev1.Namespace{}
+		ns.Name = name
+		ns.Labels = labels
+		return ns
+	}
+	cluster := func(namespace, name string, labels map[string]string) client.Object {
+		cluster := &v1beta1.PostgresCluster{}
+		cluster.Namespace = namespace
+		cluster.Name = name
+		cluster.Labels = labels
+		return cluster
+	}
+
+	reconciler := &PGAdminReconciler{}
+	reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		namespace("ns1", nil),
+		namespace("ns2", map[string]string{"env": "dev"}),
+		namespace("ns3", map[string]string{"env": "dev"}),
+		cluster("ns1", "hippo", map[string]string{"team": "a"}),
+		cluster("ns1", "rhino", map[string]string{"team": "b"}),
+		cluster("ns2", "zebra", map[string]string{"team": "a"}),
+		cluster("ns3", "lion", nil),
+	).Build()
+
+	pgAdmin := &v1beta1.PGAdmin{}
+	pgAdmin.Namespace = "ns1"
+	pgAdmin.Name = "admin"
+	pgAdmin.Spec.ServerGroups = []v1beta1.PGAdminServerGroup{
+		{
+			Name: "local",
+		},
+		{
+			Name: "team-a",
+			PostgresClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"team": "a"},
+			},
+			NamespaceSelector: &metav1.LabelSelector{},
+		},
+		{
+			Name: "dev",
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"env": "dev"},
+			},
+		},
+	}
+
+	groups, err := reconciler.findServers(ctx, pgAdmin)
+	assert.NilError(t, err)
+	assert.Equal(t, len(groups), 3)
+	assert.Equal(t, groups[0].name, "local")
+	assert.Equal(t, groups[0].clusters[0].Spec.Port != nil, true,
+		"expected defaults to be applied")
+
+	assert.Assert(t, cmp.MarshalMatches(pgAdmin.Status.ServerGroups, `
+- clusters:
+  - ns1/hippo
+  - ns1/rhino
+  name: local
+- clusters:
+  - ns1/hippo
+  - ns2/zebra
+  name: team-a
+- clusters:
+  - ns2/zebra
+  - ns3/lion
+  name: dev
+	`))
+
+	t.Run("NoGroups", func(t *testing.T) {
+		pgAdmin := pgAdmin.DeepCopy()
+		pgAdmin.Spec.ServerGroups = nil
+
+		groups, err := reconciler.findServers(ctx, pgAdmin)
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 0)
+		assert.Assert(t, pgAdmin.Status.ServerGroups == nil)
+	})
+
+	t.Run("NamespaceScoped", func(t *testing.T) {
+		recorder := events.NewRecorder(t, scheme)
+		reconciler := &PGAdminReconciler{Client: reconciler.Client}
+		reconciler.NamespaceScoped = true
+		reconciler.Recorder = recorder
+
+		pgAdmin := pgAdmin.DeepCopy()
+
+		// Selected namespaces are ignored without permission to list them.
+		groups, err := reconciler.findServers(ctx, pgAdmin)
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 3)
+		assert.Assert(t, cmp.MarshalMatches(pgAdmin.Status.ServerGroups, `
+- clusters:
+  - ns1/hippo
+  - ns1/rhino
+  name: local
+- clusters:
+  - ns1/hippo
+  name: team-a
+  namespaceSelectorIgnored: true
+- clusters:
+  - ns1/hippo
+  - ns1/rhino
+  name: dev
+  namespaceSelectorIgnored: true
+		`))
+
+		assert.Equal(t, len(recorder.Events), 2)
+		for _, event := range recorder.Events {
+			assert.Equal(t, event.Reason, "NamespaceSelectorIgnored")
+		}
+
+		// The events are not recorded again while the status is the same.
+		_, err = reconciler.findServers(ctx, pgAdmin)
+		assert.NilError(t, err)
+		assert.Equal(t, len(recorder.Events), 2)
+	})
+}
+
+func TestSelectsCluster(t *testing.T) {
+	pgAdmin := &v1beta1.PGAdmin{}
+	pgAdmin.Namespace = "ns1"
+	pgAdmin.Spec.ServerGroups = []v1beta1.PGAdminServerGroup{{
+		Name: "group",
+		PostgresClusterSelector: metav1.LabelSelector{
+			MatchLabels: map[string]string{"team": "a"},
+		},
+	}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Labels = map[string]string{"team": "a"}
+	assert.Assert(t, selectsCluster(pgAdmin, cluster))
+
+	cluster.Labels = map[string]string{"team": "b"}
+	assert.Assert(t, !selectsCluster(pgAdmin, cluster))
+
+	cluster.Labels = map[string]string{"team": "a"}
+	cluster.Namespace = "ns2"
+	assert.Assert(t, !selectsCluster(pgAdmin, cluster),
+		"expected only the same namespace without a namespace selector")
+
+	pgAdmin.Spec.ServerGroups[0].NamespaceSelector = &metav1.LabelSelector{}
+	assert.Assert(t, selectsCluster(pgAdmin, cluster))
+}
+
+func TestGenerateStatefulSet(t *testing.T) {
+	pgAdmin := &v1beta1.PGAdmin{}
+	pgAdmin.Namespace = "ns1"
+	pgAdmin.Name = "admin"
+	pgAdmin.Spec.Image = "image"
+	pgAdmin.Spec.PriorityClassName = initialize.String("some-priority")
+
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	secret := &corev1.Secret{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: naming.StandalonePGAdmin(pgAdmin)}
+
+	sts := generateStatefulSet(pgAdmin, configmap, secret, pvc)
+
+	assert.Equal(t, sts.Name, "pgadmin-admin")
+	assert.Equal(t, *sts.Spec.Replicas, int32(1))
+	assert.Equal(t, sts.Spec.Template.Spec.PriorityClassName, "some-priority")
+	assert.DeepEqual(t, sts.Spec.Selector.MatchLabels, map[string]string{
+		naming.LabelStandalonePGAdmin: "admin",
+	})
+	assert.DeepEqual(t, sts.Spec.Template.Labels, map[string]string{
+		naming.LabelStandalonePGAdmin: "admin",
+		naming.LabelRole:              naming.RolePGAdmin,
+		naming.LabelData:              naming.DataPGAdmin,
+	})
+
+	var container *corev1.Container
+	for i := range sts.Spec.Template.Spec.Containers {
+		if sts.Spec.Template.Spec.Containers[i].Name == naming.ContainerPGAdmin {
+			container = &sts.Spec.Template.Spec.Containers[i]
+		}
+	}
+	assert.Assert(t, container != nil)
+	assert.Equal(t, container.Image, "image")
+	assert.Assert(t, cmp.MarshalMatches(container.Env[:2], `
+- name: PGADMIN_SETUP_EMAIL
+  valueFrom:
+    secretKeyRef:
+      key: username
+      name: pgadmin-admin
+- name: PGADMIN_SETUP_PASSWORD
+  valueFrom:
+    secretKeyRef:
+      key: password
+      name: pgadmin-admin
+	`))
+}
+
+func TestReconcileServers(t *testing.T) {
+	ctx := context.Background()
+
+	pgAdmin := &v1beta1.PGAdmin{}
+	pgAdmin.Namespace = "ns1"
+	pgAdmin.Name = "admin"
+
+	pod := &corev1.Pod{}
+	pod.Namespace = "ns1"
+	pod.Name = "pgadmin-admin-0"
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name: naming.ContainerPGAdmin,
+		State: corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{},
+		},
+	}}
+
+	reconciler := &PGAdminReconciler{}
+	reconciler.Client = fake.NewClientBuilder().Build()
+
+	calls := 0
+	reconciler.PodExec = func(
+		namespace, pod, container string,
+		_ io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		calls++
+		assert.Equal(t, namespace, "ns1")
+		assert.Equal(t, pod, "pgadmin-admin-0")
+		assert.Equal(t, container, naming.ContainerPGAdmin)
+		return nil
+	}
+
+	groups := []serverGroup{{name: "group"}}
+
+	// No pod; nothing happens.
+	result, err := reconciler.reconcileServers(ctx, pgAdmin, groups)
+	assert.NilError(t, err)
+	assert.Equal(t, result, reconcile.Result{})
+	assert.Equal(t, calls, 0)
+	assert.Equal(t, pgAdmin.Status.ServersRevision, "")
+
+	reconciler.Client = fake.NewClientBuilder().WithObjects(pod).Build()
+
+	result, err = reconciler.reconcileServers(ctx, pgAdmin, groups)
+	assert.NilError(t, err)
+	assert.Equal(t, result, reconcile.Result{})
+	assert.Equal(t, calls, 1)
+	assert.Assert(t, pgAdmin.Status.ServersRevision != "")
+
+	// Same servers; nothing to execute.
+	_, err = reconciler.reconcileServers(ctx, pgAdmin, groups)
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 1)
+
+	// Different servers execute again.
+	groups = append(groups, serverGroup{name: "other"})
+	_, err = reconciler.reconcileServers(ctx, pgAdmin, groups)
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 2)
+	assert.DeepEqual(t, pgAdmin.Status.RegisteredServerGroups, []string{"group", "other"})
+
+	t.Run("RemovedGroups", func(t *testing.T) {
+		pgAdmin := pgAdmin.DeepCopy()
+
+		var removed []string
+		reconciler.PodExec = func(
+			_, _, _ string, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			removed = append(removed, command[4])
+			return nil
+		}
+
+		// The group that is no longer specified is deleted from pgAdmin.
+		_, err := reconciler.reconcileServers(ctx, pgAdmin, groups[1:])
+		assert.NilError(t, err)
+		assert.DeepEqual(t, removed, []string{`["group"]`})
+		assert.DeepEqual(t, pgAdmin.Status.RegisteredServerGroups, []string{"other"})
+
+		// Nothing more is deleted.
+		_, err = reconciler.reconcileServers(ctx, pgAdmin, groups[1:])
+		assert.NilError(t, err)
+		assert.DeepEqual(t, removed, []string{`["group"]`})
+	})
+
+	t.Run("MissingUsers", func(t *testing.T) {
+		pgAdmin := pgAdmin.DeepCopy()
+		pgAdmin.Status.ServersRevision = ""
+		pgAdmin.Spec.Users = []v1beta1.PGAdminUser{{Username: "alice"}}
+
+		reconciler.PodExec = func(
+			_, _, _ string, _ io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			assert.Equal(t, command[3], `["alice"]`)
+			_, err := io.WriteString(stdout, `{"missing": ["alice"]}`)
+			return err
+		}
+
+		// The user does not exist yet; try again later.
+		result, err := reconciler.reconcileServers(ctx, pgAdmin, groups)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+		assert.Equal(t, pgAdmin.Status.ServersRevision, "")
+
+		reconciler.PodExec = func(
+			_, _, _ string, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, err := io.WriteString(stdout, `{"missing": []}`)
+			return err
+		}
+
+		result, err = reconciler.reconcileServers(ctx, pgAdmin, groups)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, pgAdmin.Status.ServersRevision != "")
+	})
+}
@@ -0,0 +1,36 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package credentials reads and writes the credentials of PostgreSQL users in
+// stores outside of Kubernetes.
+package credentials
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by a Store when nothing is stored at a path.
+var ErrNotFound = errors.New("credentials not found")
+
+// Store is a backend that keeps credentials as string values at paths.
+type Store interface {
+	// Read returns the values stored at path. It returns ErrNotFound when
+	// there are none.
+	Read(ctx context.Context, path string) (map[string]string, error)
+
+	// Write replaces the values stored at path with data.
+	Write(ctx context.Context, path string, data map[string]string) error
+}
@@ -0,0 +1,167 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Vault is a Store that keeps credentials in version 2 of the key/value
+// secrets engine of HashiCorp Vault.
+// - https://developer.hashicorp.com/vault/api-docs/secret/kv/kv-v2
+type Vault struct {
+	// Address is the URL of the Vault server, e.g. "https://vault:8200".
+	Address string
+
+	// Mount is the path at which the key/value engine is enabled. Defaults
+	// to "secret".
+	Mount string
+
+	// Namespace is the Vault Enterprise namespace of Mount, if any.
+	Namespace string
+
+	// Token returns the token used to authenticate to Vault. It is called
+	// for every request so that tokens can be rotated.
+	Token func() (string, error)
+
+	// Client sends requests to Vault. Defaults to a client with a timeout.
+	Client *http.Client
+}
+
+var _ Store = (*Vault)(nil)
+
+// defaultVaultClient is used when Vault.Client is nil.
+var defaultVaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// vaultData is the body of key/value requests and responses.
+type vaultData struct {
+	Data map[string]string `json:"data"`
+}
+
+// request sends a request with body to the data endpoint of path. It returns
+// the response when its status is successful or not found.
+func (v *Vault) request(
+	ctx context.Context, method, path string, body io.Reader,
+) (*http.Response, error) {
+	mount := strings.Trim(v.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+	url := strings.TrimRight(v.Address, "/") +
+		"/v1/" + mount + "/data/" + strings.TrimLeft(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if v.Token != nil {
+		token, err := v.Token()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := v.Client
+	if client == nil {
+		client = defaultVaultClient
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if response.StatusCode/100 != 2 && response.StatusCode != http.StatusNotFound {
+		defer response.Body.Close()
+
+		// Vault describes what went wrong in a list of errors.
+		// - https://developer.hashicorp.com/vault/api-docs#error-response
+		var message struct{ Errors []string }
+		_ = json.NewDecoder(io.LimitReader(response.Body, 1<<16)).Decode(&message)
+
+		return nil, errors.Errorf("vault: %s %q: %s %s",
+			method, path, response.Status, strings.Join(message.Errors, "; "))
+	}
+	return response, nil
+}
+
+// Read returns the latest version of the values stored at path. It returns
+// ErrNotFound when path has never been written or its latest version is
+// deleted.
+func (v *Vault) Read(ctx context.Context, path string) (map[string]string, error) {
+	response, err := v.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var body struct{ Data vaultData }
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(err, "vault: decoding %q", path)
+	}
+	if body.Data.Data == nil {
+		return nil, ErrNotFound
+	}
+	return body.Data.Data, nil
+}
+
+// Write stores data as the latest version of path.
+func (v *Vault) Write(ctx context.Context, path string, data map[string]string) error {
+	body, err := json.Marshal(vaultData{Data: data})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	response, err := v.request(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return errors.Errorf("vault: %s %q: %s", http.MethodPost, path, response.Status)
+	}
+	return nil
+}
+
+// FileToken returns a function that reads a Vault token from the file at
+// path. The file is read every time so that the token can be replaced.
+func FileToken(path string) func() (string, error) {
+	return func() (string, error) {
+		b, err := os.ReadFile(path)
+		return strings.TrimSpace(string(b)), errors.WithStack(err)
+	}
+}
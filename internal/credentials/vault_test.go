@@ -0,0 +1,182 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+)
+
+// fakeVault is a minimal key/value version 2 engine mounted at "kv".
+type fakeVault struct {
+	sync.Mutex
+	data     map[string]map[string]string
+	requests []*http.Request
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, r)
+
+	if r.Header.Get("X-Vault-Token") != "s.token" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/kv/data/")
+	if path == r.URL.Path {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := f.data[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     data,
+				"metadata": map[string]interface{}{"version": 1},
+			},
+		})
+
+	case http.MethodPost:
+		var body struct{ Data map[string]string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil ||
+			r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid request"]}`))
+			return
+		}
+		f.data[path] = body.Data
+		_, _ = w.Write([]byte(`{"data":{"version":1}}`))
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeVault{data: map[string]map[string]string{
+		"ns/hippo/rhino": {"password": "stored"},
+	}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	vault := &Vault{
+		Address:   server.URL + "/",
+		Mount:     "/kv/",
+		Namespace: "team",
+		Token:     func() (string, error) { return "s.token", nil },
+		Client:    server.Client(),
+	}
+
+	t.Run("Read", func(t *testing.T) {
+		data, err := vault.Read(ctx, "ns/hippo/rhino")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, data, map[string]string{"password": "stored"})
+
+		last := fake.requests[len(fake.requests)-1]
+		assert.Equal(t, last.Method, http.MethodGet)
+		assert.Equal(t, last.URL.Path, "/v1/kv/data/ns/hippo/rhino")
+		assert.Equal(t, last.Header.Get("X-Vault-Namespace"), "team")
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := vault.Read(ctx, "ns/hippo/missing")
+		assert.Assert(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		assert.NilError(t, vault.Write(ctx, "ns/hippo/other",
+			map[string]string{"user": "other", "password": "generated"}))
+		assert.DeepEqual(t, fake.data["ns/hippo/other"],
+			map[string]string{"user": "other", "password": "generated"})
+
+		data, err := vault.Read(ctx, "ns/hippo/other")
+		assert.NilError(t, err)
+		assert.Equal(t, data["password"], "generated")
+	})
+
+	t.Run("DefaultMount", func(t *testing.T) {
+		other := *vault
+		other.Mount = ""
+
+		_, err := other.Read(ctx, "ns/hippo/rhino")
+		assert.Assert(t, errors.Is(err, ErrNotFound))
+
+		last := fake.requests[len(fake.requests)-1]
+		assert.Equal(t, last.URL.Path, "/v1/secret/data/ns/hippo/rhino")
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		other := *vault
+		other.Token = func() (string, error) { return "wrong", nil }
+
+		_, err := other.Read(ctx, "ns/hippo/rhino")
+		assert.ErrorContains(t, err, "403")
+		assert.ErrorContains(t, err, "permission denied")
+		assert.Assert(t, !errors.Is(err, ErrNotFound))
+
+		err = other.Write(ctx, "ns/hippo/rhino", map[string]string{"password": "x"})
+		assert.ErrorContains(t, err, "permission denied")
+		assert.Equal(t, fake.data["ns/hippo/rhino"]["password"], "stored")
+	})
+
+	t.Run("TokenError", func(t *testing.T) {
+		other := *vault
+		other.Token = func() (string, error) { return "", errors.New("no token") }
+
+		_, err := other.Read(ctx, "ns/hippo/rhino")
+		assert.ErrorContains(t, err, "no token")
+	})
+}
+
+func TestFileToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	token := FileToken(path)
+
+	_, err := token()
+	assert.Assert(t, err != nil, "expected an error for a missing file")
+
+	assert.NilError(t, os.WriteFile(path, []byte("s.first\n"), 0o600))
+	value, err := token()
+	assert.NilError(t, err)
+	assert.Equal(t, value, "s.first")
+
+	assert.NilError(t, os.WriteFile(path, []byte("s.second"), 0o600))
+	value, err = token()
+	assert.NilError(t, err)
+	assert.Equal(t, value, "s.second")
+}
@@ -50,4 +50,33 @@ const (
 	// timestamp), which will be stored in the PostgresCluster status to properly track completion
 	// of the Job.
 	PGBackRestRestore = annotationPrefix + "pgbackrest-restore"
+
+	// RotateRootCertificate is the annotation that is added to a PostgresCluster to replace
+	// the root certificate authority of its namespace. Each new value starts one rotation
+	// when none is in progress. The value is stored in the PostgresCluster status.
+	RotateRootCertificate = annotationPrefix + "rotate-root-certificate"
+
+	// PlanChanges is the annotation that is added to a PostgresCluster to stop applying
+	// its spec and instead record what applying it would change. Each new value makes a
+	// new plan, and the value is stored in the PostgresCluster status.
+	PlanChanges = annotationPrefix + "plan"
+
+	// RolloutApprove is the annotation that is added to a PostgresCluster to let a rollout
+	// that requires approval continue to its next step. Each new value approves one step.
+	// The value is stored in the PostgresCluster status.
+	RolloutApprove = annotationPrefix + "rollout-approve"
+
+	// VolumeSnapshotBackup is the annotation that is added to a PostgresCluster to take a
+	// snapshot backup of its volumes. Every VolumeSnapshot of that backup has the same
+	// annotation and value so that each value results in one backup.
+	VolumeSnapshotBackup = annotationPrefix + "volume-snapshot"
+
+	// VolumeSnapshotBackupLabel is an annotation on the VolumeSnapshot of a data volume that
+	// contains the "backup_label" file returned by PostgreSQL when the backup stopped. It is
+	// only present when the backup is complete.
+	VolumeSnapshotBackupLabel = annotationPrefix + "volume-snapshot-backup-label"
+
+	// VolumeSnapshotStartTime is an annotation on every VolumeSnapshot of a backup that
+	// contains the time, in RFC 3339 format, at which PostgreSQL started the backup.
+	VolumeSnapshotStartTime = annotationPrefix + "volume-snapshot-start-time"
 )
@@ -29,4 +29,8 @@ func TestAnnotationsValid(t *testing.T) {
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestConfigHash))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestCurrentConfig))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestRestore))
+	assert.Assert(t, nil == validation.IsQualifiedName(RotateRootCertificate))
+	assert.Assert(t, nil == validation.IsQualifiedName(VolumeSnapshotBackup))
+	assert.Assert(t, nil == validation.IsQualifiedName(VolumeSnapshotBackupLabel))
+	assert.Assert(t, nil == validation.IsQualifiedName(VolumeSnapshotStartTime))
 }
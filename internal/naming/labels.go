@@ -88,9 +88,18 @@ const (
 	// LabelPostgresUser identifies the PostgreSQL user an object is for or about.
 	LabelPostgresUser = labelPrefix + "pguser"
 
+	// LabelStandalonePGAdmin identifies the PGAdmin an object is for or about.
+	LabelStandalonePGAdmin = labelPrefix + "pgadmin"
+
 	// LabelStartupInstance is used to indicate the startup instance associated with a resource
 	LabelStartupInstance = labelPrefix + "startup-instance"
 
+	// LabelTablespace identifies the PostgreSQL tablespace a volume is for.
+	LabelTablespace = labelPrefix + "tablespace"
+
+	// LabelVolumeSnapshot identifies the snapshot backup a VolumeSnapshot belongs to.
+	LabelVolumeSnapshot = labelPrefix + "volume-snapshot"
+
 	RolePrimary = "primary"
 	RoleReplica = "replica"
 
@@ -105,12 +114,19 @@ const (
 	// RolePGBouncer is the LabelRole applied to PgBouncer objects.
 	RolePGBouncer = "pgbouncer"
 
+	// RolePGBouncerReadOnly is the LabelRole applied to objects of the
+	// PgBouncer proxy that connects to PostgreSQL replicas.
+	RolePGBouncerReadOnly = "pgbouncer-ro"
+
 	// RolePGAdmin is the LabelRole applied to pgAdmin objects.
 	RolePGAdmin = "pgadmin"
 
 	// RolePostgresData is the LabelRole applied to PostgreSQL data volumes.
 	RolePostgresData = "pgdata"
 
+	// RolePostgresTablespace is the LabelRole applied to PostgreSQL tablespace volumes.
+	RolePostgresTablespace = "pgtablespace"
+
 	// RolePostgresUser is the LabelRole applied to PostgreSQL user secrets.
 	RolePostgresUser = "pguser"
 
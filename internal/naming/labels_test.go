@@ -44,7 +44,10 @@ func TestLabelsValid(t *testing.T) {
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestRestoreConfig))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGMonitorDiscovery))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPostgresUser))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelStandalonePGAdmin))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelStartupInstance))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelTablespace))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelVolumeSnapshot))
 }
 
 func TestLabelValuesValid(t *testing.T) {
@@ -55,7 +58,9 @@ func TestLabelValuesValid(t *testing.T) {
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePatroniReplica))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePGAdmin))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePGBouncer))
+	assert.Assert(t, nil == validation.IsValidLabelValue(RolePGBouncerReadOnly))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePostgresData))
+	assert.Assert(t, nil == validation.IsValidLabelValue(RolePostgresTablespace))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePostgresUser))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePostgresWAL))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RolePrimary))
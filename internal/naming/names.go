@@ -219,6 +219,15 @@ func ClusterPGAdmin(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	}
 }
 
+// StandalonePGAdmin returns the ObjectMeta necessary to lookup the ConfigMap,
+// Secret, Service, StatefulSet, or Volume of a standalone pgAdmin.
+func StandalonePGAdmin(pgadmin *v1beta1.PGAdmin) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: pgadmin.Namespace,
+		Name:      "pgadmin-" + pgadmin.Name,
+	}
+}
+
 // ClusterPGBouncer returns the ObjectMeta necessary to lookup the ConfigMap,
 // Deployment, Secret, PodDisruptionBudget or Service that is cluster's
 // PgBouncer proxy.
@@ -229,6 +238,15 @@ func ClusterPGBouncer(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	}
 }
 
+// ClusterPGBouncerReadOnly returns the ObjectMeta necessary to lookup the
+// ConfigMap, Deployment, or Service that is cluster's read-only PgBouncer proxy.
+func ClusterPGBouncerReadOnly(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgbouncer-ro",
+	}
+}
+
 // ClusterPodService returns the ObjectMeta necessary to lookup the Service
 // that is responsible for the network identity of Pods.
 func ClusterPodService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
@@ -306,6 +324,16 @@ func InstanceCertificates(instance metav1.Object) metav1.ObjectMeta {
 	}
 }
 
+// InstanceIssuedCertificate returns the ObjectMeta necessary to lookup the
+// cert-manager Certificate and Secret of instance's certificate when cluster
+// certificates come from an issuer.
+func InstanceIssuedCertificate(instance metav1.Object) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: instance.GetNamespace(),
+		Name:      instance.GetName() + "-issued",
+	}
+}
+
 // InstanceSet returns the ObjectMeta necessary to lookup the objects
 // associated with a single instance set. Includes PodDisruptionBudgets
 func InstanceSet(cluster *v1beta1.PostgresCluster,
@@ -334,6 +362,15 @@ func InstancePostgresWALVolume(instance *appsv1.StatefulSet) metav1.ObjectMeta {
 	}
 }
 
+// InstancePostgresTablespaceVolume returns the ObjectMeta for the PostgreSQL
+// tablespace volume named tablespace for instance.
+func InstancePostgresTablespaceVolume(instance *appsv1.StatefulSet, tablespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: instance.GetNamespace(),
+		Name:      instance.GetName() + "-" + tablespace + "-tablespace",
+	}
+}
+
 // MonitoringUserSecret returns ObjectMeta necessary to lookup the Secret
 // containing authentication credentials for monitoring tools.
 func MonitoringUserSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
@@ -352,6 +389,46 @@ func ReplicationClientCertSecret(cluster *v1beta1.PostgresCluster) metav1.Object
 	}
 }
 
+// ClusterIssuedCertificate returns the ObjectMeta necessary to lookup the
+// cert-manager Certificate and Secret of the PostgreSQL server certificate
+// when cluster certificates come from an issuer.
+func ClusterIssuedCertificate(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-cluster-issued",
+	}
+}
+
+// ReplicationIssuedCertificate returns the ObjectMeta necessary to lookup the
+// cert-manager Certificate and Secret of the replication client certificate
+// when cluster certificates come from an issuer.
+func ReplicationIssuedCertificate(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-replication-issued",
+	}
+}
+
+// PGBackRestClientIssuedCertificate returns the ObjectMeta necessary to lookup
+// the cert-manager Certificate and Secret of the pgBackRest client certificate
+// when cluster certificates come from an issuer.
+func PGBackRestClientIssuedCertificate(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgbackrest-client-issued",
+	}
+}
+
+// PGBackRestRepoHostIssuedCertificate returns the ObjectMeta necessary to
+// lookup the cert-manager Certificate and Secret of the pgBackRest repository
+// host certificate when cluster certificates come from an issuer.
+func PGBackRestRepoHostIssuedCertificate(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-repo-host-issued",
+	}
+}
+
 // PatroniDistributedConfiguration returns the ObjectMeta necessary to lookup
 // the DCS created by Patroni for cluster. This same name is used for both
 // ConfigMap and Endpoints. See Patroni DCS "config_path".
@@ -495,6 +572,16 @@ func PostgresUserSecret(cluster *v1beta1.PostgresCluster, username string) metav
 	}
 }
 
+// PostgresUserIssuedCertificate returns the ObjectMeta necessary to lookup
+// the cert-manager Certificate and Secret of the client certificate of a
+// PostgreSQL user when cluster certificates come from an issuer.
+func PostgresUserIssuedCertificate(cluster *v1beta1.PostgresCluster, username string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgcert-" + username,
+	}
+}
+
 // PostgresTLSSecret returns the ObjectMeta necessary to lookup the Secret
 // containing the default Postgres TLS certificates and key
 func PostgresTLSSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
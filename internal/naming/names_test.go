@@ -95,6 +95,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 			{"ClusterConfigMap", ClusterConfigMap(cluster)},
 			{"ClusterPGAdmin", ClusterPGAdmin(cluster)},
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerReadOnly", ClusterPGBouncerReadOnly(cluster)},
 			{"PatroniDistributedConfiguration", PatroniDistributedConfiguration(cluster)},
 			{"PatroniLeaderConfigMap", PatroniLeaderConfigMap(cluster)},
 			{"PatroniTrigger", PatroniTrigger(cluster)},
@@ -115,6 +116,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Deployments", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerReadOnly", ClusterPGBouncerReadOnly(cluster)},
 		})
 	})
 
@@ -152,6 +154,10 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 			{"DeprecatedPostgresUserSecret", DeprecatedPostgresUserSecret(cluster)},
 			{"PostgresTLSSecret", PostgresTLSSecret(cluster)},
 			{"ReplicationClientCertSecret", ReplicationClientCertSecret(cluster)},
+			{"ClusterIssuedCertificate", ClusterIssuedCertificate(cluster)},
+			{"ReplicationIssuedCertificate", ReplicationIssuedCertificate(cluster)},
+			{"PGBackRestClientIssuedCertificate", PGBackRestClientIssuedCertificate(cluster)},
+			{"PGBackRestRepoHostIssuedCertificate", PGBackRestRepoHostIssuedCertificate(cluster)},
 			{"PGBackRestSSHSecret", PGBackRestSSHSecret(cluster)},
 			{"MonitoringUserSecret", MonitoringUserSecret(cluster)},
 		})
@@ -167,6 +173,18 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 				assert.Assert(t, !strings.HasPrefix(name, prefix), "%q may collide", name)
 			}
 		})
+
+		t.Run("PostgresUserIssuedCertificate", func(t *testing.T) {
+			value := PostgresUserIssuedCertificate(cluster, "some-user")
+
+			assert.Equal(t, value.Namespace, cluster.Namespace)
+			assert.Assert(t, nil == validation.IsDNS1123Label(value.Name))
+
+			prefix := PostgresUserIssuedCertificate(cluster, "").Name
+			for _, name := range append(names.List(), PostgresUserSecret(cluster, "some-user").Name) {
+				assert.Assert(t, !strings.HasPrefix(name, prefix), "%q may collide", name)
+			}
+		})
 	})
 
 	t.Run("ServiceAccounts", func(t *testing.T) {
@@ -179,6 +197,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Services", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerReadOnly", ClusterPGBouncerReadOnly(cluster)},
 			{"ClusterPGAdmin", ClusterPGAdmin(cluster)},
 			{"ClusterPodService", ClusterPodService(cluster)},
 			{"ClusterPrimaryService", ClusterPrimaryService(cluster)},
@@ -204,6 +223,27 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	})
 }
 
+func TestStandalonePGAdminNamesValid(t *testing.T) {
+	pgadmin := &v1beta1.PGAdmin{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1", Name: "admin",
+		},
+	}
+
+	value := StandalonePGAdmin(pgadmin)
+	assert.Equal(t, value.Namespace, pgadmin.Namespace)
+	assert.Equal(t, value.Name, "pgadmin-admin")
+
+	// The name is used for a StatefulSet, Service, ConfigMap, Secret, and Volume.
+	assert.Assert(t, nil == validation.IsDNS1035Label(value.Name))
+	assert.Assert(t, nil == validation.IsDNS1123Subdomain(value.Name))
+
+	// The name cannot conflict with the pgAdmin of a PostgresCluster.
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Name = "admin"
+	assert.Assert(t, value.Name != ClusterPGAdmin(cluster).Name)
+}
+
 func TestInstanceNamesUniqueAndValid(t *testing.T) {
 	instance := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -235,6 +275,7 @@ func TestInstanceNamesUniqueAndValid(t *testing.T) {
 		names := sets.NewString()
 		for _, tt := range []test{
 			{"InstancePostgresDataVolume", InstancePostgresDataVolume(instance)},
+			{"InstancePostgresTablespaceVolume", InstancePostgresTablespaceVolume(instance, "fast")},
 			{"InstancePostgresWALVolume", InstancePostgresWALVolume(instance)},
 		} {
 			t.Run(tt.name, func(t *testing.T) {
@@ -251,6 +292,7 @@ func TestInstanceNamesUniqueAndValid(t *testing.T) {
 		names := sets.NewString()
 		for _, tt := range []test{
 			{"InstanceCertificates", InstanceCertificates(instance)},
+			{"InstanceIssuedCertificate", InstanceIssuedCertificate(instance)},
 		} {
 			t.Run(tt.name, func(t *testing.T) {
 				assert.Equal(t, tt.value.Namespace, instance.Namespace)
@@ -125,6 +125,17 @@ func ClusterPGBouncerSelector(cluster *v1beta1.PostgresCluster) metav1.LabelSele
 	}
 }
 
+// ClusterPGBouncerReadOnlySelector selects things labeled for the read-only
+// PGBouncer in cluster.
+func ClusterPGBouncerReadOnlySelector(cluster *v1beta1.PostgresCluster) metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelCluster: cluster.Name,
+			LabelRole:    RolePGBouncerReadOnly,
+		},
+	}
+}
+
 // ClusterPostgresUsers selects things labeled for PostgreSQL users in cluster.
 func ClusterPostgresUsers(cluster string) metav1.LabelSelector {
 	return metav1.LabelSelector{
@@ -145,3 +156,24 @@ func ClusterPrimary(cluster string) metav1.LabelSelector {
 	s.MatchLabels[LabelRole] = RolePatroniLeader
 	return s
 }
+
+// ClusterVolumeSnapshots selects VolumeSnapshots of snapshot backups in cluster.
+func ClusterVolumeSnapshots(cluster string) metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelCluster: cluster,
+		},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: LabelVolumeSnapshot, Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+}
+
+// StandalonePGAdminSelector selects things labeled for the PGAdmin named pgadmin.
+func StandalonePGAdminSelector(pgadmin string) metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelStandalonePGAdmin: pgadmin,
+		},
+	}
+}
@@ -135,6 +135,22 @@ func TestClusterPGBouncerSelector(t *testing.T) {
 	assert.ErrorContains(t, err, "invalid")
 }
 
+func TestClusterPGBouncerReadOnlySelector(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Name = "something"
+
+	s, err := AsSelector(ClusterPGBouncerReadOnlySelector(cluster))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.String(), strings.Join([]string{
+		"postgres-operator.crunchydata.com/cluster=something",
+		"postgres-operator.crunchydata.com/role=pgbouncer-ro",
+	}, ","))
+
+	cluster.Name = "--bad--dog"
+	_, err = AsSelector(ClusterPGBouncerReadOnlySelector(cluster))
+	assert.ErrorContains(t, err, "invalid")
+}
+
 func TestClusterPostgresUsers(t *testing.T) {
 	s, err := AsSelector(ClusterPostgresUsers("something"))
 	assert.NilError(t, err)
@@ -156,3 +172,24 @@ func TestClusterPrimary(t *testing.T) {
 		"postgres-operator.crunchydata.com/role=master",
 	}, ","))
 }
+
+func TestClusterVolumeSnapshots(t *testing.T) {
+	s, err := AsSelector(ClusterVolumeSnapshots("something"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.String(), strings.Join([]string{
+		"postgres-operator.crunchydata.com/cluster=something",
+		"postgres-operator.crunchydata.com/volume-snapshot",
+	}, ","))
+
+	_, err = AsSelector(ClusterVolumeSnapshots("--nope--"))
+	assert.ErrorContains(t, err, "invalid")
+}
+
+func TestStandalonePGAdminSelector(t *testing.T) {
+	s, err := AsSelector(StandalonePGAdminSelector("something"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.String(), "postgres-operator.crunchydata.com/pgadmin=something")
+
+	_, err = AsSelector(StandalonePGAdminSelector("--nope--"))
+	assert.ErrorContains(t, err, "invalid")
+}
@@ -184,6 +184,45 @@ func clusterYAML(
 	return string(append([]byte(yamlGeneratedWarning), b...)), err
 }
 
+// HBARules returns the pg_hba.conf records of cluster in the order that
+// Postgres compares them to connections.
+func HBARules(cluster *v1beta1.PostgresCluster, pgHBAs postgres.HBAs) []string {
+	var section []interface{}
+	if cluster.Spec.Patroni != nil {
+		if postgresql, ok := cluster.Spec.Patroni.DynamicConfiguration["postgresql"].(map[string]interface{}); ok {
+			section, _ = postgresql["pg_hba"].([]interface{})
+		}
+	}
+	return hbaRules(pgHBAs, section)
+}
+
+// hbaRules combines pgHBAs with the "postgresql.pg_hba" section of the
+// Patroni dynamic configuration. Mandatory records come first, followed by
+// custom records, then the section. Default records come last when the section
+// is missing or empty.
+func hbaRules(pgHBAs postgres.HBAs, section []interface{}) []string {
+	hba := make([]string, 0, len(pgHBAs.Mandatory)+len(pgHBAs.Custom))
+	for i := range pgHBAs.Mandatory {
+		hba = append(hba, pgHBAs.Mandatory[i].String())
+	}
+	for i := range pgHBAs.Custom {
+		hba = append(hba, pgHBAs.Custom[i].String())
+	}
+	before := len(hba)
+	for i := range section {
+		// any pg_hba values that are not strings will be skipped
+		if value, ok := section[i].(string); ok {
+			hba = append(hba, value)
+		}
+	}
+	if len(hba) == before {
+		for i := range pgHBAs.Default {
+			hba = append(hba, pgHBAs.Default[i].String())
+		}
+	}
+	return hba
+}
+
 // DynamicConfiguration combines configuration with some PostgreSQL settings
 // and returns a value that can be marshaled to JSON.
 func DynamicConfiguration(
@@ -242,25 +281,12 @@ func DynamicConfiguration(
 	}
 	postgresql["parameters"] = parameters
 
-	// Copy the "postgresql.pg_hba" section after any mandatory values.
-	hba := make([]string, 0, len(pgHBAs.Mandatory))
-	for i := range pgHBAs.Mandatory {
-		hba = append(hba, pgHBAs.Mandatory[i].String())
-	}
-	if section, ok := postgresql["pg_hba"].([]interface{}); ok {
-		for i := range section {
-			// any pg_hba values that are not strings will be skipped
-			if value, ok := section[i].(string); ok {
-				hba = append(hba, value)
-			}
-		}
-	}
-	// When the section is missing or empty, include the recommended defaults.
-	if len(hba) == len(pgHBAs.Mandatory) {
-		for i := range pgHBAs.Default {
-			hba = append(hba, pgHBAs.Default[i].String())
-		}
+	// Copy the "postgresql.pg_hba" section after any mandatory and custom values.
+	var section []interface{}
+	if value, ok := postgresql["pg_hba"].([]interface{}); ok {
+		section = value
 	}
+	hba := hbaRules(pgHBAs, section)
 	postgresql["pg_hba"] = hba
 
 	// Enabling `pg_rewind` allows a former primary to automatically rejoin the
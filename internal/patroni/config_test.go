@@ -515,6 +515,68 @@ func TestDynamicConfiguration(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "postgresql.pg_hba: custom between mandatory and default",
+			input: map[string]interface{}{
+				"postgresql": map[string]interface{}{
+					"pg_hba": nil,
+				},
+			},
+			hbas: postgres.HBAs{
+				Mandatory: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().Local().Method("peer"),
+				},
+				Custom: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().TLS().Method("scram-sha-256"),
+				},
+				Default: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().TLS().Method("md5"),
+				},
+			},
+			expected: map[string]interface{}{
+				"loop_wait": int32(10),
+				"ttl":       int32(30),
+				"postgresql": map[string]interface{}{
+					"parameters": map[string]interface{}{},
+					"pg_hba": []string{
+						"local all all peer",
+						"hostssl all all all scram-sha-256",
+						"hostssl all all all md5",
+					},
+					"use_pg_rewind": true,
+					"use_slots":     false,
+				},
+			},
+		},
+		{
+			name: "postgresql.pg_hba: custom before input",
+			input: map[string]interface{}{
+				"postgresql": map[string]interface{}{
+					"pg_hba": []interface{}{"custom"},
+				},
+			},
+			hbas: postgres.HBAs{
+				Custom: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().TLS().Method("scram-sha-256"),
+				},
+				Default: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().TLS().Method("md5"),
+				},
+			},
+			expected: map[string]interface{}{
+				"loop_wait": int32(10),
+				"ttl":       int32(30),
+				"postgresql": map[string]interface{}{
+					"parameters": map[string]interface{}{},
+					"pg_hba": []string{
+						"hostssl all all all scram-sha-256",
+						"custom",
+					},
+					"use_pg_rewind": true,
+					"use_slots":     false,
+				},
+			},
+		},
 		{
 			name: "postgresql.pg_hba: ignore non-string types",
 			input: map[string]interface{}{
@@ -723,6 +785,40 @@ func TestDynamicConfiguration(t *testing.T) {
 	}
 }
 
+func TestHBARules(t *testing.T) {
+	hbas := postgres.HBAs{
+		Mandatory: []postgres.HostBasedAuthentication{
+			*postgres.NewHBA().Local().Method("peer"),
+		},
+		Custom: []postgres.HostBasedAuthentication{
+			*postgres.NewHBA().TLS().Method("scram-sha-256"),
+		},
+		Default: []postgres.HostBasedAuthentication{
+			*postgres.NewHBA().TLS().Method("md5"),
+		},
+	}
+
+	cluster := new(v1beta1.PostgresCluster)
+	assert.DeepEqual(t, HBARules(cluster, hbas), []string{
+		"local all all peer",
+		"hostssl all all all scram-sha-256",
+		"hostssl all all all md5",
+	})
+
+	cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+		DynamicConfiguration: map[string]interface{}{
+			"postgresql": map[string]interface{}{
+				"pg_hba": []interface{}{"custom"},
+			},
+		},
+	}
+	assert.DeepEqual(t, HBARules(cluster, hbas), []string{
+		"local all all peer",
+		"hostssl all all all scram-sha-256",
+		"custom",
+	})
+}
+
 func TestInstanceConfigFiles(t *testing.T) {
 	t.Parallel()
 
@@ -74,13 +74,13 @@ func InstanceConfigMap(ctx context.Context,
 
 // InstanceCertificates populates the shared Secret with certificates needed to run Patroni.
 func InstanceCertificates(ctx context.Context,
-	inRoot pki.Certificate, inDNS pki.Certificate,
+	inRoots pki.CertificateBundle, inDNS pki.Certificate,
 	inDNSKey pki.PrivateKey, outInstanceCertificates *corev1.Secret,
 ) error {
 	initialize.ByteMap(&outInstanceCertificates.Data)
 
 	var err error
-	outInstanceCertificates.Data[certAuthorityFileKey], err = certFile(inRoot)
+	outInstanceCertificates.Data[certAuthorityFileKey], err = certFile(inRoots)
 
 	if err == nil {
 		outInstanceCertificates.Data[certServerFileKey], err = certFile(inDNSKey, inDNS)
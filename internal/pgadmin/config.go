@@ -77,8 +77,8 @@ const (
 
 // podConfigFiles returns projections of pgAdmin's configuration files to
 // include in the configuration volume.
-func podConfigFiles(configmap *corev1.ConfigMap, spec v1beta1.PGAdminPodSpec) []corev1.VolumeProjection {
-	config := append(append([]corev1.VolumeProjection{}, spec.Config.Files...),
+func podConfigFiles(configmap *corev1.ConfigMap, spec v1beta1.PGAdminConfiguration) []corev1.VolumeProjection {
+	config := append(append([]corev1.VolumeProjection{}, spec.Files...),
 		[]corev1.VolumeProjection{
 			{
 				ConfigMap: &corev1.ConfigMapProjection{
@@ -103,14 +103,14 @@ func podConfigFiles(configmap *corev1.ConfigMap, spec v1beta1.PGAdminPodSpec) []
 	// for use with the other pgAdmin LDAP configuration.
 	// - https://www.pgadmin.org/docs/pgadmin4/latest/config_py.html
 	// - https://www.pgadmin.org/docs/pgadmin4/development/enabling_ldap_authentication.html
-	if spec.Config.LDAPBindPassword != nil {
+	if spec.LDAPBindPassword != nil {
 		config = append(config, corev1.VolumeProjection{
 			Secret: &corev1.SecretProjection{
-				LocalObjectReference: spec.Config.LDAPBindPassword.LocalObjectReference,
-				Optional:             spec.Config.LDAPBindPassword.Optional,
+				LocalObjectReference: spec.LDAPBindPassword.LocalObjectReference,
+				Optional:             spec.LDAPBindPassword.Optional,
 				Items: []corev1.KeyToPath{
 					{
-						Key:  spec.Config.LDAPBindPassword.Key,
+						Key:  spec.LDAPBindPassword.Key,
 						Path: ldapPasswordPath,
 					},
 				},
@@ -170,8 +170,8 @@ if os.path.isfile('` + ldapPasswordAbsolutePath + `'):
 }
 
 // systemSettings returns pgAdmin settings as a value that can be marshaled to JSON.
-func systemSettings(spec *v1beta1.PGAdminPodSpec) map[string]interface{} {
-	settings := *spec.Config.Settings.DeepCopy()
+func systemSettings(spec *v1beta1.PGAdminConfiguration) map[string]interface{} {
+	settings := *spec.Settings.DeepCopy()
 	if settings == nil {
 		settings = make(map[string]interface{})
 	}
@@ -18,8 +18,8 @@ import (
 func TestPodConfigFiles(t *testing.T) {
 	configmap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "some-cm"}}
 
-	spec := v1beta1.PGAdminPodSpec{
-		Config: v1beta1.PGAdminConfiguration{Files: []corev1.VolumeProjection{{
+	spec := v1beta1.PGAdminConfiguration{
+		Files: []corev1.VolumeProjection{{
 			Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{
 				Name: "test-secret",
 			}},
@@ -27,7 +27,7 @@ func TestPodConfigFiles(t *testing.T) {
 			ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{
 				Name: "test-cm",
 			}},
-		}}},
+		}},
 	}
 
 	projections := podConfigFiles(configmap, spec)
@@ -97,12 +97,12 @@ func TestStartupCommand(t *testing.T) {
 }
 
 func TestSystemSettings(t *testing.T) {
-	spec := new(v1beta1.PGAdminPodSpec)
+	spec := new(v1beta1.PGAdminConfiguration)
 	assert.Assert(t, cmp.MarshalMatches(systemSettings(spec), `
 SERVER_MODE: true
 	`))
 
-	spec.Config.Settings = map[string]interface{}{
+	spec.Settings = map[string]interface{}{
 		"ALLOWED_HOSTS": []interface{}{"225.0.0.0/8", "226.0.0.0/7", "228.0.0.0/6"},
 	}
 	assert.Assert(t, cmp.MarshalMatches(systemSettings(spec), `
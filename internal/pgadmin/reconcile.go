@@ -158,7 +158,7 @@ func ConfigMap(
 	encoder := json.NewEncoder(buffer)
 	encoder.SetEscapeHTML(false)
 	encoder.SetIndent("", "  ")
-	err := encoder.Encode(systemSettings(inCluster.Spec.UserInterface.PGAdmin))
+	err := encoder.Encode(systemSettings(&inCluster.Spec.UserInterface.PGAdmin.Config))
 	if err == nil {
 		outConfigMap.Data[settingsConfigMapKey] = buffer.String()
 	}
@@ -176,6 +176,32 @@ func Pod(
 		return
 	}
 
+	pod(inConfigMap, outPod, pgAdminVolume,
+		inCluster.Spec.UserInterface.PGAdmin.Config,
+		config.PGAdminContainerImage(inCluster),
+		inCluster.Spec.ImagePullPolicy,
+		inCluster.Spec.UserInterface.PGAdmin.Resources,
+		[]corev1.EnvVar{
+			{
+				Name:  "PGADMIN_SETUP_EMAIL",
+				Value: loginEmail,
+			},
+			{
+				Name:  "PGADMIN_SETUP_PASSWORD",
+				Value: loginPassword,
+			},
+		})
+}
+
+// pod populates a PodSpec with the containers and volumes of pgAdmin. The
+// login environment variables are added to the pgAdmin container.
+func pod(
+	inConfigMap *corev1.ConfigMap,
+	outPod *corev1.PodSpec, pgAdminVolume *corev1.PersistentVolumeClaim,
+	spec v1beta1.PGAdminConfiguration, image string,
+	imagePullPolicy corev1.PullPolicy, resources corev1.ResourceRequirements,
+	login []corev1.EnvVar,
+) {
 	// create the pgAdmin Pod volumes
 	tmp := corev1.Volume{Name: tmpVolume}
 	tmp.EmptyDir = &corev1.EmptyDirVolumeSource{
@@ -200,7 +226,7 @@ func Pod(
 	}
 	configVolume := corev1.Volume{Name: configVolumeMount.Name}
 	configVolume.Projected = &corev1.ProjectedVolumeSource{
-		Sources: podConfigFiles(inConfigMap, *inCluster.Spec.UserInterface.PGAdmin),
+		Sources: podConfigFiles(inConfigMap, spec),
 	}
 
 	startupVolumeMount := corev1.VolumeMount{
@@ -220,15 +246,7 @@ func Pod(
 	// pgadmin container
 	container := corev1.Container{
 		Name: naming.ContainerPGAdmin,
-		Env: []corev1.EnvVar{
-			{
-				Name:  "PGADMIN_SETUP_EMAIL",
-				Value: loginEmail,
-			},
-			{
-				Name:  "PGADMIN_SETUP_PASSWORD",
-				Value: loginPassword,
-			},
+		Env: append(append([]corev1.EnvVar{}, login...), []corev1.EnvVar{
 			// Setting the KRB5_CONFIG for kerberos
 			// - https://web.mit.edu/kerberos/krb5-current/doc/admin/conf_files/krb5_conf.html
 			{
@@ -242,11 +260,11 @@ func Pod(
 				Name:  "KRB5RCACHEDIR",
 				Value: "/tmp",
 			},
-		},
+		}...),
 		Command:         []string{"bash", "-c", startupScript},
-		Image:           config.PGAdminContainerImage(inCluster),
-		ImagePullPolicy: inCluster.Spec.ImagePullPolicy,
-		Resources:       inCluster.Spec.UserInterface.PGAdmin.Resources,
+		Image:           image,
+		ImagePullPolicy: imagePullPolicy,
+		Resources:       resources,
 
 		SecurityContext: initialize.RestrictedSecurityContext(),
 
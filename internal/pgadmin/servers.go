@@ -0,0 +1,222 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// Server is a PostgresCluster to register in a server group of a standalone
+// pgAdmin.
+type Server struct {
+	Group   string
+	Cluster *v1beta1.PostgresCluster
+}
+
+// WriteServersInPGAdmin uses exec and "python" to register servers in
+// a standalone pgAdmin. Each server is registered for the administrator and
+// for every user in users that exists in pgAdmin; no server is shared between
+// users. Every group is created for those users when it does not exist, and
+// servers in those groups that are not in servers are removed. Groups in
+// removed are deleted for those users along with all their servers. It returns
+// the users that do not yet exist in pgAdmin. The pgAdmin configuration
+// database must exist before calling this.
+func WriteServersInPGAdmin(
+	ctx context.Context, exec Executor,
+	users []string, groups []string, removed []string, servers []Server,
+) ([]string, error) {
+	script := strings.Join([]string{
+		pythonFindPGAdmin,
+
+		// Import pgAdmin modules now that they are on the search path.
+		`
+import json
+import sys
+
+from pgadmin import create_app
+from pgadmin.model import db, Server, ServerGroup, User
+
+with create_app().app_context():`,
+
+		// The user with id=1 is the administrator created from the setup
+		// email and password of the container. Other users are found by the
+		// name they use to log in. Users of OAuth2 exist only after they log
+		// in for the first time. The "username" attribute is part of the User
+		// model since pgAdmin v4.21.
+		// - https://git.postgresql.org/gitweb/?p=pgadmin4.git;f=web/pgadmin/model/__init__.py;hb=REL-4_30#l66
+		`
+    owners = [1]
+    missing = []
+    for name in json.loads(sys.argv[1]):
+        user = db.session.query(User).filter_by(username=name).first()
+        if user is None:
+            missing.append(name)
+        elif user.id not in owners:
+            owners.append(user.id)`,
+
+		// Each owner has its own copy of every group and server. Servers
+		// are not shared so that one user cannot use the settings or saved
+		// password of another.
+		// - https://www.pgadmin.org/docs/pgadmin4/latest/server_dialog.html
+		`
+    groups = {}
+    for owner in owners:
+        for name in sys.argv[3:]:
+            group = (
+                db.session.query(ServerGroup).filter_by(
+                    user_id=owner, name=name,
+                ).first() or
+                ServerGroup()
+            )
+            group.name = name
+            group.user_id = owner
+            db.session.add(group)
+            db.session.commit()
+            groups[(owner, name)] = group`,
+
+		// Process each line of input as a single server definition. The name
+		// of each server is the namespace and name of its PostgresCluster.
+		// Users log in to PostgreSQL with their own credentials, so no
+		// password is stored.
+		`
+    wanted = set()
+    for line in sys.stdin:
+        if not line.strip():
+            continue
+
+        data = json.loads(line)
+        for owner in owners:
+            group = groups[(owner, data['group'])]
+            server = (
+                db.session.query(Server).filter_by(
+                    servergroup_id=group.id,
+                    user_id=owner,
+                    name=data['name'],
+                ).first() or
+                Server()
+            )
+
+            server.name = data['name']
+            server.host = data['hostname']
+            server.port = data['port']
+            server.servergroup_id = group.id
+            server.user_id = owner
+            server.maintenance_db = "postgres"
+            server.username = data['username']
+            server.ssl_mode = "prefer"
+            server.shared = False
+
+            db.session.add(server)
+            db.session.commit()
+            wanted.add(server.id)`,
+
+		// Remove servers that are no longer selected by their group.
+		`
+    for group in groups.values():
+        for server in db.session.query(Server).filter_by(
+            servergroup_id=group.id,
+            user_id=group.user_id,
+        ).all():
+            if server.id not in wanted:
+                db.session.delete(server)`,
+
+		// Remove groups that are no longer specified and all their servers.
+		`
+    for owner in owners:
+        for name in json.loads(sys.argv[2]):
+            for group in db.session.query(ServerGroup).filter_by(
+                user_id=owner, name=name,
+            ).all():
+                for server in db.session.query(Server).filter_by(
+                    servergroup_id=group.id,
+                    user_id=owner,
+                ).all():
+                    db.session.delete(server)
+                db.session.delete(group)
+
+    db.session.commit()
+    print(json.dumps({'missing': missing}))`,
+	}, "\n") + "\n"
+
+	var err error
+	var stdin, stdout, stderr bytes.Buffer
+
+	encoder := json.NewEncoder(&stdin)
+	encoder.SetEscapeHTML(false)
+
+	for i := range servers {
+		cluster := servers[i].Cluster
+		primary := naming.ClusterPrimaryService(cluster)
+
+		// Suggest the first user defined in the cluster spec; users can
+		// change it when they connect.
+		username := cluster.Name
+		if len(cluster.Spec.Users) > 0 {
+			username = string(cluster.Spec.Users[0].Name)
+		}
+
+		if err == nil {
+			err = encoder.Encode(map[string]interface{}{
+				"group":    servers[i].Group,
+				"name":     cluster.Namespace + "/" + cluster.Name,
+				"hostname": primary.Name + "." + primary.Namespace + ".svc",
+				"port":     fmt.Sprint(*cluster.Spec.Port),
+				"username": username,
+			})
+		}
+	}
+
+	// Always send lists of users and removed groups, even when they are empty.
+	if users == nil {
+		users = []string{}
+	}
+	if removed == nil {
+		removed = []string{}
+	}
+	var names, stale []byte
+	if err == nil {
+		names, err = json.Marshal(users)
+	}
+	if err == nil {
+		stale, err = json.Marshal(removed)
+	}
+
+	var result struct{ Missing []string }
+	if err == nil {
+		err = exec(ctx, &stdin, &stdout, &stderr,
+			append([]string{"python", "-c", script, string(names), string(stale)}, groups...)...)
+
+		log := logging.FromContext(ctx)
+		log.V(1).Info("wrote pgAdmin servers",
+			"stdout", stdout.String(),
+			"stderr", stderr.String())
+	}
+
+	// The result is the last line of output.
+	if output := bytes.TrimSpace(stdout.Bytes()); err == nil && len(output) > 0 {
+		err = json.Unmarshal(output[bytes.LastIndexByte(output, '\n')+1:], &result)
+	}
+
+	return result.Missing, err
+}
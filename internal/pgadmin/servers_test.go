@@ -0,0 +1,162 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgadmin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/testing/require"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestWriteServersInPGAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("pass-through")
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			assert.Assert(t, stdin != nil, "should send stdin")
+			assert.Assert(t, stdout != nil, "should capture stdout")
+			assert.Assert(t, stderr != nil, "should capture stderr")
+
+			assert.Check(t, !strings.ContainsRune(strings.Join(command, ""), '\t'),
+				"Python should not be indented with tabs")
+
+			assert.Assert(t, len(command) == 7)
+			assert.DeepEqual(t, command[:2], []string{"python", "-c"})
+			assert.DeepEqual(t, command[3:], []string{`["alice"]`, `["old"]`, "one", "two"})
+			return expected
+		}
+
+		_, err := WriteServersInPGAdmin(ctx, exec,
+			[]string{"alice"}, []string{"one", "two"}, []string{"old"}, nil)
+		assert.Equal(t, expected, err)
+	})
+
+	t.Run("NoUsers", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			assert.Equal(t, command[3], `[]`)
+			assert.Equal(t, command[4], `[]`)
+			return nil
+		}
+
+		missing, err := WriteServersInPGAdmin(ctx, exec, nil, nil, nil, nil)
+		assert.NilError(t, err)
+		assert.Assert(t, len(missing) == 0)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, err := io.WriteString(stdout, "some warning\n"+`{"missing": ["bob"]}`+"\n")
+			return err
+		}
+
+		missing, err := WriteServersInPGAdmin(ctx, exec, []string{"alice", "bob"}, nil, nil, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, missing, []string{"bob"})
+	})
+
+	t.Run("Flake8", func(t *testing.T) {
+		flake8 := require.Flake8(t)
+
+		called := false
+		exec := func(
+			_ context.Context, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			called = true
+
+			// Expect a python command with an inline script.
+			assert.DeepEqual(t, command[:2], []string{"python", "-c"})
+			assert.Assert(t, len(command) > 2)
+			script := command[2]
+
+			// Write out that inline script.
+			dir := t.TempDir()
+			file := filepath.Join(dir, "script.py")
+			assert.NilError(t, os.WriteFile(file, []byte(script), 0o600))
+
+			// Expect flake8 to be happy. Ignore "E402 module level import not
+			// at top of file" in addition to the defaults.
+			cmd := exec.Command(flake8, "--extend-ignore=E402", file)
+			output, err := cmd.CombinedOutput()
+			assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+
+			return nil
+		}
+
+		_, _ = WriteServersInPGAdmin(ctx, exec, nil, nil, nil, nil)
+		assert.Assert(t, called)
+	})
+
+	t.Run("Servers", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			calls++
+
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, string(b), strings.TrimLeft(`
+{"group":"dev","hostname":"hippo-primary.ns1.svc","name":"ns1/hippo","port":"5432","username":"hippo"}
+{"group":"prod","hostname":"rhino-primary.ns2.svc","name":"ns2/rhino","port":"6000","username":"app"}
+`, "\n"))
+			return nil
+		}
+
+		_, err := WriteServersInPGAdmin(ctx, exec, nil, []string{"dev", "prod"}, nil,
+			[]Server{
+				{
+					Group: "dev",
+					Cluster: &v1beta1.PostgresCluster{
+						ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "hippo"},
+						Spec:       v1beta1.PostgresClusterSpec{Port: initialize.Int32(5432)},
+					},
+				},
+				{
+					Group: "prod",
+					Cluster: &v1beta1.PostgresCluster{
+						ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "rhino"},
+						Spec: v1beta1.PostgresClusterSpec{
+							Port: initialize.Int32(6000),
+							Users: []v1beta1.PostgresUserSpec{
+								{Name: "app"}, {Name: "other"},
+							},
+						},
+					},
+				},
+			})
+		assert.NilError(t, err)
+		assert.Equal(t, calls, 1)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgadmin
+
+import (
+	"bytes"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/config"
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
+	"github.com/adifri/postgres-operator/v5/internal/util"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// StandaloneLoginUsernameKey and StandaloneLoginPasswordKey are the keys of
+	// the Secret that holds the login of a standalone pgAdmin administrator.
+	StandaloneLoginUsernameKey = "username"
+	StandaloneLoginPasswordKey = "password" /* #nosec */
+)
+
+// StandaloneConfigMap populates a ConfigMap with the configuration needed to
+// run a standalone pgAdmin.
+func StandaloneConfigMap(
+	inPGAdmin *v1beta1.PGAdmin,
+	outConfigMap *corev1.ConfigMap,
+) error {
+	initialize.StringMap(&outConfigMap.Data)
+
+	// To avoid spurious reconciles, the following value must not change when
+	// the spec does not change. See [ConfigMap].
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(systemSettings(&inPGAdmin.Spec.Config))
+	if err == nil {
+		outConfigMap.Data[settingsConfigMapKey] = buffer.String()
+	}
+	return err
+}
+
+// StandaloneLoginSecret populates a Secret with the login of the pgAdmin
+// administrator. The password is generated once and kept thereafter.
+func StandaloneLoginSecret(
+	inSecret *corev1.Secret, outSecret *corev1.Secret, hostname string,
+) error {
+	var err error
+
+	initialize.ByteMap(&outSecret.Data)
+
+	// pgAdmin requires the login of its administrator to be an email address.
+	// Use the DNS name of the pgAdmin Service for the domain.
+	outSecret.Data[StandaloneLoginUsernameKey] = []byte("admin@" + hostname)
+
+	if inSecret != nil && len(inSecret.Data[StandaloneLoginPasswordKey]) > 0 {
+		outSecret.Data[StandaloneLoginPasswordKey] = inSecret.Data[StandaloneLoginPasswordKey]
+	} else {
+		var password string
+		password, err = util.GenerateASCIIPassword(util.DefaultGeneratedPasswordLength)
+		outSecret.Data[StandaloneLoginPasswordKey] = []byte(password)
+	}
+
+	return err
+}
+
+// StandalonePod populates a PodSpec with the containers and volumes needed to
+// run a standalone pgAdmin. The administrator logs in using the contents of
+// inSecret.
+func StandalonePod(
+	inPGAdmin *v1beta1.PGAdmin,
+	inConfigMap *corev1.ConfigMap, inSecret *corev1.Secret,
+	outPod *corev1.PodSpec, pgAdminVolume *corev1.PersistentVolumeClaim,
+) {
+	fromSecret := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: inSecret.Name},
+				Key:                  key,
+			},
+		}
+	}
+
+	pod(inConfigMap, outPod, pgAdminVolume,
+		inPGAdmin.Spec.Config,
+		config.StandalonePGAdminContainerImage(inPGAdmin),
+		inPGAdmin.Spec.ImagePullPolicy,
+		inPGAdmin.Spec.Resources,
+		[]corev1.EnvVar{
+			{
+				Name:      "PGADMIN_SETUP_EMAIL",
+				ValueFrom: fromSecret(StandaloneLoginUsernameKey),
+			},
+			{
+				Name:      "PGADMIN_SETUP_PASSWORD",
+				ValueFrom: fromSecret(StandaloneLoginPasswordKey),
+			},
+		})
+
+	// The pgAdmin container mounts the "tmp" volume for its run directory.
+	// Add that volume and mount it at "/tmp" in every container.
+	tmp := corev1.Volume{Name: tmpVolume}
+	tmp.EmptyDir = &corev1.EmptyDirVolumeSource{
+		Medium: corev1.StorageMediumMemory,
+	}
+	outPod.Volumes = append(outPod.Volumes, tmp)
+
+	for i := range outPod.InitContainers {
+		outPod.InitContainers[i].VolumeMounts = append(outPod.InitContainers[i].VolumeMounts,
+			corev1.VolumeMount{Name: tmpVolume, MountPath: "/tmp"})
+	}
+	for i := range outPod.Containers {
+		outPod.Containers[i].VolumeMounts = append(outPod.Containers[i].VolumeMounts,
+			corev1.VolumeMount{Name: tmpVolume, MountPath: "/tmp"})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgadmin
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestStandaloneConfigMap(t *testing.T) {
+	pgAdmin := new(v1beta1.PGAdmin)
+	pgAdmin.Spec.Config.Settings = map[string]interface{}{
+		"AUTHENTICATION_SOURCES": []interface{}{"oauth2", "internal"},
+	}
+
+	configmap := new(corev1.ConfigMap)
+	assert.NilError(t, StandaloneConfigMap(pgAdmin, configmap))
+
+	assert.Equal(t, configmap.Data[settingsConfigMapKey], `{
+  "AUTHENTICATION_SOURCES": [
+    "oauth2",
+    "internal"
+  ],
+  "SERVER_MODE": true
+}
+`)
+}
+
+func TestStandaloneLoginSecret(t *testing.T) {
+	secret := new(corev1.Secret)
+	assert.NilError(t, StandaloneLoginSecret(nil, secret, "pgadmin-x.ns.svc"))
+
+	assert.Equal(t, string(secret.Data[StandaloneLoginUsernameKey]), "admin@pgadmin-x.ns.svc")
+	password := string(secret.Data[StandaloneLoginPasswordKey])
+	assert.Assert(t, len(password) > 0)
+
+	// The password is kept thereafter.
+	next := new(corev1.Secret)
+	assert.NilError(t, StandaloneLoginSecret(secret, next, "pgadmin-x.ns.svc"))
+	assert.Equal(t, string(next.Data[StandaloneLoginPasswordKey]), password)
+}
@@ -28,6 +28,26 @@ import (
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+// The location of pgAdmin files can vary by container image. Look for
+// typical names in the module search path: the PyPI package is named
+// "pgadmin4" while custom builds might use "pgadmin4-web". The pgAdmin
+// packages expect to find themselves on the search path, so prepend
+// that directory there (like pgAdmin does in its WSGI entrypoint).
+// - https://pypi.org/project/pgadmin4/
+// - https://git.postgresql.org/gitweb/?p=pgadmin4.git;f=web/pgAdmin4.wsgi;hb=REL-4_30#l18
+const pythonFindPGAdmin = `
+import importlib.util
+import os
+import sys
+
+spec = importlib.util.find_spec('.pgadmin', (
+    importlib.util.find_spec('pgadmin4') or
+    importlib.util.find_spec('pgadmin4-web')
+).name)
+root = os.path.dirname(spec.submodule_search_locations[0])
+if sys.path[0] != root:
+    sys.path.insert(0, root)`
+
 type Executor func(
 	ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
 ) error
@@ -57,25 +77,7 @@ import types
 cluster = types.SimpleNamespace()
 (cluster.name, cluster.hostname, cluster.port) = sys.argv[1:]`,
 
-		// The location of pgAdmin files can vary by container image. Look for
-		// typical names in the module search path: the PyPI package is named
-		// "pgadmin4" while custom builds might use "pgadmin4-web". The pgAdmin
-		// packages expect to find themselves on the search path, so prepend
-		// that directory there (like pgAdmin does in its WSGI entrypoint).
-		// - https://pypi.org/project/pgadmin4/
-		// - https://git.postgresql.org/gitweb/?p=pgadmin4.git;f=web/pgAdmin4.wsgi;hb=REL-4_30#l18
-		`
-import importlib.util
-import os
-import sys
-
-spec = importlib.util.find_spec('.pgadmin', (
-    importlib.util.find_spec('pgadmin4') or
-    importlib.util.find_spec('pgadmin4-web')
-).name)
-root = os.path.dirname(spec.submodule_search_locations[0])
-if sys.path[0] != root:
-    sys.path.insert(0, root)`,
+		pythonFindPGAdmin,
 
 		// Import pgAdmin modules now that they are on the search path.
 		// NOTE: When testing with the REPL, use the `__enter__` method to
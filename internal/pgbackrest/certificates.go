@@ -48,6 +48,12 @@ const (
 	certRepoSecretKey           = "pgbackrest-repo-host.crt" // #nosec G101 this is a name, not a credential
 )
 
+// SecretCertificateKeys returns the keys of the client and repository host
+// certificates in the Secret populated by [Secret] or [IssuedSecret].
+func SecretCertificateKeys() []string {
+	return []string{certClientSecretKey, certRepoSecretKey}
+}
+
 // certFile concatenates the results of multiple PEM-encoding marshalers.
 func certFile(texts ...encoding.TextMarshaler) ([]byte, error) {
 	var out []byte
@@ -87,8 +93,8 @@ func clientCertificates() []corev1.KeyToPath {
 	}
 }
 
-// clientCommonName returns a client certificate common name (CN) for cluster.
-func clientCommonName(cluster metav1.Object) string {
+// ClientCommonName returns a client certificate common name (CN) for cluster.
+func ClientCommonName(cluster metav1.Object) string {
 	// The common name (ASN.1 OID 2.5.4.3) of a certificate must be
 	// 64 characters or less. ObjectMeta.UID is a UUID in its 36-character
 	// string representation.
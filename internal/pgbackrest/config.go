@@ -460,7 +460,7 @@ func serverConfig(cluster *v1beta1.PostgresCluster) iniSectionSet {
 	// The client certificate for this cluster is allowed to connect for any stanza.
 	// Without the wildcard "*", the "pgbackrest info" and "pgbackrest repo-ls"
 	// commands fail with "access denied" when invoked without a "--stanza" flag.
-	global.Add("tls-server-auth", clientCommonName(cluster)+"=*")
+	global.Add("tls-server-auth", ClientCommonName(cluster)+"=*")
 
 	global.Set("tls-server-ca-file", certAuthorityAbsolutePath)
 	global.Set("tls-server-cert-file", certServerAbsolutePath)
@@ -297,11 +297,18 @@ func addServerContainerAndVolume(
 		container.Resources = *resources
 	}
 
-	// Mount PostgreSQL volumes that are present in pod.
+	// Mount PostgreSQL volumes that are present in pod. The server reads the
+	// files of tablespaces through their volumes.
 	postgresMounts := map[string]corev1.VolumeMount{
 		postgres.DataVolumeMount().Name: postgres.DataVolumeMount(),
 		postgres.WALVolumeMount().Name:  postgres.WALVolumeMount(),
 	}
+	for _, set := range cluster.Spec.InstanceSets {
+		for _, tablespace := range set.TablespaceVolumes {
+			mount := postgres.TablespaceVolumeMount(tablespace.Name)
+			postgresMounts[mount.Name] = mount
+		}
+	}
 	for i := range pod.Volumes {
 		if mount, ok := postgresMounts[pod.Volumes[i].Name]; ok {
 			container.VolumeMounts = append(container.VolumeMounts, mount)
@@ -377,7 +384,7 @@ func AddServerToRepoPod(
 // InstanceCertificates populates the shared Secret with certificates needed to run pgBackRest.
 func InstanceCertificates(ctx context.Context,
 	inCluster *v1beta1.PostgresCluster,
-	inRoot pki.Certificate,
+	inRoots pki.CertificateBundle,
 	inDNS pki.Certificate, inDNSKey pki.PrivateKey,
 	outInstanceCertificates *corev1.Secret,
 ) error {
@@ -496,7 +503,7 @@ func Secret(ctx context.Context,
 		// option can stay the same when PostgreSQL instances and repository
 		// hosts are added or removed.
 		leaf := &pki.LeafCertificate{}
-		commonName := clientCommonName(inCluster)
+		commonName := ClientCommonName(inCluster)
 		dnsNames := []string{commonName}
 
 		if err == nil {
@@ -511,7 +518,7 @@ func Secret(ctx context.Context,
 		}
 
 		if err == nil {
-			outSecret.Data[certAuthoritySecretKey], err = certFile(inRoot.Certificate)
+			outSecret.Data[certAuthoritySecretKey], err = certFile(inRoot.TrustBundle())
 		}
 		if err == nil {
 			outSecret.Data[certClientPrivateKeySecretKey], err = certFile(leaf.PrivateKey)
@@ -550,3 +557,38 @@ func Secret(ctx context.Context,
 
 	return err
 }
+
+// IssuedSecret populates the pgBackRest Secret with certificates that were
+// issued outside of PGO. The client certificate should have the common name
+// of ClientCommonName, and the repository host certificate should have the
+// DNS names of its Pod.
+func IssuedSecret(
+	inRepoHost *appsv1.StatefulSet,
+	inAuthority pki.Certificate,
+	inClient, inRepoHostLeaf *pki.LeafCertificate,
+	outSecret *corev1.Secret,
+) error {
+	var err error
+
+	if inRepoHost != nil {
+		initialize.ByteMap(&outSecret.Data)
+
+		if err == nil {
+			outSecret.Data[certAuthoritySecretKey], err = certFile(inAuthority)
+		}
+		if err == nil {
+			outSecret.Data[certClientPrivateKeySecretKey], err = certFile(inClient.PrivateKey)
+		}
+		if err == nil {
+			outSecret.Data[certClientSecretKey], err = certFile(inClient.Certificate)
+		}
+		if err == nil {
+			outSecret.Data[certRepoPrivateKeySecretKey], err = certFile(inRepoHostLeaf.PrivateKey)
+		}
+		if err == nil {
+			outSecret.Data[certRepoSecretKey], err = certFile(inRepoHostLeaf.Certificate)
+		}
+	}
+
+	return err
+}
@@ -647,6 +647,31 @@ func TestAddServerToInstancePod(t *testing.T) {
         name: instance-secret-name
 		`))
 	})
+
+	t.Run("TablespaceVolumes", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+			Name:              "00",
+			TablespaceVolumes: []v1beta1.TablespaceVolume{{Name: "fast"}},
+		}}
+
+		out := pod.DeepCopy()
+		out.Volumes = append(out.Volumes, corev1.Volume{Name: "tablespace-fast"})
+		AddServerToInstancePod(cluster, out, "instance-secret-name")
+
+		// The TLS server has tablespace volumes mounted.
+		assert.Assert(t, marshalMatches(out.Containers[2].VolumeMounts, `
+- mountPath: /etc/pgbackrest/server
+  name: pgbackrest-server
+  readOnly: true
+- mountPath: /pgdata
+  name: postgres-data
+- mountPath: /pgwal
+  name: postgres-wal
+- mountPath: /tablespaces/fast
+  name: tablespace-fast
+		`))
+	})
 }
 
 func TestAddServerToRepoPod(t *testing.T) {
@@ -892,3 +917,40 @@ func TestSecret(t *testing.T) {
 		assert.Assert(t, !reflect.DeepEqual(leaf.PrivateKey, leaf2.PrivateKey))
 	})
 }
+
+func TestIssuedSecret(t *testing.T) {
+	t.Parallel()
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	client, err := root.GenerateLeafCertificate("client", []string{"client"})
+	assert.NilError(t, err)
+	server, err := root.GenerateLeafCertificate("server", []string{"server"})
+	assert.NilError(t, err)
+
+	t.Run("NoRepoHost", func(t *testing.T) {
+		intent := new(corev1.Secret)
+		assert.NilError(t, IssuedSecret(nil, root.Certificate, client, server, intent))
+		assert.Assert(t, intent.Data == nil)
+	})
+
+	host := new(appsv1.StatefulSet)
+	intent := new(corev1.Secret)
+	assert.NilError(t, IssuedSecret(host, root.Certificate, client, server, intent))
+
+	authority := pki.Certificate{}
+	assert.NilError(t, authority.UnmarshalText(intent.Data["pgbackrest.ca-roots"]))
+	assert.Assert(t, authority.Equal(root.Certificate))
+
+	leaf := &pki.LeafCertificate{}
+	assert.NilError(t, leaf.Certificate.UnmarshalText(intent.Data["pgbackrest-client.crt"]))
+	assert.NilError(t, leaf.PrivateKey.UnmarshalText(intent.Data["pgbackrest-client.key"]))
+	assert.Assert(t, leaf.Certificate.Equal(client.Certificate))
+	assert.Assert(t, leaf.PrivateKey.Equal(client.PrivateKey))
+
+	assert.NilError(t, leaf.Certificate.UnmarshalText(intent.Data["pgbackrest-repo-host.crt"]))
+	assert.NilError(t, leaf.PrivateKey.UnmarshalText(intent.Data["pgbackrest-repo-host.key"]))
+	assert.Assert(t, leaf.Certificate.Equal(server.Certificate))
+	assert.Assert(t, leaf.PrivateKey.Equal(server.PrivateKey))
+}
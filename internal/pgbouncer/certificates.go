@@ -40,6 +40,10 @@ const (
 	certFrontendSecretKey           = "pgbouncer-frontend.crt"
 )
 
+// FrontendCertificateKey returns the key of the generated PgBouncer
+// certificate in the Secret populated by [Secret].
+func FrontendCertificateKey() string { return certFrontendSecretKey }
+
 // backendAuthority creates a volume projection of the PostgreSQL server
 // certificate authority.
 func backendAuthority(postgres *corev1.SecretProjection) corev1.VolumeProjection {
@@ -21,8 +21,10 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/adifri/postgres-operator/v5/internal/naming"
+	"github.com/adifri/postgres-operator/v5/internal/postgres"
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -31,19 +33,34 @@ const (
 
 	authFileAbsolutePath  = configDirectory + "/" + authFileProjectionPath
 	emptyFileAbsolutePath = configDirectory + "/" + emptyFileProjectionPath
+	hbaFileAbsolutePath   = configDirectory + "/" + hbaFileProjectionPath
 	iniFileAbsolutePath   = configDirectory + "/" + iniFileProjectionPath
 
 	authFileProjectionPath  = "~postgres-operator/users.txt"
 	emptyFileProjectionPath = "pgbouncer.ini"
+	hbaFileProjectionPath   = "~postgres-operator/hba.conf"
 	iniFileProjectionPath   = "~postgres-operator.ini"
 
 	authFileSecretKey   = "pgbouncer-users.txt" // #nosec G101 this is a name, not a credential
 	passwordSecretKey   = "pgbouncer-password"  // #nosec G101 this is a name, not a credential
 	verifierSecretKey   = "pgbouncer-verifier"  // #nosec G101 this is a name, not a credential
 	emptyConfigMapKey   = "pgbouncer-empty"
+	hbaFileConfigMapKey = "pgbouncer-hba.conf"
 	iniFileConfigMapKey = "pgbouncer.ini"
 )
 
+// consolePasswordSecretKey returns the key of the PgBouncer Secret that holds
+// the plaintext password of the admin console user name.
+func consolePasswordSecretKey(name string) string {
+	return "pgbouncer-console-" + name + "-password"
+}
+
+// consoleVerifierSecretKey returns the key of the PgBouncer Secret that holds
+// the SCRAM verifier of the admin console user name.
+func consoleVerifierSecretKey(name string) string {
+	return "pgbouncer-console-" + name + "-verifier"
+}
+
 const (
 	iniGeneratedWarning = "" +
 		"# Generated by postgres-operator. DO NOT EDIT.\n" +
@@ -71,8 +88,9 @@ func (vs iniValueSet) String() string {
 	return b.String()
 }
 
-// authFileContents returns a PgBouncer user database.
-func authFileContents(password string) []byte {
+// authFileContents returns a PgBouncer user database. The console argument
+// maps the name of each admin console user to its SCRAM verifier.
+func authFileContents(password string, console map[string]string) []byte {
 	// > There should be at least 2 fields, surrounded by double quotes.
 	// > Double quotes in a field value can be escaped by writing two double quotes.
 	// - https://www.pgbouncer.org/config.html#authentication-file-format
@@ -82,14 +100,127 @@ func authFileContents(password string) []byte {
 
 	user1 := quote(postgresqlUser) + " " + quote(password) + "\n"
 
+	// PgBouncer authenticates admin console users using only this file, so
+	// those users must be listed here. Their SCRAM verifiers work with either
+	// the "md5" or "scram-sha-256" authentication method.
+	// - https://www.pgbouncer.org/config.html#auth_file
+	names := make([]string, 0, len(console))
+	for name := range console {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		user1 += quote(name) + " " + quote(console[name]) + "\n"
+	}
+
 	return []byte(user1)
 }
 
-func clusterINI(cluster *v1beta1.PostgresCluster) string {
-	var (
-		pgBouncerPort = *cluster.Spec.Proxy.PGBouncer.Port
-		postgresPort  = *cluster.Spec.Port
-	)
+// consoleUsers returns the sorted names of all admin console users in auth.
+func consoleUsers(auth *v1beta1.PGBouncerAuthentication) []string {
+	var names []string
+	if auth != nil {
+		seen := make(map[v1beta1.PGBouncerConsoleUser]bool)
+		for _, list := range [][]v1beta1.PGBouncerConsoleUser{
+			auth.AdminUsers, auth.StatsUsers,
+		} {
+			for _, name := range list {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, string(name))
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hbaFileContents returns the PgBouncer HBA file for auth.
+// - https://www.pgbouncer.org/config.html#hba-file-format
+func hbaFileContents(auth *v1beta1.PGBouncerAuthentication) string {
+	// PgBouncer understands the same records as PostgreSQL, except that
+	// it does not support groups, files, or some authentication methods.
+	// Expand each rule into one record for every combination of database,
+	// user, and network.
+	var records []postgres.HostBasedAuthentication
+
+	// PGO pauses and resumes PgBouncer through the admin console from inside
+	// the pod. This comes first so that no rule can reject it.
+	records = append(records, *postgres.NewHBA().TLS().
+		Database("pgbouncer").User(postgresqlUser).Network(consoleAddress + "/32").
+		Method("scram-sha-256"))
+
+	for _, rule := range auth.Rules {
+		databases := []string{"all"}
+		if len(rule.Databases) > 0 {
+			databases = make([]string, len(rule.Databases))
+			for i := range rule.Databases {
+				databases[i] = string(rule.Databases[i])
+			}
+		}
+		users := []string{"all"}
+		if len(rule.Users) > 0 {
+			users = make([]string, len(rule.Users))
+			for i := range rule.Users {
+				users[i] = string(rule.Users[i])
+			}
+		}
+		networks := []string{"all"}
+		if len(rule.Networks) > 0 {
+			networks = rule.Networks
+		}
+
+		for _, database := range databases {
+			for _, user := range users {
+				for _, network := range networks {
+					hba := postgres.NewHBA().Method(rule.Method)
+
+					switch rule.Connection {
+					case "host":
+						hba.TCP()
+					case "hostnossl":
+						hba.NoSSL()
+					default:
+						hba.TLS()
+					}
+					if database != "all" {
+						hba.Database(database)
+					}
+					if user != "all" {
+						hba.User(user)
+					}
+					if network != "all" {
+						hba.Network(network)
+					}
+
+					records = append(records, *hba)
+				}
+			}
+		}
+	}
+
+	// When there are no rules, allow TLS connections to any database using
+	// passwords. This matches the behavior without an HBA file.
+	if len(auth.Rules) == 0 {
+		records = append(records, *postgres.NewHBA().TLS().Method("md5"))
+	}
+
+	var b strings.Builder
+	b.WriteString(iniGeneratedWarning)
+	for _, record := range records {
+		b.WriteString(record.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// clusterGlobal returns the settings in the [pgbouncer] section of the main
+// PgBouncer configuration file. When readOnly is true, the settings are for
+// PgBouncer pods that connect to PostgreSQL replicas.
+func clusterGlobal(cluster *v1beta1.PostgresCluster, readOnly bool) iniValueSet {
+	pgBouncerPort := *cluster.Spec.Proxy.PGBouncer.Port
 
 	global := iniValueSet{
 		// Prior to PostgreSQL v12, the default setting for "extra_float_digits"
@@ -110,13 +241,6 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 		"auth_query": "SELECT username, password from pgbouncer.get_auth($1)",
 		"auth_user":  postgresqlUser,
 
-		// TODO(cbandy): Use an HBA file to control authentication of PgBouncer
-		// accounts; e.g. "admin_users" below.
-		// - https://www.pgbouncer.org/config.html#hba-file-format
-		//"auth_hba_file": "",
-		//"auth_type":     "hba",
-		//"admin_users": "pgbouncer",
-
 		// Require TLS encryption on client connections.
 		"client_tls_sslmode":   "require",
 		"client_tls_cert_file": certFrontendAbsolutePath,
@@ -133,16 +257,52 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 
 		// Disable Unix sockets to keep the filesystem read-only.
 		"unix_socket_dir": "",
+
+		// Allow PGO to pause and resume PgBouncer through the admin console.
+		"admin_users": postgresqlUser,
+	}
+
+	// When authentication is specified, use an HBA file to control how clients
+	// authenticate and allow console users to access the admin console.
+	// - https://www.pgbouncer.org/config.html#auth_type
+	if auth := cluster.Spec.Proxy.PGBouncer.Authentication; auth != nil {
+		global["auth_hba_file"] = hbaFileAbsolutePath
+		global["auth_type"] = "hba"
+
+		admins := []string{postgresqlUser}
+		var stats []string
+		for _, name := range auth.AdminUsers {
+			admins = append(admins, string(name))
+		}
+		for _, name := range auth.StatsUsers {
+			stats = append(stats, string(name))
+		}
+		global["admin_users"] = strings.Join(admins, ",")
+		global["stats_users"] = strings.Join(stats, ",")
 	}
 
 	// Override the above with any specified settings.
 	for k, v := range cluster.Spec.Proxy.PGBouncer.Config.Global {
 		global[k] = v
 	}
+	if readOnly {
+		for k, v := range cluster.Spec.Proxy.PGBouncer.ReadOnly.Global {
+			global[k] = v
+		}
+	}
 
 	// Prevent the user from bypassing the main configuration file.
 	global["conffile"] = iniFileAbsolutePath
 
+	return global
+}
+
+// clusterINI returns the main PgBouncer configuration file. When readOnly is
+// true, the file is for PgBouncer pods that connect to PostgreSQL replicas.
+func clusterINI(cluster *v1beta1.PostgresCluster, readOnly bool) string {
+	global := clusterGlobal(cluster, readOnly)
+	postgresPort := *cluster.Spec.Port
+
 	// Use a wildcard to automatically create connection pools based on database
 	// names. These pools connect to cluster's primary service. The service name
 	// is an RFC 1123 DNS label so it does not need to be quoted nor escaped.
@@ -164,7 +324,60 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 		databases = iniValueSet(cluster.Spec.Proxy.PGBouncer.Config.Databases)
 	}
 
-	users := iniValueSet(cluster.Spec.Proxy.PGBouncer.Config.Users)
+	// Read-only pools always connect to cluster's replica service. Specified
+	// databases likely refer to the primary, so they do not apply here.
+	if readOnly {
+		databases = iniValueSet{
+			"*": fmt.Sprintf("host=%s port=%d",
+				naming.ClusterReplicaService(cluster).Name, postgresPort),
+		}
+	}
+
+	// Add any valid pool definitions. Pools connect to the primary service
+	// unless they target replicas or PgBouncer itself is read-only.
+	pools, _ := databasePools(cluster)
+	for _, pool := range pools {
+		service := naming.ClusterPrimaryService(cluster).Name
+		if readOnly || pool.Target == v1beta1.PGBouncerPoolTargetReplica {
+			service = naming.ClusterReplicaService(cluster).Name
+		}
+
+		settings := []string{fmt.Sprintf("host=%s port=%d", service, postgresPort)}
+		if pool.PoolMode != "" {
+			settings = append(settings, "pool_mode="+pool.PoolMode)
+		}
+		if pool.PoolSize != nil {
+			settings = append(settings, fmt.Sprintf("pool_size=%d", *pool.PoolSize))
+		}
+		if pool.ReservePoolSize != nil {
+			settings = append(settings, fmt.Sprintf("reserve_pool=%d", *pool.ReservePoolSize))
+		}
+		if pool.MaxDBConnections != nil {
+			settings = append(settings, fmt.Sprintf("max_db_connections=%d", *pool.MaxDBConnections))
+		}
+
+		databases[quoteININame(string(pool.Database))] = strings.Join(settings, " ")
+	}
+
+	users := iniValueSet{}
+	for k, v := range cluster.Spec.Proxy.PGBouncer.Config.Users {
+		users[k] = v
+	}
+
+	// Add any valid user settings.
+	userSettings, _ := userPools(cluster)
+	for _, pool := range userSettings {
+		var settings []string
+		if pool.PoolMode != "" {
+			settings = append(settings, "pool_mode="+pool.PoolMode)
+		}
+		if pool.MaxUserConnections != nil {
+			settings = append(settings, fmt.Sprintf("max_user_connections=%d", *pool.MaxUserConnections))
+		}
+		if len(settings) > 0 {
+			users[quoteININame(string(pool.User))] = strings.Join(settings, " ")
+		}
+	}
 
 	// Include any custom configuration file, then apply global settings, then
 	// pool definitions.
@@ -181,10 +394,98 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 	return result
 }
 
+// quoteININame returns name quoted for use as a key in the [databases] or
+// [users] section of a PgBouncer configuration file, when necessary.
+// - https://www.pgbouncer.org/config.html#section-databases
+func quoteININame(name string) string {
+	for _, r := range name {
+		if !(r == '_' || ('0' <= r && r <= '9') || ('A' <= r && r <= 'Z') || ('a' <= r && r <= 'z')) {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+	}
+	return name
+}
+
+// databasePools returns the pool definitions of cluster that are valid and
+// errors describing those that are not.
+func databasePools(
+	cluster *v1beta1.PostgresCluster,
+) ([]v1beta1.PGBouncerDatabasePool, field.ErrorList) {
+	var valid []v1beta1.PGBouncerDatabasePool
+	var errs field.ErrorList
+
+	if cluster.Spec.Proxy.PGBouncer.Pools == nil {
+		return valid, errs
+	}
+
+	path := field.NewPath("spec", "proxy", "pgBouncer", "pools", "databases")
+	for i, pool := range cluster.Spec.Proxy.PGBouncer.Pools.Databases {
+		path := path.Index(i)
+		var invalid field.ErrorList
+
+		// PgBouncer handles connections to the "pgbouncer" database itself.
+		if pool.Database == "pgbouncer" {
+			invalid = append(invalid, field.Invalid(path.Child("database"),
+				pool.Database, "is reserved for the PgBouncer admin console"))
+		}
+
+		// Pools cannot be defined in two places.
+		if _, ok := cluster.Spec.Proxy.PGBouncer.Config.Databases[string(pool.Database)]; ok {
+			invalid = append(invalid, field.Duplicate(path.Child("database"), pool.Database))
+		}
+
+		// A pool cannot hold more connections than its database allows.
+		if pool.MaxDBConnections != nil && *pool.MaxDBConnections > 0 && pool.PoolSize != nil {
+			size := *pool.PoolSize
+			if pool.ReservePoolSize != nil {
+				size += *pool.ReservePoolSize
+			}
+			if size > *pool.MaxDBConnections {
+				invalid = append(invalid, field.Invalid(path.Child("maxDBConnections"),
+					*pool.MaxDBConnections, fmt.Sprintf(
+						"should be at least poolSize plus reservePoolSize (%d)", size)))
+			}
+		}
+
+		if len(invalid) == 0 {
+			valid = append(valid, pool)
+		}
+		errs = append(errs, invalid...)
+	}
+
+	return valid, errs
+}
+
+// userPools returns the user settings of cluster that are valid and errors
+// describing those that are not.
+func userPools(
+	cluster *v1beta1.PostgresCluster,
+) ([]v1beta1.PGBouncerUserPool, field.ErrorList) {
+	var valid []v1beta1.PGBouncerUserPool
+	var errs field.ErrorList
+
+	if cluster.Spec.Proxy.PGBouncer.Pools == nil {
+		return valid, errs
+	}
+
+	path := field.NewPath("spec", "proxy", "pgBouncer", "pools", "users")
+	for i, pool := range cluster.Spec.Proxy.PGBouncer.Pools.Users {
+		// User settings cannot be defined in two places.
+		if _, ok := cluster.Spec.Proxy.PGBouncer.Config.Users[string(pool.User)]; ok {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("user"), pool.User))
+		} else {
+			valid = append(valid, pool)
+		}
+	}
+
+	return valid, errs
+}
+
 // podConfigFiles returns projections of PgBouncer's configuration files to
 // include in the configuration volume.
 func podConfigFiles(
 	config v1beta1.PGBouncerConfiguration,
+	auth *v1beta1.PGBouncerAuthentication,
 	configmap *corev1.ConfigMap, secret *corev1.Secret,
 ) []corev1.VolumeProjection {
 	// Start with an empty file at /etc/pgbouncer/pgbouncer.ini. This file can
@@ -209,16 +510,24 @@ func podConfigFiles(
 	projections = append(projections, config.Files...)
 
 	// Add our non-empty configurations last so that they take precedence.
+	generated := []corev1.KeyToPath{{
+		Key:  iniFileConfigMapKey,
+		Path: iniFileProjectionPath,
+	}}
+	if auth != nil {
+		generated = append(generated, corev1.KeyToPath{
+			Key:  hbaFileConfigMapKey,
+			Path: hbaFileProjectionPath,
+		})
+	}
+
 	projections = append(projections, []corev1.VolumeProjection{
 		{
 			ConfigMap: &corev1.ConfigMapProjection{
 				LocalObjectReference: corev1.LocalObjectReference{
 					Name: configmap.Name,
 				},
-				Items: []corev1.KeyToPath{{
-					Key:  iniFileConfigMapKey,
-					Path: iniFileProjectionPath,
-				}},
+				Items: generated,
 			},
 		},
 		{
@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
 	"github.com/adifri/postgres-operator/v5/internal/testing/require"
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -45,8 +46,76 @@ func TestAuthFileContents(t *testing.T) {
 	t.Parallel()
 
 	password := `very"random`
-	data := authFileContents(password)
+	data := authFileContents(password, nil)
 	assert.Equal(t, string(data), `"_crunchypgbouncer" "very""random"`+"\n")
+
+	t.Run("ConsoleUsers", func(t *testing.T) {
+		data := authFileContents(password, map[string]string{
+			"zed": "SCRAM-SHA-256$z",
+			"ann": "SCRAM-SHA-256$a",
+		})
+		assert.Equal(t, string(data), strings.Join([]string{
+			`"_crunchypgbouncer" "very""random"`,
+			`"ann" "SCRAM-SHA-256$a"`,
+			`"zed" "SCRAM-SHA-256$z"`,
+		}, "\n")+"\n")
+	})
+}
+
+func TestConsoleUsers(t *testing.T) {
+	t.Parallel()
+
+	assert.Assert(t, consoleUsers(nil) == nil)
+	assert.DeepEqual(t, consoleUsers(&v1beta1.PGBouncerAuthentication{
+		AdminUsers: []v1beta1.PGBouncerConsoleUser{"ops", "dba"},
+		StatsUsers: []v1beta1.PGBouncerConsoleUser{"monitor", "ops"},
+	}), []string{"dba", "monitor", "ops"})
+}
+
+func TestHBAFileContents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, hbaFileContents(&v1beta1.PGBouncerAuthentication{}), strings.Trim(`
+# Generated by postgres-operator. DO NOT EDIT.
+# Your changes will not be saved.
+hostssl "pgbouncer" "_crunchypgbouncer" "127.0.0.1/32" scram-sha-256
+hostssl all all all md5
+		`, "\t\n")+"\n")
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		assert.Equal(t, hbaFileContents(&v1beta1.PGBouncerAuthentication{
+			Rules: []v1beta1.PGBouncerHBARule{
+				{
+					Databases: []v1beta1.PostgresIdentifier{"pgbouncer"},
+					Users:     []v1beta1.PostgresIdentifier{"ops", "monitor"},
+					Networks:  []string{"10.0.0.0/8"},
+					Method:    "scram-sha-256",
+				},
+				{
+					Connection: "host",
+					Databases:  []v1beta1.PostgresIdentifier{"pgbouncer"},
+					Method:     "reject",
+				},
+				{
+					Connection: "hostssl",
+					Databases:  []v1beta1.PostgresIdentifier{"app"},
+					Networks:   []string{"10.1.0.0/16", "10.2.0.0/16"},
+					Method:     "md5",
+				},
+			},
+		}), strings.Trim(`
+# Generated by postgres-operator. DO NOT EDIT.
+# Your changes will not be saved.
+hostssl "pgbouncer" "_crunchypgbouncer" "127.0.0.1/32" scram-sha-256
+hostssl "pgbouncer" "ops" "10.0.0.0/8" scram-sha-256
+hostssl "pgbouncer" "monitor" "10.0.0.0/8" scram-sha-256
+host "pgbouncer" all all reject
+hostssl "app" all "10.1.0.0/16" md5
+hostssl "app" all "10.2.0.0/16" md5
+		`, "\t\n")+"\n")
+	})
 }
 
 func TestClusterINI(t *testing.T) {
@@ -64,7 +133,7 @@ func TestClusterINI(t *testing.T) {
 	*cluster.Spec.Proxy.PGBouncer.Port = 8888
 
 	t.Run("Default", func(t *testing.T) {
-		assert.Equal(t, clusterINI(cluster), strings.Trim(`
+		assert.Equal(t, clusterINI(cluster, false), strings.Trim(`
 # Generated by postgres-operator. DO NOT EDIT.
 # Your changes will not be saved.
 
@@ -72,6 +141,7 @@ func TestClusterINI(t *testing.T) {
 %include /etc/pgbouncer/pgbouncer.ini
 
 [pgbouncer]
+admin_users = _crunchypgbouncer
 auth_file = /etc/pgbouncer/~postgres-operator/users.txt
 auth_query = SELECT username, password from pgbouncer.get_auth($1)
 auth_user = _crunchypgbouncer
@@ -104,7 +174,7 @@ unix_socket_dir =
 			"app": "mode=rad",
 		}
 
-		assert.Equal(t, clusterINI(cluster), strings.Trim(`
+		assert.Equal(t, clusterINI(cluster, false), strings.Trim(`
 # Generated by postgres-operator. DO NOT EDIT.
 # Your changes will not be saved.
 
@@ -112,6 +182,7 @@ unix_socket_dir =
 %include /etc/pgbouncer/pgbouncer.ini
 
 [pgbouncer]
+admin_users = _crunchypgbouncer
 auth_file = /etc/pgbouncer/~postgres-operator/users.txt
 auth_query = SELECT username, password from pgbouncer.get_auth($1)
 auth_user = _crunchypgbouncer
@@ -137,8 +208,191 @@ app = mode=rad
 
 		// The "conffile" setting cannot be changed.
 		cluster.Spec.Proxy.PGBouncer.Config.Global["conffile"] = "too-far"
-		assert.Assert(t, !strings.Contains(clusterINI(cluster), "too-far"))
+		assert.Assert(t, !strings.Contains(clusterINI(cluster, false), "too-far"))
 	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config.Global = map[string]string{
+			"pool_mode": "session",
+			"verbose":   "whomp",
+		}
+		cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+			Global: map[string]string{"pool_mode": "transaction"},
+		}
+
+		result := clusterINI(cluster, true)
+
+		// Read-only settings override global settings.
+		assert.Assert(t, strings.Contains(result, "\npool_mode = transaction\n"), "got:\n%s", result)
+		assert.Assert(t, strings.Contains(result, "\nverbose = whomp\n"), "got:\n%s", result)
+
+		// Specified databases are replaced by the replica service.
+		assert.Assert(t, strings.HasSuffix(result, strings.Trim(`
+[databases]
+* = host=foo-baz-replicas port=9999
+
+[users]
+app = mode=rad
+		`, "\t\n")+"\n"), "got:\n%s", result)
+
+		// The main file is unaffected.
+		assert.Assert(t, strings.Contains(clusterINI(cluster, false), "\npool_mode = session\n"))
+	})
+
+	t.Run("Authentication", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config = v1beta1.PGBouncerConfiguration{}
+		cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthentication{
+			AdminUsers: []v1beta1.PGBouncerConsoleUser{"ops", "dba"},
+			StatsUsers: []v1beta1.PGBouncerConsoleUser{"monitor"},
+		}
+
+		assert.Equal(t, clusterINI(cluster, false), strings.Trim(`
+# Generated by postgres-operator. DO NOT EDIT.
+# Your changes will not be saved.
+
+[pgbouncer]
+%include /etc/pgbouncer/pgbouncer.ini
+
+[pgbouncer]
+admin_users = _crunchypgbouncer,ops,dba
+auth_file = /etc/pgbouncer/~postgres-operator/users.txt
+auth_hba_file = /etc/pgbouncer/~postgres-operator/hba.conf
+auth_query = SELECT username, password from pgbouncer.get_auth($1)
+auth_type = hba
+auth_user = _crunchypgbouncer
+client_tls_ca_file = /etc/pgbouncer/~postgres-operator/frontend-ca.crt
+client_tls_cert_file = /etc/pgbouncer/~postgres-operator/frontend-tls.crt
+client_tls_key_file = /etc/pgbouncer/~postgres-operator/frontend-tls.key
+client_tls_sslmode = require
+conffile = /etc/pgbouncer/~postgres-operator.ini
+ignore_startup_parameters = extra_float_digits
+listen_addr = *
+listen_port = 8888
+server_tls_ca_file = /etc/pgbouncer/~postgres-operator/backend-ca.crt
+server_tls_sslmode = verify-full
+stats_users = monitor
+unix_socket_dir =
+
+[databases]
+* = host=foo-baz-primary port=9999
+		`, "\t\n")+"\n")
+	})
+}
+
+func TestClusterINIPools(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Default()
+
+	cluster.Name = "foo-baz"
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Pools = &v1beta1.PGBouncerPools{
+		Databases: []v1beta1.PGBouncerDatabasePool{
+			{Database: "app", PoolMode: "transaction", PoolSize: initialize.Int32(10)},
+			{Database: "reports", Target: "replica", ReservePoolSize: initialize.Int32(2),
+				MaxDBConnections: initialize.Int32(50)},
+			{Database: "odd name", PoolSize: initialize.Int32(5)},
+			{Database: "pgbouncer"},
+		},
+		Users: []v1beta1.PGBouncerUserPool{
+			{User: "batch", PoolMode: "session", MaxUserConnections: initialize.Int32(3)},
+			{User: "nothing"},
+		},
+	}
+	cluster.Spec.Proxy.PGBouncer.Config.Users = map[string]string{
+		"app": "mode=rad",
+	}
+	cluster.Default()
+
+	t.Run("Primary", func(t *testing.T) {
+		result := clusterINI(cluster, false)
+		assert.Assert(t, strings.HasSuffix(result, strings.Trim(`
+[databases]
+"odd name" = host=foo-baz-primary port=5432 pool_size=5
+* = host=foo-baz-primary port=5432
+app = host=foo-baz-primary port=5432 pool_mode=transaction pool_size=10
+reports = host=foo-baz-replicas port=5432 reserve_pool=2 max_db_connections=50
+
+[users]
+app = mode=rad
+batch = pool_mode=session max_user_connections=3
+		`, "\t\n")+"\n"), "got:\n%s", result)
+
+		// The spec is not modified.
+		assert.Equal(t, len(cluster.Spec.Proxy.PGBouncer.Config.Users), 1)
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.ReadOnly = new(v1beta1.PGBouncerReadOnlySpec)
+
+		// Every pool connects to replicas.
+		result := clusterINI(cluster, true)
+		assert.Assert(t, strings.Contains(result, strings.Trim(`
+[databases]
+"odd name" = host=foo-baz-replicas port=5432 pool_size=5
+* = host=foo-baz-replicas port=5432
+app = host=foo-baz-replicas port=5432 pool_mode=transaction pool_size=10
+reports = host=foo-baz-replicas port=5432 reserve_pool=2 max_db_connections=50
+		`, "\t\n")+"\n"), "got:\n%s", result)
+	})
+}
+
+func TestDatabasePools(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+
+	valid, errs := databasePools(cluster)
+	assert.Assert(t, len(valid) == 0)
+	assert.Assert(t, len(errs) == 0)
+
+	cluster.Spec.Proxy.PGBouncer.Config.Databases = map[string]string{"raw": "host=x"}
+	cluster.Spec.Proxy.PGBouncer.Pools = &v1beta1.PGBouncerPools{
+		Databases: []v1beta1.PGBouncerDatabasePool{
+			{Database: "ok", PoolSize: initialize.Int32(5), MaxDBConnections: initialize.Int32(5)},
+			{Database: "pgbouncer"},
+			{Database: "raw"},
+			{Database: "big", PoolSize: initialize.Int32(5), ReservePoolSize: initialize.Int32(1),
+				MaxDBConnections: initialize.Int32(5)},
+			{Database: "unlimited", PoolSize: initialize.Int32(5), MaxDBConnections: initialize.Int32(0)},
+		},
+	}
+
+	valid, errs = databasePools(cluster)
+	assert.Equal(t, len(valid), 2)
+	assert.Equal(t, valid[0].Database, v1beta1.PostgresIdentifier("ok"))
+	assert.Equal(t, valid[1].Database, v1beta1.PostgresIdentifier("unlimited"))
+
+	assert.Equal(t, len(errs), 3)
+	assert.ErrorContains(t, errs[0], "spec.proxy.pgBouncer.pools.databases[1].database")
+	assert.ErrorContains(t, errs[0], "admin console")
+	assert.ErrorContains(t, errs[1], "spec.proxy.pgBouncer.pools.databases[2].database: Duplicate")
+	assert.ErrorContains(t, errs[2], "spec.proxy.pgBouncer.pools.databases[3].maxDBConnections")
+	assert.ErrorContains(t, errs[2], "(6)")
+}
+
+func TestUserPools(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Config.Users = map[string]string{"raw": "pool_mode=session"}
+	cluster.Spec.Proxy.PGBouncer.Pools = &v1beta1.PGBouncerPools{
+		Users: []v1beta1.PGBouncerUserPool{{User: "raw"}, {User: "ok"}},
+	}
+
+	valid, errs := userPools(cluster)
+	assert.DeepEqual(t, valid, []v1beta1.PGBouncerUserPool{{User: "ok"}})
+	assert.Equal(t, len(errs), 1)
+	assert.ErrorContains(t, errs[0], "spec.proxy.pgBouncer.pools.users[0].user: Duplicate")
 }
 
 func TestPodConfigFiles(t *testing.T) {
@@ -149,7 +403,7 @@ func TestPodConfigFiles(t *testing.T) {
 	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-shh"}}
 
 	t.Run("Default", func(t *testing.T) {
-		projections := podConfigFiles(config, configmap, secret)
+		projections := podConfigFiles(config, nil, configmap, secret)
 		assert.Assert(t, marshalMatches(projections, `
 - configMap:
     items:
@@ -182,7 +436,7 @@ func TestPodConfigFiles(t *testing.T) {
 			}},
 		}
 
-		projections := podConfigFiles(config, configmap, secret)
+		projections := podConfigFiles(config, nil, configmap, secret)
 		assert.Assert(t, marshalMatches(projections, `
 - configMap:
     items:
@@ -201,6 +455,30 @@ func TestPodConfigFiles(t *testing.T) {
     - key: pgbouncer.ini
       path: ~postgres-operator.ini
     name: some-cm
+- secret:
+    items:
+    - key: pgbouncer-users.txt
+      path: ~postgres-operator/users.txt
+    name: some-shh
+		`))
+	})
+
+	t.Run("Authentication", func(t *testing.T) {
+		auth := new(v1beta1.PGBouncerAuthentication)
+		projections := podConfigFiles(v1beta1.PGBouncerConfiguration{}, auth, configmap, secret)
+		assert.Assert(t, marshalMatches(projections, `
+- configMap:
+    items:
+    - key: pgbouncer-empty
+      path: pgbouncer.ini
+    name: some-cm
+- configMap:
+    items:
+    - key: pgbouncer.ini
+      path: ~postgres-operator.ini
+    - key: pgbouncer-hba.conf
+      path: ~postgres-operator/hba.conf
+    name: some-cm
 - secret:
     items:
     - key: pgbouncer-users.txt
@@ -0,0 +1,107 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbouncer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/adifri/postgres-operator/v5/internal/logging"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// consoleAddress is where PGO connects to the PgBouncer admin console from
+// inside a PgBouncer Pod.
+const consoleAddress = "127.0.0.1"
+
+// Executor calls commands in a PgBouncer container.
+type Executor func(
+	ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+) error
+
+// console runs command in the PgBouncer admin console as the PGO user. It
+// stops waiting for the command after timeout.
+// - https://www.pgbouncer.org/usage.html#admin-console
+func (exec Executor) console(
+	ctx context.Context, inCluster *v1beta1.PostgresCluster, inSecret *corev1.Secret,
+	timeout time.Duration, command string,
+) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	password, ok := inSecret.Data[passwordSecretKey]
+	if !ok {
+		return "", errors.Errorf("missing %q in secret %s", passwordSecretKey, inSecret.Name)
+	}
+
+	// Read the password from stdin so it does not appear in the list of
+	// processes. Coreutils `timeout` stops psql when a command like PAUSE
+	// waits too long; PgBouncer carries on with the command regardless.
+	const script = `
+read -r -d '' PGPASSWORD || true
+export PGPASSWORD
+exec timeout "$2" psql --no-psqlrc --quiet --set=ON_ERROR_STOP=1 --command="$3" -- "$1"
+`
+	dsn := fmt.Sprintf("host=%s port=%d dbname=pgbouncer user=%s sslmode=require connect_timeout=5",
+		consoleAddress, *inCluster.Spec.Proxy.PGBouncer.Port, postgresqlUser)
+
+	err := exec(ctx, bytes.NewReader(password), &stdout, &stderr,
+		"bash", "-ceu", "--", script, "-", dsn,
+		fmt.Sprint(int64(timeout.Round(time.Second)/time.Second)), command)
+
+	logging.FromContext(ctx).V(1).Info("pgbouncer console",
+		"command", command,
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+	)
+
+	return stderr.String(), err
+}
+
+// Pause runs the PAUSE command of the admin console. PgBouncer waits for
+// current queries to finish then disconnects from PostgreSQL. New client
+// queries wait until Resume is called. When current queries do not finish
+// within timeout, Pause returns an error and PgBouncer continues to pause.
+// - https://www.pgbouncer.org/usage.html#pause-db
+func (exec Executor) Pause(
+	ctx context.Context, inCluster *v1beta1.PostgresCluster, inSecret *corev1.Secret,
+	timeout time.Duration,
+) error {
+	_, err := exec.console(ctx, inCluster, inSecret, timeout, "PAUSE")
+	return err
+}
+
+// Resume runs the RESUME command of the admin console. PgBouncer reconnects to
+// PostgreSQL and sends queries that are waiting. It does nothing when
+// PgBouncer is not paused.
+// - https://www.pgbouncer.org/usage.html#resume-db
+func (exec Executor) Resume(
+	ctx context.Context, inCluster *v1beta1.PostgresCluster, inSecret *corev1.Secret,
+) error {
+	stderr, err := exec.console(ctx, inCluster, inSecret, 30*time.Second, "RESUME")
+
+	// PgBouncer restarts unpaused, and RESUME can be called more than once.
+	if err != nil && strings.Contains(stderr, "not paused") {
+		err = nil
+	}
+	return err
+}
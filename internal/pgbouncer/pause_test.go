@@ -0,0 +1,102 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbouncer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestExecutorPause(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+	cluster.Default()
+
+	secret := new(corev1.Secret)
+	secret.Data = map[string][]byte{"pgbouncer-password": []byte("secret!")}
+
+	expected := errors.New("bang")
+	called := false
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		called = true
+		assert.Assert(t, stderr != nil, "should capture stderr")
+		assert.Assert(t, stdout != nil, "should capture stdout")
+
+		// The password goes through stdin.
+		b, err := io.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Equal(t, string(b), "secret!")
+
+		assert.Equal(t, len(command), 8)
+		assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+		assert.DeepEqual(t, command[4:], []string{"-",
+			"host=127.0.0.1 port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require connect_timeout=5",
+			"90", "PAUSE",
+		})
+
+		script := command[3]
+		assert.Assert(t, strings.Contains(script, `read -r -d '' PGPASSWORD`))
+		assert.Assert(t, strings.Contains(script, `exec timeout "$2" psql`))
+		return expected
+	}
+
+	err := Executor(exec).Pause(context.Background(), cluster, secret, 90*time.Second)
+	assert.Equal(t, expected, err, "should call exec")
+	assert.Assert(t, called)
+
+	t.Run("NoPassword", func(t *testing.T) {
+		err := Executor(exec).Pause(context.Background(), cluster, new(corev1.Secret), time.Second)
+		assert.ErrorContains(t, err, "pgbouncer-password")
+	})
+}
+
+func TestExecutorResume(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+	cluster.Default()
+
+	secret := new(corev1.Secret)
+	secret.Data = map[string][]byte{"pgbouncer-password": []byte("secret!")}
+
+	expected := errors.New("bang")
+	output := ""
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		assert.Equal(t, len(command), 8)
+		assert.DeepEqual(t, command[6:], []string{"30", "RESUME"})
+		_, _ = stderr.Write([]byte(output))
+		return expected
+	}
+
+	err := Executor(exec).Resume(context.Background(), cluster, secret)
+	assert.Equal(t, expected, err, "should call exec")
+
+	// PgBouncer that is not paused is not an error.
+	output = "ERROR:  pooler is not paused/suspended\n"
+	assert.NilError(t, Executor(exec).Resume(context.Background(), cluster, secret))
+}
@@ -17,10 +17,12 @@ package pgbouncer
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/adifri/postgres-operator/v5/internal/config"
 	"github.com/adifri/postgres-operator/v5/internal/initialize"
@@ -44,15 +46,130 @@ func ConfigMap(
 	initialize.StringMap(&outConfigMap.Data)
 
 	outConfigMap.Data[emptyConfigMapKey] = ""
-	outConfigMap.Data[iniFileConfigMapKey] = clusterINI(inCluster)
+	outConfigMap.Data[iniFileConfigMapKey] = clusterINI(inCluster, false)
+
+	if auth := inCluster.Spec.Proxy.PGBouncer.Authentication; auth != nil {
+		outConfigMap.Data[hbaFileConfigMapKey] = hbaFileContents(auth)
+	}
 }
 
-// Secret populates the PgBouncer Secret.
+// ReadOnlyConfigMap populates the ConfigMap of the read-only PgBouncer. It has
+// the same files as the PgBouncer ConfigMap, but its pools connect to replicas.
+func ReadOnlyConfigMap(
+	inCluster *v1beta1.PostgresCluster,
+	outConfigMap *corev1.ConfigMap,
+) {
+	if inCluster.Spec.Proxy == nil || inCluster.Spec.Proxy.PGBouncer == nil ||
+		inCluster.Spec.Proxy.PGBouncer.ReadOnly == nil {
+		// Read-only PgBouncer is disabled; there is nothing to do.
+		return
+	}
+
+	ConfigMap(inCluster, outConfigMap)
+
+	outConfigMap.Data[iniFileConfigMapKey] = clusterINI(inCluster, true)
+}
+
+// ValidatePools returns errors describing any invalid pool definitions in the
+// PgBouncer spec of inCluster.
+func ValidatePools(inCluster *v1beta1.PostgresCluster) field.ErrorList {
+	if inCluster.Spec.Proxy == nil || inCluster.Spec.Proxy.PGBouncer == nil {
+		// PgBouncer is disabled; there is nothing to do.
+		return nil
+	}
+
+	_, errs := databasePools(inCluster)
+	_, userErrs := userPools(inCluster)
+	return append(errs, userErrs...)
+}
+
+// Pools returns the effective settings of every connection pool in the
+// PgBouncer configuration and errors describing any invalid pool definitions.
+// When readOnly is true, the settings are those of the read-only PgBouncer,
+// which has no pools when it is disabled.
+func Pools(
+	inCluster *v1beta1.PostgresCluster, readOnly bool,
+) ([]v1beta1.PGBouncerPoolStatus, field.ErrorList) {
+	if inCluster.Spec.Proxy == nil || inCluster.Spec.Proxy.PGBouncer == nil {
+		// PgBouncer is disabled; there is nothing to do.
+		return nil, nil
+	}
+	if readOnly && inCluster.Spec.Proxy.PGBouncer.ReadOnly == nil {
+		// Read-only PgBouncer is disabled; there is nothing to do.
+		return nil, nil
+	}
+
+	global := clusterGlobal(inCluster, readOnly)
+
+	// Start with the PgBouncer defaults then apply any global settings.
+	// - https://www.pgbouncer.org/config.html#generic-settings
+	setting := func(key string, fallback int32) int32 {
+		if value, err := strconv.ParseInt(global[key], 10, 32); err == nil {
+			return int32(value)
+		}
+		return fallback
+	}
+	defaults := v1beta1.PGBouncerPoolStatus{
+		Target:           v1beta1.PGBouncerPoolTargetPrimary,
+		PoolMode:         "session",
+		PoolSize:         setting("default_pool_size", 20),
+		ReservePoolSize:  setting("reserve_pool_size", 0),
+		MaxDBConnections: setting("max_db_connections", 0),
+	}
+	if mode := global["pool_mode"]; mode != "" {
+		defaults.PoolMode = mode
+	}
+
+	// Every pool of the read-only PgBouncer connects to replicas.
+	if readOnly {
+		defaults.Target = v1beta1.PGBouncerPoolTargetReplica
+	}
+
+	var result []v1beta1.PGBouncerPoolStatus
+
+	// The wildcard pool exists unless databases are specified. The read-only
+	// PgBouncer ignores specified databases, so it always has the wildcard.
+	if readOnly || len(inCluster.Spec.Proxy.PGBouncer.Config.Databases) == 0 {
+		wildcard := defaults
+		wildcard.Database = "*"
+		result = append(result, wildcard)
+	}
+
+	pools, errs := databasePools(inCluster)
+	for _, pool := range pools {
+		status := defaults
+		status.Database = string(pool.Database)
+
+		if pool.Target != "" && !readOnly {
+			status.Target = pool.Target
+		}
+		if pool.PoolMode != "" {
+			status.PoolMode = pool.PoolMode
+		}
+		if pool.PoolSize != nil {
+			status.PoolSize = *pool.PoolSize
+		}
+		if pool.ReservePoolSize != nil {
+			status.ReservePoolSize = *pool.ReservePoolSize
+		}
+		if pool.MaxDBConnections != nil {
+			status.MaxDBConnections = *pool.MaxDBConnections
+		}
+		result = append(result, status)
+	}
+
+	_, userErrs := userPools(inCluster)
+
+	return result, append(errs, userErrs...)
+}
+
+// Secret populates the PgBouncer Secret. The frontend certificate is valid for
+// the DNS names of inService and, when it is not nil, inReadOnlyService.
 func Secret(ctx context.Context,
 	inCluster *v1beta1.PostgresCluster,
 	inRoot *pki.RootCertificateAuthority,
 	inSecret *corev1.Secret,
-	inService *corev1.Service,
+	inService, inReadOnlyService *corev1.Service,
 	outSecret *corev1.Secret,
 ) error {
 	if inCluster.Spec.Proxy == nil || inCluster.Spec.Proxy.PGBouncer == nil {
@@ -77,16 +194,41 @@ func Secret(ctx context.Context,
 	if err == nil {
 		// Store the SCRAM verifier alongside the plaintext password so that
 		// later reconciles don't generate it repeatedly.
-		outSecret.Data[authFileSecretKey] = authFileContents(password)
 		outSecret.Data[passwordSecretKey] = []byte(password)
 		outSecret.Data[verifierSecretKey] = []byte(verifier)
 	}
 
+	// Do the same for every admin console user. Credentials of users that are
+	// no longer specified are not copied into outSecret.
+	console := make(map[string]string)
+	for _, name := range consoleUsers(inCluster.Spec.Proxy.PGBouncer.Authentication) {
+		password := string(inSecret.Data[consolePasswordSecretKey(name)])
+		verifier := string(inSecret.Data[consoleVerifierSecretKey(name)])
+
+		if err == nil && (len(password) == 0 || len(verifier) == 0) {
+			password, verifier, err = generatePassword()
+			err = errors.WithStack(err)
+		}
+		if err == nil {
+			console[name] = verifier
+			outSecret.Data[consolePasswordSecretKey(name)] = []byte(password)
+			outSecret.Data[consoleVerifierSecretKey(name)] = []byte(verifier)
+		}
+	}
+
+	if err == nil {
+		outSecret.Data[authFileSecretKey] = authFileContents(password, console)
+	}
+
 	if inCluster.Spec.Proxy.PGBouncer.CustomTLSSecret == nil {
 		leaf := &pki.LeafCertificate{}
 		dnsNames := naming.ServiceDNSNames(ctx, inService)
 		dnsFQDN := dnsNames[0]
 
+		if inReadOnlyService != nil {
+			dnsNames = append(dnsNames, naming.ServiceDNSNames(ctx, inReadOnlyService)...)
+		}
+
 		if err == nil {
 			// Unmarshal and validate the stored leaf. These first errors can
 			// be ignored because they result in an invalid leaf which is then
@@ -99,7 +241,7 @@ func Secret(ctx context.Context,
 		}
 
 		if err == nil {
-			outSecret.Data[certFrontendAuthoritySecretKey], err = inRoot.Certificate.MarshalText()
+			outSecret.Data[certFrontendAuthoritySecretKey], err = inRoot.TrustBundle().MarshalText()
 		}
 		if err == nil {
 			outSecret.Data[certFrontendPrivateKeySecretKey], err = leaf.PrivateKey.MarshalText()
@@ -131,7 +273,8 @@ func Pod(
 	configVolume := corev1.Volume{Name: configVolumeMount.Name}
 	configVolume.Projected = &corev1.ProjectedVolumeSource{
 		Sources: append(append([]corev1.VolumeProjection{},
-			podConfigFiles(inCluster.Spec.Proxy.PGBouncer.Config, inConfigMap, inSecret)...),
+			podConfigFiles(inCluster.Spec.Proxy.PGBouncer.Config,
+				inCluster.Spec.Proxy.PGBouncer.Authentication, inConfigMap, inSecret)...),
 			frontendCertificate(inCluster.Spec.Proxy.PGBouncer.CustomTLSSecret, inSecret),
 			backendAuthority(inPostgreSQLCertificate),
 		),
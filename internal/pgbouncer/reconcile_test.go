@@ -17,6 +17,7 @@ package pgbouncer
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -24,6 +25,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/adifri/postgres-operator/v5/internal/initialize"
 	"github.com/adifri/postgres-operator/v5/internal/pki"
 	"github.com/adifri/postgres-operator/v5/internal/postgres"
 	"github.com/adifri/postgres-operator/v5/internal/util"
@@ -50,13 +52,154 @@ func TestConfigMap(t *testing.T) {
 	ConfigMap(cluster, config)
 
 	// The output of clusterINI should go into config.
-	data := clusterINI(cluster)
+	data := clusterINI(cluster, false)
 	assert.DeepEqual(t, config.Data["pgbouncer.ini"], data)
 
 	// No change when called again.
 	before := config.DeepCopy()
 	ConfigMap(cluster, config)
 	assert.DeepEqual(t, before, config)
+
+	// There is no HBA file without authentication settings.
+	_, ok := config.Data["pgbouncer-hba.conf"]
+	assert.Assert(t, !ok)
+
+	t.Run("Authentication", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Authentication = new(v1beta1.PGBouncerAuthentication)
+
+		config := new(corev1.ConfigMap)
+		ConfigMap(cluster, config)
+
+		// The output of hbaFileContents should go into config.
+		assert.DeepEqual(t, config.Data["pgbouncer-hba.conf"],
+			hbaFileContents(cluster.Spec.Proxy.PGBouncer.Authentication))
+	})
+}
+
+func TestReadOnlyConfigMap(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Default()
+
+	config := new(corev1.ConfigMap)
+
+	t.Run("Disabled", func(t *testing.T) {
+		// Nothing happens when read-only PgBouncer is disabled.
+		constant := config.DeepCopy()
+		ReadOnlyConfigMap(cluster, config)
+		assert.DeepEqual(t, constant, config)
+	})
+
+	cluster.Spec.Proxy.PGBouncer.ReadOnly = new(v1beta1.PGBouncerReadOnlySpec)
+	cluster.Spec.Proxy.PGBouncer.Authentication = new(v1beta1.PGBouncerAuthentication)
+
+	ReadOnlyConfigMap(cluster, config)
+
+	// The read-only output of clusterINI should go into config.
+	assert.DeepEqual(t, config.Data["pgbouncer.ini"], clusterINI(cluster, true))
+
+	// Other files match the PgBouncer ConfigMap.
+	assert.Equal(t, config.Data["pgbouncer-empty"], "")
+	assert.DeepEqual(t, config.Data["pgbouncer-hba.conf"],
+		hbaFileContents(cluster.Spec.Proxy.PGBouncer.Authentication))
+}
+
+func TestPools(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+
+	t.Run("Disabled", func(t *testing.T) {
+		pools, errs := Pools(cluster, false)
+		assert.Assert(t, pools == nil)
+		assert.Assert(t, errs == nil)
+		pools, errs = Pools(cluster, true)
+		assert.Assert(t, pools == nil)
+		assert.Assert(t, errs == nil)
+		assert.Assert(t, ValidatePools(cluster) == nil)
+	})
+
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Default()
+
+	t.Run("Default", func(t *testing.T) {
+		pools, errs := Pools(cluster, false)
+		assert.Assert(t, len(errs) == 0)
+		assert.DeepEqual(t, pools, []v1beta1.PGBouncerPoolStatus{{
+			Database: "*", Target: "primary", PoolMode: "session", PoolSize: 20,
+		}})
+	})
+
+	t.Run("Pools", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config.Global = map[string]string{
+			"pool_mode":          "transaction",
+			"default_pool_size":  "15",
+			"max_db_connections": "40",
+		}
+		cluster.Spec.Proxy.PGBouncer.Pools = &v1beta1.PGBouncerPools{
+			Databases: []v1beta1.PGBouncerDatabasePool{
+				{Database: "app", Target: "replica", PoolSize: initialize.Int32(5),
+					ReservePoolSize: initialize.Int32(1)},
+				{Database: "pgbouncer"},
+			},
+			Users: []v1beta1.PGBouncerUserPool{{User: "u"}},
+		}
+		cluster.Spec.Proxy.PGBouncer.Config.Users = map[string]string{"u": ""}
+
+		pools, errs := Pools(cluster, false)
+		assert.Equal(t, len(errs), 2)
+		assert.DeepEqual(t, ValidatePools(cluster), errs)
+		assert.DeepEqual(t, pools, []v1beta1.PGBouncerPoolStatus{
+			{Database: "*", Target: "primary", PoolMode: "transaction",
+				PoolSize: 15, MaxDBConnections: 40},
+			{Database: "app", Target: "replica", PoolMode: "transaction",
+				PoolSize: 5, ReservePoolSize: 1, MaxDBConnections: 40},
+		})
+
+		// There is no wildcard when databases are specified.
+		cluster.Spec.Proxy.PGBouncer.Config.Databases = map[string]string{"x": "y"}
+		pools, _ = Pools(cluster, false)
+		assert.Equal(t, len(pools), 1)
+		assert.Equal(t, pools[0].Database, "app")
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+
+		// There are no read-only pools until read-only PgBouncer is enabled.
+		pools, _ := Pools(cluster, true)
+		assert.Assert(t, pools == nil)
+
+		cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+			Global: map[string]string{"default_pool_size": "10"},
+		}
+		cluster.Spec.Proxy.PGBouncer.Config.Databases = map[string]string{"x": "y"}
+		cluster.Spec.Proxy.PGBouncer.Pools = &v1beta1.PGBouncerPools{
+			Databases: []v1beta1.PGBouncerDatabasePool{
+				{Database: "app", Target: "primary", PoolMode: "transaction"},
+			},
+		}
+
+		// Every read-only pool, including the wildcard, connects to replicas.
+		pools, errs := Pools(cluster, true)
+		assert.Assert(t, len(errs) == 0)
+		assert.DeepEqual(t, pools, []v1beta1.PGBouncerPoolStatus{
+			{Database: "*", Target: "replica", PoolMode: "session", PoolSize: 10},
+			{Database: "app", Target: "replica", PoolMode: "transaction", PoolSize: 10},
+		})
+
+		// The read-only settings do not apply to the other PgBouncer.
+		pools, _ = Pools(cluster, false)
+		assert.DeepEqual(t, pools, []v1beta1.PGBouncerPoolStatus{
+			{Database: "app", Target: "primary", PoolMode: "transaction", PoolSize: 20},
+		})
+	})
 }
 
 func TestSecret(t *testing.T) {
@@ -74,7 +217,7 @@ func TestSecret(t *testing.T) {
 	t.Run("Disabled", func(t *testing.T) {
 		// Nothing happens when PgBouncer is disabled.
 		constant := intent.DeepCopy()
-		assert.NilError(t, Secret(ctx, cluster, root, existing, service, intent))
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, intent))
 		assert.DeepEqual(t, constant, intent)
 	})
 
@@ -83,7 +226,7 @@ func TestSecret(t *testing.T) {
 	cluster.Default()
 
 	constant := existing.DeepCopy()
-	assert.NilError(t, Secret(ctx, cluster, root, existing, service, intent))
+	assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, intent))
 	assert.DeepEqual(t, constant, existing)
 
 	// A password should be generated.
@@ -96,8 +239,63 @@ func TestSecret(t *testing.T) {
 	// Assuming the intent is written, no change when called again.
 	existing.Data = intent.Data
 	before := intent.DeepCopy()
-	assert.NilError(t, Secret(ctx, cluster, root, existing, service, intent))
+	assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, intent))
 	assert.DeepEqual(t, before, intent)
+
+	t.Run("ReadOnlyService", func(t *testing.T) {
+		service := &corev1.Service{}
+		service.Namespace, service.Name = "ns1", "pg-pgbouncer"
+		readOnly := &corev1.Service{}
+		readOnly.Namespace, readOnly.Name = "ns1", "pg-pgbouncer-ro"
+
+		intent := new(corev1.Secret)
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, readOnly, intent))
+
+		leaf := new(pki.LeafCertificate)
+		assert.NilError(t, leaf.Certificate.UnmarshalText(intent.Data["pgbouncer-frontend.crt"]))
+
+		names := strings.Join(leaf.Certificate.DNSNames(), " ")
+		assert.Assert(t, strings.Contains(names, "pg-pgbouncer.ns1.svc "), "got %q", names)
+		assert.Assert(t, strings.Contains(names, "pg-pgbouncer-ro.ns1.svc "), "got %q", names)
+	})
+
+	t.Run("ConsoleUsers", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthentication{
+			AdminUsers: []v1beta1.PGBouncerConsoleUser{"ops"},
+			StatsUsers: []v1beta1.PGBouncerConsoleUser{"monitor"},
+		}
+
+		existing := &corev1.Secret{Data: before.Data}
+		intent := new(corev1.Secret)
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, intent))
+
+		// The PgBouncer password is kept.
+		assert.DeepEqual(t, intent.Data["pgbouncer-password"], before.Data["pgbouncer-password"])
+
+		// A password should be generated for every console user.
+		for _, name := range []string{"ops", "monitor"} {
+			assert.Assert(t, len(intent.Data["pgbouncer-console-"+name+"-password"]) != 0)
+			assert.Assert(t, len(intent.Data["pgbouncer-console-"+name+"-verifier"]) != 0)
+
+			// Console users are in the user database.
+			assert.Assert(t, strings.Contains(
+				string(intent.Data["pgbouncer-users.txt"]), `"`+name+`" "SCRAM-SHA-256$`))
+		}
+
+		// Assuming the intent is written, no change when called again.
+		existing.Data = intent.Data
+		again := new(corev1.Secret)
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, again))
+		assert.DeepEqual(t, intent.Data, again.Data)
+
+		// Credentials of removed users are dropped.
+		cluster.Spec.Proxy.PGBouncer.Authentication.StatsUsers = nil
+		removed := new(corev1.Secret)
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, removed))
+		assert.Assert(t, len(removed.Data["pgbouncer-console-monitor-password"]) == 0)
+		assert.Assert(t, len(removed.Data["pgbouncer-console-ops-password"]) != 0)
+	})
 }
 
 func TestPod(t *testing.T) {
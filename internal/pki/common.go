@@ -29,6 +29,16 @@ import (
 // signature algorithm with the P-256 curve.
 const certificateSignatureAlgorithm = x509.ECDSAWithSHA384
 
+const (
+	// defaultLeafLifetime is how long leaf certificates are valid when a root
+	// certificate authority does not say otherwise.
+	defaultLeafLifetime = time.Hour * 24 * 365
+
+	// leafStartValid is how long before their creation that leaf certificates
+	// become valid. This allows for some clock skew between machines.
+	leafStartValid = time.Hour * -1
+)
+
 // currentTime returns the current local time. It is a variable so it can be
 // replaced during testing.
 var currentTime = time.Now
@@ -49,18 +59,15 @@ func generateSerialNumber() (*big.Int, error) {
 func generateLeafCertificate(
 	signer *x509.Certificate, signerPrivate *ecdsa.PrivateKey,
 	signeePublic *ecdsa.PublicKey, serialNumber *big.Int,
-	commonName string, dnsNames []string,
+	commonName string, dnsNames []string, lifetime time.Duration,
 ) (*x509.Certificate, error) {
-	const leafExpiration = time.Hour * 24 * 365
-	const leafStartValid = time.Hour * -1
-
 	now := currentTime()
 	template := &x509.Certificate{
 		BasicConstraintsValid: true,
 		DNSNames:              dnsNames,
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 		NotBefore:             now.Add(leafStartValid),
-		NotAfter:              now.Add(leafExpiration),
+		NotAfter:              now.Add(lifetime),
 		SerialNumber:          serialNumber,
 		SignatureAlgorithm:    certificateSignatureAlgorithm,
 		Subject: pkix.Name{
@@ -67,6 +67,55 @@ func (c *Certificate) UnmarshalText(data []byte) error {
 	return err
 }
 
+// CertificateBundle is a sequence of certificates, such as the root
+// certificate authorities that a server or client trusts.
+type CertificateBundle []Certificate
+
+var (
+	_ encoding.TextMarshaler   = CertificateBundle{}
+	_ encoding.TextUnmarshaler = (*CertificateBundle)(nil)
+)
+
+// MarshalText returns the PEM encodings of the certificates in b, one after
+// the other.
+func (b CertificateBundle) MarshalText() ([]byte, error) {
+	var out []byte
+	for i := range b {
+		text, err := b[i].MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, text...)
+	}
+	return out, nil
+}
+
+// UnmarshalText populates b from the PEM encodings of one or more certificates.
+func (b *CertificateBundle) UnmarshalText(data []byte) error {
+	var bundle CertificateBundle
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+		if block.Type != pemLabelCertificate {
+			return fmt.Errorf("not a PEM-encoded certificate")
+		}
+
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		bundle = append(bundle, Certificate{x509: parsed})
+	}
+
+	if len(bundle) == 0 {
+		return fmt.Errorf("not a PEM-encoded certificate")
+	}
+	*b = bundle
+	return nil
+}
+
 var (
 	_ encoding.TextMarshaler   = PrivateKey{}
 	_ encoding.TextMarshaler   = (*PrivateKey)(nil)
@@ -100,6 +100,54 @@ func TestCertificateTextMarshaling(t *testing.T) {
 	})
 }
 
+func TestCertificateBundleTextMarshaling(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		var sink CertificateBundle
+		assert.ErrorContains(t, sink.UnmarshalText(nil), "PEM-encoded")
+		assert.ErrorContains(t, sink.UnmarshalText([]byte{}), "PEM-encoded")
+
+		txt, err := CertificateBundle{}.MarshalText()
+		assert.NilError(t, err)
+		assert.Equal(t, len(txt), 0)
+	})
+
+	root, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+	other, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	bundle := CertificateBundle{root.Certificate, other.Certificate}
+	txt, err := bundle.MarshalText()
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(txt), "-----BEGIN CERTIFICATE-----\n"), 2)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var sink CertificateBundle
+		assert.NilError(t, sink.UnmarshalText(txt))
+		assert.DeepEqual(t, bundle, sink)
+	})
+
+	t.Run("FirstCertificate", func(t *testing.T) {
+		// A single certificate reads the first certificate of a bundle.
+		var sink Certificate
+		assert.NilError(t, sink.UnmarshalText(txt))
+		assert.DeepEqual(t, root.Certificate, sink)
+	})
+
+	t.Run("NotCertificate", func(t *testing.T) {
+		key, err := root.PrivateKey.MarshalText()
+		assert.NilError(t, err)
+
+		var sink CertificateBundle
+		assert.ErrorContains(t, sink.UnmarshalText(append(txt, key...)), "PEM-encoded")
+	})
+
+	t.Run("Zero", func(t *testing.T) {
+		_, err := CertificateBundle{{}}.MarshalText()
+		assert.ErrorContains(t, err, "malformed")
+	})
+}
+
 func TestPrivateKeyTextMarshaling(t *testing.T) {
 	t.Run("Zero", func(t *testing.T) {
 		// Zero cannot marshal.
@@ -17,7 +17,9 @@ package pki
 
 import (
 	"crypto/ecdsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"math/big"
 	"time"
 )
@@ -44,6 +46,33 @@ func (c Certificate) CommonName() string {
 	return c.x509.Subject.CommonName
 }
 
+// Fingerprint returns the SHA-256 hash of the certificate in hexadecimal.
+func (c Certificate) Fingerprint() string {
+	if c.x509 == nil {
+		return ""
+	}
+	sum := sha256.Sum256(c.x509.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NotAfter returns the time at which the certificate expires.
+func (c Certificate) NotAfter() time.Time {
+	if c.x509 == nil {
+		return time.Time{}
+	}
+	return c.x509.NotAfter
+}
+
+// RenewalTime returns the time at which the certificate should be replaced.
+// That is renewBefore its expiration when renewBefore is positive and shorter
+// than its lifetime. Otherwise, it is after two thirds of its lifetime.
+func (c Certificate) RenewalTime(renewBefore time.Duration) time.Time {
+	if c.x509 == nil {
+		return time.Time{}
+	}
+	return renewalTime(c.x509.NotBefore, c.x509.NotAfter, renewBefore)
+}
+
 // DNSNames returns a copy of the certificate subject alternative names
 // (ASN.1 OID 2.5.29.17) that are DNS names.
 func (c Certificate) DNSNames() []string {
@@ -86,6 +115,19 @@ type LeafCertificate struct {
 type RootCertificateAuthority struct {
 	Certificate Certificate
 	PrivateKey  PrivateKey
+
+	// Trusted are other root certificates to trust alongside Certificate,
+	// such as while one root certificate authority replaces another.
+	Trusted []Certificate
+
+	// LeafLifetime is how long the leaf certificates it generates are valid.
+	// When zero, they are valid for one year.
+	LeafLifetime time.Duration
+
+	// LeafRenewBefore is how long before expiration its leaf certificates
+	// should be replaced. When zero, they are replaced after two thirds of
+	// their lifetime.
+	LeafRenewBefore time.Duration
 }
 
 // NewRootCertificateAuthority generates a new key and self-signed certificate
@@ -142,6 +184,26 @@ func RootIsValid(root *RootCertificateAuthority) bool {
 	return ok
 }
 
+// RootNeedsRotation checks if root is valid but past its "renewal by" time,
+// as defined by the before and after times of its expiration and the default
+// ratio. Such a root should be replaced before leaves it signs outlive it.
+func RootNeedsRotation(root *RootCertificateAuthority) bool {
+	return RootIsValid(root) && !isBeforeRenewalTime(
+		root.Certificate.x509.NotBefore, root.Certificate.x509.NotAfter)
+}
+
+// TrustBundle returns the certificate of root followed by the other root
+// certificates it trusts.
+func (root *RootCertificateAuthority) TrustBundle() CertificateBundle {
+	bundle := CertificateBundle{root.Certificate}
+	for _, other := range root.Trusted {
+		if other.x509 != nil && !other.Equal(root.Certificate) {
+			bundle = append(bundle, other)
+		}
+	}
+	return bundle
+}
+
 // GenerateLeafCertificate generates a new key and certificate signed by root.
 func (root *RootCertificateAuthority) GenerateLeafCertificate(
 	commonName string, dnsNames []string,
@@ -157,12 +219,20 @@ func (root *RootCertificateAuthority) GenerateLeafCertificate(
 		leaf.PrivateKey.ecdsa = key
 		leaf.Certificate.x509, err = generateLeafCertificate(
 			root.Certificate.x509, root.PrivateKey.ecdsa, &key.PublicKey, serial,
-			commonName, dnsNames)
+			commonName, dnsNames, root.leafLifetime())
 	}
 
 	return &leaf, err
 }
 
+// leafLifetime returns how long the leaf certificates of root are valid.
+func (root *RootCertificateAuthority) leafLifetime() time.Duration {
+	if root.LeafLifetime > 0 {
+		return root.LeafLifetime
+	}
+	return defaultLeafLifetime
+}
+
 // leafIsValid checks if leaf is valid according to this package's policies and
 // is signed by root.
 func (root *RootCertificateAuthority) leafIsValid(leaf *LeafCertificate) bool {
@@ -197,11 +267,16 @@ func (root *RootCertificateAuthority) leafIsValid(leaf *LeafCertificate) bool {
 		leaf.PrivateKey.ecdsa != nil &&
 		leaf.PrivateKey.ecdsa.PublicKey.Equal(leaf.Certificate.x509.PublicKey)
 
+	// It is not valid for longer than root would generate it. This replaces
+	// leaves after their lifetime is shortened.
+	ok = ok && leaf.Certificate.x509.NotAfter.Sub(leaf.Certificate.x509.NotBefore) <=
+		root.leafLifetime()-leafStartValid
+
 	// It is not yet past the "renewal by" time,
 	// as defined by the before and after times of the certificate's expiration
-	// and the default ratio
-	ok = ok && isBeforeRenewalTime(leaf.Certificate.x509.NotBefore,
-		leaf.Certificate.x509.NotAfter)
+	// and the renewal window of root
+	ok = ok && currentTime().Before(renewalTime(leaf.Certificate.x509.NotBefore,
+		leaf.Certificate.x509.NotAfter, root.LeafRenewBefore))
 
 	return ok
 }
@@ -210,9 +285,19 @@ func (root *RootCertificateAuthority) leafIsValid(leaf *LeafCertificate) bool {
 // is after the default renewal time of
 // 1/3rds before the certificate's expiry
 func isBeforeRenewalTime(before, after time.Time) bool {
+	return currentTime().Before(renewalTime(before, after, 0))
+}
+
+// renewalTime returns the time at which a certificate valid from before until
+// after should be replaced. When renewBefore is positive and shorter than the
+// validity period, that is renewBefore the expiration. Otherwise, it is 1/3rd
+// of the validity period before the expiration.
+func renewalTime(before, after time.Time, renewBefore time.Duration) time.Time {
 	renewalDuration := after.Sub(before) / renewalRatio
-	renewalTime := after.Add(-1 * renewalDuration)
-	return currentTime().Before(renewalTime)
+	if renewBefore > 0 && renewBefore < after.Sub(before) {
+		renewalDuration = renewBefore
+	}
+	return after.Add(-1 * renewalDuration)
 }
 
 // RegenerateLeafWhenNecessary returns leaf when it is valid according to this
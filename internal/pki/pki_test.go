@@ -45,6 +45,19 @@ func TestCertificateDNSNames(t *testing.T) {
 	assert.Assert(t, zero.DNSNames() == nil)
 }
 
+func TestCertificateFingerprint(t *testing.T) {
+	assert.Equal(t, Certificate{}.Fingerprint(), "")
+
+	root, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+	other, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(root.Certificate.Fingerprint()), 64)
+	assert.Equal(t, root.Certificate.Fingerprint(), root.Certificate.Fingerprint())
+	assert.Assert(t, root.Certificate.Fingerprint() != other.Certificate.Fingerprint())
+}
+
 func TestCertificateHasSubject(t *testing.T) {
 	zero := Certificate{}
 
@@ -183,7 +196,9 @@ func TestRootIsInvalid(t *testing.T) {
 		leaf, err := root.GenerateLeafCertificate("", nil)
 		assert.NilError(t, err)
 
-		assert.Assert(t, !RootIsValid((*RootCertificateAuthority)(leaf)))
+		assert.Assert(t, !RootIsValid(&RootCertificateAuthority{
+			Certificate: leaf.Certificate, PrivateKey: leaf.PrivateKey,
+		}))
 	})
 
 	t.Run("TooEarly", func(t *testing.T) {
@@ -215,6 +230,40 @@ func TestRootIsInvalid(t *testing.T) {
 	})
 }
 
+func TestRootNeedsRotation(t *testing.T) {
+	assert.Assert(t, !RootNeedsRotation(nil))
+	assert.Assert(t, !RootNeedsRotation(&RootCertificateAuthority{}))
+
+	root, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+	assert.Assert(t, !RootNeedsRotation(root), "expected new root to be fine")
+
+	original := currentTime
+	t.Cleanup(func() { currentTime = original })
+
+	// Two thirds of the way to its expiration, the root needs replacing.
+	currentTime = func() time.Time {
+		lifetime := root.Certificate.x509.NotAfter.Sub(root.Certificate.x509.NotBefore)
+		return root.Certificate.x509.NotBefore.Add(lifetime * 3 / 4)
+	}
+	assert.Assert(t, RootNeedsRotation(root))
+}
+
+func TestRootTrustBundle(t *testing.T) {
+	root, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	other, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, root.TrustBundle(), CertificateBundle{root.Certificate})
+
+	// The bundle skips duplicates and empty certificates.
+	root.Trusted = []Certificate{other.Certificate, root.Certificate, {}}
+	assert.DeepEqual(t, root.TrustBundle(),
+		CertificateBundle{root.Certificate, other.Certificate})
+}
+
 func TestLeafCertificate(t *testing.T) {
 	serials := StringSet{}
 	root, err := NewRootCertificateAuthority()
@@ -373,7 +422,9 @@ func TestLeafIsInvalid(t *testing.T) {
 	})
 
 	t.Run("IsAuthority", func(t *testing.T) {
-		assert.Assert(t, !root.leafIsValid((*LeafCertificate)(root)))
+		assert.Assert(t, !root.leafIsValid(&LeafCertificate{
+			Certificate: root.Certificate, PrivateKey: root.PrivateKey,
+		}))
 	})
 
 	t.Run("TooEarly", func(t *testing.T) {
@@ -432,6 +483,80 @@ func TestIsBeforeRenewalTime(t *testing.T) {
 	assert.Assert(t, !isBeforeRenewalTime(sixHoursAgo, twoHoursInTheFuture))
 }
 
+func TestRenewalTime(t *testing.T) {
+	before := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	after := before.Add(90 * time.Hour)
+
+	// The default is one third of the validity period.
+	assert.Equal(t, renewalTime(before, after, 0), after.Add(-30*time.Hour))
+
+	assert.Equal(t, renewalTime(before, after, 10*time.Hour), after.Add(-10*time.Hour))
+	assert.Equal(t, renewalTime(before, after, 60*time.Hour), after.Add(-60*time.Hour))
+
+	// A window that is not shorter than the validity period is ignored.
+	assert.Equal(t, renewalTime(before, after, 90*time.Hour), after.Add(-30*time.Hour))
+	assert.Equal(t, renewalTime(before, after, 100*time.Hour), after.Add(-30*time.Hour))
+}
+
+func TestLeafLifetime(t *testing.T) {
+	root, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	t.Run("Default", func(t *testing.T) {
+		leaf, err := root.GenerateLeafCertificate("", nil)
+		assert.NilError(t, err)
+
+		lifetime := leaf.Certificate.NotAfter().Sub(time.Now())
+		assert.Assert(t, lifetime > 364*24*time.Hour && lifetime <= 365*24*time.Hour,
+			"got %v", lifetime)
+		assert.Equal(t,
+			leaf.Certificate.RenewalTime(0),
+			leaf.Certificate.NotAfter().Add(-(365*24*time.Hour+time.Hour)/3))
+	})
+
+	shorter := &RootCertificateAuthority{
+		Certificate: root.Certificate, PrivateKey: root.PrivateKey,
+		LeafLifetime: 30 * 24 * time.Hour, LeafRenewBefore: 7 * 24 * time.Hour,
+	}
+
+	t.Run("Configured", func(t *testing.T) {
+		leaf, err := shorter.GenerateLeafCertificate("", nil)
+		assert.NilError(t, err)
+		assert.Assert(t, shorter.leafIsValid(leaf))
+
+		lifetime := leaf.Certificate.NotAfter().Sub(time.Now())
+		assert.Assert(t, lifetime > 29*24*time.Hour && lifetime <= 30*24*time.Hour,
+			"got %v", lifetime)
+		assert.Equal(t,
+			leaf.Certificate.RenewalTime(shorter.LeafRenewBefore),
+			leaf.Certificate.NotAfter().Add(-7*24*time.Hour))
+
+		original := currentTime
+		t.Cleanup(func() { currentTime = original })
+
+		// It is replaced within a week of expiring.
+		currentTime = func() time.Time { return time.Now().Add(22 * 24 * time.Hour) }
+		assert.Assert(t, shorter.leafIsValid(leaf))
+
+		currentTime = func() time.Time { return time.Now().Add(24 * 24 * time.Hour) }
+		assert.Assert(t, !shorter.leafIsValid(leaf))
+	})
+
+	t.Run("Shortened", func(t *testing.T) {
+		leaf, err := root.GenerateLeafCertificate("", nil)
+		assert.NilError(t, err)
+
+		assert.Assert(t, root.leafIsValid(leaf))
+		assert.Assert(t, !shorter.leafIsValid(leaf),
+			"expected leaf that outlives the lifetime to be replaced")
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.Assert(t, Certificate{}.NotAfter().IsZero())
+		assert.Assert(t, Certificate{}.RenewalTime(time.Hour).IsZero())
+	})
+}
+
 func TestRegenerateLeaf(t *testing.T) {
 	root, err := NewRootCertificateAuthority()
 	assert.NilError(t, err)
@@ -44,6 +44,9 @@ safelink() (
 	// walMountPath is where to mount the optional WAL volume.
 	walMountPath = "/pgwal"
 
+	// tablespaceMountPath is where to mount the optional tablespace volumes.
+	tablespaceMountPath = "/tablespaces"
+
 	// downwardAPIPath is where to mount the downwardAPI volume.
 	downwardAPIPath = "/etc/database-containerinfo"
 
@@ -70,6 +73,13 @@ func DataDirectory(cluster *v1beta1.PostgresCluster) string {
 	return fmt.Sprintf("%s/pg%d", dataMountPath, cluster.Spec.PostgresVersion)
 }
 
+// SnapshotMarkerFile returns the absolute path to a file that exists on data
+// volumes provisioned from a snapshot backup until the "backup_label" file of
+// that backup is written.
+func SnapshotMarkerFile(cluster *v1beta1.PostgresCluster) string {
+	return DataDirectory(cluster) + "_snapshot"
+}
+
 // WALDirectory returns the absolute path to the directory where an instance
 // stores its WAL files.
 // - https://www.postgresql.org/docs/current/wal.html
@@ -84,6 +94,13 @@ func WALDirectory(
 	return fmt.Sprintf("%s/pg%d_wal", walStorage, cluster.Spec.PostgresVersion)
 }
 
+// TablespaceDirectory returns the absolute path to the directory where an
+// instance stores the files of tablespace.
+// - https://www.postgresql.org/docs/current/manage-ag-tablespaces.html
+func TablespaceDirectory(tablespace string) string {
+	return fmt.Sprintf("%s/%s/data", tablespaceMountPath, tablespace)
+}
+
 // Environment returns the environment variables required to invoke PostgreSQL
 // utilities.
 func Environment(cluster *v1beta1.PostgresCluster) []corev1.EnvVar {
@@ -180,9 +197,12 @@ func startupCommand(
 	version := fmt.Sprint(cluster.Spec.PostgresVersion)
 	walDir := WALDirectory(cluster, instance)
 
-	args := []string{version, walDir, naming.PGBackRestPGDataLogPath}
+	args := []string{version, walDir, naming.PGBackRestPGDataLogPath, SnapshotMarkerFile(cluster)}
+	for i := range instance.TablespaceVolumes {
+		args = append(args, TablespaceDirectory(instance.TablespaceVolumes[i].Name))
+	}
 	script := strings.Join([]string{
-		`declare -r expected_major_version="$1" pgwal_directory="$2" pgbrLog_directory="$3"`,
+		`declare -r expected_major_version="$1" pgwal_directory="$2" pgbrLog_directory="$3" snapshot_marker="$4"`,
 
 		// Function to log values in a basic structured format.
 		`results() { printf '::postgres-operator: %s::%s\n' "$@"; }`,
@@ -220,6 +240,13 @@ func startupCommand(
 		// - https://issue.k8s.io/93802#issuecomment-717646167
 		`install --directory --mode=0700 "${postgres_data_directory}"`,
 
+		// Create the directories of any tablespaces, the remaining arguments.
+		// Like the data directory, these must be writable by only PostgreSQL.
+		`for directory in "${@:5}"; do`,
+		`  results 'tablespace directory' "${directory}"`,
+		`  install --directory --mode=0700 "${directory}"`,
+		`done`,
+
 		// Create the pgBackRest log directory.
 		`results 'pgBackRest log directory' "${pgbrLog_directory}"`,
 		`install --directory --mode=0775 "${pgbrLog_directory}"`,
@@ -245,6 +272,13 @@ func startupCommand(
 		`results 'data version' "${postgres_data_version:=$(< "${postgres_data_directory}/PG_VERSION")}"`,
 		`[ "${postgres_data_version}" = "${expected_major_version}" ]`,
 
+		// A data volume provisioned from a snapshot backup cannot be recovered
+		// without the "backup_label" file of that backup. Wait for the operator
+		// to write that file and remove the marker.
+		// - https://www.postgresql.org/docs/current/continuous-archiving.html#BACKUP-LOWLEVEL-BASE-BACKUP
+		`[ -f "${snapshot_marker}" ] && results 'waiting for backup label' "${snapshot_marker}"`,
+		`while [ -f "${snapshot_marker}" ]; do sleep 5; done`,
+
 		// Safely move the WAL directory onto the intended volume. PostgreSQL
 		// always writes WAL files in the "pg_wal" directory inside the data
 		// directory. The recommended way to relocate it is with a symbolic
@@ -81,3 +81,65 @@ SELECT pg_catalog.format('CREATE DATABASE %I',
 
 	return err
 }
+
+// CreateTablespacesInPostgreSQL calls exec to create tablespaces that do not
+// exist in PostgreSQL. Each is located in the volume mounted for it.
+func CreateTablespacesInPostgreSQL(
+	ctx context.Context, exec Executor, tablespaces []string,
+) error {
+	log := logging.FromContext(ctx)
+
+	var err error
+	var sql bytes.Buffer
+
+	// Prevent unexpected dereferences by emptying "search_path". The "pg_catalog"
+	// schema is still searched, and only temporary objects can be created.
+	// - https://www.postgresql.org/docs/current/runtime-config-client.html#GUC-SEARCH-PATH
+	_, _ = sql.WriteString(`SET search_path TO '';`)
+
+	// Fill a temporary table with the JSON of the tablespace specifications.
+	// "\copy" reads from subsequent lines until the special line "\.".
+	// - https://www.postgresql.org/docs/current/app-psql.html#APP-PSQL-META-COMMANDS-COPY
+	_, _ = sql.WriteString(`
+CREATE TEMPORARY TABLE input (id serial, data json);
+\copy input (data) from stdin with (format text)
+`)
+
+	encoder := json.NewEncoder(&sql)
+	encoder.SetEscapeHTML(false)
+
+	for i := range tablespaces {
+		if err == nil {
+			err = encoder.Encode(map[string]interface{}{
+				"tablespace": tablespaces[i],
+				"location":   TablespaceDirectory(tablespaces[i]),
+			})
+		}
+	}
+	_, _ = sql.WriteString(`\.` + "\n")
+
+	// Create tablespaces that do not already exist. This cannot happen inside
+	// a transaction block, so execute each statement separately.
+	// - https://www.postgresql.org/docs/current/sql-createtablespace.html
+	_, _ = sql.WriteString(`
+SELECT pg_catalog.format('CREATE TABLESPACE %I LOCATION %L',
+       pg_catalog.json_extract_path_text(input.data, 'tablespace'),
+       pg_catalog.json_extract_path_text(input.data, 'location'))
+  FROM input
+ WHERE NOT EXISTS (
+       SELECT 1 FROM pg_catalog.pg_tablespace
+       WHERE spcname = pg_catalog.json_extract_path_text(input.data, 'tablespace'))
+ ORDER BY input.id
+\gexec
+`)
+
+	stdout, stderr, err := exec.Exec(ctx, &sql,
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("created PostgreSQL tablespaces", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
@@ -101,3 +101,76 @@ SELECT pg_catalog.format('CREATE DATABASE %I',
 		assert.Equal(t, calls, 1)
 	})
 }
+
+func TestCreateTablespacesInPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("pass-through")
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			assert.Assert(t, stdout != nil, "should capture stdout")
+			assert.Assert(t, stderr != nil, "should capture stderr")
+			return expected
+		}
+
+		assert.Equal(t, expected, CreateTablespacesInPostgreSQL(ctx, exec, nil))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls++
+
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Equal(t, string(b), strings.TrimLeft(`
+SET search_path TO '';
+CREATE TEMPORARY TABLE input (id serial, data json);
+\copy input (data) from stdin with (format text)
+\.
+
+SELECT pg_catalog.format('CREATE TABLESPACE %I LOCATION %L',
+       pg_catalog.json_extract_path_text(input.data, 'tablespace'),
+       pg_catalog.json_extract_path_text(input.data, 'location'))
+  FROM input
+ WHERE NOT EXISTS (
+       SELECT 1 FROM pg_catalog.pg_tablespace
+       WHERE spcname = pg_catalog.json_extract_path_text(input.data, 'tablespace'))
+ ORDER BY input.id
+\gexec
+`, "\n"))
+			return nil
+		}
+
+		assert.NilError(t, CreateTablespacesInPostgreSQL(ctx, exec, nil))
+		assert.Equal(t, calls, 1)
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls++
+
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, cmp.Contains(string(b), `
+\copy input (data) from stdin with (format text)
+{"location":"/tablespaces/fast/data","tablespace":"fast"}
+{"location":"/tablespaces/archive/data","tablespace":"archive"}
+\.
+`))
+			return nil
+		}
+
+		assert.NilError(t, CreateTablespacesInPostgreSQL(ctx, exec,
+			[]string{"fast", "archive"},
+		))
+		assert.Equal(t, calls, 1)
+	})
+}
@@ -17,7 +17,13 @@ package postgres
 
 import (
 	"fmt"
+	"net"
+	"sort"
 	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 // NewHBAs returns HostBasedAuthentication records required by this package.
@@ -46,8 +52,110 @@ func NewHBAs() HBAs {
 	}
 }
 
-// HBAs is a pairing of HostBasedAuthentication records.
-type HBAs struct{ Mandatory, Default []HostBasedAuthentication }
+// HBAs is a grouping of HostBasedAuthentication records. Mandatory records
+// come first, followed by Custom records from the cluster spec. Default
+// records come last and apply only when nothing else is configured.
+type HBAs struct{ Mandatory, Custom, Default []HostBasedAuthentication }
+
+// CustomHBAs appends to outHBAs the authentication rules of inCluster. Each
+// network of a rule becomes a separate record. It returns an error describing
+// every rule that Postgres would not accept.
+// - https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
+func CustomHBAs(inCluster *v1beta1.PostgresCluster, outHBAs *HBAs) error {
+	if inCluster.Spec.Authentication == nil {
+		return nil
+	}
+
+	var custom []HostBasedAuthentication
+	var errs field.ErrorList
+	path := field.NewPath("spec", "authentication", "rules")
+
+	for i, rule := range inCluster.Spec.Authentication.Rules {
+		rulePath := path.Index(i)
+		hba := NewHBA()
+
+		switch rule.Connection {
+		case "", "hostssl":
+			hba.TLS()
+		case "host":
+			hba.TCP()
+		case "hostnossl":
+			hba.NoSSL()
+		default:
+			errs = append(errs, field.NotSupported(rulePath.Child("connection"),
+				rule.Connection, []string{"host", "hostssl", "hostnossl"}))
+		}
+
+		// Connections over TCP cannot be authenticated by the operating system
+		// of the server. Certificates require TLS.
+		// - https://www.postgresql.org/docs/current/auth-methods.html
+		switch rule.Method {
+		case "":
+			errs = append(errs, field.Required(rulePath.Child("method"), ""))
+		case "peer":
+			errs = append(errs, field.Invalid(rulePath.Child("method"),
+				rule.Method, "peer authentication requires a local connection"))
+		case "cert":
+			if hba.origin != "hostssl" {
+				errs = append(errs, field.Invalid(rulePath.Child("method"),
+					rule.Method, "certificate authentication requires a hostssl connection"))
+			}
+		}
+		if _, ok := rule.Options["clientcert"]; ok && hba.origin != "hostssl" {
+			errs = append(errs, field.Invalid(rulePath.Child("options", "clientcert"),
+				rule.Options["clientcert"], "client certificates require a hostssl connection"))
+		}
+		names := make([]string, 0, len(rule.Options))
+		for name := range rule.Options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if name == "" || strings.Trim(name, "abcdefghijklmnopqrstuvwxyz_") != "" {
+				errs = append(errs, field.Invalid(rulePath.Child("options"),
+					name, "option names must contain only lowercase letters and underscores"))
+			}
+		}
+		hba.Method(rule.Method)
+		if len(rule.Options) > 0 {
+			hba.Options(rule.Options)
+		}
+
+		if len(rule.Databases) > 0 {
+			names := make([]string, len(rule.Databases))
+			for j := range rule.Databases {
+				names[j] = string(rule.Databases[j])
+			}
+			hba.Databases(names...)
+		}
+		if len(rule.Users) > 0 {
+			names := make([]string, len(rule.Users))
+			for j := range rule.Users {
+				names[j] = string(rule.Users[j])
+			}
+			hba.Users(names...)
+		}
+
+		if len(rule.Networks) == 0 {
+			custom = append(custom, *hba)
+		}
+		for j, network := range rule.Networks {
+			if _, _, err := net.ParseCIDR(network); err != nil {
+				errs = append(errs, field.Invalid(rulePath.Child("networks").Index(j),
+					network, "must be an IP address range in CIDR notation"))
+			}
+			record := *hba
+			custom = append(custom, *record.Network(network))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+
+	outHBAs.Custom = append(outHBAs.Custom, custom...)
+	return nil
+}
 
 // HostBasedAuthentication represents a single record for pg_hba.conf.
 // - https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
@@ -64,6 +172,14 @@ func (HostBasedAuthentication) quote(value string) string {
 	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
 }
 
+func (hba HostBasedAuthentication) quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i := range values {
+		quoted[i] = hba.quote(values[i])
+	}
+	return strings.Join(quoted, ",")
+}
+
 // AllDatabases makes hba match connections made to any database.
 func (hba *HostBasedAuthentication) AllDatabases() *HostBasedAuthentication {
 	hba.database = "all"
@@ -88,6 +204,12 @@ func (hba *HostBasedAuthentication) Database(name string) *HostBasedAuthenticati
 	return hba
 }
 
+// Databases makes hba match connections made to any of the named databases.
+func (hba *HostBasedAuthentication) Databases(names ...string) *HostBasedAuthentication {
+	hba.database = hba.quoteList(names)
+	return hba
+}
+
 // Local makes hba match connection attempts using Unix-domain sockets.
 func (hba *HostBasedAuthentication) Local() *HostBasedAuthentication {
 	hba.origin = "local"
@@ -112,11 +234,18 @@ func (hba *HostBasedAuthentication) NoSSL() *HostBasedAuthentication {
 	return hba
 }
 
-// Options specifies any options for the authentication method.
+// Options specifies any options for the authentication method. They are
+// written in the order of their names.
 func (hba *HostBasedAuthentication) Options(opts map[string]string) *HostBasedAuthentication {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	hba.options = ""
-	for k, v := range opts {
-		hba.options = fmt.Sprintf("%s %s=%s", hba.options, k, hba.quote(v))
+	for _, k := range keys {
+		hba.options = fmt.Sprintf("%s %s=%s", hba.options, k, hba.quote(opts[k]))
 	}
 	return hba
 }
@@ -158,6 +287,12 @@ func (hba *HostBasedAuthentication) User(name string) *HostBasedAuthentication {
 	return hba
 }
 
+// Users makes hba match connections by any of the named users.
+func (hba *HostBasedAuthentication) Users(names ...string) *HostBasedAuthentication {
+	hba.user = hba.quoteList(names)
+	return hba
+}
+
 // String returns hba formatted for the pg_hba.conf file without a newline.
 func (hba HostBasedAuthentication) String() string {
 	if hba.origin == "local" {
@@ -22,6 +22,7 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/adifri/postgres-operator/v5/internal/testing/cmp"
+	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestNewHBAs(t *testing.T) {
@@ -70,4 +71,96 @@ func TestHostBasedAuthentication(t *testing.T) {
 
 	assert.Equal(t, `hostnossl all all all reject`,
 		NewHBA().NoSSL().Method("reject").String())
+
+	assert.Equal(t, `hostssl "app","other" "a","b" all ldap  ldapport="389" ldapserver="ldap.example.com"`,
+		NewHBA().TLS().Databases("app", "other").Users("a", "b").
+			Method("ldap").Options(map[string]string{
+			"ldapserver": "ldap.example.com",
+			"ldapport":   "389",
+		}).String())
+}
+
+func TestCustomHBAs(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		hbas := HBAs{}
+
+		assert.NilError(t, CustomHBAs(cluster, &hbas))
+		assert.Assert(t, hbas.Custom == nil)
+
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{}
+		assert.NilError(t, CustomHBAs(cluster, &hbas))
+		assert.Assert(t, hbas.Custom == nil)
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARuleSpec{
+				{Method: "scram-sha-256"},
+				{
+					Connection: "host",
+					Databases:  []v1beta1.PostgresIdentifier{"app"},
+					Users:      []v1beta1.PostgresIdentifier{"batch", "report"},
+					Networks:   []string{"10.0.0.0/8", "fd00::/8"},
+					Method:     "reject",
+				},
+				{
+					Connection: "hostssl",
+					Method:     "cert",
+					Options:    map[string]string{"map": "corp"},
+				},
+			},
+		}
+
+		hbas := HBAs{Custom: []HostBasedAuthentication{*NewHBA().TLS().Method("trust")}}
+		assert.NilError(t, CustomHBAs(cluster, &hbas))
+
+		printed := make([]string, len(hbas.Custom))
+		for i := range hbas.Custom {
+			printed[i] = hbas.Custom[i].String()
+		}
+		assert.DeepEqual(t, printed, []string{
+			`hostssl all all all trust`,
+			`hostssl all all all scram-sha-256`,
+			`host "app" "batch","report" "10.0.0.0/8" reject`,
+			`host "app" "batch","report" "fd00::/8" reject`,
+			`hostssl all all all cert  map="corp"`,
+		})
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARuleSpec{
+				{Method: "md5"},
+				{Connection: "local", Method: "md5"},
+				{Networks: []string{"10.0.0.1"}, Method: "md5"},
+				{Connection: "host", Method: "cert"},
+				{Method: "peer"},
+				{},
+				{Connection: "hostnossl", Method: "md5", Options: map[string]string{
+					"clientcert": "verify-full", "Bad-Name": "x",
+				}},
+			},
+		}
+
+		hbas := HBAs{}
+		err := CustomHBAs(cluster, &hbas)
+		assert.Assert(t, err != nil)
+		assert.Assert(t, hbas.Custom == nil, "expected no changes on error")
+
+		for _, expected := range []string{
+			`spec.authentication.rules[1].connection: Unsupported value: "local"`,
+			`spec.authentication.rules[2].networks[0]: Invalid value: "10.0.0.1"`,
+			`spec.authentication.rules[3].method: Invalid value: "cert"`,
+			`spec.authentication.rules[4].method: Invalid value: "peer"`,
+			`spec.authentication.rules[5].method: Required value`,
+			`spec.authentication.rules[6].options.clientcert: Invalid value: "verify-full"`,
+			`spec.authentication.rules[6].options: Invalid value: "Bad-Name"`,
+		} {
+			assert.ErrorContains(t, err, expected)
+		}
+		assert.Assert(t, !strings.Contains(err.Error(), "rules[0]"))
+	})
 }
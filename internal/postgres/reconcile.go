@@ -43,6 +43,15 @@ func WALVolumeMount() corev1.VolumeMount {
 	return corev1.VolumeMount{Name: "postgres-wal", MountPath: walMountPath}
 }
 
+// TablespaceVolumeMount returns the name and mount path of the PostgreSQL
+// tablespace volume for tablespace.
+func TablespaceVolumeMount(tablespace string) corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      "tablespace-" + tablespace,
+		MountPath: tablespaceMountPath + "/" + tablespace,
+	}
+}
+
 // DownwardAPIVolumeMount returns the name and mount path of the DownwardAPI volume.
 func DownwardAPIVolumeMount() corev1.VolumeMount {
 	return corev1.VolumeMount{
@@ -68,6 +77,7 @@ func InstancePod(ctx context.Context,
 	inInstanceSpec *v1beta1.PostgresInstanceSetSpec,
 	inClusterCertificates, inClientCertificates *corev1.SecretProjection,
 	inDataVolume, inWALVolume *corev1.PersistentVolumeClaim,
+	inTablespaceVolumes map[string]*corev1.PersistentVolumeClaim,
 	outInstancePod *corev1.PodSpec,
 ) {
 	certVolumeMount := corev1.VolumeMount{
@@ -247,6 +257,27 @@ func InstancePod(ctx context.Context,
 		outInstancePod.Volumes = append(outInstancePod.Volumes, walVolume)
 	}
 
+	// Mount each tablespace PVC in the order of inInstanceSpec. The startup
+	// command creates a directory for PostgreSQL in each.
+	for _, tablespace := range inInstanceSpec.TablespaceVolumes {
+		if pvc := inTablespaceVolumes[tablespace.Name]; pvc != nil {
+			tablespaceVolumeMount := TablespaceVolumeMount(tablespace.Name)
+			tablespaceVolume := corev1.Volume{
+				Name: tablespaceVolumeMount.Name,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: pvc.Name,
+						ReadOnly:  false,
+					},
+				},
+			}
+
+			container.VolumeMounts = append(container.VolumeMounts, tablespaceVolumeMount)
+			startup.VolumeMounts = append(startup.VolumeMounts, tablespaceVolumeMount)
+			outInstancePod.Volumes = append(outInstancePod.Volumes, tablespaceVolume)
+		}
+	}
+
 	outInstancePod.Containers = []corev1.Container{container, reloader}
 
 	// If the InstanceSidecars feature gate is enabled and instance sidecars are
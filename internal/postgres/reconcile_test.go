@@ -22,6 +22,7 @@ import (
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/adifri/postgres-operator/v5/internal/initialize"
 	"github.com/adifri/postgres-operator/v5/internal/naming"
@@ -118,7 +119,7 @@ func TestInstancePod(t *testing.T) {
 	// without WAL volume nor WAL volume spec
 	pod := new(corev1.PodSpec)
 	InstancePod(ctx, cluster, instance,
-		serverSecretProjection, clientSecretProjection, dataVolume, nil, pod)
+		serverSecretProjection, clientSecretProjection, dataVolume, nil, nil, pod)
 
 	assert.Assert(t, marshalMatches(pod, `
 containers:
@@ -201,7 +202,7 @@ initContainers:
   - -ceu
   - --
   - |-
-    declare -r expected_major_version="$1" pgwal_directory="$2" pgbrLog_directory="$3"
+    declare -r expected_major_version="$1" pgwal_directory="$2" pgbrLog_directory="$3" snapshot_marker="$4"
     results() { printf '::postgres-operator: %s::%s\n' "$@"; }
     safelink() (
       local desired="$1" name="$2" current
@@ -223,12 +224,18 @@ initContainers:
     [ -d "${bootstrap_dir}" ] && results 'bootstrap directory' "${bootstrap_dir}"
     [ -d "${bootstrap_dir}" ] && postgres_data_directory="${bootstrap_dir}"
     install --directory --mode=0700 "${postgres_data_directory}"
+    for directory in "${@:5}"; do
+      results 'tablespace directory' "${directory}"
+      install --directory --mode=0700 "${directory}"
+    done
     results 'pgBackRest log directory' "${pgbrLog_directory}"
     install --directory --mode=0775 "${pgbrLog_directory}"
     install -D --mode=0600 -t "/tmp/replication" "/pgconf/tls/replication"/{tls.crt,tls.key,ca.crt}
     [ -f "${postgres_data_directory}/PG_VERSION" ] || exit 0
     results 'data version' "${postgres_data_version:=$(< "${postgres_data_directory}/PG_VERSION")}"
     [ "${postgres_data_version}" = "${expected_major_version}" ]
+    [ -f "${snapshot_marker}" ] && results 'waiting for backup label' "${snapshot_marker}"
+    while [ -f "${snapshot_marker}" ]; do sleep 5; done
     safelink "${pgwal_directory}" "${postgres_data_directory}/pg_wal"
     results 'wal directory' "$(realpath "${postgres_data_directory}/pg_wal")"
     rm -f "${postgres_data_directory}/recovery.signal"
@@ -236,6 +243,7 @@ initContainers:
   - "11"
   - /pgdata/pg11_wal
   - /pgdata/pgbackrest/log
+  - /pgdata/pg11_snapshot
   env:
   - name: PGDATA
     value: /pgdata/pg11
@@ -329,7 +337,7 @@ volumes:
 
 		pod := new(corev1.PodSpec)
 		InstancePod(ctx, cluster, instance,
-			serverSecretProjection, clientSecretProjection, dataVolume, walVolume, pod)
+			serverSecretProjection, clientSecretProjection, dataVolume, walVolume, nil, pod)
 
 		assert.Assert(t, len(pod.Containers) > 0)
 		assert.Assert(t, len(pod.InitContainers) > 0)
@@ -419,7 +427,7 @@ volumes:
 
 		// Startup moves WAL files to data volume.
 		assert.DeepEqual(t, pod.InitContainers[0].Command[4:],
-			[]string{"startup", "11", "/pgdata/pg11_wal", "/pgdata/pgbackrest/log"})
+			[]string{"startup", "11", "/pgdata/pg11_wal", "/pgdata/pgbackrest/log", "/pgdata/pg11_snapshot"})
 	})
 
 	t.Run("WithAdditionalConfigFiles", func(t *testing.T) {
@@ -436,7 +444,7 @@ volumes:
 
 		pod := new(corev1.PodSpec)
 		InstancePod(ctx, clusterWithConfig, instance,
-			serverSecretProjection, clientSecretProjection, dataVolume, nil, pod)
+			serverSecretProjection, clientSecretProjection, dataVolume, nil, nil, pod)
 
 		assert.Assert(t, len(pod.Containers) > 0)
 		assert.Assert(t, len(pod.InitContainers) > 0)
@@ -473,7 +481,7 @@ volumes:
 
 		t.Run("SidecarNotEnabled", func(t *testing.T) {
 			InstancePod(ctx, cluster, sidecarInstance,
-				serverSecretProjection, clientSecretProjection, dataVolume, nil, pod)
+				serverSecretProjection, clientSecretProjection, dataVolume, nil, nil, pod)
 
 			assert.Equal(t, len(pod.Containers), 2, "expected 2 containers in Pod, got %d", len(pod.Containers))
 		})
@@ -481,7 +489,7 @@ volumes:
 		t.Run("SidecarEnabled", func(t *testing.T) {
 			assert.NilError(t, util.AddAndSetFeatureGates(string(util.InstanceSidecars+"=true")))
 			InstancePod(ctx, cluster, sidecarInstance,
-				serverSecretProjection, clientSecretProjection, dataVolume, nil, pod)
+				serverSecretProjection, clientSecretProjection, dataVolume, nil, nil, pod)
 
 			assert.Equal(t, len(pod.Containers), 3, "expected 3 containers in Pod, got %d", len(pod.Containers))
 
@@ -505,7 +513,7 @@ volumes:
 
 		pod := new(corev1.PodSpec)
 		InstancePod(ctx, cluster, instance,
-			serverSecretProjection, clientSecretProjection, dataVolume, walVolume, pod)
+			serverSecretProjection, clientSecretProjection, dataVolume, walVolume, nil, pod)
 
 		assert.Assert(t, len(pod.Containers) > 0)
 		assert.Assert(t, len(pod.InitContainers) > 0)
@@ -593,7 +601,48 @@ volumes:
 
 		// Startup moves WAL files to WAL volume.
 		assert.DeepEqual(t, pod.InitContainers[0].Command[4:],
-			[]string{"startup", "11", "/pgwal/pg11_wal", "/pgdata/pgbackrest/log"})
+			[]string{"startup", "11", "/pgwal/pg11_wal", "/pgdata/pgbackrest/log", "/pgdata/pg11_snapshot"})
+	})
+
+	t.Run("WithTablespaceVolumes", func(t *testing.T) {
+		instance := new(v1beta1.PostgresInstanceSetSpec)
+		instance.TablespaceVolumes = []v1beta1.TablespaceVolume{
+			{Name: "fast"}, {Name: "archive"},
+		}
+
+		tablespaceVolumes := map[string]*corev1.PersistentVolumeClaim{
+			"archive": {ObjectMeta: metav1.ObjectMeta{Name: "archivevol"}},
+			"fast":    {ObjectMeta: metav1.ObjectMeta{Name: "fastvol"}},
+		}
+
+		pod := new(corev1.PodSpec)
+		InstancePod(ctx, cluster, instance,
+			serverSecretProjection, clientSecretProjection, dataVolume, nil, tablespaceVolumes, pod)
+
+		assert.Assert(t, marshalMatches(pod.Containers[0].VolumeMounts[3:], `
+- mountPath: /tablespaces/fast
+  name: tablespace-fast
+- mountPath: /tablespaces/archive
+  name: tablespace-archive`), "expected tablespace mounts in spec order")
+
+		assert.Assert(t, marshalMatches(pod.InitContainers[0].VolumeMounts[2:], `
+- mountPath: /tablespaces/fast
+  name: tablespace-fast
+- mountPath: /tablespaces/archive
+  name: tablespace-archive`))
+
+		assert.Assert(t, marshalMatches(pod.Volumes[3:], `
+- name: tablespace-fast
+  persistentVolumeClaim:
+    claimName: fastvol
+- name: tablespace-archive
+  persistentVolumeClaim:
+    claimName: archivevol`))
+
+		// Startup creates a directory in each tablespace volume.
+		assert.DeepEqual(t, pod.InitContainers[0].Command[4:],
+			[]string{"startup", "11", "/pgdata/pg11_wal", "/pgdata/pgbackrest/log", "/pgdata/pg11_snapshot",
+				"/tablespaces/fast/data", "/tablespaces/archive/data"})
 	})
 }
 
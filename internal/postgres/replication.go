@@ -0,0 +1,60 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReplicaStatus is what a primary knows about one of its replicas.
+type ReplicaStatus struct {
+	// State is the state of the WAL sender, such as "streaming".
+	State string `json:"state"`
+
+	// Lag is the number of bytes of WAL that the replica has not replayed.
+	// It is negative when the replica has not reported its position.
+	Lag int64 `json:"lag"`
+}
+
+// ReplicaStatuses calls exec on a primary to get the status of its replicas
+// by application_name. Patroni sets that to the name of the replica member.
+// - https://www.postgresql.org/docs/current/monitoring-stats.html#MONITORING-PG-STAT-REPLICATION-VIEW
+func ReplicaStatuses(ctx context.Context, exec Executor) (map[string]ReplicaStatus, error) {
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT COALESCE(pg_catalog.json_object_agg(application_name, pg_catalog.json_build_object(
+         'state', state,
+         'lag', COALESCE(pg_catalog.pg_wal_lsn_diff(pg_catalog.pg_current_wal_lsn(), replay_lsn), -1)::bigint
+       )), '{}')
+  FROM pg_catalog.pg_stat_replication;
+`), map[string]string{
+		"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+		"QUIET":         "on", // Do not print successful commands to stdout.
+	})
+
+	var replicas map[string]ReplicaStatus
+	if err == nil {
+		err = errors.Wrap(json.Unmarshal([]byte(stdout), &replicas), "unexpected output")
+	} else {
+		err = errors.Wrap(err, strings.TrimSpace(stderr))
+	}
+	return replicas, err
+}
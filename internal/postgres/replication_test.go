@@ -0,0 +1,78 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReplicaStatuses(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Error", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, _, stderr io.Writer, _ ...string,
+		) error {
+			_, _ = stderr.Write([]byte("psql: error: connection refused\n"))
+			return errors.New("exit status 2")
+		}
+
+		_, err := ReplicaStatuses(ctx, exec)
+		assert.ErrorContains(t, err, "connection refused: exit status 2")
+	})
+
+	t.Run("Arguments", func(t *testing.T) {
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), "pg_stat_replication"))
+			assert.DeepEqual(t, command, []string{
+				"psql", "-Xw", "--file=-", "--set=ON_ERROR_STOP=on", "--set=QUIET=on",
+			})
+
+			_, _ = stdout.Write([]byte(`{"hippo-abc-0" : {"state" : "streaming", "lag" : 0}, ` +
+				`"hippo-def-0" : {"state" : "catchup", "lag" : -1}}` + "\n"))
+			return nil
+		}
+
+		replicas, err := ReplicaStatuses(ctx, exec)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, replicas, map[string]ReplicaStatus{
+			"hippo-abc-0": {State: "streaming", Lag: 0},
+			"hippo-def-0": {State: "catchup", Lag: -1},
+		})
+	})
+
+	t.Run("Unexpected", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, _ = stdout.Write([]byte("Tuples only is on.\n{}"))
+			return nil
+		}
+
+		_, err := ReplicaStatuses(ctx, exec)
+		assert.ErrorContains(t, err, "unexpected output")
+	})
+}
@@ -24,6 +24,20 @@ import (
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+// UserHBAs appends to outHBAs the rules for users of inCluster that
+// authenticate with client certificates. They must connect over TLS and
+// cannot use passwords.
+// - https://www.postgresql.org/docs/current/auth-cert.html
+func UserHBAs(inCluster *v1beta1.PostgresCluster, outHBAs *HBAs) {
+	for _, user := range inCluster.Spec.Users {
+		if user.Certificate != nil {
+			outHBAs.Mandatory = append(outHBAs.Mandatory,
+				*NewHBA().TLS().User(string(user.Name)).Method("cert"),
+				*NewHBA().TCP().User(string(user.Name)).Method("reject"))
+		}
+	}
+}
+
 // WriteUsersInPostgreSQL calls exec to create users that do not exist in
 // PostgreSQL. Once they exist, it updates their options and passwords and
 // grants them access to their specified databases. The databases must already
@@ -28,6 +28,22 @@ import (
 	"github.com/adifri/postgres-operator/v5/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+func TestUserHBAs(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+		{Name: "app"},
+		{Name: "service", Certificate: &v1beta1.PostgresUserCertificateSpec{}},
+	}
+
+	hbas := HBAs{}
+	UserHBAs(cluster, &hbas)
+
+	assert.Assert(t, hbas.Default == nil)
+	assert.Equal(t, len(hbas.Mandatory), 2)
+	assert.Equal(t, hbas.Mandatory[0].String(), `hostssl all "service" all cert`)
+	assert.Equal(t, hbas.Mandatory[1].String(), `host all "service" all reject`)
+}
+
 func TestWriteUsersInPostgreSQL(t *testing.T) {
 	ctx := context.Background()
 
@@ -0,0 +1,113 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CertificatesSpec configures the certificates that PGO generates.
+type CertificatesSpec struct {
+
+	// How long leaf certificates are valid after they are generated or issued.
+	// Certificates that are valid for longer are replaced. Defaults to one year.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	// +kubebuilder:validation:Type=string
+	// +optional
+	LeafLifetime *metav1.Duration `json:"leafLifetime,omitempty"`
+
+	// How long before expiration leaf certificates are replaced. Warning events
+	// are emitted for any certificate that is this close to expiring. Defaults
+	// to one third of the certificate lifetime.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	// +kubebuilder:validation:Type=string
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+// CertificateStatus describes one certificate of a cluster.
+type CertificateStatus struct {
+
+	// The name of the Secret that holds the certificate.
+	SecretName string `json:"secretName"`
+
+	// The key in the Secret that holds the certificate.
+	Key string `json:"key"`
+
+	// When the certificate expires.
+	NotAfter metav1.Time `json:"notAfter"`
+}
+
+// Phases of a root certificate rotation.
+const (
+	// RootRotationTrusting means components trust both the old and the new
+	// root certificate while the old one still signs their certificates.
+	RootRotationTrusting = "Trusting"
+
+	// RootRotationReissuing means the new root certificate signs replacements
+	// for every certificate while components still trust the old one.
+	RootRotationReissuing = "Reissuing"
+
+	// RootRotationRetiring means components stop trusting the old root certificate.
+	RootRotationRetiring = "Retiring"
+
+	// RootRotationComplete means components trust only the new root certificate.
+	RootRotationComplete = "Complete"
+)
+
+// RootCertificateRotationStatus describes the replacement of the root
+// certificate authority that signs the certificates of a cluster.
+type RootCertificateRotationStatus struct {
+
+	// The SHA-256 fingerprint of the new root certificate.
+	// +optional
+	Root string `json:"root,omitempty"`
+
+	// The value of the "postgres-operator.crunchydata.com/rotate-root-certificate"
+	// annotation that started the rotation, if any.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// The phase of the cluster: Trusting, Reissuing, Retiring, or Complete.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// When the cluster entered its phase.
+	// +optional
+	PhaseTime *metav1.Time `json:"phaseTime,omitempty"`
+
+	// The phase of each component that has certificates.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Components []RootCertificateRotationComponent `json:"components,omitempty"`
+}
+
+// RootCertificateRotationComponent describes the certificates of one
+// component during a root certificate rotation.
+type RootCertificateRotationComponent struct {
+
+	// The component: "instances", "pgbackrest", "pgbouncer", "postgres",
+	// "replication", or "users".
+	Name string `json:"name"`
+
+	// The last phase in which the certificates of the component were written.
+	Phase string `json:"phase"`
+
+	// When the certificates of the component were written for its phase.
+	// +optional
+	PhaseTime *metav1.Time `json:"phaseTime,omitempty"`
+}
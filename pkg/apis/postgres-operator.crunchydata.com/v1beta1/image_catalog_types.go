@@ -0,0 +1,191 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PostgresImageCatalogSpec defines the images in a PostgresImageCatalog.
+type PostgresImageCatalogSpec struct {
+
+	// Images of PostgreSQL by major version. Changing an image causes the
+	// PostgreSQL pods of every cluster that uses it to restart.
+	// +listType=map
+	// +listMapKey=postgresVersion
+	// +optional
+	Postgres []PostgresImageCatalogPostgres `json:"postgres,omitempty"`
+
+	// Images of other components. Changing an image causes the pods of
+	// every cluster that uses it to restart.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Components []PostgresImageCatalogComponent `json:"components,omitempty"`
+}
+
+// PostgresImageCatalogPostgres defines the images of one major version of
+// PostgreSQL.
+type PostgresImageCatalogPostgres struct {
+
+	// The major version of PostgreSQL installed in the images.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=14
+	PostgresVersion int `json:"postgresVersion"`
+
+	// The image of PostgreSQL without PostGIS, pinned by digest.
+	// +optional
+	// +kubebuilder:validation:Pattern=`@sha256:[0-9a-f]{64}$`
+	Image string `json:"image,omitempty"`
+
+	// Images of PostgreSQL with the PostGIS extension by PostGIS version.
+	// +listType=map
+	// +listMapKey=version
+	// +optional
+	PostGIS []PostgresImageCatalogPostGIS `json:"postGIS,omitempty"`
+}
+
+// PostgresImageCatalogPostGIS defines the image of one version of PostGIS.
+type PostgresImageCatalogPostGIS struct {
+
+	// The PostGIS extension version installed in the image.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// The image of PostgreSQL with PostGIS, pinned by digest.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`@sha256:[0-9a-f]{64}$`
+	Image string `json:"image"`
+}
+
+// PostgresImageCatalogComponent defines the image of a component other than
+// PostgreSQL.
+type PostgresImageCatalogComponent struct {
+
+	// The name of the component.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum={pgadmin,pgbackrest,pgbouncer,pgexporter}
+	Name string `json:"name"`
+
+	// The image of the component, pinned by digest.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`@sha256:[0-9a-f]{64}$`
+	Image string `json:"image"`
+}
+
+// Names of the components in a PostgresImageCatalog.
+const (
+	ImageCatalogComponentPGAdmin    = "pgadmin"
+	ImageCatalogComponentPGBackRest = "pgbackrest"
+	ImageCatalogComponentPGBouncer  = "pgbouncer"
+	ImageCatalogComponentPGExporter = "pgexporter"
+)
+
+// PostgresImage returns the image of PostgreSQL with postGIS installed, if
+// any. It returns an empty string when there is no such image.
+func (s *PostgresImageCatalogSpec) PostgresImage(postgresVersion int, postGIS string) string {
+	for _, postgres := range s.Postgres {
+		if postgres.PostgresVersion != postgresVersion {
+			continue
+		}
+		if postGIS == "" {
+			return postgres.Image
+		}
+		for _, gis := range postgres.PostGIS {
+			if gis.Version == postGIS {
+				return gis.Image
+			}
+		}
+	}
+	return ""
+}
+
+// ComponentImage returns the image of the component named name. It returns
+// an empty string when there is no such component.
+func (s *PostgresImageCatalogSpec) ComponentImage(name string) string {
+	for _, component := range s.Components {
+		if component.Name == name {
+			return component.Image
+		}
+	}
+	return ""
+}
+
+// PostgresImageCatalogReference identifies a PostgresImageCatalog.
+type PostgresImageCatalogReference struct {
+
+	// The name of the PostgresImageCatalog.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// PostgresImageCatalogStatus records the images of a PostgresCluster that
+// came from a PostgresImageCatalog. Images that are set in the spec of the
+// cluster or missing from the catalog are empty.
+type PostgresImageCatalogStatus struct {
+
+	// The name of the PostgresImageCatalog.
+	Name string `json:"name"`
+
+	// The image of PostgreSQL.
+	// +optional
+	Postgres string `json:"postgres,omitempty"`
+
+	// The image of pgAdmin.
+	// +optional
+	PGAdmin string `json:"pgadmin,omitempty"`
+
+	// The image of pgBackRest.
+	// +optional
+	PGBackRest string `json:"pgbackrest,omitempty"`
+
+	// The image of PgBouncer.
+	// +optional
+	PGBouncer string `json:"pgbouncer,omitempty"`
+
+	// The image of the PostgreSQL Exporter.
+	// +optional
+	PGExporter string `json:"pgexporter,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// PostgresImageCatalog is the Schema for the postgresimagecatalogs API. It
+// maps PostgreSQL versions and component names to container images so that
+// images can change without redeploying the operator.
+type PostgresImageCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PostgresImageCatalogSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PostgresImageCatalogList contains a list of PostgresImageCatalog
+type PostgresImageCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PostgresImageCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PostgresImageCatalog{}, &PostgresImageCatalogList{})
+}
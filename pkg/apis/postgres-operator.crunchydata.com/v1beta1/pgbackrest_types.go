@@ -17,6 +17,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -349,6 +350,11 @@ type RepoPVC struct {
 	// Defines a PersistentVolumeClaim spec used to create and/or bind a volume
 	// +kubebuilder:validation:Required
 	VolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"volumeClaimSpec"`
+
+	// Grow the repository volume as it fills up. The volume never shrinks,
+	// even when this is removed.
+	// +optional
+	AutoGrow *VolumeAutoGrowSpec `json:"autoGrow,omitempty"`
 }
 
 // RepoAzure represents a pgBackRest repository that is created using Azure storage
@@ -412,6 +418,10 @@ type RepoStatus struct {
 	// commands accordingly.
 	// +optional
 	RepoOptionsHash string `json:"repoOptionsHash,omitempty"`
+
+	// The storage requested of the repository volume after it grew automatically.
+	// +optional
+	VolumeSize *resource.Quantity `json:"volumeSize,omitempty"`
 }
 
 // PGBackRestDataSource defines a pgBackRest configuration specifically for restoring from cloud-based data source
@@ -17,6 +17,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -66,6 +67,13 @@ type PGBouncerPodSpec struct {
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// Authentication settings for PgBouncer clients and its admin console.
+	// When specified, PgBouncer authenticates clients using an HBA file.
+	// Changes to these values are automatically reloaded.
+	// More info: https://www.pgbouncer.org/config.html#hba-file-format
+	// +optional
+	Authentication *PGBouncerAuthentication `json:"authentication,omitempty"`
+
 	// Configuration settings for the PgBouncer process. Changes to any of these
 	// values will be automatically reloaded without validation. Be careful, as
 	// you may put PgBouncer into an unusable state.
@@ -100,6 +108,21 @@ type PGBouncerPodSpec struct {
 	// +kubebuilder:validation:Minimum=1024
 	Port *int32 `json:"port,omitempty"`
 
+	// Settings for pausing PgBouncer while the PostgreSQL primary changes.
+	// When specified, PgBouncer holds client queries during planned
+	// switchovers and primary restarts, then resumes once the new primary
+	// accepts writes.
+	// More info: https://www.pgbouncer.org/usage.html#pause-db
+	// +optional
+	Pause *PGBouncerPauseSpec `json:"pause,omitempty"`
+
+	// Typed connection pool definitions. These are added to the pools that
+	// PgBouncer creates automatically for every database. Invalid definitions
+	// are ignored and reported in the ProxyPoolsValid condition.
+	// More info: https://www.pgbouncer.org/config.html#section-databases
+	// +optional
+	Pools *PGBouncerPools `json:"pools,omitempty"`
+
 	// Priority class name for the pgBouncer pod. Changing this value causes
 	// PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
@@ -117,6 +140,12 @@ type PGBouncerPodSpec struct {
 	// +optional
 	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
 
+	// Defines a second set of PgBouncer pods that pool connections to
+	// PostgreSQL replicas. These pods share all other settings with the
+	// primary PgBouncer pods but are exposed through their own Service.
+	// +optional
+	ReadOnly *PGBouncerReadOnlySpec `json:"readOnly,omitempty"`
+
 	// Compute resources of a PgBouncer container. Changing this value causes
 	// PgBouncer to restart.
 	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers
@@ -144,6 +173,204 @@ type PGBouncerPodSpec struct {
 	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 }
 
+// PGBouncerPauseSpec defines how PgBouncer pauses during planned changes to
+// the PostgreSQL primary.
+type PGBouncerPauseSpec struct {
+
+	// Number of seconds PgBouncer can remain paused. When this elapses,
+	// PGO resumes PgBouncer even if no primary accepts writes.
+	// +optional
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// PGBouncerAuthentication defines who can use the PgBouncer admin console and
+// how clients authenticate to PgBouncer.
+type PGBouncerAuthentication struct {
+
+	// Users that can connect to the "pgbouncer" admin console and run any
+	// command, such as SHOW, PAUSE, and RELOAD. A password for each user is
+	// generated and stored in the PgBouncer Secret. These names should not
+	// match any PostgreSQL user that connects through PgBouncer.
+	// More info: https://www.pgbouncer.org/config.html#admin_users
+	// +listType=set
+	// +optional
+	AdminUsers []PGBouncerConsoleUser `json:"adminUsers,omitempty"`
+
+	// Users that can connect to the "pgbouncer" admin console and run
+	// read-only SHOW commands. A password for each user is generated and
+	// stored in the PgBouncer Secret. These names should not match any
+	// PostgreSQL user that connects through PgBouncer.
+	// More info: https://www.pgbouncer.org/config.html#stats_users
+	// +listType=set
+	// +optional
+	StatsUsers []PGBouncerConsoleUser `json:"statsUsers,omitempty"`
+
+	// Rules that determine which clients can connect to which databases and
+	// how they authenticate. The first rule that matches a connection is used.
+	// When empty, any client can connect to any database over TLS using
+	// a password.
+	// +optional
+	Rules []PGBouncerHBARule `json:"rules,omitempty"`
+}
+
+// PGBouncerConsoleUser is the name of a user of the PgBouncer admin console.
+// The value goes into keys of a corev1.Secret, so it may contain only lowercase
+// letters, numbers, and hyphen.
+//
+// +kubebuilder:validation:MaxLength=63
+// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+type PGBouncerConsoleUser string
+
+// PGBouncerHBARule is a single record of the PgBouncer HBA file.
+// More info: https://www.pgbouncer.org/config.html#hba-file-format
+type PGBouncerHBARule struct {
+
+	// The kind of connection this rule matches. "hostssl" matches only
+	// connections using TLS, "hostnossl" matches only connections without
+	// TLS, and "host" matches both.
+	// +kubebuilder:default=hostssl
+	// +kubebuilder:validation:Enum={host,hostssl,hostnossl}
+	// +optional
+	Connection string `json:"connection,omitempty"`
+
+	// Databases to which this rule applies. The "pgbouncer" database is the
+	// admin console. When empty, this rule applies to all databases.
+	// +listType=set
+	// +optional
+	Databases []PostgresIdentifier `json:"databases,omitempty"`
+
+	// Users to which this rule applies. When empty, this rule applies to
+	// all users.
+	// +listType=set
+	// +optional
+	Users []PostgresIdentifier `json:"users,omitempty"`
+
+	// Blocks of client IP addresses in CIDR notation to which this rule
+	// applies. When empty, this rule applies to all addresses.
+	// +listType=set
+	// +optional
+	Networks []string `json:"networks,omitempty"`
+
+	// The authentication method to use when a connection matches this rule.
+	// The "md5" method verifies passwords stored as either MD5 or SCRAM-SHA-256.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum={trust,reject,md5,password,scram-sha-256,cert}
+	Method string `json:"method"`
+}
+
+// PGBouncerPools defines connection pools for specific databases and users.
+type PGBouncerPools struct {
+
+	// Connection pools for specific databases. A database cannot also be
+	// defined in the "config.databases" field.
+	// +listType=map
+	// +listMapKey=database
+	// +optional
+	Databases []PGBouncerDatabasePool `json:"databases,omitempty"`
+
+	// Connection settings for specific users. A user cannot also be defined
+	// in the "config.users" field.
+	// +listType=map
+	// +listMapKey=user
+	// +optional
+	Users []PGBouncerUserPool `json:"users,omitempty"`
+}
+
+// PGBouncerDatabasePool defines the connection pool of one database.
+type PGBouncerDatabasePool struct {
+
+	// The name of the database requested by clients. The "pgbouncer" database
+	// is the admin console and cannot be used.
+	// +kubebuilder:validation:Required
+	Database PostgresIdentifier `json:"database"`
+
+	// The role of PostgreSQL instances to which this pool connects. Read-only
+	// PgBouncer pods always connect to replicas.
+	// +kubebuilder:default=primary
+	// +kubebuilder:validation:Enum={primary,replica}
+	// +optional
+	Target string `json:"target,omitempty"`
+
+	// When PostgreSQL connections are returned to the pool. Defaults to the
+	// global "pool_mode" setting.
+	// +kubebuilder:validation:Enum={session,transaction,statement}
+	// +optional
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// Maximum number of PostgreSQL connections for each user of this pool.
+	// Defaults to the global "default_pool_size" setting.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PoolSize *int32 `json:"poolSize,omitempty"`
+
+	// Number of additional PostgreSQL connections for each user of this pool
+	// when clients wait too long. Defaults to the global "reserve_pool_size"
+	// setting.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReservePoolSize *int32 `json:"reservePoolSize,omitempty"`
+
+	// Maximum number of PostgreSQL connections for all users of this pool.
+	// Zero means unlimited. Defaults to the global "max_db_connections" setting.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDBConnections *int32 `json:"maxDBConnections,omitempty"`
+}
+
+// PGBouncerDatabasePool targets.
+const (
+	PGBouncerPoolTargetPrimary = "primary"
+	PGBouncerPoolTargetReplica = "replica"
+)
+
+// PGBouncerUserPool defines connection settings of one user.
+type PGBouncerUserPool struct {
+
+	// The name of the user.
+	// +kubebuilder:validation:Required
+	User PostgresIdentifier `json:"user"`
+
+	// When PostgreSQL connections of this user are returned to the pool.
+	// Defaults to the pool mode of each database.
+	// +kubebuilder:validation:Enum={session,transaction,statement}
+	// +optional
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// Maximum number of PostgreSQL connections for this user across all pools.
+	// Zero means unlimited.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxUserConnections *int32 `json:"maxUserConnections,omitempty"`
+}
+
+// PGBouncerReadOnlySpec defines PgBouncer pods that connect to PostgreSQL
+// replicas rather than the primary.
+type PGBouncerReadOnlySpec struct {
+
+	// Settings that apply to the read-only PgBouncer process. These override
+	// any settings of the same name in the global PgBouncer configuration.
+	// More info: https://www.pgbouncer.org/config.html
+	// +optional
+	Global map[string]string `json:"global,omitempty"`
+
+	// Number of desired read-only PgBouncer pods.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Minimum number of read-only pods that should be available at a time.
+	// Defaults to one when the replicas field is greater than one.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// Specification of the service that exposes read-only PgBouncer pods.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+}
+
 // PGBouncerSidecars defines the configuration for pgBouncer sidecar containers
 type PGBouncerSidecars struct {
 	// Defines the configuration for the pgBouncer config sidecar container
@@ -163,10 +390,30 @@ func (s *PGBouncerPodSpec) Default() {
 		s.Replicas = new(int32)
 		*s.Replicas = 1
 	}
+
+	if s.ReadOnly != nil && s.ReadOnly.Replicas == nil {
+		s.ReadOnly.Replicas = new(int32)
+		*s.ReadOnly.Replicas = 1
+	}
+
+	if s.Pause != nil && s.Pause.TimeoutSeconds == nil {
+		s.Pause.TimeoutSeconds = new(int32)
+		*s.Pause.TimeoutSeconds = 60
+	}
 }
 
 type PGBouncerPodStatus struct {
 
+	// When PGO paused PgBouncer for a change of the PostgreSQL primary. PGO
+	// resumes PgBouncer once a primary accepts writes.
+	// +optional
+	PausedAt *metav1.Time `json:"pausedAt,omitempty"`
+
+	// The effective settings of each connection pool in the PgBouncer
+	// configuration, including the pool that is created automatically.
+	// +optional
+	Pools []PGBouncerPoolStatus `json:"pools,omitempty"`
+
 	// Identifies the revision of PgBouncer assets that have been installed into
 	// PostgreSQL.
 	PostgreSQLRevision string `json:"postgresRevision,omitempty"`
@@ -176,4 +423,38 @@ type PGBouncerPodStatus struct {
 
 	// Total number of non-terminated pods.
 	Replicas int32 `json:"replicas,omitempty"`
+
+	// The effective settings of each connection pool in the configuration of
+	// the read-only PgBouncer. These pools connect to replicas.
+	// +optional
+	ReadOnlyPools []PGBouncerPoolStatus `json:"readOnlyPools,omitempty"`
+
+	// Total number of ready read-only pods.
+	ReadOnlyReadyReplicas int32 `json:"readOnlyReadyReplicas,omitempty"`
+
+	// Total number of non-terminated read-only pods.
+	ReadOnlyReplicas int32 `json:"readOnlyReplicas,omitempty"`
+}
+
+// PGBouncerPoolStatus describes the effective settings of a connection pool.
+type PGBouncerPoolStatus struct {
+
+	// The name of the database requested by clients. The automatic pool is "*".
+	Database string `json:"database"`
+
+	// The role of PostgreSQL instances to which this pool connects.
+	Target string `json:"target"`
+
+	// When PostgreSQL connections are returned to the pool.
+	PoolMode string `json:"poolMode"`
+
+	// Maximum number of PostgreSQL connections for each user of this pool.
+	PoolSize int32 `json:"poolSize"`
+
+	// Number of additional PostgreSQL connections for each user of this pool.
+	ReservePoolSize int32 `json:"reservePoolSize"`
+
+	// Maximum number of PostgreSQL connections for all users of this pool.
+	// Zero means unlimited.
+	MaxDBConnections int32 `json:"maxDBConnections"`
 }
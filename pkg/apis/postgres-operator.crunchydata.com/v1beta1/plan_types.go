@@ -0,0 +1,61 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+// PostgresClusterPlanStatus describes what applying the spec of a cluster
+// would change.
+type PostgresClusterPlanStatus struct {
+
+	// The value of the plan annotation that this plan is for.
+	ID string `json:"id"`
+
+	// The generation of the cluster spec that was planned.
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// Objects that would be created, updated, or deleted.
+	// +listType=atomic
+	// +optional
+	Changes []PostgresClusterPlannedChange `json:"changes,omitempty"`
+}
+
+// PostgresClusterPlannedChange describes a change to one object.
+type PostgresClusterPlannedChange struct {
+
+	// The kind of object, such as StatefulSet or ConfigMap.
+	Kind string `json:"kind"`
+
+	// The name of the object. A name that ends in "*" stands for one that
+	// will be generated.
+	Name string `json:"name"`
+
+	// What would happen to the object: Create, Update, or Delete.
+	Action string `json:"action"`
+
+	// The fields that would change when the object is updated.
+	// +listType=atomic
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+
+	// Whether the change replaces pods.
+	// +optional
+	Rollout bool `json:"rollout,omitempty"`
+}
+
+const (
+	PostgresClusterPlannedCreate = "Create"
+	PostgresClusterPlannedUpdate = "Update"
+	PostgresClusterPlannedDelete = "Delete"
+)
@@ -15,6 +15,10 @@
 
 package v1beta1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // PostgreSQL identifiers are limited in length but may contain any character.
 // More info: https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-IDENTIFIERS
 //
@@ -67,4 +71,127 @@ type PostgresUserSpec struct {
 	// Properties of the password generated for this user.
 	// +optional
 	Password *PostgresPasswordSpec `json:"password,omitempty"`
+
+	// Properties of the client certificate issued for this user. When set,
+	// this user must connect to PostgreSQL over TLS with the certificate in
+	// its Secret rather than a password.
+	// More info: https://www.postgresql.org/docs/current/auth-cert.html
+	// +optional
+	Certificate *PostgresUserCertificateSpec `json:"certificate,omitempty"`
+
+	// Where the credentials of this user are kept outside of Kubernetes.
+	// PGO must be configured with a credential store to use this.
+	// +optional
+	CredentialStore *PostgresUserCredentialStoreSpec `json:"credentialStore,omitempty"`
+}
+
+// PostgresUserCredentialStoreSpec describes the credentials of a user in the
+// credential store of PGO.
+type PostgresUserCredentialStoreSpec struct {
+
+	// How the password in the store relates to the password in the Secret of
+	// this user.
+	// "Source" means PGO reads the password from the store and never changes it.
+	// "Publish" means PGO generates the password and writes it to the store.
+	// +kubebuilder:default=Source
+	// +kubebuilder:validation:Enum={Source,Publish}
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// The path of the credentials in the store. Defaults to
+	// "{namespace}/{cluster}/{user}".
+	// +kubebuilder:validation:Pattern=`^[^/].*$`
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// PostgresUserCredentialStoreSpec modes.
+const (
+	PostgresCredentialStoreModePublish = "Publish"
+	PostgresCredentialStoreModeSource  = "Source"
+)
+
+// PostgresUserCertificateSpec describes the client certificate of a user.
+type PostgresUserCertificateSpec struct {
+
+	// How long the certificate is valid after it is issued. Defaults to the
+	// leaf lifetime of the cluster.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	// +kubebuilder:validation:Type=string
+	// +optional
+	Lifetime *metav1.Duration `json:"lifetime,omitempty"`
+
+	// How long before expiration the certificate is replaced. Defaults to the
+	// renewal window of the cluster.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	// +kubebuilder:validation:Type=string
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+type PostgresAuthenticationSpec struct {
+	// Postgres compares every new connection to these rules in the order they
+	// are defined. The first rule that matches determines if and how the
+	// connection must then authenticate. These rules come after those that PGO
+	// requires and before its defaults. Any "pg_hba" records in the Patroni
+	// dynamic configuration come after these rules and replace the defaults.
+	// More info: https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
+	// +kubebuilder:validation:MaxItems=64
+	// +listType=atomic
+	// +optional
+	Rules []PostgresHBARuleSpec `json:"rules,omitempty"`
+}
+
+type PostgresHBARuleSpec struct {
+	// The connection transport this rule matches:
+	// "host" for network connections that may or may not be encrypted,
+	// "hostssl" for network connections encrypted using TLS, and
+	// "hostnossl" for network connections without TLS.
+	// +kubebuilder:validation:Enum={host,hostssl,hostnossl}
+	// +kubebuilder:default=hostssl
+	// +optional
+	Connection string `json:"connection,omitempty"`
+
+	// Which databases this rule matches. When omitted or empty, this rule
+	// matches all databases.
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=atomic
+	// +optional
+	Databases []PostgresIdentifier `json:"databases,omitempty"`
+
+	// Which user names this rule matches. When omitted or empty, this rule
+	// matches all users.
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=atomic
+	// +optional
+	Users []PostgresIdentifier `json:"users,omitempty"`
+
+	// Which IP address ranges this rule matches, in CIDR notation. When omitted
+	// or empty, this rule matches all addresses.
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=atomic
+	// +optional
+	Networks []string `json:"networks,omitempty"`
+
+	// The authentication method to use when a connection matches this rule.
+	// The special value "reject" refuses the connection even when other rules
+	// would allow it.
+	// More info: https://www.postgresql.org/docs/current/auth-methods.html
+	// +kubebuilder:validation:Pattern=`^[-a-z0-9]+$`
+	// +kubebuilder:validation:MinLength=1
+	Method string `json:"method"`
+
+	// Additional settings for this rule or its authentication method.
+	// +kubebuilder:validation:MaxProperties=20
+	// +mapType=atomic
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type PostgresAuthenticationStatus struct {
+	// The pg_hba.conf records that PGO configures, in the order that
+	// Postgres compares them to connections.
+	// +listType=atomic
+	// +optional
+	Rules []string `json:"rules,omitempty"`
 }
@@ -133,6 +133,44 @@ pgBouncer:
   resources: {}
 		`)+"\n")
 	})
+
+	t.Run("PgBouncer read-only proxy", func(t *testing.T) {
+		var cluster PostgresCluster
+		cluster.Spec.Proxy = &PostgresProxySpec{PGBouncer: &PGBouncerPodSpec{
+			ReadOnly: &PGBouncerReadOnlySpec{},
+		}}
+		cluster.Default()
+
+		b, err := yaml.Marshal(cluster.Spec.Proxy.PGBouncer.ReadOnly)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, string(b), "replicas: 1\n")
+	})
+
+	t.Run("PgBouncer pause", func(t *testing.T) {
+		var cluster PostgresCluster
+		cluster.Spec.Proxy = &PostgresProxySpec{PGBouncer: &PGBouncerPodSpec{
+			Pause: &PGBouncerPauseSpec{},
+		}}
+		cluster.Default()
+
+		b, err := yaml.Marshal(cluster.Spec.Proxy.PGBouncer.Pause)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, string(b), "timeoutSeconds: 60\n")
+	})
+
+	t.Run("VolumeSnapshots", func(t *testing.T) {
+		var cluster PostgresCluster
+		cluster.Spec.Backups.Snapshots = &VolumeSnapshotBackups{}
+		cluster.Default()
+
+		b, err := yaml.Marshal(cluster.Spec.Backups.Snapshots)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, string(b), strings.TrimSpace(`
+bootstrapReplicas: true
+retention: 3
+volumeSnapshotClassName: ""
+		`)+"\n")
+	})
 }
 
 func TestPostgresInstanceSetSpecDefault(t *testing.T) {
@@ -148,6 +186,22 @@ name: "05"
 replicas: 1
 resources: {}
 	`)+"\n")
+
+	t.Run("AutoGrow", func(t *testing.T) {
+		var spec PostgresInstanceSetSpec
+		spec.DataVolumeAutoGrow = &VolumeAutoGrowSpec{}
+		spec.WALVolumeAutoGrow = &VolumeAutoGrowSpec{}
+		spec.Default(0)
+
+		b, err := yaml.Marshal(spec.DataVolumeAutoGrow)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, string(b), strings.TrimSpace(`
+growthPercent: 25
+maxSize: "0"
+thresholdPercent: 80
+		`)+"\n")
+		assert.DeepEqual(t, spec.WALVolumeAutoGrow, spec.DataVolumeAutoGrow)
+	})
 }
 
 func TestMetadataGetLabels(t *testing.T) {
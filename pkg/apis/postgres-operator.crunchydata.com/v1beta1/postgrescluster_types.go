@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -32,6 +33,10 @@ type PostgresClusterSpec struct {
 	// +optional
 	DataSource *DataSource `json:"dataSource,omitempty"`
 
+	// Authentication settings for the PostgreSQL server.
+	// +optional
+	Authentication *PostgresAuthenticationSpec `json:"authentication,omitempty"`
+
 	// PostgreSQL backup configuration
 	// +kubebuilder:validation:Required
 	Backups Backups `json:"backups"`
@@ -57,6 +62,20 @@ type PostgresClusterSpec struct {
 	// +optional
 	CustomReplicationClientTLSSecret *corev1.SecretProjection `json:"customReplicationTLSSecret,omitempty"`
 
+	// The cert-manager Issuer or ClusterIssuer of certificates for PostgreSQL,
+	// Patroni, pgBackRest, and replication. When set, PGO creates cert-manager
+	// Certificates rather than signing certificates with its own root
+	// certificate authority. CustomTLSSecret and CustomReplicationClientTLSSecret
+	// take precedence over certificates from this issuer.
+	// More info: https://cert-manager.io/docs/concepts/issuer/
+	// +optional
+	CertificateIssuer *CertificateIssuerReference `json:"certificateIssuer,omitempty"`
+
+	// Lifetimes of the certificates that PGO generates or requests from the
+	// CertificateIssuer.
+	// +optional
+	Certificates *CertificatesSpec `json:"certificates,omitempty"`
+
 	// DatabaseInitSQL defines a ConfigMap containing custom SQL that will
 	// be run after the cluster is initialized. This ConfigMap must be in the same
 	// namespace as the cluster.
@@ -79,6 +98,13 @@ type PostgresClusterSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1
 	Image string `json:"image,omitempty"`
 
+	// The PostgresImageCatalog of images to use when an image is not set in
+	// this spec. Images in the catalog take precedence over operator
+	// environment variables. Changing an image in the catalog causes pods
+	// that use it to restart.
+	// +optional
+	ImageCatalog *PostgresImageCatalogReference `json:"imageCatalog,omitempty"`
+
 	// ImagePullPolicy is used to determine when Kubernetes will attempt to
 	// pull (download) container images.
 	// More info: https://kubernetes.io/docs/concepts/containers/images/#image-pull-policy
@@ -165,6 +191,14 @@ type PostgresClusterSpec struct {
 	// +optional
 	SupplementalGroups []int64 `json:"supplementalGroups,omitempty"`
 
+	// How changes to the PostgreSQL image are rolled out to instances.
+	// +optional
+	UpdateStrategy *PostgresUpdateStrategySpec `json:"updateStrategy,omitempty"`
+
+	// How changes to instances are rolled out across instance sets.
+	// +optional
+	RolloutPolicy *PostgresRolloutPolicySpec `json:"rolloutPolicy,omitempty"`
+
 	// Users to create inside PostgreSQL and the databases they should access.
 	// The default creates one user that can access one database matching the
 	// PostgresCluster name. An empty list creates no users. Removing a user
@@ -177,6 +211,25 @@ type PostgresClusterSpec struct {
 	Config PostgresAdditionalConfig `json:"config,omitempty"`
 }
 
+// CertificateIssuerReference identifies a cert-manager Issuer or ClusterIssuer.
+type CertificateIssuerReference struct {
+	// Name of the issuer.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Kind of the issuer: Issuer or ClusterIssuer. Defaults to Issuer.
+	// +optional
+	// +kubebuilder:default=Issuer
+	// +kubebuilder:validation:Enum={Issuer,ClusterIssuer}
+	Kind string `json:"kind,omitempty"`
+
+	// API group of the issuer. Defaults to cert-manager.io.
+	// +optional
+	// +kubebuilder:default=cert-manager.io
+	Group string `json:"group,omitempty"`
+}
+
 // DataSource defines data sources for a new PostgresCluster.
 type DataSource struct {
 	// Defines a pgBackRest cloud-based data source that can be used to pre-populate the
@@ -294,6 +347,10 @@ func (s *PostgresClusterSpec) Default() {
 		s.InstanceSets[i].Default(i)
 	}
 
+	if s.Backups.Snapshots != nil {
+		s.Backups.Snapshots.Default()
+	}
+
 	if s.Patroni == nil {
 		s.Patroni = new(PatroniSpec)
 	}
@@ -319,6 +376,10 @@ type Backups struct {
 	// pgBackRest archive configuration
 	// +kubebuilder:validation:Required
 	PGBackRest PGBackRestArchive `json:"pgbackrest"`
+
+	// Backups that take CSI VolumeSnapshots of PostgreSQL volumes
+	// +optional
+	Snapshots *VolumeSnapshotBackups `json:"snapshots,omitempty"`
 }
 
 // PostgresClusterStatus defines the observed state of PostgresCluster
@@ -358,6 +419,39 @@ type PostgresClusterStatus struct {
 	// +optional
 	StartupInstanceSet string `json:"startupInstanceSet,omitempty"`
 
+	// How the PostgreSQL server authenticates connections.
+	// +optional
+	Authentication *PostgresAuthenticationStatus `json:"authentication,omitempty"`
+
+	// The images resolved from spec.imageCatalog.
+	// +optional
+	ImageCatalog *PostgresImageCatalogStatus `json:"imageCatalog,omitempty"`
+
+	// The most recent changes to the PostgreSQL image that were rolled out
+	// with the "Verified" update strategy, oldest first.
+	// +listType=atomic
+	// +optional
+	ImageRollouts []PostgresImageRolloutStatus `json:"imageRollouts,omitempty"`
+
+	// The progress of changes to instances when spec.rolloutPolicy is set.
+	// +optional
+	Rollout *PostgresRolloutStatus `json:"rollout,omitempty"`
+
+	// What applying the spec would change when the cluster has the
+	// "postgres-operator.crunchydata.com/plan" annotation.
+	// +optional
+	Plan *PostgresClusterPlanStatus `json:"plan,omitempty"`
+
+	// The certificates of this cluster and when they expire.
+	// +listType=atomic
+	// +optional
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+
+	// Progress of replacing the root certificate authority that signs the
+	// certificates of this cluster.
+	// +optional
+	RootCertificateRotation *RootCertificateRotationStatus `json:"rootCertificateRotation,omitempty"`
+
 	// Current state of the PostgreSQL user interface.
 	// +optional
 	UserInterface *PostgresUserInterfaceStatus `json:"userInterface,omitempty"`
@@ -373,14 +467,20 @@ type PostgresClusterStatus struct {
 	// +optional
 	DatabaseInitSQL *string `json:"databaseInitSQL,omitempty"`
 
+	// Snapshot backups of PostgreSQL volumes, newest first.
+	// +optional
+	VolumeSnapshots []VolumeSnapshotBackupStatus `json:"volumeSnapshots,omitempty"`
+
 	// observedGeneration represents the .metadata.generation on which the status was based.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
 	// conditions represent the observations of postgrescluster's current state.
-	// Known .status.conditions.type are: "PersistentVolumeResizing",
-	// "Progressing", "ProxyAvailable"
+	// Known .status.conditions.type are: "BackupsHealthy", "Degraded",
+	// "ImageCatalogResolved", "PersistentVolumeMigrating", "PersistentVolumeResizing",
+	// "PrimaryAvailable", "Progressing", "ProxyAvailable", "Ready", "ReplicasHealthy",
+	// "RolloutPolicyValid"
 	// +optional
 	// +listType=map
 	// +listMapKey=type
@@ -390,9 +490,21 @@ type PostgresClusterStatus struct {
 
 // PostgresClusterStatus condition types.
 const (
-	PersistentVolumeResizing   = "PersistentVolumeResizing"
-	PostgresClusterProgressing = "Progressing"
-	ProxyAvailable             = "ProxyAvailable"
+	PersistentVolumeMigrating       = "PersistentVolumeMigrating"
+	PersistentVolumeResizing        = "PersistentVolumeResizing"
+	ImageCatalogResolved            = "ImageCatalogResolved"
+	PostgresClusterProgressing      = "Progressing"
+	ProxyAvailable                  = "ProxyAvailable"
+	ProxyPoolsValid                 = "ProxyPoolsValid"
+	RolloutPolicyValid              = "RolloutPolicyValid"
+	VolumeSnapshotBackupProgressing = "VolumeSnapshotBackupProgressing"
+
+	// These summarize the health of the whole cluster.
+	PostgresClusterBackupsHealthy   = "BackupsHealthy"
+	PostgresClusterDegraded         = "Degraded"
+	PostgresClusterPrimaryAvailable = "PrimaryAvailable"
+	PostgresClusterReady            = "Ready"
+	PostgresClusterReplicasHealthy  = "ReplicasHealthy"
 )
 
 type PostgresInstanceSetSpec struct {
@@ -438,6 +550,11 @@ type PostgresInstanceSetSpec struct {
 	// +kubebuilder:validation:Required
 	DataVolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"dataVolumeClaimSpec"`
 
+	// Grow the PostgreSQL data volumes as they fill up. The volumes never
+	// shrink, even when this is removed.
+	// +optional
+	DataVolumeAutoGrow *VolumeAutoGrowSpec `json:"dataVolumeAutoGrow,omitempty"`
+
 	// Priority class name for the PostgreSQL pod. Changing this value causes
 	// PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
@@ -463,6 +580,15 @@ type PostgresInstanceSetSpec struct {
 	// +optional
 	Sidecars *InstanceSidecars `json:"sidecars,omitempty"`
 
+	// Additional volumes for PostgreSQL tablespaces. Each is mounted at
+	// "/tablespaces/{name}" and PostgreSQL files belong in its "data" directory.
+	// Changing this value causes PostgreSQL to restart.
+	// More info: https://www.postgresql.org/docs/current/manage-ag-tablespaces.html
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	TablespaceVolumes []TablespaceVolume `json:"tablespaceVolumes,omitempty"`
+
 	// Tolerations of a PostgreSQL pod. Changing this value causes PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
 	// +optional
@@ -478,6 +604,22 @@ type PostgresInstanceSetSpec struct {
 	// More info: https://www.postgresql.org/docs/current/wal.html
 	// +optional
 	WALVolumeClaimSpec *corev1.PersistentVolumeClaimSpec `json:"walVolumeClaimSpec,omitempty"`
+
+	// Grow the PostgreSQL WAL volumes as they fill up. The volumes never
+	// shrink, even when this is removed.
+	// +optional
+	WALVolumeAutoGrow *VolumeAutoGrowSpec `json:"walVolumeAutoGrow,omitempty"`
+
+	// How to apply changes to volume claims that Kubernetes cannot make in
+	// place, such as a smaller storage request or another storage class.
+	// "InPlace" reports that the volumes cannot be changed. "Replace" creates
+	// instances with the new volumes one at a time, waits for each to become
+	// a ready replica, then removes an instance with the old volumes,
+	// switching over when that instance is the primary.
+	// +optional
+	// +kubebuilder:default=InPlace
+	// +kubebuilder:validation:Enum={InPlace,Replace}
+	VolumeChangePolicy string `json:"volumeChangePolicy,omitempty"`
 }
 
 // InstanceSidecars defines the configuration for instance sidecar containers
@@ -497,6 +639,37 @@ func (s *PostgresInstanceSetSpec) Default(i int) {
 		s.Replicas = new(int32)
 		*s.Replicas = 1
 	}
+	if s.DataVolumeAutoGrow != nil {
+		s.DataVolumeAutoGrow.Default()
+	}
+	if s.WALVolumeAutoGrow != nil {
+		s.WALVolumeAutoGrow.Default()
+	}
+}
+
+// PostgresInstanceSetSpec.VolumeChangePolicy values.
+const (
+	VolumeChangeInPlace = "InPlace"
+	VolumeChangeReplace = "Replace"
+)
+
+// TablespaceVolume defines a PersistentVolumeClaim for a PostgreSQL tablespace.
+type TablespaceVolume struct {
+	// The name of the volume and its tablespace. Must be unique in the instance
+	// set, and every instance set must define the same tablespace volumes.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9]*$`
+	Name string `json:"name"`
+
+	// Defines a PersistentVolumeClaim for the tablespace.
+	// +kubebuilder:validation:Required
+	DataVolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"dataVolumeClaimSpec"`
+
+	// Whether or not to create a PostgreSQL tablespace with this name in the
+	// volume. The tablespace is created once and never dropped.
+	// +optional
+	CreateTablespace bool `json:"createTablespace,omitempty"`
 }
 
 type PostgresInstanceSetStatus struct {
@@ -513,6 +686,14 @@ type PostgresInstanceSetStatus struct {
 	// Total number of pods that have the desired specification.
 	// +optional
 	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// The storage requested of data volumes after they grew automatically.
+	// +optional
+	DataVolumeSize *resource.Quantity `json:"dataVolumeSize,omitempty"`
+
+	// The storage requested of WAL volumes after they grew automatically.
+	// +optional
+	WALVolumeSize *resource.Quantity `json:"walVolumeSize,omitempty"`
 }
 
 // PostgresProxySpec is a union of the supported PostgreSQL proxies.
@@ -585,6 +766,13 @@ type PostgresAdditionalConfig struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Primary",type=string,JSONPath=`.status.conditions[?(@.type=="PrimaryAvailable")].status`
+// +kubebuilder:printcolumn:name="Replicas",type=string,JSONPath=`.status.conditions[?(@.type=="ReplicasHealthy")].status`
+// +kubebuilder:printcolumn:name="Backups",type=string,JSONPath=`.status.conditions[?(@.type=="BackupsHealthy")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Postgres",type=integer,JSONPath=`.spec.postgresVersion`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +operator-sdk:csv:customresourcedefinitions:resources={{ConfigMap,v1},{Secret,v1},{Service,v1},{CronJob,v1beta1},{Deployment,v1},{Job,v1},{StatefulSet,v1},{PersistentVolumeClaim,v1}}
 
 // PostgresCluster is the Schema for the postgresclusters API
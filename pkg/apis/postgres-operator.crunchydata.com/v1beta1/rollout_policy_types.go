@@ -0,0 +1,107 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PostgresRolloutPolicySpec defines how changes to instances are rolled out
+// across instance sets. A rollout proceeds in steps; each step updates one or
+// more instance sets, and the next step starts only after every instance in
+// the previous one runs the change and is available.
+type PostgresRolloutPolicySpec struct {
+
+	// The name of an instance set that is updated before any other.
+	// +optional
+	Canary string `json:"canary,omitempty"`
+
+	// How instance sets other than the canary are grouped into steps. "None"
+	// updates them together. "InstanceSet" updates them one at a time in the
+	// order they appear in spec.instances.
+	// +kubebuilder:default=None
+	// +kubebuilder:validation:Enum={None,InstanceSet}
+	// +optional
+	Partition string `json:"partition,omitempty"`
+
+	// The most instances in the cluster that can be unavailable while they
+	// are updated.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+
+	// How long to wait after one step completes before the next one starts.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PauseSeconds *int32 `json:"pauseSeconds,omitempty"`
+
+	// Whether each step after the first waits for approval. Approve a step by
+	// setting the "postgres-operator.crunchydata.com/rollout-approve"
+	// annotation of the cluster to a new value, such as a timestamp.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+const (
+	PostgresRolloutPartitionNone        = "None"
+	PostgresRolloutPartitionInstanceSet = "InstanceSet"
+)
+
+// PostgresRolloutStatus records the progress of a rollout across instance sets.
+type PostgresRolloutStatus struct {
+
+	// The instance sets of the current or most recent step.
+	// +listType=atomic
+	// +optional
+	InstanceSets []string `json:"instanceSets,omitempty"`
+
+	// The phase of the rollout: Progressing, Paused, AwaitingApproval, or
+	// Complete.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Details about the phase, such as what the rollout is waiting for.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the current step started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// When the current step completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// The last value of the approval annotation that started a step.
+	// +optional
+	Approved string `json:"approved,omitempty"`
+}
+
+const (
+	// PostgresRolloutProgressing means instances of the current step are
+	// being updated.
+	PostgresRolloutProgressing = "Progressing"
+
+	// PostgresRolloutPaused means the next step waits for spec.rolloutPolicy.pauseSeconds.
+	PostgresRolloutPaused = "Paused"
+
+	// PostgresRolloutAwaitingApproval means the next step waits for approval.
+	PostgresRolloutAwaitingApproval = "AwaitingApproval"
+
+	// PostgresRolloutComplete means every instance runs the change.
+	PostgresRolloutComplete = "Complete"
+)
@@ -17,6 +17,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -52,3 +53,39 @@ type Sidecar struct {
 	// +optional
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 }
+
+// VolumeAutoGrowSpec defines when and how much a volume grows as its
+// filesystem fills up. The storage class of the volume must allow expansion.
+// More info: https://kubernetes.io/docs/concepts/storage/persistent-volumes/#expanding-persistent-volumes-claims
+type VolumeAutoGrowSpec struct {
+	// The percentage of the filesystem that must be used before the volume
+	// grows. Defaults to 80.
+	// +optional
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	ThresholdPercent *int32 `json:"thresholdPercent,omitempty"`
+
+	// How much the volume grows each time, as a percentage of its current
+	// size. Defaults to 25.
+	// +optional
+	// +kubebuilder:default=25
+	// +kubebuilder:validation:Minimum=1
+	GrowthPercent *int32 `json:"growthPercent,omitempty"`
+
+	// The largest size to which the volume can grow.
+	// +kubebuilder:validation:Required
+	MaxSize resource.Quantity `json:"maxSize"`
+}
+
+// Default sets the default values for any fields that are not set.
+func (s *VolumeAutoGrowSpec) Default() {
+	if s.ThresholdPercent == nil {
+		s.ThresholdPercent = new(int32)
+		*s.ThresholdPercent = 80
+	}
+	if s.GrowthPercent == nil {
+		s.GrowthPercent = new(int32)
+		*s.GrowthPercent = 25
+	}
+}
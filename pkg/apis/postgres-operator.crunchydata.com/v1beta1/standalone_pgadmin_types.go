@@ -0,0 +1,207 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PGAdminSpec defines the desired state of PGAdmin
+type PGAdminSpec struct {
+	// +optional
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// Scheduling constraints of a pgAdmin pod. Changing this value causes
+	// pgAdmin to restart.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Configuration settings for the pgAdmin process. Changes to any of these
+	// values will be loaded without validation. Be careful, as
+	// you may put pgAdmin into an unusable state. OAuth2 and OpenID Connect
+	// login can be enabled using the AUTHENTICATION_SOURCES and OAUTH2_CONFIG
+	// settings.
+	// More info: https://www.pgadmin.org/docs/pgadmin4/latest/oauth2.html
+	// +optional
+	Config PGAdminConfiguration `json:"config,omitempty"`
+
+	// Defines a PersistentVolumeClaim for pgAdmin data.
+	// More info: https://kubernetes.io/docs/concepts/storage/persistent-volumes
+	// +kubebuilder:validation:Required
+	DataVolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"dataVolumeClaimSpec"`
+
+	// Name of a container image that can run pgAdmin 4. Changing this value causes
+	// pgAdmin to restart. The image may also be set using the RELATED_IMAGE_PGADMIN
+	// environment variable.
+	// More info: https://kubernetes.io/docs/concepts/containers/images
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImagePullPolicy is used to determine when Kubernetes will attempt to
+	// pull (download) container images.
+	// More info: https://kubernetes.io/docs/concepts/containers/images/#image-pull-policy
+	// +kubebuilder:validation:Enum={Always,Never,IfNotPresent}
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// The image pull secrets used to pull from a private registry.
+	// Changing this value causes pgAdmin to restart.
+	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/pull-image-private-registry/
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Priority class name for the pgAdmin pod. Changing this value causes pgAdmin
+	// to restart.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	// +optional
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
+
+	// Compute resources of a pgAdmin container. Changing this value causes
+	// pgAdmin to restart.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Groups of PostgresClusters to register in pgAdmin. Each cluster is
+	// registered as a server of the pgAdmin administrator and of every user in
+	// users; users log in to PostgreSQL with their own credentials.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ServerGroups []PGAdminServerGroup `json:"serverGroups,omitempty"`
+
+	// Specification of the service that exposes pgAdmin.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+
+	// Tolerations of a pgAdmin pod. Changing this value causes pgAdmin to restart.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// pgAdmin users that get their own copy of every server in serverGroups.
+	// Servers are registered for a user after that user exists in pgAdmin,
+	// e.g. after their first OAuth2 login.
+	// +listType=map
+	// +listMapKey=username
+	// +optional
+	Users []PGAdminUser `json:"users,omitempty"`
+}
+
+// PGAdminUser is a user of pgAdmin.
+type PGAdminUser struct {
+	// The name the user logs in to pgAdmin with, usually an email address.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+}
+
+// PGAdminServerGroup selects PostgresClusters to register in pgAdmin under
+// one server group.
+type PGAdminServerGroup struct {
+	// The name of the server group in pgAdmin.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Selects the namespaces in which to look for PostgresClusters. When
+	// omitted, only the namespace of this PGAdmin is searched. An empty
+	// selector matches every namespace the operator watches.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Selects the PostgresClusters to register. An empty selector matches
+	// every PostgresCluster in the selected namespaces.
+	// +kubebuilder:validation:Required
+	PostgresClusterSelector metav1.LabelSelector `json:"postgresClusterSelector"`
+}
+
+// PGAdminStatus defines the observed state of PGAdmin
+type PGAdminStatus struct {
+
+	// The PostgresClusters registered in each server group, as
+	// "namespace/name".
+	// +optional
+	ServerGroups []PGAdminServerGroupStatus `json:"serverGroups,omitempty"`
+
+	// The server groups that PGO last registered in pgAdmin. Groups that are
+	// removed from the spec are deleted from pgAdmin along with their servers.
+	// +optional
+	RegisteredServerGroups []string `json:"registeredServerGroups,omitempty"`
+
+	// Hash that indicates which servers have been registered in pgAdmin.
+	// +optional
+	ServersRevision string `json:"serversRevision,omitempty"`
+
+	// observedGeneration represents the .metadata.generation on which the status was based.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// PGAdminServerGroupStatus lists the PostgresClusters registered in a pgAdmin
+// server group.
+type PGAdminServerGroupStatus struct {
+	Name string `json:"name"`
+
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Whether or not the namespaceSelector of this group is ignored because
+	// the operator is installed for a single namespace.
+	// +optional
+	NamespaceSelectorIgnored bool `json:"namespaceSelectorIgnored,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// PGAdmin is the Schema for the pgadmins API. It runs one pgAdmin that can
+// connect to many PostgresClusters.
+type PGAdmin struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PGAdminSpec   `json:"spec,omitempty"`
+	Status PGAdminStatus `json:"status,omitempty"`
+}
+
+// Default implements "sigs.k8s.io/controller-runtime/pkg/webhook.Defaulter" so
+// a webhook can be registered for the type.
+// - https://book.kubebuilder.io/reference/webhook-overview.html
+func (p *PGAdmin) Default() {
+	if len(p.APIVersion) == 0 {
+		p.APIVersion = GroupVersion.String()
+	}
+	if len(p.Kind) == 0 {
+		p.Kind = "PGAdmin"
+	}
+}
+
+// +kubebuilder:object:root=true
+
+// PGAdminList contains a list of PGAdmin
+type PGAdminList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PGAdmin `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PGAdmin{}, &PGAdminList{})
+}
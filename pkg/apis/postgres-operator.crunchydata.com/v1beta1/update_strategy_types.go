@@ -0,0 +1,105 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PostgresUpdateStrategySpec defines how changes to the PostgreSQL image are
+// rolled out to instances.
+type PostgresUpdateStrategySpec struct {
+
+	// The type of update. "Rolling" replaces one instance at a time, replicas
+	// before the primary, as soon as the previous one is ready. "Verified"
+	// also waits for each replica to stream from the primary with acceptable
+	// lag before replacing the next instance, and it reverts to the previous
+	// image when an instance does not become healthy in time.
+	// +kubebuilder:default=Rolling
+	// +kubebuilder:validation:Enum={Rolling,Verified}
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// How long an instance can take to become healthy with a new image before
+	// the update is reverted. Applies to the "Verified" type.
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=30
+	// +optional
+	HealthCheckTimeoutSeconds *int32 `json:"healthCheckTimeoutSeconds,omitempty"`
+
+	// The most WAL that a healthy replica has yet to replay. Applies to the
+	// "Verified" type.
+	// +kubebuilder:default="16Mi"
+	// +optional
+	MaxReplicationLag *resource.Quantity `json:"maxReplicationLag,omitempty"`
+}
+
+const (
+	PostgresUpdateStrategyRolling  = "Rolling"
+	PostgresUpdateStrategyVerified = "Verified"
+)
+
+// PostgresImageRolloutStatus records a change to the PostgreSQL image of a
+// cluster.
+type PostgresImageRolloutStatus struct {
+
+	// The image that instances ran before the change.
+	FromImage string `json:"fromImage"`
+
+	// The image that instances are changing to.
+	ToImage string `json:"toImage"`
+
+	// The phase of the change: Progressing, Verifying, Complete, RolledBack, or
+	// Superseded.
+	Phase string `json:"phase"`
+
+	// Instances that run the new image and passed their health checks.
+	// +listType=atomic
+	// +optional
+	Verified []string `json:"verified,omitempty"`
+
+	// Details about the phase, such as why the change was reverted.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the change started.
+	StartTime metav1.Time `json:"startTime"`
+
+	// When the change completed, was reverted, or was superseded.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+const (
+	// PostgresImageRolloutProgressing means instances are being replaced.
+	PostgresImageRolloutProgressing = "Progressing"
+
+	// PostgresImageRolloutVerifying means no more instances will be replaced
+	// until those with the new image are healthy.
+	PostgresImageRolloutVerifying = "Verifying"
+
+	// PostgresImageRolloutComplete means every instance runs the new image.
+	PostgresImageRolloutComplete = "Complete"
+
+	// PostgresImageRolloutRolledBack means an instance was not healthy with
+	// the new image, and instances are returning to the previous image.
+	PostgresImageRolloutRolledBack = "RolledBack"
+
+	// PostgresImageRolloutSuperseded means the image changed again before
+	// this change completed.
+	PostgresImageRolloutSuperseded = "Superseded"
+)
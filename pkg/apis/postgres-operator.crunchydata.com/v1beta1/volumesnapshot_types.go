@@ -0,0 +1,87 @@
+/*
+ Copyright 2021 - 2022 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VolumeSnapshotBackups defines backups that take CSI VolumeSnapshots of the
+// data and WAL volumes of the primary PostgreSQL instance.
+// More info: https://docs.k8s.io/concepts/storage/volume-snapshots/
+type VolumeSnapshotBackups struct {
+
+	// The VolumeSnapshotClass used to take snapshots. It must belong to the
+	// CSI driver that provisions the PostgreSQL volumes.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName"`
+
+	// Minutes between snapshots. When not set, snapshots are taken only when
+	// the "postgres-operator.crunchydata.com/volume-snapshot" annotation of
+	// the PostgresCluster changes.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	IntervalMinutes *int32 `json:"intervalMinutes,omitempty"`
+
+	// Number of complete snapshot backups to keep.
+	// +optional
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	Retention *int32 `json:"retention,omitempty"`
+
+	// Whether or not new replicas are provisioned from the most recent ready
+	// snapshot rather than copied from pgBackRest or the primary.
+	// +optional
+	// +kubebuilder:default=true
+	BootstrapReplicas *bool `json:"bootstrapReplicas,omitempty"`
+}
+
+// Default sets the default values for any fields that are not set.
+func (s *VolumeSnapshotBackups) Default() {
+	if s.Retention == nil {
+		s.Retention = new(int32)
+		*s.Retention = 3
+	}
+	if s.BootstrapReplicas == nil {
+		s.BootstrapReplicas = new(bool)
+		*s.BootstrapReplicas = true
+	}
+}
+
+// VolumeSnapshotBackupStatus describes one snapshot backup.
+type VolumeSnapshotBackupStatus struct {
+
+	// Name of the backup. Every VolumeSnapshot of the backup has this value
+	// in its "postgres-operator.crunchydata.com/volume-snapshot" label.
+	Name string `json:"name"`
+
+	// Name of the VolumeSnapshot of the data volume.
+	DataVolumeSnapshot string `json:"dataVolumeSnapshot"`
+
+	// Name of the VolumeSnapshot of the WAL volume, if any.
+	// +optional
+	WALVolumeSnapshot string `json:"walVolumeSnapshot,omitempty"`
+
+	// The instance set of the primary when the backup was taken.
+	InstanceSet string `json:"instanceSet"`
+
+	// When PostgreSQL started the backup.
+	StartTime metav1.Time `json:"startTime"`
+
+	// Whether or not every VolumeSnapshot of the backup can provision volumes.
+	ReadyToUse bool `json:"readyToUse"`
+}